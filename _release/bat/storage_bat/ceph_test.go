@@ -25,10 +25,12 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-var driver storage.CephDriver
+var driver storage.BlockDriver
 
 type storageConfig struct {
-	CephID string `yaml:"ceph_id"`
+	CephID    string `yaml:"ceph_id"`
+	Driver    string `yaml:"driver"`
+	LocalPath string `yaml:"local_path"`
 }
 
 type configuration struct {
@@ -37,13 +39,13 @@ type configuration struct {
 	} `yaml:"configure"`
 }
 
-// Check creating a ceph backed block device works
+// Check creating a block device works
 //
 // TestCreateBlockDevice creates a block device containing some random data,
 // checks for errors and then deletes it.
 func TestCreateBlockDevice(t *testing.T) {
-	if driver.ID == "" {
-		t.Skip("Skipping test: Ceph ID not set")
+	if driver == nil {
+		t.Skip("Skipping test: storage driver not configured")
 	}
 
 	path, err := bat.CreateRandomFile(20)
@@ -63,13 +65,13 @@ func TestCreateBlockDevice(t *testing.T) {
 	}
 }
 
-// Test creating a sized ceph backed block device.
+// Test creating a sized block device.
 //
 // TestCreateSizedBlockDevice creates a block device of a fixed size, checking
 // for errors and then checks that the size is a expected.
 func TestCreateSizedBlockDevice(t *testing.T) {
-	if driver.ID == "" {
-		t.Skip("Skipping test: Ceph ID not set")
+	if driver == nil {
+		t.Skip("Skipping test: storage driver not configured")
 	}
 
 	device, err := driver.CreateBlockDevice("", "", 1)
@@ -92,14 +94,14 @@ func TestCreateSizedBlockDevice(t *testing.T) {
 	}
 }
 
-// Check copying a ceph backed block device works
+// Check copying a block device works
 //
 // TestCopyBlockDevice creates a block device containing some random data,
 // checks for errors and then copies it. The created volumes are then
 // deleted.
 func TestCopyBlockDevice(t *testing.T) {
-	if driver.ID == "" {
-		t.Skip("Skipping test: Ceph ID not set")
+	if driver == nil {
+		t.Skip("Skipping test: storage driver not configured")
 	}
 
 	path, err := bat.CreateRandomFile(20)
@@ -129,6 +131,24 @@ func TestCopyBlockDevice(t *testing.T) {
 	}
 }
 
+// newDriver returns the block driver the cluster is configured to use, or
+// nil if there is none.
+func newDriver(config storageConfig) storage.BlockDriver {
+	if config.Driver == "local" {
+		path := config.LocalPath
+		if path == "" {
+			path = storage.DefaultLocalPath
+		}
+		return storage.LocalDriver{Path: path}
+	}
+
+	if config.CephID == "" {
+		return nil
+	}
+
+	return storage.CephDriver{ID: config.CephID}
+}
+
 func TestMain(m *testing.M) {
 	var config configuration
 	data, err := ioutil.ReadFile("/etc/ciao/configuration.yaml")
@@ -139,7 +159,7 @@ func TestMain(m *testing.M) {
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing config file: %v", err)
 		} else {
-			driver.ID = config.Configure.Storage.CephID
+			driver = newDriver(config.Configure.Storage)
 		}
 	}
 
@@ -26,6 +26,7 @@
 package main
 
 import (
+	"bytes"
 	"crypto/x509"
 	"encoding/pem"
 	"flag"
@@ -33,6 +34,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/tabwriter"
 
@@ -50,8 +52,14 @@ var (
 	organization = flag.String("organization", "", "Certificates organization")
 	installDir   = flag.String("directory", ".", "Installation directory")
 	dumpCert     = flag.String("dump", "", "Print details about provided certificate")
+	crl          = flag.String("crl", "", "Certificate revocation list to update")
 )
 
+var subcommands = map[string]func(args []string){
+	"revoke": revokeCertificates,
+	"renew":  renewCertificates,
+}
+
 func verifyCert(CACert string, certName string) {
 	if *isAnchor == true || *verify == false {
 		return
@@ -198,10 +206,139 @@ func dumpCertificate(certName string) {
 	w.Flush()
 }
 
+// writeFile atomically replaces a file, so that SSNTP clients and
+// servers watching it never load a partially written one.
+func writeFile(name string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name))
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func revokeCertificates(certNames []string) {
+	if *anchorCert == "" {
+		log.Fatalf("Missing required --anchor-cert parameter")
+	}
+
+	if *crl == "" {
+		log.Fatalf("Missing required --crl parameter")
+	}
+
+	if len(certNames) == 0 {
+		log.Fatalf("Missing certificates to revoke")
+	}
+
+	bytesAnchorCert, err := ioutil.ReadFile(*anchorCert)
+	if err != nil {
+		log.Fatalf("Could not load %s: %v", *anchorCert, err)
+	}
+
+	bytesCRL, err := ioutil.ReadFile(*crl)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("Could not load %s: %v", *crl, err)
+	}
+
+	var revoked [][]byte
+	for _, certName := range certNames {
+		bytesCert, err := ioutil.ReadFile(certName)
+		if err != nil {
+			log.Fatalf("Could not load %s: %v", certName, err)
+		}
+		revoked = append(revoked, bytesCert)
+	}
+
+	var crlOut bytes.Buffer
+	err = certs.RevokeCerts(bytesAnchorCert, bytesCRL, revoked, &crlOut)
+	if err != nil {
+		log.Fatalf("Failed to revoke certificates: %v", err)
+	}
+
+	err = writeFile(*crl, crlOut.Bytes())
+	if err != nil {
+		log.Fatalf("Failed to write %s: %v", *crl, err)
+	}
+
+	serials, err := certs.RevokedSerials(crlOut.Bytes())
+	if err != nil {
+		log.Fatalf("Failed to parse %s: %v", *crl, err)
+	}
+
+	fmt.Printf("--------------------------------------------------------\n")
+	fmt.Printf("CRL: [%s]\n", *crl)
+	for _, serial := range serials {
+		fmt.Printf("Revoked serial: %s\n", serial)
+	}
+	fmt.Printf("--------------------------------------------------------\n")
+	fmt.Printf("You should now copy \"%s\" to the location passed to ", *crl)
+	fmt.Printf("your SSNTP servers through their Config CRL field.\n")
+}
+
+func renewCertificates(certNames []string) {
+	if *anchorCert == "" {
+		log.Fatalf("Missing required --anchor-cert parameter")
+	}
+
+	if len(certNames) == 0 {
+		log.Fatalf("Missing certificates to renew")
+	}
+
+	bytesAnchorCert, err := ioutil.ReadFile(*anchorCert)
+	if err != nil {
+		log.Fatalf("Could not load %s: %v", *anchorCert, err)
+	}
+
+	for _, certName := range certNames {
+		bytesCert, err := ioutil.ReadFile(certName)
+		if err != nil {
+			log.Fatalf("Could not load %s: %v", certName, err)
+		}
+
+		var certOut bytes.Buffer
+		err = certs.RenewCert(bytesAnchorCert, bytesCert, &certOut)
+		if err != nil {
+			log.Fatalf("Failed to renew %s: %v", certName, err)
+		}
+
+		renewedName := filepath.Join(*installDir, filepath.Base(certName))
+		err = writeFile(renewedName, certOut.Bytes())
+		if err != nil {
+			log.Fatalf("Failed to write %s: %v", renewedName, err)
+		}
+
+		fmt.Printf("Renewed certificate: [%s]\n", renewedName)
+	}
+
+	fmt.Printf("SSNTP clients and servers pick renewed certificates up ")
+	fmt.Printf("without restarting.\n")
+}
+
 func main() {
 	var role ssntp.Role
 
 	flag.Var(&role, "role", "Comma separated list of SSNTP role [agent, scheduler, controller, netagent, server, cnciagent]")
+
+	if len(os.Args) > 1 {
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			_ = flag.CommandLine.Parse(os.Args[2:])
+			subcommand(flag.Args())
+			return
+		}
+	}
+
 	flag.Parse()
 
 	if *dumpCert != "" {
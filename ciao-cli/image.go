@@ -43,6 +43,8 @@ type imageAddCommand struct {
 	file       string
 	template   string
 	visibility string
+	diskFormat string
+	checksum   string
 }
 
 func (cmd *imageAddCommand) usage(...string) {
@@ -65,6 +67,10 @@ func (cmd *imageAddCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
 	cmd.Flag.StringVar(&cmd.visibility, "visibility", string(types.Private),
 		"Image visibility (internal,public,private)")
+	cmd.Flag.StringVar(&cmd.diskFormat, "disk-format", "",
+		"Format of the image file (raw,qcow2,vmdk,vhdx), probed if not set")
+	cmd.Flag.StringVar(&cmd.checksum, "checksum", "",
+		"Expected checksum of the image file ([md5|sha1|sha256|sha512:]hex digest)")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
@@ -95,7 +101,14 @@ func (cmd *imageAddCommand) run(args []string) error {
 		}
 	}
 
-	id, err := c.CreateImage(cmd.name, imageVisibility, cmd.id, f)
+	diskFormat := types.DiskFormat(cmd.diskFormat)
+	switch diskFormat {
+	case "", types.Raw, types.QCOW2, types.VMDK, types.VHDX:
+	default:
+		fatalf("Invalid disk format [%v]", diskFormat)
+	}
+
+	id, err := c.CreateImage(cmd.name, imageVisibility, cmd.id, diskFormat, cmd.checksum, f)
 	if err != nil {
 		return errors.Wrap(err, "Error creating image")
 	}
@@ -257,6 +270,15 @@ func (cmd *imageDeleteCommand) run(args []string) error {
 func dumpImage(i *types.Image) {
 	fmt.Printf("\tName\t\t[%s]\n", i.Name)
 	fmt.Printf("\tSize\t\t[%d bytes]\n", i.Size)
+	if i.VirtualSize != 0 {
+		fmt.Printf("\tVirtualSize\t[%d bytes]\n", i.VirtualSize)
+	}
+	if i.DiskFormat != "" {
+		fmt.Printf("\tDiskFormat\t[%s]\n", i.DiskFormat)
+	}
+	if i.Checksum != "" {
+		fmt.Printf("\tChecksum\t[%s]\n", i.Checksum)
+	}
 	fmt.Printf("\tID\t\t[%s]\n", i.ID)
 	fmt.Printf("\tState\t\t[%s]\n", i.State)
 	fmt.Printf("\tVisibility\t[%s]\n", i.Visibility)
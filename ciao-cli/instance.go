@@ -17,28 +17,42 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/gorilla/websocket"
 	"github.com/intel/tfortools"
 	"github.com/pkg/errors"
 )
 
 var instanceCommand = &command{
 	SubCommands: map[string]subCommand{
-		"add":     new(instanceAddCommand),
-		"delete":  new(instanceDeleteCommand),
-		"list":    new(instanceListCommand),
-		"show":    new(instanceShowCommand),
-		"restart": new(instanceRestartCommand),
-		"stop":    new(instanceStopCommand),
+		"add":      new(instanceAddCommand),
+		"delete":   new(instanceDeleteCommand),
+		"list":     new(instanceListCommand),
+		"show":     new(instanceShowCommand),
+		"restart":  new(instanceRestartCommand),
+		"stop":     new(instanceStopCommand),
+		"reboot":   new(instanceRebootCommand),
+		"resize":   new(instanceResizeCommand),
+		"evacuate": new(instanceEvacuateCommand),
+		"pause":    &instancePowerCommand{action: "pause"},
+		"unpause":  &instancePowerCommand{action: "unpause"},
+		"suspend":  &instancePowerCommand{action: "suspend"},
+		"resume":   &instancePowerCommand{action: "resume"},
+		"migrate":  &instancePowerCommand{action: "migrate"},
+		"log":      new(instanceLogCommand),
+		"console":  new(instanceConsoleCommand),
 	},
 }
 
@@ -282,10 +296,11 @@ func validateInstanceVolumeSubArgCombo(vols *volumeFlagSlice) error {
 }
 
 // implement the flag.Value interface, eg:
-// type Value interface {
-// 	String() string
-// 	Set(string) error
-// }
+//
+//	type Value interface {
+//		String() string
+//		Set(string) error
+//	}
 func (v *volumeFlagSlice) String() string {
 	var out string
 
@@ -367,13 +382,16 @@ func (v *volumeFlagSlice) Set(value string) error {
 }
 
 type instanceAddCommand struct {
-	Flag      flag.FlagSet
-	workload  string
-	instances int
-	label     string
-	volumes   volumeFlagSlice
-	name      string
-	template  string
+	Flag         flag.FlagSet
+	workload     string
+	instances    int
+	label        string
+	volumes      volumeFlagSlice
+	name         string
+	template     string
+	selector     string
+	affinity     string
+	antiAffinity string
 }
 
 func (cmd *instanceAddCommand) usage(...string) {
@@ -397,6 +415,9 @@ func (cmd *instanceAddCommand) parseArgs(args []string) []string {
 	cmd.Flag.Var(&cmd.volumes, "volume", "volume descriptor argument list")
 	cmd.Flag.StringVar(&cmd.name, "name", "", "Name for this instance. When multiple instances are requested this is used as a prefix")
 	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.StringVar(&cmd.selector, "node-selector", "", "Comma separated key=value node labels the instances must be placed on")
+	cmd.Flag.StringVar(&cmd.affinity, "affinity", "", "Comma separated workload UUIDs whose instances must share a node with the new instances")
+	cmd.Flag.StringVar(&cmd.antiAffinity, "anti-affinity", "", "Comma separated workload UUIDs whose instances must not share a node with the new instances")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
@@ -425,6 +446,16 @@ func (cmd *instanceAddCommand) validateAddCommandArgs() {
 		}
 	}
 
+	if cmd.selector != "" {
+		for _, label := range strings.Split(cmd.selector, ",") {
+			kv := strings.SplitN(label, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				errorf("Invalid value for -node-selector: %s is not key=value", label)
+				cmd.usage()
+			}
+		}
+	}
+
 	for _, volume := range cmd.volumes {
 		//NOTE: volume.uuid itself may only be validated by controller as
 		//only it knows which storage interface is in use and what
@@ -462,6 +493,22 @@ func populateCreateServerRequest(cmd *instanceAddCommand, server *api.CreateServ
 	server.Server.MinInstances = 1
 	server.Server.Name = cmd.name
 
+	if cmd.selector != "" {
+		server.Server.NodeSelector = make(map[string]string)
+		for _, label := range strings.Split(cmd.selector, ",") {
+			kv := strings.SplitN(label, "=", 2)
+			server.Server.NodeSelector[kv[0]] = kv[1]
+		}
+	}
+
+	if cmd.affinity != "" {
+		server.Server.Affinity = strings.Split(cmd.affinity, ",")
+	}
+
+	if cmd.antiAffinity != "" {
+		server.Server.AntiAffinity = strings.Split(cmd.antiAffinity, ",")
+	}
+
 	for _, volume := range cmd.volumes {
 		bd := api.BlockDeviceMapping{
 			DeviceName:          "", //unsupported
@@ -670,6 +717,382 @@ func startStopInstance(instance string, stop bool) error {
 	return nil
 }
 
+type instanceRebootCommand struct {
+	Flag     flag.FlagSet
+	instance string
+	hard     bool
+}
+
+func (cmd *instanceRebootCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance reboot [flags]
+
+Reboot a running Ciao instance
+
+The reboot flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instanceRebootCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.BoolVar(&cmd.hard, "hard", false, "Reset the instance rather than asking it to reboot")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instanceRebootCommand) run([]string) error {
+	err := checkInstanceArgs(cmd.instance)
+	if err != nil {
+		cmd.usage()
+		return err
+	}
+
+	err = c.RebootInstance(cmd.instance, cmd.hard)
+	if err != nil {
+		return errors.Wrap(err, "Error rebooting instance")
+	}
+	fmt.Printf("Instance %s rebooted\n", cmd.instance)
+	return nil
+}
+
+type instanceEvacuateCommand struct {
+	Flag     flag.FlagSet
+	instance string
+	force    bool
+}
+
+func (cmd *instanceEvacuateCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance evacuate [flags]
+
+Restart on another node a Ciao instance whose node has failed.  As a
+disconnected node may still be running the instance, -force must be given
+to confirm that such a node is down.  Only admins may use -force.
+
+The evacuate flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instanceEvacuateCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.BoolVar(&cmd.force, "force", false, "Evacuate the instance from a disconnected node known to be down")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instanceEvacuateCommand) run([]string) error {
+	err := checkInstanceArgs(cmd.instance)
+	if err != nil {
+		cmd.usage()
+		return err
+	}
+
+	err = c.EvacuateInstance(cmd.instance, cmd.force)
+	if err != nil {
+		return errors.Wrap(err, "Error evacuating instance")
+	}
+	fmt.Printf("Instance %s evacuating\n", cmd.instance)
+	return nil
+}
+
+type instanceResizeCommand struct {
+	Flag     flag.FlagSet
+	instance string
+	workload string
+}
+
+func (cmd *instanceResizeCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance resize [flags]
+
+Give a Ciao instance the VCPUs and memory of another workload.  A running
+instance is stopped and restarted with its new resources.
+
+The resize flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instanceResizeCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.StringVar(&cmd.workload, "workload", "", "Workload UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instanceResizeCommand) run([]string) error {
+	err := checkInstanceArgs(cmd.instance)
+	if err != nil {
+		cmd.usage()
+		return err
+	}
+
+	if cmd.workload == "" {
+		cmd.usage()
+		return errors.New("Missing required -workload parameter")
+	}
+
+	err = c.ResizeInstance(cmd.instance, cmd.workload)
+	if err != nil {
+		return errors.Wrap(err, "Error resizing instance")
+	}
+	fmt.Printf("Instance %s resized\n", cmd.instance)
+	return nil
+}
+
+type instanceLogCommand struct {
+	Flag     flag.FlagSet
+	instance string
+	lines    int
+}
+
+func (cmd *instanceLogCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance log [flags]
+
+Show the console log of a Ciao VM instance, i.e., what the instance wrote
+to its first serial port.
+
+The log flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instanceLogCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.IntVar(&cmd.lines, "lines", 0, "Number of lines to show, 0 for all available lines")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instanceLogCommand) run([]string) error {
+	err := checkInstanceArgs(cmd.instance)
+	if err != nil {
+		cmd.usage()
+		return err
+	}
+
+	if cmd.lines < 0 {
+		cmd.usage()
+		return errors.New("Invalid -lines parameter")
+	}
+
+	log, err := c.GetInstanceConsoleLog(cmd.instance, cmd.lines)
+	if err != nil {
+		return errors.Wrap(err, "Error getting instance console log")
+	}
+	fmt.Print(log)
+	return nil
+}
+
+// consoleEscape, Ctrl-], closes an interactive console.
+const consoleEscape = 0x1d
+
+type instanceConsoleCommand struct {
+	Flag     flag.FlagSet
+	instance string
+}
+
+func (cmd *instanceConsoleCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance console [flags]
+
+Connect to the serial console of a running Ciao VM instance.  Press Ctrl-]
+to disconnect.
+
+The console flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instanceConsoleCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+// makeTerminalRaw puts the terminal attached to stdin, if any, in raw mode
+// so that every key press is sent to the console, and returns a function
+// restoring its previous mode.
+func makeTerminalRaw() func() {
+	stty := func(args ...string) (string, error) {
+		c := exec.Command("stty", args...)
+		c.Stdin = os.Stdin
+		out, err := c.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+
+	state, err := stty("-g")
+	if err != nil {
+		return func() {}
+	}
+
+	if _, err := stty("raw", "-echo"); err != nil {
+		return func() {}
+	}
+
+	return func() { _, _ = stty(state) }
+}
+
+func (cmd *instanceConsoleCommand) run([]string) error {
+	err := checkInstanceArgs(cmd.instance)
+	if err != nil {
+		cmd.usage()
+		return err
+	}
+
+	ws, err := c.OpenInstanceConsole(cmd.instance)
+	if err != nil {
+		return errors.Wrap(err, "Error opening instance console")
+	}
+	defer func() { _ = ws.Close() }()
+
+	fmt.Fprintf(os.Stderr, "Connected to the console of %s. Press Ctrl-] to disconnect.\n",
+		cmd.instance)
+
+	restore := makeTerminalRaw()
+	defer restore()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				break
+			}
+			_, _ = os.Stdout.Write(data)
+		}
+		done <- struct{}{}
+	}()
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := os.Stdin.Read(buf)
+			data := buf[:n]
+			escape := bytes.IndexByte(data, consoleEscape)
+			if escape >= 0 {
+				data = data[:escape]
+			}
+			if len(data) > 0 {
+				if ws.WriteMessage(websocket.BinaryMessage, data) != nil {
+					break
+				}
+			}
+			if escape >= 0 || err != nil {
+				break
+			}
+		}
+		done <- struct{}{}
+	}()
+
+	<-done
+
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+
+	restore()
+	fmt.Fprintf(os.Stderr, "\nDisconnected from the console of %s\n", cmd.instance)
+	return nil
+}
+
+var instancePowerActions = map[string]struct {
+	description string
+	done        string
+	do          func(string) error
+}{
+	"pause": {
+		description: "Pause a running Ciao instance",
+		done:        "paused",
+		do:          func(i string) error { return c.PauseInstance(i) },
+	},
+	"unpause": {
+		description: "Unpause a paused Ciao instance",
+		done:        "unpaused",
+		do:          func(i string) error { return c.UnpauseInstance(i) },
+	},
+	"suspend": {
+		description: "Suspend a running Ciao instance to disk",
+		done:        "suspended",
+		do:          func(i string) error { return c.SuspendInstance(i) },
+	},
+	"resume": {
+		description: "Resume a suspended Ciao instance",
+		done:        "resumed",
+		do:          func(i string) error { return c.ResumeInstance(i) },
+	},
+	"migrate": {
+		description: "Live migrate a running Ciao instance to another node",
+		done:        "migrating",
+		do:          func(i string) error { return c.MigrateInstance(i) },
+	},
+}
+
+type instancePowerCommand struct {
+	Flag     flag.FlagSet
+	instance string
+	action   string
+}
+
+func (cmd *instancePowerCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance %s [flags]
+
+%s
+
+The %s flags are:
+
+`, cmd.action, instancePowerActions[cmd.action].description, cmd.action)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instancePowerCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instancePowerCommand) run([]string) error {
+	err := checkInstanceArgs(cmd.instance)
+	if err != nil {
+		cmd.usage()
+		return err
+	}
+
+	action := instancePowerActions[cmd.action]
+	err = action.do(cmd.instance)
+	if err != nil {
+		return errors.Wrapf(err, "Error performing %s on instance", cmd.action)
+	}
+	fmt.Printf("Instance %s %s\n", cmd.instance, action.done)
+	return nil
+}
+
+func checkInstanceArgs(instance string) error {
+	if c.TenantID == "" {
+		return errors.New("Missing required -tenant-id parameter")
+	}
+
+	if instance == "" {
+		return errors.New("Missing required -instance parameter")
+	}
+
+	return nil
+}
+
 type instanceListCommand struct {
 	Flag     flag.FlagSet
 	workload string
@@ -816,6 +1239,8 @@ func dumpInstance(server *api.ServerDetails) {
 	fmt.Printf("\tMAC Address: %s\n", server.PrivateAddresses[0].MacAddr)
 	fmt.Printf("\tCN UUID: %s\n", server.NodeID)
 	fmt.Printf("\tTenant UUID: %s\n", server.TenantID)
+	fmt.Printf("\tVCPUs: %d\n", server.VCPUs)
+	fmt.Printf("\tMemory: %d MB\n", server.MemMB)
 	if server.SSHIP != "" {
 		fmt.Printf("\tSSH IP: %s\n", server.SSHIP)
 		fmt.Printf("\tSSH Port: %d\n", server.SSHPort)
@@ -338,14 +338,22 @@ func showCNCINode(cmd *nodeShowCommand) error {
 }
 
 type nodeEvacuateCommand struct {
-	Flag   flag.FlagSet
-	nodeID string
+	Flag    flag.FlagSet
+	nodeID  string
+	rebuild bool
+	force   bool
 }
 
 func (cmd *nodeEvacuateCommand) usage(...string) {
 	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] node evacuate
 
-Evacuate a node
+Evacuate a node.  Running VM instances are live migrated to other nodes and
+the remaining instances are stopped.  If -rebuild is specified the stopped
+instances are restarted on other nodes.  -rebuild can also be used to
+restart the instances of a node that has failed.  As a disconnected node
+may still be running its instances, -force must be given as well to confirm
+that such a node is down.  The node is asked to delete its copies of the
+rebuilt instances if it reconnects.
 
 The evacuate flags are:
 `)
@@ -355,12 +363,18 @@ The evacuate flags are:
 
 func (cmd *nodeEvacuateCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.nodeID, "node-id", "", "Node ID")
+	cmd.Flag.BoolVar(&cmd.rebuild, "rebuild", false, "Restart the instances of the node on other nodes")
+	cmd.Flag.BoolVar(&cmd.force, "force", false, "Rebuild the instances of a disconnected node known to be down")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
 }
 
 func (cmd *nodeEvacuateCommand) run(args []string) error {
+	if cmd.rebuild {
+		return c.RebuildNode(cmd.nodeID, cmd.force)
+	}
+
 	return c.ChangeNodeStatus(cmd.nodeID, types.NodeStatusMaintenance)
 }
 
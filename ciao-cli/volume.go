@@ -32,15 +32,51 @@ import (
 
 var volumeCommand = &command{
 	SubCommands: map[string]subCommand{
-		"add":    new(volumeAddCommand),
-		"list":   new(volumeListCommand),
-		"show":   new(volumeShowCommand),
-		"delete": new(volumeDeleteCommand),
-		"attach": new(volumeAttachCommand),
-		"detach": new(volumeDetachCommand),
+		"add":      new(volumeAddCommand),
+		"list":     new(volumeListCommand),
+		"show":     new(volumeShowCommand),
+		"delete":   new(volumeDeleteCommand),
+		"attach":   new(volumeAttachCommand),
+		"detach":   new(volumeDetachCommand),
+		"extend":   new(volumeExtendCommand),
+		"snapshot": volumeSnapshotCmd,
 	},
 }
 
+// volumeSnapshotCommand dispatches the "volume snapshot" sub-commands.
+type volumeSnapshotCommand struct {
+	command
+	subCmd subCommand
+}
+
+var volumeSnapshotCmd = &volumeSnapshotCommand{
+	command: command{
+		SubCommands: map[string]subCommand{
+			"add":    new(volumeSnapshotAddCommand),
+			"list":   new(volumeSnapshotListCommand),
+			"show":   new(volumeSnapshotShowCommand),
+			"delete": new(volumeSnapshotDeleteCommand),
+		},
+	},
+}
+
+func (cmd *volumeSnapshotCommand) parseArgs(args []string) []string {
+	if len(args) < 1 {
+		cmd.usage("volume snapshot")
+	}
+
+	cmd.subCmd = cmd.SubCommands[args[0]]
+	if cmd.subCmd == nil {
+		cmd.usage("volume snapshot")
+	}
+
+	return cmd.subCmd.parseArgs(args[1:])
+}
+
+func (cmd *volumeSnapshotCommand) run(args []string) error {
+	return cmd.subCmd.run(args)
+}
+
 type volumeAddCommand struct {
 	Flag        flag.FlagSet
 	size        int
@@ -65,7 +101,7 @@ The add flags are:
 func (cmd *volumeAddCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.name, "name", "", "Volume name")
 	cmd.Flag.StringVar(&cmd.sourceType, "source_type", "image", "The type of the source to clone from")
-	cmd.Flag.StringVar(&cmd.source, "source", "", "ID of image or volume to clone from")
+	cmd.Flag.StringVar(&cmd.source, "source", "", "ID of image, volume or snapshot to clone from")
 	cmd.Flag.IntVar(&cmd.size, "size", 1, "Size of the volume in GB")
 	cmd.Flag.StringVar(&cmd.description, "description", "", "Volume description")
 	cmd.Flag.Usage = func() { cmd.usage() }
@@ -84,6 +120,8 @@ func (cmd *volumeAddCommand) run(args []string) error {
 		createReq.ImageRef = cmd.source
 	} else if cmd.sourceType == "volume" {
 		createReq.SourceVolID = cmd.source
+	} else if cmd.sourceType == "snapshot" {
+		createReq.SnapshotID = cmd.source
 	} else {
 		fatalf("Unknown source type [%s]\n", cmd.sourceType)
 	}
@@ -306,8 +344,9 @@ func (cmd *volumeAttachCommand) run(args []string) error {
 }
 
 type volumeDetachCommand struct {
-	Flag   flag.FlagSet
-	volume string
+	Flag       flag.FlagSet
+	volume     string
+	attachment string
 }
 
 func (cmd *volumeDetachCommand) usage(...string) {
@@ -323,6 +362,7 @@ The detach flags are:
 
 func (cmd *volumeDetachCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.volume, "volume", "", "Volume UUID")
+	cmd.Flag.StringVar(&cmd.attachment, "attachment", "", "Attachment UUID (optional)")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
@@ -334,7 +374,7 @@ func (cmd *volumeDetachCommand) run(args []string) error {
 		cmd.usage()
 	}
 
-	err := c.DetachVolume(cmd.volume)
+	err := c.DetachVolume(cmd.volume, cmd.attachment)
 	if err != nil {
 		return errors.Wrap(err, "Error detaching volume")
 	}
@@ -345,6 +385,52 @@ func (cmd *volumeDetachCommand) run(args []string) error {
 	return err
 }
 
+type volumeExtendCommand struct {
+	Flag   flag.FlagSet
+	volume string
+	size   int
+}
+
+func (cmd *volumeExtendCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] volume extend [flags]
+
+Extends a volume.  Running instances to which the volume is attached are
+informed of its new size.
+
+The extend flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *volumeExtendCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.volume, "volume", "", "Volume UUID")
+	cmd.Flag.IntVar(&cmd.size, "size", 0, "New size of the volume in GiB")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *volumeExtendCommand) run(args []string) error {
+	if cmd.volume == "" {
+		errorf("missing required -volume parameter")
+		cmd.usage()
+	}
+
+	if cmd.size <= 0 {
+		errorf("missing required -size parameter")
+		cmd.usage()
+	}
+
+	err := c.ExtendVolume(cmd.volume, cmd.size)
+	if err != nil {
+		return errors.Wrap(err, "Error extending volume")
+	}
+
+	fmt.Printf("Extended volume: %s to %d GB\n", cmd.volume, cmd.size)
+	return nil
+}
+
 func dumpVolume(v *types.Volume) {
 	fmt.Printf("\tName             [%s]\n", v.Name)
 	fmt.Printf("\tSize             [%d GB]\n", v.Size)
@@ -354,3 +440,196 @@ func dumpVolume(v *types.Volume) {
 	fmt.Printf("\tState            [%s]\n", v.State)
 	fmt.Printf("\tDescription      [%s]\n", v.Description)
 }
+
+type volumeSnapshotAddCommand struct {
+	Flag        flag.FlagSet
+	volume      string
+	name        string
+	description string
+}
+
+func (cmd *volumeSnapshotAddCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] volume snapshot add [flags]
+
+Take a point in time snapshot of a volume
+
+The add flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *volumeSnapshotAddCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.volume, "volume", "", "Volume UUID")
+	cmd.Flag.StringVar(&cmd.name, "name", "", "Snapshot name")
+	cmd.Flag.StringVar(&cmd.description, "description", "", "Snapshot description")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *volumeSnapshotAddCommand) run(args []string) error {
+	if cmd.volume == "" {
+		errorf("missing required -volume parameter")
+		cmd.usage()
+	}
+
+	req := api.RequestedSnapshot{
+		VolumeID:    cmd.volume,
+		Name:        cmd.name,
+		Description: cmd.description,
+	}
+
+	snapshot, err := c.CreateVolumeSnapshot(req)
+	if err != nil {
+		return errors.Wrap(err, "Error creating volume snapshot")
+	}
+
+	fmt.Printf("Created new volume snapshot: %s\n", snapshot.ID)
+
+	return nil
+}
+
+type volumeSnapshotListCommand struct {
+	Flag     flag.FlagSet
+	volume   string
+	template string
+}
+
+func (cmd *volumeSnapshotListCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] volume snapshot list [flags]
+
+List all volume snapshots
+
+The list flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n%s", tfortools.GenerateUsageDecorated("f", []types.VolumeSnapshot{}, nil))
+	os.Exit(2)
+}
+
+func (cmd *volumeSnapshotListCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.volume, "volume", "", "Only list the snapshots of this volume UUID")
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *volumeSnapshotListCommand) run(args []string) error {
+	snapshots, err := c.ListVolumeSnapshots(cmd.volume)
+	if err != nil {
+		return errors.Wrap(err, "Error listing volume snapshots")
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreateTime.Before(snapshots[j].CreateTime)
+	})
+
+	if cmd.template != "" {
+		return tfortools.OutputToTemplate(os.Stdout, "volume-snapshot-list", cmd.template,
+			&snapshots, nil)
+	}
+
+	for i, s := range snapshots {
+		fmt.Printf("Snapshot #%d\n", i+1)
+		dumpVolumeSnapshot(&s)
+		fmt.Printf("\n")
+	}
+
+	return nil
+}
+
+type volumeSnapshotShowCommand struct {
+	Flag     flag.FlagSet
+	snapshot string
+	template string
+}
+
+func (cmd *volumeSnapshotShowCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] volume snapshot show [flags]
+
+Show information about a volume snapshot
+
+The show flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n%s", tfortools.GenerateUsageDecorated("f", types.VolumeSnapshot{}, nil))
+	os.Exit(2)
+}
+
+func (cmd *volumeSnapshotShowCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.snapshot, "snapshot", "", "Snapshot UUID")
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *volumeSnapshotShowCommand) run(args []string) error {
+	if cmd.snapshot == "" {
+		errorf("missing required -snapshot parameter")
+		cmd.usage()
+	}
+
+	snapshot, err := c.GetVolumeSnapshot(cmd.snapshot)
+	if err != nil {
+		return errors.Wrap(err, "Error getting volume snapshot")
+	}
+
+	if cmd.template != "" {
+		return tfortools.OutputToTemplate(os.Stdout, "volume-snapshot-show", cmd.template,
+			&snapshot, nil)
+	}
+
+	dumpVolumeSnapshot(&snapshot)
+	return nil
+}
+
+type volumeSnapshotDeleteCommand struct {
+	Flag     flag.FlagSet
+	snapshot string
+}
+
+func (cmd *volumeSnapshotDeleteCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] volume snapshot delete [flags]
+
+Deletes a volume snapshot
+
+The delete flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *volumeSnapshotDeleteCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.snapshot, "snapshot", "", "Snapshot UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *volumeSnapshotDeleteCommand) run(args []string) error {
+	if cmd.snapshot == "" {
+		errorf("missing required -snapshot parameter")
+		cmd.usage()
+	}
+
+	err := c.DeleteVolumeSnapshot(cmd.snapshot)
+	if err != nil {
+		return errors.Wrap(err, "Error deleting volume snapshot")
+	}
+
+	return nil
+}
+
+func dumpVolumeSnapshot(s *types.VolumeSnapshot) {
+	fmt.Printf("\tName             [%s]\n", s.Name)
+	fmt.Printf("\tUUID             [%s]\n", s.ID)
+	fmt.Printf("\tVolume           [%s]\n", s.VolumeID)
+	fmt.Printf("\tSize             [%d GB]\n", s.Size)
+	fmt.Printf("\tCreated          [%s]\n", s.CreateTime)
+	fmt.Printf("\tDescription      [%s]\n", s.Description)
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/ciao-project/ciao/ciao-controller/types"
 	"github.com/ciao-project/ciao/payloads"
@@ -149,11 +151,18 @@ type disk struct {
 	Ephemeral bool    `yaml:"ephemeral"`
 }
 
+// Affinity and AntiAffinity list workload IDs, or "self" for the workload
+// being defined.
 type defaultResources struct {
-	VCPUs int `yaml:"vcpus"`
-	MemMB int `yaml:"mem_mb"`
+	VCPUs        int               `yaml:"vcpus"`
+	MemMB        int               `yaml:"mem_mb"`
+	NodeSelector map[string]string `yaml:"node_selector,omitempty"`
+	Affinity     []string          `yaml:"affinity,omitempty"`
+	AntiAffinity []string          `yaml:"anti_affinity,omitempty"`
 }
 
+const selfWorkload = "self"
+
 // we currently only use the first disk due to lack of support
 // in types.Workload for multiple storage resources.
 type workloadOptions struct {
@@ -255,9 +264,63 @@ func optToReq(opt workloadOptions, req *types.Workload) error {
 	}
 	req.Defaults = append(req.Defaults, r)
 
+	// the controller makes blank affinity rules refer to the new workload
+	affinity := make([]string, len(defaults.Affinity))
+	for i, w := range defaults.Affinity {
+		if w != selfWorkload {
+			affinity[i] = w
+		}
+	}
+
+	antiAffinity := make([]string, len(defaults.AntiAffinity))
+	for i, w := range defaults.AntiAffinity {
+		if w != selfWorkload {
+			antiAffinity[i] = w
+		}
+	}
+
+	req.Defaults = append(req.Defaults,
+		placementResources(defaults.NodeSelector, affinity, antiAffinity)...)
+
 	return nil
 }
 
+func placementResources(nodeSelector map[string]string, affinity []string, antiAffinity []string) []payloads.RequestedResource {
+	var resources []payloads.RequestedResource
+
+	keys := make([]string, 0, len(nodeSelector))
+	for key := range nodeSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		resources = append(resources, payloads.RequestedResource{
+			Type:        payloads.NodeSelector,
+			ValueString: key + "=" + nodeSelector[key],
+			Mandatory:   true,
+		})
+	}
+
+	for _, w := range affinity {
+		resources = append(resources, payloads.RequestedResource{
+			Type:        payloads.Affinity,
+			ValueString: w,
+			Mandatory:   true,
+		})
+	}
+
+	for _, w := range antiAffinity {
+		resources = append(resources, payloads.RequestedResource{
+			Type:        payloads.AntiAffinity,
+			ValueString: w,
+			Mandatory:   true,
+		})
+	}
+
+	return resources
+}
+
 func outputWorkload(w types.Workload) {
 	var opt workloadOptions
 
@@ -270,6 +333,25 @@ func outputWorkload(w types.Workload) {
 			opt.Defaults.VCPUs = d.Value
 		} else if d.Type == payloads.MemMB {
 			opt.Defaults.MemMB = d.Value
+		} else if d.Type == payloads.NodeSelector {
+			label := strings.SplitN(d.ValueString, "=", 2)
+			if len(label) == 2 {
+				if opt.Defaults.NodeSelector == nil {
+					opt.Defaults.NodeSelector = make(map[string]string)
+				}
+				opt.Defaults.NodeSelector[label[0]] = label[1]
+			}
+		} else if d.Type == payloads.Affinity || d.Type == payloads.AntiAffinity {
+			workload := d.ValueString
+			if workload == w.ID {
+				workload = selfWorkload
+			}
+
+			if d.Type == payloads.Affinity {
+				opt.Defaults.Affinity = append(opt.Defaults.Affinity, workload)
+			} else {
+				opt.Defaults.AntiAffinity = append(opt.Defaults.AntiAffinity, workload)
+			}
 		}
 	}
 
@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,11 +30,16 @@ import (
 	"github.com/ciao-project/ciao/uuid"
 	"github.com/golang/glog"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 )
 
 // Port is the default port number for the ciao API.
 const Port = 8889
 
+// EnrollPort is the default port number for the node enrollment API.
+// Unlike the ciao API it does not require a client certificate.
+const EnrollPort = 8890
+
 const (
 	// PoolsV1 is the content-type string for v1 of our pools resource
 	PoolsV1 = "x.ciao.pools.v1"
@@ -58,6 +64,9 @@ const (
 
 	// InstancesV1 is the content-type string for v1 of our intances resource
 	InstancesV1 = "x.ciao.instances.v1"
+
+	// EnrollmentV1 is the content-type string for v1 of our enrollment resource
+	EnrollmentV1 = "x.ciao.enrollment.v1"
 )
 
 // ErrorImage defines all possible image handling errors
@@ -79,13 +88,35 @@ var (
 
 	// ErrQuota is returned when the tenant exceeds its quota
 	ErrQuota = errors.New("Tenant over quota")
+
+	// ErrBadDiskFormat is returned when an image is created with, or its
+	// uploaded data probes as, a disk format that is not supported.
+	ErrBadDiskFormat = errors.New("Unsupported disk format, expected raw, qcow2, vmdk or vhdx")
+
+	// ErrBadChecksum is returned when an image is created with a
+	// malformed checksum.
+	ErrBadChecksum = errors.New("Bad checksum, expected [md5|sha1|sha256|sha512:]hex digest")
+
+	// ErrChecksumMismatch is returned when the checksum of the uploaded
+	// image data does not match the one the image was created with.
+	ErrChecksumMismatch = errors.New("Uploaded image data does not match checksum")
+
+	// ErrImageCorrupt is returned when the uploaded image data is not a
+	// valid image of the disk format the image was created with.
+	ErrImageCorrupt = errors.New("Uploaded image data is not a valid image of its disk format")
 )
 
 // CreateImageRequest contains information for a create image request.
+//
+// DiskFormat is probed from the uploaded data when empty.  Checksum is the expected digest of the
+// uploaded data, in the form algorithm:hex where algorithm is one of md5,
+// sha1, sha256 or sha512.  A digest without an algorithm is a sha256 one.
 type CreateImageRequest struct {
 	Name       string           `json:"name,omitempty"`
 	ID         string           `json:"id,omitempty"`
 	Visibility types.Visibility `json:"visibility,omitempty"`
+	DiskFormat types.DiskFormat `json:"disk_format,omitempty"`
+	Checksum   string           `json:"checksum,omitempty"`
 }
 
 // RequestedVolume contains information about a volume to be created.
@@ -95,6 +126,15 @@ type RequestedVolume struct {
 	Description string `json:"description,omitempty"`
 	Name        string `json:"name,omitempty"`
 	ImageRef    string `json:"imageRef,omitempty"`
+	SnapshotID  string `json:"snapshot_id,omitempty"`
+}
+
+// RequestedSnapshot contains information about a volume snapshot to be
+// created.
+type RequestedSnapshot struct {
+	VolumeID    string `json:"volume_id"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 // BlockDeviceMapping represents extra block devices that can be added to an instance
@@ -145,9 +185,47 @@ type CreateServerRequest struct {
 		MinInstances        int                  `json:"min_count"`
 		BlockDeviceMappings []BlockDeviceMapping `json:"block_device_mapping,omitempty"`
 		Metadata            map[string]string    `json:"metadata,omitempty"`
+		NodeSelector        map[string]string    `json:"node_selector,omitempty"`
+		Affinity            []string             `json:"affinity,omitempty"`
+		AntiAffinity        []string             `json:"anti_affinity,omitempty"`
 	} `json:"server"`
 }
 
+const (
+	// RebootSoft asks the guest of an instance to reboot.
+	RebootSoft = "SOFT"
+
+	// RebootHard resets an instance without notifying its guest.
+	RebootHard = "HARD"
+)
+
+// RebootRequest contains the arguments of a reboot instance action.
+type RebootRequest struct {
+	// Type is the type of reboot, either RebootSoft or RebootHard.
+	// A soft reboot is performed if no type is given.
+	Type string `json:"type,omitempty"`
+}
+
+// ResizeRequest contains the arguments of a resize instance action.
+type ResizeRequest struct {
+	// FlavorRef is the ID of the workload whose resources the instance
+	// is to be given.
+	FlavorRef string `json:"flavorRef"`
+}
+
+// EvacuateRequest contains the arguments of an evacuate instance action.
+type EvacuateRequest struct {
+	// Force confirms that the node of the instance is down, allowing the
+	// instance to be rebuilt while that node is disconnected.  Only
+	// privileged users may force an evacuation.
+	Force bool `json:"force,omitempty"`
+}
+
+// ConsoleLogResponse contains the tail of the console log of an instance.
+type ConsoleLogResponse struct {
+	Output string `json:"output"`
+}
+
 // PrivateAddresses contains information about a single instance network
 // interface.
 type PrivateAddresses struct {
@@ -168,6 +246,8 @@ type ServerDetails struct {
 	TenantID         string             `json:"tenant_id"`
 	SSHIP            string             `json:"ssh_ip"`
 	SSHPort          int                `json:"ssh_port"`
+	VCPUs            int                `json:"vcpus"`
+	MemMB            int                `json:"mem_mb"`
 }
 
 // Servers holds multiple servers including a count
@@ -223,7 +303,9 @@ func errorResponse(err error) Response {
 		types.ErrTenantNotFound,
 		types.ErrAddressNotFound,
 		types.ErrInstanceNotFound,
-		types.ErrWorkloadNotFound:
+		types.ErrWorkloadNotFound,
+		types.ErrEnrollmentNotFound,
+		types.ErrSnapshotNotFound:
 		return Response{http.StatusNotFound, nil}
 
 	case types.ErrQuota,
@@ -236,9 +318,20 @@ func errorResponse(err error) Response {
 		types.ErrBadRequest,
 		types.ErrPoolEmpty,
 		types.ErrDuplicatePoolName,
-		types.ErrWorkloadInUse:
+		types.ErrWorkloadInUse,
+		types.ErrEnrollmentDisabled,
+		types.ErrInvalidEnrollmentToken,
+		types.ErrEnrollmentRejected,
+		types.ErrVolumeHasSnapshots,
+		types.ErrNodeNotFenced:
 		return Response{http.StatusForbidden, nil}
 
+	case ErrBadDiskFormat,
+		ErrBadChecksum,
+		ErrChecksumMismatch,
+		ErrImageCorrupt:
+		return Response{http.StatusBadRequest, nil}
+
 	default:
 		return Response{http.StatusInternalServerError, nil}
 	}
@@ -267,6 +360,12 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	contentType := r.Header.Get("Content-Type")
 
 	resp, err := h.Handler(h.Context, w, r)
+
+	// the handler has taken over the connection, e.g., for a websocket.
+	if err == nil && resp.status == http.StatusSwitchingProtocols {
+		return
+	}
+
 	if err != nil {
 		data := HTTPErrorData{
 			Code:    resp.status,
@@ -827,6 +926,43 @@ func changeNodeStatus(c *Context, w http.ResponseWriter, r *http.Request) (Respo
 	return Response{http.StatusNoContent, nil}, nil
 }
 
+// evacuateNode places a node into maintenance mode.  If the rebuild query
+// parameter is true, the instances of the node that cannot be live migrated
+// are restarted on other nodes.  A disconnected node may only be rebuilt if
+// the force query parameter is also true, confirming that the node is down.
+func evacuateNode(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	ID := vars["node_id"]
+
+	values := r.URL.Query()
+	flags := map[string]bool{"rebuild": false, "force": false}
+	for name := range flags {
+		if len(values[name]) == 0 {
+			continue
+		}
+
+		v, err := strconv.ParseBool(values[name][0])
+		if err != nil {
+			return Response{http.StatusBadRequest, nil},
+				fmt.Errorf("Invalid %s value %s", name, values[name][0])
+		}
+		flags[name] = v
+	}
+
+	var err error
+	if flags["rebuild"] {
+		err = c.RebuildNode(ID, flags["force"])
+	} else {
+		err = c.EvacuateNode(ID)
+	}
+
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusAccepted, nil}, nil
+}
+
 func listTenants(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
 	var resp types.TenantsListResponse
 
@@ -1116,9 +1252,9 @@ func volumeActionDetach(bc *Context, m map[string]interface{}, tenant string, vo
 
 	m = val.(map[string]interface{})
 
-	// attachment-id is optional
+	// attachment_id is optional
 	var attachment string
-	val = m["attachment-id"]
+	val = m["attachment_id"]
 	if val != nil {
 		attachment = val.(string)
 	}
@@ -1131,6 +1267,28 @@ func volumeActionDetach(bc *Context, m map[string]interface{}, tenant string, vo
 	return Response{http.StatusAccepted, nil}, nil
 }
 
+func volumeActionExtend(bc *Context, m map[string]interface{}, tenant string, volume string) (Response, error) {
+	val := m["extend"]
+
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return Response{http.StatusBadRequest, nil}, nil
+	}
+
+	// we have to have the new size
+	size, ok := m["new_size"].(float64)
+	if !ok || size <= 0 {
+		return Response{http.StatusBadRequest, nil}, nil
+	}
+
+	err := bc.ExtendVolume(tenant, volume, int(size))
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusAccepted, nil}, nil
+}
+
 func volumeAction(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
 	vars := mux.Vars(r)
 	tenant := vars["tenant"]
@@ -1150,7 +1308,7 @@ func volumeAction(bc *Context, w http.ResponseWriter, r *http.Request) (Response
 
 	m := req.(map[string]interface{})
 
-	// for now, we will support only attach and detach
+	// for now, we will support only attach, detach and extend
 
 	if m["attach"] != nil {
 		return volumeActionAttach(bc, m, tenant, volume)
@@ -1160,9 +1318,90 @@ func volumeAction(bc *Context, w http.ResponseWriter, r *http.Request) (Response
 		return volumeActionDetach(bc, m, tenant, volume)
 	}
 
+	if m["extend"] != nil {
+		return volumeActionExtend(bc, m, tenant, volume)
+	}
+
 	return Response{http.StatusBadRequest, nil}, err
 }
 
+func createVolumeSnapshot(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req RequestedSnapshot
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	if req.VolumeID == "" {
+		return Response{http.StatusBadRequest, nil}, errors.New("Missing volume_id")
+	}
+
+	snapshot, err := bc.CreateVolumeSnapshot(tenant, req)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusAccepted, snapshot}, nil
+}
+
+func listVolumeSnapshots(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	snapshots, err := bc.ListVolumeSnapshots(tenant)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	volume := r.URL.Query().Get("volume_id")
+	if volume == "" {
+		return Response{http.StatusOK, snapshots}, nil
+	}
+
+	filtered := []types.VolumeSnapshot{}
+	for _, s := range snapshots {
+		if s.VolumeID == volume {
+			filtered = append(filtered, s)
+		}
+	}
+
+	return Response{http.StatusOK, filtered}, nil
+}
+
+func showVolumeSnapshot(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	snapshot := vars["snapshot_id"]
+
+	s, err := bc.ShowVolumeSnapshot(tenant, snapshot)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, s}, nil
+}
+
+func deleteVolumeSnapshot(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	snapshot := vars["snapshot_id"]
+
+	err := bc.DeleteVolumeSnapshot(tenant, snapshot)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusAccepted, nil}, nil
+}
+
 func createInstance(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
 	vars := mux.Vars(r)
 	tenant := vars["tenant"]
@@ -1262,15 +1501,91 @@ func instanceAction(c *Context, w http.ResponseWriter, r *http.Request) (Respons
 		return Response{http.StatusBadRequest, nil}, err
 	}
 
-	bodyString := string(body)
+	// The body contains a single action keyed by its name, e.g.,
+	// {"pause":null} or {"reboot":{"type":"HARD"}}.  Older clients send
+	// the bare os-start and os-stop action names.
 
-	if strings.Contains(bodyString, "os-start") {
-		err = c.StartServer(tenant, server)
-	} else if strings.Contains(bodyString, "os-stop") {
-		err = c.StopServer(tenant, server)
-	} else {
-		return Response{http.StatusServiceUnavailable, nil},
-			errors.New("Unsupported Action")
+	var actions map[string]json.RawMessage
+	err = json.Unmarshal(body, &actions)
+	if err != nil {
+		action := strings.TrimSpace(string(body))
+		if action != "os-start" && action != "os-stop" {
+			return Response{http.StatusBadRequest, nil}, err
+		}
+		actions = map[string]json.RawMessage{action: nil}
+	}
+
+	if len(actions) != 1 {
+		return Response{http.StatusBadRequest, nil},
+			errors.New("Exactly one action must be specified")
+	}
+
+	for action, args := range actions {
+		switch action {
+		case "os-start":
+			err = c.StartServer(tenant, server)
+		case "os-stop":
+			err = c.StopServer(tenant, server)
+		case "reboot":
+			var req RebootRequest
+			if len(args) > 0 && string(args) != "null" {
+				err = json.Unmarshal(args, &req)
+				if err != nil {
+					return Response{http.StatusBadRequest, nil}, err
+				}
+			}
+
+			switch strings.ToUpper(req.Type) {
+			case "", RebootSoft:
+				err = c.RebootServer(tenant, server, false)
+			case RebootHard:
+				err = c.RebootServer(tenant, server, true)
+			default:
+				return Response{http.StatusBadRequest, nil},
+					fmt.Errorf("Invalid reboot type %s", req.Type)
+			}
+		case "pause":
+			err = c.PauseServer(tenant, server)
+		case "unpause":
+			err = c.UnpauseServer(tenant, server)
+		case "suspend":
+			err = c.SuspendServer(tenant, server)
+		case "resume":
+			err = c.ResumeServer(tenant, server)
+		case "os-migrateLive":
+			err = c.MigrateServer(tenant, server)
+		case "evacuate":
+			var req EvacuateRequest
+			if len(args) > 0 && string(args) != "null" {
+				err = json.Unmarshal(args, &req)
+				if err != nil {
+					return Response{http.StatusBadRequest, nil}, err
+				}
+			}
+
+			if req.Force && !service.GetPrivilege(r.Context()) {
+				return Response{http.StatusForbidden, nil},
+					errors.New("Only privileged users may force an evacuation")
+			}
+
+			err = c.EvacuateServer(tenant, server, req.Force)
+		case "resize":
+			var req ResizeRequest
+			err = json.Unmarshal(args, &req)
+			if err != nil {
+				return Response{http.StatusBadRequest, nil}, err
+			}
+
+			if req.FlavorRef == "" {
+				return Response{http.StatusBadRequest, nil},
+					errors.New("Missing flavorRef")
+			}
+
+			err = c.ResizeServer(tenant, server, req.FlavorRef)
+		default:
+			return Response{http.StatusServiceUnavailable, nil},
+				errors.New("Unsupported Action")
+		}
 	}
 
 	if err != nil {
@@ -1280,6 +1595,186 @@ func instanceAction(c *Context, w http.ResponseWriter, r *http.Request) (Respons
 	return Response{http.StatusAccepted, nil}, nil
 }
 
+func instanceConsoleLog(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	server := vars["instance_id"]
+
+	lines := 0
+	if value := r.URL.Query().Get("lines"); value != "" {
+		var err error
+		lines, err = strconv.Atoi(value)
+		if err != nil || lines < 0 {
+			return Response{http.StatusBadRequest, nil},
+				fmt.Errorf("Invalid number of lines %s", value)
+		}
+	}
+
+	output, err := c.ConsoleLog(tenant, server, lines)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, ConsoleLogResponse{Output: output}}, nil
+}
+
+var consoleUpgrader websocket.Upgrader
+
+// proxyConsole copies the websocket messages received from the client to the
+// console and the console output to the client as binary messages, until
+// either side closes its connection.
+func proxyConsole(ws *websocket.Conn, console io.ReadWriteCloser) {
+	done := make(chan struct{})
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := console.Read(buf)
+			if n > 0 {
+				if ws.WriteMessage(websocket.BinaryMessage, buf[:n]) != nil {
+					break
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		_ = ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		_ = ws.Close()
+		close(done)
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+
+		if _, err := console.Write(data); err != nil {
+			break
+		}
+	}
+
+	_ = console.Close()
+	<-done
+}
+
+func instanceConsole(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	server := vars["instance_id"]
+
+	if !websocket.IsWebSocketUpgrade(r) {
+		return Response{http.StatusBadRequest, nil},
+			errors.New("Console must be accessed through a websocket")
+	}
+
+	console, err := c.OpenConsole(tenant, server)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	// Upgrade replies to the client itself if it fails.
+	ws, err := consoleUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		glog.Warningf("Unable to upgrade console connection of %s: %v", server, err)
+		_ = console.Close()
+		return Response{http.StatusSwitchingProtocols, nil}, nil
+	}
+
+	proxyConsole(ws, console)
+
+	return Response{http.StatusSwitchingProtocols, nil}, nil
+}
+
+func createEnrollmentToken(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req types.EnrollmentTokenRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	token, err := c.CreateEnrollmentToken(req)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusCreated, token}, nil
+}
+
+func listEnrollmentRequests(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	requests, err := c.ListEnrollmentRequests()
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	resp := types.EnrollmentRequestsResponse{
+		Requests: requests,
+	}
+
+	return Response{http.StatusOK, resp}, nil
+}
+
+func changeEnrollmentRequestStatus(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	ID := vars["request_id"]
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var status types.EnrollmentRequestStatus
+	err = json.Unmarshal(body, &status)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	if status.Status != types.EnrollmentApproved &&
+		status.Status != types.EnrollmentRejected {
+		return Response{http.StatusBadRequest, nil},
+			fmt.Errorf("Cannot transition enrollment request %s to %s",
+				ID, status.Status)
+	}
+
+	err = c.UpdateEnrollmentRequest(ID, status.Status)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusNoContent, nil}, nil
+}
+
+func enroll(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req types.EnrollRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	resp, err := c.Enroll(req)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	if resp.Status == types.EnrollmentPending {
+		return Response{http.StatusAccepted, resp}, nil
+	}
+
+	return Response{http.StatusOK, resp}, nil
+}
+
 // Service is an interface which must be implemented by the ciao API context.
 type Service interface {
 	AddPool(name string, subnet *string, ips []string) (types.Pool, error)
@@ -1298,6 +1793,7 @@ type Service interface {
 	ListQuotas(tenantID string) []types.QuotaDetails
 	UpdateQuotas(tenantID string, qds []types.QuotaDetails) error
 	EvacuateNode(nodeID string) error
+	RebuildNode(nodeID string, force bool) error
 	RestoreNode(nodeID string) error
 	ListTenants() ([]types.TenantSummary, error)
 	ShowTenant(ID string) (types.TenantConfig, error)
@@ -1313,14 +1809,33 @@ type Service interface {
 	DeleteVolume(tenant string, volume string) error
 	AttachVolume(tenant string, volume string, instance string, mountpoint string) error
 	DetachVolume(tenant string, volume string, attachment string) error
+	ExtendVolume(tenant string, volume string, size int) error
 	ListVolumesDetail(tenant string) ([]types.Volume, error)
 	ShowVolumeDetails(tenant string, volume string) (types.Volume, error)
+	CreateVolumeSnapshot(tenant string, req RequestedSnapshot) (types.VolumeSnapshot, error)
+	ListVolumeSnapshots(tenant string) ([]types.VolumeSnapshot, error)
+	ShowVolumeSnapshot(tenant string, snapshot string) (types.VolumeSnapshot, error)
+	DeleteVolumeSnapshot(tenant string, snapshot string) error
 	CreateServer(string, CreateServerRequest) (interface{}, error)
 	ListServersDetail(tenant string) ([]ServerDetails, error)
 	ShowServerDetails(tenant string, server string) (Server, error)
 	DeleteServer(tenant string, server string) error
 	StartServer(tenant string, server string) error
 	StopServer(tenant string, server string) error
+	RebootServer(tenant string, server string, hard bool) error
+	PauseServer(tenant string, server string) error
+	UnpauseServer(tenant string, server string) error
+	SuspendServer(tenant string, server string) error
+	ResumeServer(tenant string, server string) error
+	MigrateServer(tenant string, server string) error
+	EvacuateServer(tenant string, server string, force bool) error
+	ResizeServer(tenant string, server string, workload string) error
+	ConsoleLog(tenant string, server string, lines int) (string, error)
+	OpenConsole(tenant string, server string) (io.ReadWriteCloser, error)
+	CreateEnrollmentToken(req types.EnrollmentTokenRequest) (types.EnrollmentToken, error)
+	ListEnrollmentRequests() ([]types.EnrollmentRequest, error)
+	UpdateEnrollmentRequest(ID string, status types.EnrollmentStatus) error
+	Enroll(req types.EnrollRequest) (types.EnrollResponse, error)
 }
 
 // Context is used to provide the services and current URL to the handlers.
@@ -1498,6 +2013,10 @@ func Routes(config Config, r *mux.Router) *mux.Router {
 	route.Methods("PUT")
 	route.HeadersRegexp("Content-Type", matchContent)
 
+	route = r.Handle("/node/{node_id:"+uuid.UUIDRegex+"}/evacuate", Handler{context, evacuateNode, true})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
 	// images
 	matchContent = fmt.Sprintf("application/(%s|json)", ImagesV1)
 
@@ -1564,6 +2083,23 @@ func Routes(config Config, r *mux.Router) *mux.Router {
 	route.Methods("POST")
 	route.HeadersRegexp("Content-Type", matchContent)
 
+	// Volume snapshots
+	route = r.Handle("/{tenant}/snapshots", Handler{context, createVolumeSnapshot, false})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/snapshots", Handler{context, listVolumeSnapshots, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/snapshots/{snapshot_id}", Handler{context, showVolumeSnapshot, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/snapshots/{snapshot_id}", Handler{context, deleteVolumeSnapshot, false})
+	route.Methods("DELETE")
+	route.HeadersRegexp("Content-Type", matchContent)
+
 	// Instances
 	matchContent = fmt.Sprintf("application/(%s|json)", InstancesV1)
 
@@ -1587,5 +2123,48 @@ func Routes(config Config, r *mux.Router) *mux.Router {
 	route.Methods("POST")
 	route.HeadersRegexp("Content-Type", matchContent)
 
+	route = r.Handle("/{tenant}/instances/{instance_id}/console-log", Handler{context, instanceConsoleLog, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/instances/{instance_id}/console", Handler{context, instanceConsole, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	// node enrollment
+	matchContent = fmt.Sprintf("application/(%s|json)", EnrollmentV1)
+
+	route = r.Handle("/enrollment/tokens", Handler{context, createEnrollmentToken, true})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/enrollment/requests", Handler{context, listEnrollmentRequests, true})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/enrollment/requests/{request_id:"+uuid.UUIDRegex+"}", Handler{context, changeEnrollmentRequestStatus, true})
+	route.Methods("PUT")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	return r
+}
+
+// EnrollRoutes returns the node enrollment API endpoint. Joining nodes
+// do not have a certificate yet, so this endpoint must be served without
+// requiring client certificates. Nodes authenticate with a join token
+// created through the ciao API instead.
+func EnrollRoutes(config Config, r *mux.Router) *mux.Router {
+	context := &Context{config.URL, config.CiaoService}
+
+	if r == nil {
+		r = mux.NewRouter()
+	}
+
+	matchContent := fmt.Sprintf("application/(%s|json)", EnrollmentV1)
+
+	route := r.Handle("/enroll", Handler{context, enroll, false})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
 	return r
 }
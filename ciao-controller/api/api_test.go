@@ -18,8 +18,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 	storage "github.com/ciao-project/ciao/ciao-storage"
 	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/service"
+	"github.com/gorilla/websocket"
 )
 
 type test struct {
@@ -286,6 +289,70 @@ var tests = []test{
 		http.StatusAccepted,
 		"null",
 	},
+	{
+		"POST",
+		"/validtenantid/volumes/validvolumeid/action",
+		`{"extend":{"new_size":20}}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/volumes/validvolumeid/action",
+		`{"extend":{}}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusBadRequest,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/snapshots",
+		`{"volume_id":"validvolumeid","name":"my snapshot","description":"before upgrade"}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusAccepted,
+		`{"id":"validsnapshotid","volume_id":"validvolumeid","tenant_id":"validtenantid","created":"0001-01-01T00:00:00Z","size":10,"name":"my snapshot","description":"before upgrade"}`,
+	},
+	{
+		"POST",
+		"/validtenantid/snapshots",
+		`{"name":"my snapshot"}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusBadRequest,
+		`{"error":{"code":400,"name":"Bad Request","message":"Missing volume_id"}}` + "\n",
+	},
+	{
+		"GET",
+		"/validtenantid/snapshots",
+		"",
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusOK,
+		`[{"id":"validsnapshotid","volume_id":"validvolumeid","tenant_id":"validtenantid","created":"0001-01-01T00:00:00Z","size":10,"name":"my snapshot","description":"before upgrade"}]`,
+	},
+	{
+		"GET",
+		"/validtenantid/snapshots?volume_id=othervolumeid",
+		"",
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusOK,
+		`[]`,
+	},
+	{
+		"GET",
+		"/validtenantid/snapshots/validsnapshotid",
+		"",
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusOK,
+		`{"id":"validsnapshotid","volume_id":"validvolumeid","tenant_id":"validtenantid","created":"0001-01-01T00:00:00Z","size":10,"name":"my snapshot","description":"before upgrade"}`,
+	},
+	{
+		"DELETE",
+		"/validtenantid/snapshots/validsnapshotid",
+		"",
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusAccepted,
+		"null",
+	},
 	{
 		"POST",
 		"/validtenantid/instances",
@@ -300,14 +367,14 @@ var tests = []test{
 		"",
 		fmt.Sprintf("application/%s", InstancesV1),
 		http.StatusOK,
-		`{"total_servers":1,"servers":[{"private_addresses":[{"addr":"192.169.0.1","mac_addr":"00:02:00:01:02:03"}],"created":"0001-01-01T00:00:00Z","workload_id":"testWorkloadUUID","node_id":"nodeUUID","id":"testUUID","name":"","volumes":null,"status":"active","tenant_id":"validtenantid","ssh_ip":"","ssh_port":0}]}`},
+		`{"total_servers":1,"servers":[{"private_addresses":[{"addr":"192.169.0.1","mac_addr":"00:02:00:01:02:03"}],"created":"0001-01-01T00:00:00Z","workload_id":"testWorkloadUUID","node_id":"nodeUUID","id":"testUUID","name":"","volumes":null,"status":"active","tenant_id":"validtenantid","ssh_ip":"","ssh_port":0,"vcpus":2,"mem_mb":512}]}`},
 	{
 		"GET",
 		"/validtenantid/instances/instanceid",
 		"",
 		fmt.Sprintf("application/%s", InstancesV1),
 		http.StatusOK,
-		`{"server":{"private_addresses":[{"addr":"192.169.0.1","mac_addr":"00:02:00:01:02:03"}],"created":"0001-01-01T00:00:00Z","workload_id":"testWorkloadUUID","node_id":"nodeUUID","id":"instanceid","name":"","volumes":null,"status":"active","tenant_id":"validtenantid","ssh_ip":"","ssh_port":0}}`,
+		`{"server":{"private_addresses":[{"addr":"192.169.0.1","mac_addr":"00:02:00:01:02:03"}],"created":"0001-01-01T00:00:00Z","workload_id":"testWorkloadUUID","node_id":"nodeUUID","id":"instanceid","name":"","volumes":null,"status":"active","tenant_id":"validtenantid","ssh_ip":"","ssh_port":0,"vcpus":2,"mem_mb":512}}`,
 	},
 	{
 		"DELETE",
@@ -333,6 +400,206 @@ var tests = []test{
 		http.StatusAccepted,
 		"null",
 	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		"os-start",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		"os-stop\n",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		"pause",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusBadRequest,
+		`{"error":{"code":400,"name":"Bad Request","message":"invalid character 'p' looking for beginning of value"}}` + "\n",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"reboot":{"type":"HARD"}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"reboot":{"type":"WARM"}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusBadRequest,
+		`{"error":{"code":400,"name":"Bad Request","message":"Invalid reboot type WARM"}}` + "\n",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"pause":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"unpause":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"suspend":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"resume":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"os-migrateLive":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"evacuate":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"evacuate":{"force":true}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"resize":{"flavorRef":"ba58f471-0735-4773-9550-188e2d012941"}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"resize":{}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusBadRequest,
+		`{"error":{"code":400,"name":"Bad Request","message":"Missing flavorRef"}}` + "\n",
+	},
+	{
+		"GET",
+		"/validtenantid/instances/instanceid/console-log?lines=1",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusOK,
+		`{"output":"login:"}`,
+	},
+	{
+		"GET",
+		"/validtenantid/instances/instanceid/console-log?lines=-1",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusBadRequest,
+		`{"error":{"code":400,"name":"Bad Request","message":"Invalid number of lines -1"}}` + "\n",
+	},
+	{
+		"GET",
+		"/validtenantid/instances/instanceid/console",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusBadRequest,
+		`{"error":{"code":400,"name":"Bad Request","message":"Console must be accessed through a websocket"}}` + "\n",
+	},
+	{
+		"POST",
+		"/node/ba58f471-0735-4773-9550-188e2d012941/evacuate",
+		"",
+		fmt.Sprintf("application/%s", NodeV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/node/ba58f471-0735-4773-9550-188e2d012941/evacuate?rebuild=true",
+		"",
+		fmt.Sprintf("application/%s", NodeV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/node/ba58f471-0735-4773-9550-188e2d012941/evacuate?rebuild=maybe",
+		"",
+		fmt.Sprintf("application/%s", NodeV1),
+		http.StatusBadRequest,
+		`{"error":{"code":400,"name":"Bad Request","message":"Invalid rebuild value maybe"}}` + "\n",
+	},
+	{
+		"POST",
+		"/node/ba58f471-0735-4773-9550-188e2d012941/evacuate?rebuild=true&force=true",
+		"",
+		fmt.Sprintf("application/%s", NodeV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/node/ba58f471-0735-4773-9550-188e2d012941/evacuate?rebuild=true&force=maybe",
+		"",
+		fmt.Sprintf("application/%s", NodeV1),
+		http.StatusBadRequest,
+		`{"error":{"code":400,"name":"Bad Request","message":"Invalid force value maybe"}}` + "\n",
+	},
+	{
+		"POST",
+		"/enrollment/tokens",
+		`{"role":"agent","auto_approve":true}`,
+		fmt.Sprintf("application/%s", EnrollmentV1),
+		http.StatusCreated,
+		`{"token":"0123456789abcdef","role":"agent","auto_approve":true,"expiry":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"GET",
+		"/enrollment/requests",
+		"",
+		fmt.Sprintf("application/%s", EnrollmentV1),
+		http.StatusOK,
+		`{"requests":[{"id":"ba58f471-0735-4773-9550-188e2d012941","role":"agent","hosts":["node1"],"ips":["198.51.100.1"],"fingerprint":"00:11:22","status":"pending","created":"0001-01-01T00:00:00Z"}]}`,
+	},
+	{
+		"PUT",
+		"/enrollment/requests/ba58f471-0735-4773-9550-188e2d012941",
+		`{"status":"approved"}`,
+		fmt.Sprintf("application/%s", EnrollmentV1),
+		http.StatusNoContent,
+		"null",
+	},
 }
 
 type testCiaoService struct{}
@@ -484,6 +751,10 @@ func (ts testCiaoService) EvacuateNode(nodeID string) error {
 	return nil
 }
 
+func (ts testCiaoService) RebuildNode(nodeID string, force bool) error {
+	return nil
+}
+
 func (ts testCiaoService) RestoreNode(nodeID string) error {
 	return nil
 }
@@ -636,6 +907,10 @@ func (ts testCiaoService) DetachVolume(tenant string, volume string, attachment
 	return nil
 }
 
+func (ts testCiaoService) ExtendVolume(tenant string, volume string, size int) error {
+	return nil
+}
+
 func (ts testCiaoService) ListVolumesDetail(tenant string) ([]types.Volume, error) {
 	return []types.Volume{
 		{
@@ -661,6 +936,37 @@ func (ts testCiaoService) ListVolumesDetail(tenant string) ([]types.Volume, erro
 	}, nil
 }
 
+func (ts testCiaoService) CreateVolumeSnapshot(tenant string, req RequestedSnapshot) (types.VolumeSnapshot, error) {
+	return types.VolumeSnapshot{
+		ID:          "validsnapshotid",
+		VolumeID:    req.VolumeID,
+		TenantID:    tenant,
+		Size:        10,
+		Name:        req.Name,
+		Description: req.Description,
+	}, nil
+}
+
+func (ts testCiaoService) ListVolumeSnapshots(tenant string) ([]types.VolumeSnapshot, error) {
+	s, err := ts.ShowVolumeSnapshot(tenant, "validsnapshotid")
+	return []types.VolumeSnapshot{s}, err
+}
+
+func (ts testCiaoService) ShowVolumeSnapshot(tenant string, snapshot string) (types.VolumeSnapshot, error) {
+	return types.VolumeSnapshot{
+		ID:          snapshot,
+		VolumeID:    "validvolumeid",
+		TenantID:    tenant,
+		Size:        10,
+		Name:        "my snapshot",
+		Description: "before upgrade",
+	}, nil
+}
+
+func (ts testCiaoService) DeleteVolumeSnapshot(tenant string, snapshot string) error {
+	return nil
+}
+
 func (ts testCiaoService) CreateServer(tenant string, req CreateServerRequest) (interface{}, error) {
 	req.Server.ID = "validServerID"
 	return req, nil
@@ -675,6 +981,8 @@ func (ts testCiaoService) ListServersDetail(tenant string) ([]ServerDetails, err
 		TenantID:   tenant,
 		WorkloadID: "testWorkloadUUID",
 		Status:     "active",
+		VCPUs:      2,
+		MemMB:      512,
 		PrivateAddresses: []PrivateAddresses{
 			{
 				Addr:    "192.169.0.1",
@@ -695,6 +1003,8 @@ func (ts testCiaoService) ShowServerDetails(tenant string, server string) (Serve
 		TenantID:   tenant,
 		WorkloadID: "testWorkloadUUID",
 		Status:     "active",
+		VCPUs:      2,
+		MemMB:      512,
 		PrivateAddresses: []PrivateAddresses{
 			{
 				Addr:    "192.169.0.1",
@@ -718,6 +1028,88 @@ func (ts testCiaoService) StopServer(tenant string, server string) error {
 	return nil
 }
 
+func (ts testCiaoService) RebootServer(tenant string, server string, hard bool) error {
+	return nil
+}
+
+func (ts testCiaoService) PauseServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) UnpauseServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) SuspendServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) ResumeServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) MigrateServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) EvacuateServer(tenant string, server string, force bool) error {
+	return nil
+}
+
+func (ts testCiaoService) ResizeServer(tenant string, server string, workload string) error {
+	return nil
+}
+
+func (ts testCiaoService) ConsoleLog(tenant string, server string, lines int) (string, error) {
+	return "login:", nil
+}
+
+// OpenConsole returns a console that echoes its input.
+func (ts testCiaoService) OpenConsole(tenant string, server string) (io.ReadWriteCloser, error) {
+	console, echo := net.Pipe()
+	go func() {
+		_, _ = io.Copy(echo, echo)
+		_ = echo.Close()
+	}()
+	return console, nil
+}
+
+func (ts testCiaoService) CreateEnrollmentToken(req types.EnrollmentTokenRequest) (types.EnrollmentToken, error) {
+	return types.EnrollmentToken{
+		Token:       "0123456789abcdef",
+		Role:        req.Role,
+		AutoApprove: req.AutoApprove,
+	}, nil
+}
+
+func (ts testCiaoService) ListEnrollmentRequests() ([]types.EnrollmentRequest, error) {
+	return []types.EnrollmentRequest{
+		{
+			ID:          "ba58f471-0735-4773-9550-188e2d012941",
+			Role:        "agent",
+			Hosts:       []string{"node1"},
+			IPs:         []string{"198.51.100.1"},
+			Fingerprint: "00:11:22",
+			Status:      types.EnrollmentPending,
+		},
+	}, nil
+}
+
+func (ts testCiaoService) UpdateEnrollmentRequest(ID string, status types.EnrollmentStatus) error {
+	return nil
+}
+
+func (ts testCiaoService) Enroll(req types.EnrollRequest) (types.EnrollResponse, error) {
+	if req.Token != "0123456789abcdef" {
+		return types.EnrollResponse{}, types.ErrInvalidEnrollmentToken
+	}
+
+	return types.EnrollResponse{
+		ID:     "ba58f471-0735-4773-9550-188e2d012941",
+		Status: types.EnrollmentPending,
+	}, nil
+}
+
 func TestResponse(t *testing.T) {
 	var ts testCiaoService
 
@@ -747,6 +1139,39 @@ func TestResponse(t *testing.T) {
 	}
 }
 
+func TestInstanceConsole(t *testing.T) {
+	var ts testCiaoService
+
+	server := httptest.NewServer(Routes(Config{"", ts}, nil))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") +
+		"/validtenantid/instances/instanceid/console"
+	header := http.Header{}
+	header.Set("Content-Type", fmt.Sprintf("application/%s", InstancesV1))
+
+	ws, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("Unable to open console: %v", err)
+	}
+	defer func() { _ = ws.Close() }()
+
+	err = ws.WriteMessage(websocket.BinaryMessage, []byte("root\n"))
+	if err != nil {
+		t.Fatalf("Unable to write to console: %v", err)
+	}
+
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("Unable to read from console: %v", err)
+	}
+
+	if string(data) != "root\n" {
+		t.Errorf("Expected console output %q, got %q", "root\n", data)
+	}
+}
+
 func TestRoutes(t *testing.T) {
 	var ts testCiaoService
 	config := Config{"", ts}
@@ -756,3 +1181,56 @@ func TestRoutes(t *testing.T) {
 		t.Fatalf("No routes returned")
 	}
 }
+
+func TestEnrollRoutes(t *testing.T) {
+	var ts testCiaoService
+
+	mux := EnrollRoutes(Config{"", ts}, nil)
+
+	enrollTests := []test{
+		{
+			"POST",
+			"/enroll",
+			`{"token":"0123456789abcdef","csr":"csr"}`,
+			fmt.Sprintf("application/%s", EnrollmentV1),
+			http.StatusAccepted,
+			`{"id":"ba58f471-0735-4773-9550-188e2d012941","status":"pending"}`,
+		},
+		{
+			"POST",
+			"/enroll",
+			`{"token":"invalid","csr":"csr"}`,
+			fmt.Sprintf("application/%s", EnrollmentV1),
+			http.StatusForbidden,
+			`{"error":{"code":403,"name":"Forbidden","message":"Invalid enrollment token"}}` + "\n",
+		},
+		{
+			"GET",
+			"/enrollment/requests",
+			"",
+			fmt.Sprintf("application/%s", EnrollmentV1),
+			http.StatusNotFound,
+			"404 page not found\n",
+		},
+	}
+
+	for i, tt := range enrollTests {
+		req, err := http.NewRequest(tt.method, tt.request, bytes.NewBuffer([]byte(tt.requestBody)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		req.Header.Set("Content-Type", tt.media)
+
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expectedStatus {
+			t.Errorf("test %d: got %v, expected %v", i, rr.Code, tt.expectedStatus)
+		}
+
+		if rr.Body.String() != tt.expectedResponse {
+			t.Errorf("test %d: %s: failed\ngot: %v\nexp: %v", i, tt.request, rr.Body.String(), tt.expectedResponse)
+		}
+	}
+}
@@ -17,9 +17,13 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/ciao-project/ciao/ciao-controller/types"
@@ -36,7 +40,15 @@ type controllerClient interface {
 	StartWorkload(config string) error
 	DeleteInstance(instanceID string, nodeID string) error
 	StopInstance(instanceID string, nodeID string) error
-	RestartInstance(i *types.Instance, w *types.Workload, t *types.Tenant) error
+	RestartInstance(i *types.Instance, w *types.Workload, t *types.Tenant, preferredNode string) error
+	RebootInstance(instanceID string, nodeID string, hard bool) error
+	PauseInstance(instanceID string, nodeID string) error
+	UnpauseInstance(instanceID string, nodeID string) error
+	SuspendInstance(instanceID string, nodeID string) error
+	ResumeInstance(instanceID string, nodeID string) error
+	MigrateInstance(i *types.Instance, w *types.Workload, t *types.Tenant) error
+	ConsoleLog(instanceID string, nodeID string, lines int) (string, error)
+	OpenConsole(instanceID string, nodeID string) (net.Conn, error)
 	RemoveInstance(instanceID string)
 	EvacuateNode(nodeID string) error
 	RestoreNode(nodeID string) error
@@ -44,6 +56,8 @@ type controllerClient interface {
 	mapExternalIP(t types.Tenant, m types.MappedIP) error
 	unMapExternalIP(t types.Tenant, m types.MappedIP) error
 	attachVolume(volID string, instanceID string, nodeID string) error
+	detachVolume(volID string, instanceID string, nodeID string) error
+	extendVolume(volID string, instanceID string, nodeID string, sizeGiB int) error
 	ssntpClient() *ssntp.Client
 }
 
@@ -53,6 +67,10 @@ type ssntpClient struct {
 	name  string
 }
 
+// consoleTimeout is the time we wait for a launcher to reply to a CONSOLE
+// command.
+const consoleTimeout = 30 * time.Second
+
 func (client *ssntpClient) ConnectNotify() {
 	glog.Info(client.name, " connected")
 }
@@ -73,7 +91,7 @@ func (client *ssntpClient) CommandNotify(command ssntp.Command, frame *ssntp.Fra
 
 	if command == ssntp.STATS {
 		stats.Init()
-		err := yaml.Unmarshal(payload, &stats)
+		err := payloads.Unmarshal(payload, &stats)
 		if err != nil {
 			glog.Warningf("Error unmarshalling STATS: %v", err)
 			return
@@ -153,13 +171,33 @@ func (client *ssntpClient) RemoveInstance(instanceID string) {
 	}
 }
 
-func (client *ssntpClient) instanceDeleted(payload []byte) {
+// rebuiltInstanceDeleted returns true if nodeID has deleted its copy of an
+// instance that has been rebuilt on another node.
+func (client *ssntpClient) rebuiltInstanceDeleted(instanceID string, nodeID string) bool {
+	deleted, err := client.ctl.ds.RebuiltInstanceDeleted(instanceID, nodeID)
+	if err != nil {
+		glog.Warningf("Error updating rebuilt instance in datastore: %v", err)
+	}
+	if deleted {
+		glog.Infof("Node %s deleted its copy of rebuilt instance %s", nodeID, instanceID)
+	}
+
+	return deleted
+}
+
+func (client *ssntpClient) instanceDeleted(payload []byte, nodeID string) {
 	var event payloads.EventInstanceDeleted
 	err := yaml.Unmarshal(payload, &event)
 	if err != nil {
 		glog.Warningf("Error unmarshalling InstanceDeleted: %v", err)
 		return
 	}
+
+	if client.rebuiltInstanceDeleted(event.InstanceDeleted.InstanceUUID, nodeID) {
+		return
+	}
+
+	instancesDeleted.Inc()
 	client.RemoveInstance(event.InstanceDeleted.InstanceUUID)
 }
 
@@ -197,6 +235,55 @@ func (client *ssntpClient) instanceStopped(payload []byte) {
 	}
 }
 
+func (client *ssntpClient) migrationTargetReady(payload []byte) {
+	var event payloads.EventMigrationTargetReady
+	err := yaml.Unmarshal(payload, &event)
+	if err != nil {
+		glog.Warningf("Error unmarshalling MigrationTargetReady: %v", err)
+		return
+	}
+	ready := &event.MigrationTargetReady
+
+	i, err := client.ctl.ds.GetInstance(ready.InstanceUUID)
+	if err != nil {
+		glog.Warningf("Error getting instance from datastore: %v", err)
+		return
+	}
+
+	// The target gives up and removes itself if it does not receive
+	// the instance in time, so there's nothing to clean up here.
+
+	if i.State != payloads.Migrating {
+		glog.Warningf("Instance %s is no longer migrating", i.ID)
+		return
+	}
+
+	err = client.sendMigrateCommand(i.ID, i.NodeID, ready.NodeUUID, ready.URI)
+	if err != nil {
+		glog.Warningf("Error migrating instance %s: %v", i.ID, err)
+		err = client.ctl.ds.MigrateFailure(i.ID, payloads.MigrateTransferFailure, "")
+		if err != nil {
+			glog.Warningf("Error adding MigrateFailure to datastore: %v", err)
+		}
+	}
+}
+
+func (client *ssntpClient) instanceMigrated(payload []byte) {
+	var event payloads.EventInstanceMigrated
+	err := yaml.Unmarshal(payload, &event)
+	if err != nil {
+		glog.Warningf("Error unmarshalling InstanceMigrated: %v", err)
+		return
+	}
+	migrated := &event.InstanceMigrated
+	glog.Infof("Migrated instance %s to %s", migrated.InstanceUUID, migrated.TargetNodeUUID)
+
+	err = client.ctl.ds.InstanceMigrated(migrated.InstanceUUID, migrated.TargetNodeUUID)
+	if err != nil {
+		glog.Warningf("Error migrating instance in datastore: %v", err)
+	}
+}
+
 func (client *ssntpClient) concentratorInstanceAdded(payload []byte) {
 	var event payloads.EventConcentratorInstanceAdded
 	err := yaml.Unmarshal(payload, &event)
@@ -254,6 +341,7 @@ func (client *ssntpClient) nodeConnected(payload []byte) {
 	glog.Infof("Node %s connected", nodeConnected.Connected.NodeUUID)
 
 	client.ctl.ds.AddNode(nodeConnected.Connected.NodeUUID, nodeConnected.Connected.NodeType)
+	client.ctl.deleteRebuiltInstances(nodeConnected.Connected.NodeUUID)
 }
 
 func (client *ssntpClient) nodeDisconnected(payload []byte) {
@@ -330,7 +418,7 @@ func (client *ssntpClient) EventNotify(event ssntp.Event, frame *ssntp.Frame) {
 
 	switch event {
 	case ssntp.InstanceDeleted:
-		client.instanceDeleted(payload)
+		client.instanceDeleted(payload, frame.Origin.String())
 
 	case ssntp.InstanceStopped:
 		client.instanceStopped(payload)
@@ -353,6 +441,12 @@ func (client *ssntpClient) EventNotify(event ssntp.Event, frame *ssntp.Frame) {
 	case ssntp.PublicIPUnassigned:
 		client.unassignEvent(payload)
 
+	case ssntp.MigrationTargetReady:
+		client.migrationTargetReady(payload)
+
+	case ssntp.InstanceMigrated:
+		client.instanceMigrated(payload)
+
 	}
 }
 
@@ -363,7 +457,17 @@ func (client *ssntpClient) startFailure(payload []byte) {
 		glog.Warningf("Error unmarshalling StartFailure: %v", err)
 		return
 	}
-	if failure.Reason.IsFatal() && !failure.Restart {
+	commandFailures.Inc(ssntp.START.String(), string(failure.Reason))
+
+	// The target of a live migration failed to start.  The instance
+	// still runs on its original node and keeps its resources.
+
+	migrating := false
+	if i, err := client.ctl.ds.GetInstance(failure.InstanceUUID); err == nil {
+		migrating = i.State == payloads.Migrating
+	}
+
+	if failure.Reason.IsFatal() && !failure.Restart && !migrating {
 		client.deleteEphemeralStorage(failure.InstanceUUID)
 		err = client.releaseResources(failure.InstanceUUID)
 		if err != nil {
@@ -425,6 +529,22 @@ func (client *ssntpClient) restartFailure(payload []byte) {
 	}
 }
 
+func (client *ssntpClient) deleteFailure(payload []byte) {
+	var failure payloads.ErrorDeleteFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling DeleteFailure: %v", err)
+		return
+	}
+	commandFailures.Inc(ssntp.DELETE.String(), string(failure.Reason))
+	glog.Warningf("Unable to delete instance %s on node %s: %s",
+		failure.InstanceUUID, failure.NodeUUID, failure.Reason)
+
+	if failure.Reason == payloads.DeleteNoInstance {
+		client.rebuiltInstanceDeleted(failure.InstanceUUID, failure.NodeUUID)
+	}
+}
+
 func (client *ssntpClient) attachVolumeFailure(payload []byte) {
 	var failure payloads.ErrorAttachVolumeFailure
 	err := yaml.Unmarshal(payload, &failure)
@@ -432,12 +552,98 @@ func (client *ssntpClient) attachVolumeFailure(payload []byte) {
 		glog.Warningf("Error unmarshalling AttachVolumeFailure: %v", err)
 		return
 	}
+	commandFailures.Inc(ssntp.AttachVolume.String(), string(failure.Reason))
 	err = client.ctl.ds.AttachVolumeFailure(failure.InstanceUUID, failure.VolumeUUID, failure.Reason)
 	if err != nil {
 		glog.Warningf("Error handling AttachVolumeFailure in datastore: %v", err)
 	}
 }
 
+func (client *ssntpClient) detachVolumeFailure(payload []byte) {
+	var failure payloads.ErrorDetachVolumeFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling DetachVolumeFailure: %v", err)
+		return
+	}
+	commandFailures.Inc(ssntp.DetachVolume.String(), string(failure.Reason))
+	err = client.ctl.ds.DetachVolumeFailure(failure.InstanceUUID, failure.VolumeUUID, failure.Reason)
+	if err != nil {
+		glog.Warningf("Error handling DetachVolumeFailure in datastore: %v", err)
+	}
+}
+
+func (client *ssntpClient) extendVolumeFailure(payload []byte) {
+	var failure payloads.ErrorExtendVolumeFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling ExtendVolumeFailure: %v", err)
+		return
+	}
+	commandFailures.Inc(ssntp.ExtendVolume.String(), string(failure.Reason))
+	glog.Warningf("Unable to notify instance %s on node %s of new size of volume %s: %s",
+		failure.InstanceUUID, failure.NodeUUID, failure.VolumeUUID, failure.Reason)
+
+	err = client.ctl.ds.ExtendVolumeFailure(failure.InstanceUUID, failure.VolumeUUID, failure.Reason)
+	if err != nil {
+		glog.Warningf("Error handling ExtendVolumeFailure in datastore: %v", err)
+	}
+}
+
+func (client *ssntpClient) powerFailure(cmd ssntp.Command, payload []byte) {
+	var failure payloads.ErrorPowerFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling %s failure: %v", cmd, err)
+		return
+	}
+	commandFailures.Inc(cmd.String(), string(failure.Reason))
+	glog.Warningf("Unable to %s instance %s on node %s: %s",
+		cmd, failure.InstanceUUID, failure.NodeUUID, failure.Reason)
+
+	i, err := client.ctl.ds.GetInstance(failure.InstanceUUID)
+	if err != nil {
+		return
+	}
+
+	msg := fmt.Sprintf("%s of %s failed: %s", cmd, failure.InstanceUUID, failure.Reason.String())
+	err = client.ctl.ds.LogError(i.TenantID, msg)
+	if err != nil {
+		glog.Warningf("Error logging error: %v", err)
+	}
+}
+
+func (client *ssntpClient) migrateFailure(payload []byte) {
+	var failure payloads.ErrorMigrateFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling MigrateFailure: %v", err)
+		return
+	}
+	commandFailures.Inc(ssntp.MIGRATE.String(), string(failure.Reason))
+	glog.Warningf("Unable to migrate instance %s on node %s: %s",
+		failure.InstanceUUID, failure.NodeUUID, failure.Reason)
+
+	err = client.ctl.ds.MigrateFailure(failure.InstanceUUID, failure.Reason, failure.NodeUUID)
+	if err != nil {
+		glog.Warningf("Error adding MigrateFailure to datastore: %v", err)
+	}
+}
+
+func (client *ssntpClient) consoleFailure(payload []byte) error {
+	var failure payloads.ErrorConsoleFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling ConsoleFailure: %v", err)
+		return err
+	}
+	commandFailures.Inc(ssntp.CONSOLE.String(), string(failure.Reason))
+	glog.Warningf("Unable to access console of instance %s on node %s: %s",
+		failure.InstanceUUID, failure.NodeUUID, failure.Reason)
+
+	return fmt.Errorf("Unable to access console: %s", failure.Reason.String())
+}
+
 func (client *ssntpClient) assignError(payload []byte) {
 	var failure payloads.ErrorPublicIPFailure
 	err := yaml.Unmarshal(payload, &failure)
@@ -492,9 +698,36 @@ func (client *ssntpClient) ErrorNotify(err ssntp.Error, frame *ssntp.Frame) {
 	case ssntp.RestartFailure:
 		client.restartFailure(payload)
 
+	case ssntp.DeleteFailure:
+		client.deleteFailure(payload)
+
 	case ssntp.AttachVolumeFailure:
 		client.attachVolumeFailure(payload)
 
+	case ssntp.DetachVolumeFailure:
+		client.detachVolumeFailure(payload)
+
+	case ssntp.ExtendVolumeFailure:
+		client.extendVolumeFailure(payload)
+
+	case ssntp.RebootFailure:
+		client.powerFailure(ssntp.REBOOT, payload)
+
+	case ssntp.PauseFailure:
+		client.powerFailure(ssntp.PAUSE, payload)
+
+	case ssntp.UnpauseFailure:
+		client.powerFailure(ssntp.UNPAUSE, payload)
+
+	case ssntp.SuspendFailure:
+		client.powerFailure(ssntp.SUSPEND, payload)
+
+	case ssntp.ResumeFailure:
+		client.powerFailure(ssntp.RESUME, payload)
+
+	case ssntp.MigrateFailure:
+		client.migrateFailure(payload)
+
 	case ssntp.AssignPublicIPFailure:
 		client.assignError(payload)
 
@@ -522,6 +755,9 @@ func (client *ssntpClient) StartTracedWorkload(config string, startTime time.Tim
 	}
 
 	_, err := client.ssntp.SendTracedCommand(ssntp.START, []byte(config), traceConfig)
+	if err == nil {
+		commandsSent.Inc(ssntp.START.String())
+	}
 
 	return err
 }
@@ -531,6 +767,9 @@ func (client *ssntpClient) StartWorkload(config string) error {
 	glog.V(1).Info(config)
 
 	_, err := client.ssntp.SendCommand(ssntp.START, []byte(config))
+	if err == nil {
+		commandsSent.Inc(ssntp.START.String())
+	}
 
 	return err
 }
@@ -545,6 +784,9 @@ func (client *ssntpClient) deleteInstance(payload *payloads.Delete, instanceID s
 	glog.V(1).Info(string(y))
 
 	_, err = client.ssntp.SendCommand(ssntp.DELETE, y)
+	if err == nil {
+		commandsSent.Inc(ssntp.DELETE.String())
+	}
 
 	return err
 }
@@ -581,15 +823,35 @@ func (client *ssntpClient) StopInstance(instanceID string, nodeID string) error
 	return client.deleteInstance(&payload, instanceID, nodeID)
 }
 
+// RestartInstance restarts a stopped instance.  The scheduler will place it
+// on preferredNode, if it is not empty and the instance still fits on it, or
+// on any other node.
 func (client *ssntpClient) RestartInstance(i *types.Instance, w *types.Workload,
-	t *types.Tenant) error {
-	var cnci *types.Instance
-
+	t *types.Tenant, preferredNode string) error {
 	err := client.ctl.ds.InstanceRestarting(i.ID)
 	if err != nil {
 		return errors.Wrapf(err, "Unable to update instance state before restarting")
 	}
 
+	glog.Info("RESTART instance: ", i.ID)
+
+	return client.startInstance(i, w, t, true, "", preferredNode)
+}
+
+// MigrateInstance starts the target of a live migration of an instance.  The
+// scheduler will place it on any node but the one the instance runs on.
+func (client *ssntpClient) MigrateInstance(i *types.Instance, w *types.Workload,
+	t *types.Tenant) error {
+	glog.Info("MIGRATE instance: ", i.ID, " from node ", i.NodeID)
+
+	return client.startInstance(i, w, t, false, i.NodeID, "")
+}
+
+func (client *ssntpClient) startInstance(i *types.Instance, w *types.Workload,
+	t *types.Tenant, restart bool, migrationSource string, preferredNode string) error {
+	var cnci *types.Instance
+	var err error
+
 	if !i.CNCI {
 		// get the CNCI for this instance
 		cnci, err = t.CNCIctrl.GetInstanceCNCI(i.ID)
@@ -610,7 +872,7 @@ func (client *ssntpClient) RestartInstance(i *types.Instance, w *types.Workload,
 
 	attachments := client.ctl.ds.GetStorageAttachments(i.ID)
 
-	restartCmd := payloads.StartCmd{
+	startCmd := payloads.StartCmd{
 		TenantUUID:          i.TenantID,
 		InstanceUUID:        i.ID,
 		FWType:              payloads.Firmware(w.FWType),
@@ -621,30 +883,32 @@ func (client *ssntpClient) RestartInstance(i *types.Instance, w *types.Workload,
 			VnicMAC:  i.MACAddress,
 			VnicUUID: i.VnicUUID,
 		},
-		Storage: make([]payloads.StorageResource, len(attachments)),
-		Restart: true,
+		Storage:         make([]payloads.StorageResource, len(attachments)),
+		Restart:         restart,
+		MigrationSource: migrationSource,
+		PreferredNode:   preferredNode,
 	}
 
 	if cnci != nil {
-		restartCmd.Networking.ConcentratorUUID = cnci.ID
-		restartCmd.Networking.ConcentratorIP = cnci.IPAddress
-		restartCmd.Networking.Subnet = i.Subnet
-		restartCmd.Networking.PrivateIP = i.IPAddress
+		startCmd.Networking.ConcentratorUUID = cnci.ID
+		startCmd.Networking.ConcentratorIP = cnci.IPAddress
+		startCmd.Networking.Subnet = i.Subnet
+		startCmd.Networking.PrivateIP = i.IPAddress
 	}
 
 	if w.VMType == payloads.Docker {
-		restartCmd.DockerImage = w.ImageName
+		startCmd.DockerImage = w.ImageName
 	}
 
 	for k := range attachments {
-		vol := &restartCmd.Storage[k]
+		vol := &startCmd.Storage[k]
 		vol.ID = attachments[k].BlockID
 		vol.Bootable = attachments[k].Boot
 		vol.Ephemeral = attachments[k].Ephemeral
 	}
 
 	payload := payloads.Start{
-		Start: restartCmd,
+		Start: startCmd,
 	}
 
 	y, err := yaml.Marshal(payload)
@@ -666,7 +930,6 @@ func (client *ssntpClient) RestartInstance(i *types.Instance, w *types.Workload,
 	_, _ = buf.Write(b)
 	_, _ = buf.WriteString("\n...\n")
 
-	glog.Info("RESTART instance: ", i.ID)
 	glog.V(1).Info(buf.String())
 
 	_, err = client.ssntp.SendCommand(ssntp.START, buf.Bytes())
@@ -674,6 +937,210 @@ func (client *ssntpClient) RestartInstance(i *types.Instance, w *types.Workload,
 	return err
 }
 
+// sendMigrateCommand asks the node an instance runs on to migrate it to the
+// target node, which waits for the instance state on uri.
+func (client *ssntpClient) sendMigrateCommand(instanceID string, nodeID string,
+	targetID string, uri string) error {
+	payload := payloads.Migrate{
+		Migrate: payloads.MigrateCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+			TargetAgentUUID:   targetID,
+			URI:               uri,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Info("MIGRATE instance: ", instanceID, " to node ", targetID)
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.MIGRATE, y)
+	if err == nil {
+		commandsSent.Inc(ssntp.MIGRATE.String())
+	}
+
+	return err
+}
+
+// sendConsoleCommand sends a CONSOLE command to the node an instance runs on
+// and waits for its reply. The payload of the reply is returned if it is the
+// expected event.
+func (client *ssntpClient) sendConsoleCommand(instanceID string, nodeID string,
+	consoleType payloads.ConsoleType, lines int, event ssntp.Event) ([]byte, error) {
+	payload := payloads.Console{
+		Console: payloads.ConsoleCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+			Type:              consoleType,
+			Lines:             lines,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	glog.Info("CONSOLE instance: ", instanceID, " type ", consoleType)
+	glog.V(1).Info(string(y))
+
+	ctx, cancel := context.WithTimeout(context.Background(), consoleTimeout)
+	defer cancel()
+
+	reply, err := client.ssntp.SendCommandWait(ctx, ssntp.CONSOLE, y)
+	if err == context.DeadlineExceeded {
+		commandsSent.Inc(ssntp.CONSOLE.String())
+		return nil, fmt.Errorf("Timed out waiting for console of %s", instanceID)
+	} else if err != nil {
+		return nil, err
+	}
+	commandsSent.Inc(ssntp.CONSOLE.String())
+
+	glog.V(1).Info(string(reply.Payload))
+
+	switch {
+	case reply.Type == ssntp.ERROR && (ssntp.Error)(reply.Operand) == ssntp.ConsoleFailure:
+		return nil, client.consoleFailure(reply.Payload)
+	case reply.Type == ssntp.EVENT && (ssntp.Event)(reply.Operand) == event:
+		return reply.Payload, nil
+	}
+
+	return nil, fmt.Errorf("Unexpected reply to console request: %s", reply)
+}
+
+// ConsoleLog returns the last lines of the console log of an instance, or
+// as much of the log as its node is willing to send if lines is 0.
+func (client *ssntpClient) ConsoleLog(instanceID string, nodeID string, lines int) (string, error) {
+	payload, err := client.sendConsoleCommand(instanceID, nodeID,
+		payloads.ConsoleTypeLog, lines, ssntp.ConsoleLog)
+	if err != nil {
+		return "", err
+	}
+
+	var event payloads.EventConsoleLog
+	err = yaml.Unmarshal(payload, &event)
+	if err != nil {
+		return "", errors.Wrap(err, "Error unmarshalling ConsoleLog")
+	}
+
+	return event.ConsoleLog.Log, nil
+}
+
+// OpenConsole returns a connection to the serial console of an instance.
+func (client *ssntpClient) OpenConsole(instanceID string, nodeID string) (net.Conn, error) {
+	payload, err := client.sendConsoleCommand(instanceID, nodeID,
+		payloads.ConsoleTypeInteractive, 0, ssntp.ConsoleReady)
+	if err != nil {
+		return nil, err
+	}
+
+	var event payloads.EventConsoleReady
+	err = yaml.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error unmarshalling ConsoleReady")
+	}
+	ready := &event.ConsoleReady
+
+	config := &ssntp.Config{CAcert: *caCert, Cert: *cert}
+	tlsConfig, err := ssntp.PeerTLSConfig(config, false, ssntp.AGENT)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load console certificates")
+	}
+
+	dialer := &net.Dialer{Timeout: consoleTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", ready.Address, tlsConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to connect to console of %s", instanceID)
+	}
+
+	w := bufio.NewWriter(conn)
+	_, _ = w.WriteString(ready.Token + "\n")
+	err = w.Flush()
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrapf(err, "Unable to connect to console of %s", instanceID)
+	}
+
+	return conn, nil
+}
+
+func (client *ssntpClient) sendPowerCommand(cmd ssntp.Command, payload interface{}, instanceID string) error {
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Info(cmd, " instance: ", instanceID)
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(cmd, y)
+	if err == nil {
+		commandsSent.Inc(cmd.String())
+	}
+
+	return err
+}
+
+func (client *ssntpClient) RebootInstance(instanceID string, nodeID string, hard bool) error {
+	payload := payloads.Reboot{
+		Reboot: payloads.RebootCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+			Hard:              hard,
+		},
+	}
+
+	return client.sendPowerCommand(ssntp.REBOOT, &payload, instanceID)
+}
+
+func (client *ssntpClient) PauseInstance(instanceID string, nodeID string) error {
+	payload := payloads.Pause{
+		Pause: payloads.PowerCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	return client.sendPowerCommand(ssntp.PAUSE, &payload, instanceID)
+}
+
+func (client *ssntpClient) UnpauseInstance(instanceID string, nodeID string) error {
+	payload := payloads.Unpause{
+		Unpause: payloads.PowerCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	return client.sendPowerCommand(ssntp.UNPAUSE, &payload, instanceID)
+}
+
+func (client *ssntpClient) SuspendInstance(instanceID string, nodeID string) error {
+	payload := payloads.Suspend{
+		Suspend: payloads.PowerCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	return client.sendPowerCommand(ssntp.SUSPEND, &payload, instanceID)
+}
+
+func (client *ssntpClient) ResumeInstance(instanceID string, nodeID string) error {
+	payload := payloads.Resume{
+		Resume: payloads.PowerCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	return client.sendPowerCommand(ssntp.RESUME, &payload, instanceID)
+}
+
 func (client *ssntpClient) EvacuateNode(nodeID string) error {
 	evacuateCmd := payloads.EvacuateCmd{
 		WorkloadAgentUUID: nodeID,
@@ -736,6 +1203,60 @@ func (client *ssntpClient) attachVolume(volID string, instanceID string, nodeID
 	glog.V(1).Info(string(y))
 
 	_, err = client.ssntp.SendCommand(ssntp.AttachVolume, y)
+	if err == nil {
+		commandsSent.Inc(ssntp.AttachVolume.String())
+	}
+
+	return err
+}
+
+func (client *ssntpClient) detachVolume(volID string, instanceID string, nodeID string) error {
+	payload := payloads.DetachVolume{
+		Detach: payloads.VolumeCmd{
+			InstanceUUID:      instanceID,
+			VolumeUUID:        volID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("DetachVolume %s from %s\n", volID, instanceID)
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.DetachVolume, y)
+	if err == nil {
+		commandsSent.Inc(ssntp.DetachVolume.String())
+	}
+
+	return err
+}
+
+func (client *ssntpClient) extendVolume(volID string, instanceID string, nodeID string, sizeGiB int) error {
+	payload := payloads.ExtendVolume{
+		Extend: payloads.ExtendVolumeCmd{
+			InstanceUUID:      instanceID,
+			VolumeUUID:        volID,
+			WorkloadAgentUUID: nodeID,
+			SizeGiB:           sizeGiB,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("ExtendVolume %s of %s to %d GiB\n", volID, instanceID, sizeGiB)
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.ExtendVolume, y)
+	if err == nil {
+		commandsSent.Inc(ssntp.ExtendVolume.String())
+	}
 
 	return err
 }
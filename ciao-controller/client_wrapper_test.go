@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
@@ -91,8 +92,41 @@ func (client *ssntpClientWrapper) StopInstance(instanceID string, nodeID string)
 }
 
 func (client *ssntpClientWrapper) RestartInstance(i *types.Instance, w *types.Workload,
+	t *types.Tenant, preferredNode string) error {
+	return client.realClient.RestartInstance(i, w, t, preferredNode)
+}
+
+func (client *ssntpClientWrapper) RebootInstance(instanceID string, nodeID string, hard bool) error {
+	return client.realClient.RebootInstance(instanceID, nodeID, hard)
+}
+
+func (client *ssntpClientWrapper) PauseInstance(instanceID string, nodeID string) error {
+	return client.realClient.PauseInstance(instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) UnpauseInstance(instanceID string, nodeID string) error {
+	return client.realClient.UnpauseInstance(instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) SuspendInstance(instanceID string, nodeID string) error {
+	return client.realClient.SuspendInstance(instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) ResumeInstance(instanceID string, nodeID string) error {
+	return client.realClient.ResumeInstance(instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) MigrateInstance(i *types.Instance, w *types.Workload,
 	t *types.Tenant) error {
-	return client.realClient.RestartInstance(i, w, t)
+	return client.realClient.MigrateInstance(i, w, t)
+}
+
+func (client *ssntpClientWrapper) ConsoleLog(instanceID string, nodeID string, lines int) (string, error) {
+	return client.realClient.ConsoleLog(instanceID, nodeID, lines)
+}
+
+func (client *ssntpClientWrapper) OpenConsole(instanceID string, nodeID string) (net.Conn, error) {
+	return client.realClient.OpenConsole(instanceID, nodeID)
 }
 
 func (client *ssntpClientWrapper) EvacuateNode(nodeID string) error {
@@ -115,6 +149,14 @@ func (client *ssntpClientWrapper) attachVolume(volID string, instanceID string,
 	return client.realClient.attachVolume(volID, instanceID, nodeID)
 }
 
+func (client *ssntpClientWrapper) detachVolume(volID string, instanceID string, nodeID string) error {
+	return client.realClient.detachVolume(volID, instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) extendVolume(volID string, instanceID string, nodeID string, sizeGiB int) error {
+	return client.realClient.extendVolume(volID, instanceID, nodeID, sizeGiB)
+}
+
 func (client *ssntpClientWrapper) ssntpClient() *ssntp.Client {
 	return client.realClient.ssntpClient()
 }
@@ -29,6 +29,12 @@ import (
 )
 
 func (c *controller) restartInstance(instanceID string) error {
+	return c.restartInstanceOnNode(instanceID, "")
+}
+
+// restartInstanceOnNode restarts a stopped instance on preferredNode if the
+// instance still fits on it, or on any other node otherwise.
+func (c *controller) restartInstanceOnNode(instanceID string, preferredNode string) error {
 	// should I bother to see if instanceID is valid?
 	i, err := c.ds.GetInstance(instanceID)
 	if err != nil {
@@ -57,7 +63,7 @@ func (c *controller) restartInstance(instanceID string) error {
 	}
 
 	go func() {
-		if err := c.client.RestartInstance(i, &w, t); err != nil {
+		if err := c.client.RestartInstance(i, &w, t, preferredNode); err != nil {
 			glog.Warningf("Error restarting instance: %v", err)
 		}
 	}()
@@ -65,6 +71,160 @@ func (c *controller) restartInstance(instanceID string) error {
 	return nil
 }
 
+// rebuildInstance restarts an instance on a new node, keeping its network
+// identity and volumes.  The instance must either be stopped or be assigned
+// to a node that is no longer connected.  As such a node may only be
+// partitioned from the cluster, with the instance still running, force must
+// be set to confirm that the node is down.  The node will be told to delete
+// its copy of the instance if it ever reconnects.
+func (c *controller) rebuildInstance(instanceID string, force bool) error {
+	i, err := c.ds.GetInstance(instanceID)
+	if err != nil {
+		return err
+	}
+
+	if i.CNCI {
+		return errors.New("You may not rebuild a CNCI")
+	}
+
+	if i.State == payloads.Migrating {
+		return errors.New("You may not rebuild a migrating instance")
+	}
+
+	if i.NodeID == "" && i.State != payloads.Exited {
+		return fmt.Errorf("Instance must be %s, not %s",
+			payloads.Exited, i.State)
+	}
+
+	if i.NodeID != "" {
+		if _, err := c.ds.GetNode(i.NodeID); err == nil {
+			return fmt.Errorf("Instance is %s on node %s", i.State, i.NodeID)
+		}
+
+		if !force {
+			return types.ErrNodeNotFenced
+		}
+	}
+
+	err = c.ds.InstanceRebuilding(instanceID)
+	if err != nil {
+		return err
+	}
+
+	return c.restartInstance(instanceID)
+}
+
+// resizeStopTimeout is the maximum amount of time resizeInstance waits for
+// a running instance to stop.
+const resizeStopTimeout = 2 * time.Minute
+
+// resourcesDelta returns the resources that need to be consumed and those
+// that can be released when moving from the from resources to the to
+// resources.
+func resourcesDelta(from, to []payloads.RequestedResource) (grow, shrink []payloads.RequestedResource) {
+	values := make(map[payloads.Resource]int)
+	for _, r := range to {
+		values[r.Type] += r.Value
+	}
+	for _, r := range from {
+		values[r.Type] -= r.Value
+	}
+
+	for t, v := range values {
+		if v > 0 {
+			grow = append(grow, payloads.RequestedResource{Type: t, Value: v})
+		} else if v < 0 {
+			shrink = append(shrink, payloads.RequestedResource{Type: t, Value: -v})
+		}
+	}
+
+	return grow, shrink
+}
+
+// resizeInstance gives an instance the resources of another workload.  A
+// running instance is stopped and then restarted with its new resources,
+// preferably on the node it was running on.
+func (c *controller) resizeInstance(instanceID string, workloadID string) error {
+	i, err := c.ds.GetInstance(instanceID)
+	if err != nil {
+		return err
+	}
+
+	if i.CNCI {
+		return errors.New("You may not resize a CNCI")
+	}
+
+	if i.State != payloads.Running && i.State != payloads.Exited {
+		return fmt.Errorf("Instance must be %s or %s, not %s",
+			payloads.Running, payloads.Exited, i.State)
+	}
+
+	if i.WorkloadID == workloadID {
+		return fmt.Errorf("Instance already uses workload %s", workloadID)
+	}
+
+	from, err := c.ds.GetWorkload(i.TenantID, i.WorkloadID)
+	if err != nil {
+		return err
+	}
+
+	to, err := c.ds.GetWorkload(i.TenantID, workloadID)
+	if err != nil {
+		return err
+	}
+
+	if from.VMType != to.VMType || from.FWType != to.FWType ||
+		from.ImageName != to.ImageName {
+		return errors.New("Workload must have the same VM type, firmware and image")
+	}
+
+	grow, shrink := resourcesDelta(from.Defaults, to.Defaults)
+	if len(grow) > 0 {
+		res := <-c.qs.Consume(i.TenantID, grow...)
+		if !res.Allowed() {
+			c.qs.Release(i.TenantID, res.Resources()...)
+			return types.ErrQuota
+		}
+	}
+
+	nodeID := i.NodeID
+	running := i.State == payloads.Running
+	if running {
+		if err := c.stopInstance(instanceID); err != nil {
+			c.qs.Release(i.TenantID, grow...)
+			return err
+		}
+	}
+
+	go func() {
+		if running && !c.waitForInstances([]string{instanceID}, resizeStopTimeout,
+			func(i *types.Instance) bool {
+				return i.State != payloads.Exited
+			}) {
+			glog.Warningf("Instance %s did not stop, not resizing", instanceID)
+			c.qs.Release(i.TenantID, grow...)
+			return
+		}
+
+		if err := c.ds.InstanceResized(instanceID, workloadID); err != nil {
+			glog.Warningf("Error resizing instance %s: %v", instanceID, err)
+			c.qs.Release(i.TenantID, grow...)
+			return
+		}
+		c.qs.Release(i.TenantID, shrink...)
+
+		if !running {
+			return
+		}
+
+		if err := c.restartInstanceOnNode(instanceID, nodeID); err != nil {
+			glog.Warningf("Error restarting resized instance %s: %v", instanceID, err)
+		}
+	}()
+
+	return nil
+}
+
 func (c *controller) stopInstance(instanceID string) error {
 	// get node id.  If there is no node id we can't send a delete
 	i, err := c.ds.GetInstance(instanceID)
@@ -80,6 +240,10 @@ func (c *controller) stopInstance(instanceID string) error {
 		return errors.New("You may not stop a pending instance")
 	}
 
+	if i.State == payloads.Migrating {
+		return errors.New("You may not stop a migrating instance")
+	}
+
 	go func() {
 		if err := c.client.StopInstance(instanceID, i.NodeID); err != nil {
 			glog.Warningf("Error stopping instance: %v", err)
@@ -89,6 +253,139 @@ func (c *controller) stopInstance(instanceID string) error {
 	return nil
 }
 
+// powerInstance checks that an instance is assigned to a node and is in the
+// state required by a power command before sending the command with send.
+func (c *controller) powerInstance(instanceID string, state string,
+	send func(instanceID string, nodeID string) error) error {
+	i, err := c.ds.GetInstance(instanceID)
+	if err != nil {
+		return err
+	}
+
+	if i.NodeID == "" {
+		return types.ErrInstanceNotAssigned
+	}
+
+	if i.State != state {
+		return fmt.Errorf("Instance must be %s, not %s", state, i.State)
+	}
+
+	go func() {
+		if err := send(instanceID, i.NodeID); err != nil {
+			glog.Warningf("Error sending power command for instance %s: %v",
+				instanceID, err)
+		}
+	}()
+
+	return nil
+}
+
+func (c *controller) rebootInstance(instanceID string, hard bool) error {
+	return c.powerInstance(instanceID, payloads.Running,
+		func(instanceID string, nodeID string) error {
+			return c.client.RebootInstance(instanceID, nodeID, hard)
+		})
+}
+
+func (c *controller) pauseInstance(instanceID string) error {
+	return c.powerInstance(instanceID, payloads.Running, c.client.PauseInstance)
+}
+
+func (c *controller) unpauseInstance(instanceID string) error {
+	return c.powerInstance(instanceID, payloads.Paused, c.client.UnpauseInstance)
+}
+
+func (c *controller) suspendInstance(instanceID string) error {
+	return c.powerInstance(instanceID, payloads.Running, c.client.SuspendInstance)
+}
+
+func (c *controller) resumeInstance(instanceID string) error {
+	return c.powerInstance(instanceID, payloads.Suspended, c.client.ResumeInstance)
+}
+
+func (c *controller) migrateInstance(instanceID string) error {
+	i, err := c.ds.GetInstance(instanceID)
+	if err != nil {
+		return err
+	}
+
+	if i.NodeID == "" {
+		return types.ErrInstanceNotAssigned
+	}
+
+	if i.CNCI {
+		return errors.New("You may not migrate a CNCI")
+	}
+
+	w, err := c.ds.GetWorkload(i.TenantID, i.WorkloadID)
+	if err != nil {
+		return err
+	}
+
+	if w.VMType != payloads.QEMU {
+		return errors.New("You may only migrate VM instances")
+	}
+
+	t, err := c.ds.GetTenant(i.TenantID)
+	if err != nil {
+		return err
+	}
+
+	err = c.ds.InstanceMigrating(instanceID)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := c.client.MigrateInstance(i, &w, t); err != nil {
+			glog.Warningf("Error migrating instance: %v", err)
+			err = c.ds.MigrateFailure(instanceID, payloads.MigrateTransferFailure, "")
+			if err != nil {
+				glog.Warningf("Error adding MigrateFailure to datastore: %v", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// consoleNode returns the node running the instance whose console is to
+// be accessed.
+func (c *controller) consoleNode(instanceID string) (string, error) {
+	i, err := c.ds.GetInstance(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	if i.NodeID == "" {
+		return "", types.ErrInstanceNotAssigned
+	}
+
+	if i.State == payloads.Migrating {
+		return "", errors.New("Instance is being migrated")
+	}
+
+	return i.NodeID, nil
+}
+
+func (c *controller) consoleLog(instanceID string, lines int) (string, error) {
+	nodeID, err := c.consoleNode(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	return c.client.ConsoleLog(instanceID, nodeID, lines)
+}
+
+func (c *controller) openConsole(instanceID string) (net.Conn, error) {
+	nodeID, err := c.consoleNode(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.client.OpenConsole(instanceID, nodeID)
+}
+
 // delete an instance, wait for the deleted event.
 func (c *controller) deleteInstanceSync(instanceID string) error {
 	wait := make(chan struct{})
@@ -146,6 +443,10 @@ func (c *controller) deleteInstance(instanceID string) error {
 		return types.ErrInstanceNotAssigned
 	}
 
+	if i.State == payloads.Migrating {
+		return errors.New("You may not delete a migrating instance")
+	}
+
 	// check for any external IPs
 	IPs := c.ds.GetMappedIPs(&i.TenantID)
 	for _, m := range IPs {
@@ -281,6 +582,12 @@ func (c *controller) startWorkload(w types.WorkloadRequest) ([]*types.Instance,
 		return nil, err
 	}
 
+	if len(w.Constraints) > 0 {
+		defaults := make([]payloads.RequestedResource, 0, len(wl.Defaults)+len(w.Constraints))
+		defaults = append(defaults, wl.Defaults...)
+		wl.Defaults = append(defaults, w.Constraints...)
+	}
+
 	err = c.confirmTenant(w.TenantID)
 	if err != nil {
 		return nil, err
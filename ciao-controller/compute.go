@@ -16,6 +16,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"regexp"
 	"sort"
 	"strconv"
@@ -23,6 +24,7 @@ import (
 	"github.com/ciao-project/ciao/ciao-controller/api"
 	"github.com/ciao-project/ciao/ciao-controller/types"
 	"github.com/ciao-project/ciao/ciao-storage"
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/uuid"
 	"github.com/gorilla/mux"
 )
@@ -36,6 +38,18 @@ func instanceToServer(ctl *controller, instance *types.Instance) (api.ServerDeta
 		volumes = append(volumes, vol.BlockID)
 	}
 
+	var vcpus, memMB int
+	if wl, err := ctl.ds.GetWorkload(instance.TenantID, instance.WorkloadID); err == nil {
+		for _, r := range wl.Defaults {
+			switch r.Type {
+			case payloads.VCPUs:
+				vcpus = r.Value
+			case payloads.MemMB:
+				memMB = r.Value
+			}
+		}
+	}
+
 	server := api.ServerDetails{
 		NodeID:     instance.NodeID,
 		ID:         instance.ID,
@@ -53,6 +67,8 @@ func instanceToServer(ctl *controller, instance *types.Instance) (api.ServerDeta
 		SSHPort: instance.SSHPort,
 		Created: instance.CreateTime,
 		Name:    instance.Name,
+		VCPUs:   vcpus,
+		MemMB:   memMB,
 	}
 
 	return server, nil
@@ -334,6 +350,44 @@ func abstractBlockDevices(blockDeviceMappings []api.BlockDeviceMapping) (volumes
 	return
 }
 
+// serverPlacementConstraints converts the node selector, affinity and
+// anti-affinity of a server creation request into requested resources.
+func serverPlacementConstraints(server api.CreateServerRequest) []payloads.RequestedResource {
+	var constraints []payloads.RequestedResource
+
+	keys := make([]string, 0, len(server.Server.NodeSelector))
+	for key := range server.Server.NodeSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		constraints = append(constraints, payloads.RequestedResource{
+			Type:        payloads.NodeSelector,
+			ValueString: key + "=" + server.Server.NodeSelector[key],
+			Mandatory:   true,
+		})
+	}
+
+	for _, workloadID := range server.Server.Affinity {
+		constraints = append(constraints, payloads.RequestedResource{
+			Type:        payloads.Affinity,
+			ValueString: workloadID,
+			Mandatory:   true,
+		})
+	}
+
+	for _, workloadID := range server.Server.AntiAffinity {
+		constraints = append(constraints, payloads.RequestedResource{
+			Type:        payloads.AntiAffinity,
+			ValueString: workloadID,
+			Mandatory:   true,
+		})
+	}
+
+	return constraints
+}
+
 func (c *controller) CreateServer(tenant string, server api.CreateServerRequest) (resp interface{}, err error) {
 	nInstances := 1
 
@@ -358,15 +412,23 @@ func (c *controller) CreateServer(tenant string, server api.CreateServerRequest)
 	}
 	volumes := abstractBlockDevices(blockDeviceMappings)
 
+	constraints := serverPlacementConstraints(server)
+	err = c.validateWorkloadPlacement(tenant, constraints)
+	if err != nil {
+		return server, err
+	}
+	setPlacementWorkload(constraints, server.Server.WorkloadID)
+
 	label := server.Server.Metadata["label"]
 
 	w := types.WorkloadRequest{
-		WorkloadID: server.Server.WorkloadID,
-		TenantID:   tenant,
-		Instances:  nInstances,
-		TraceLabel: label,
-		Volumes:    volumes,
-		Name:       server.Server.Name,
+		WorkloadID:  server.Server.WorkloadID,
+		TenantID:    tenant,
+		Instances:   nInstances,
+		TraceLabel:  label,
+		Volumes:     volumes,
+		Name:        server.Server.Name,
+		Constraints: constraints,
 	}
 	var e error
 	instances, err := c.startWorkload(w)
@@ -495,6 +557,96 @@ func (c *controller) StopServer(tenant string, ID string) error {
 	return err
 }
 
+func (c *controller) RebootServer(tenant string, ID string, hard bool) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.rebootInstance(ID, hard)
+}
+
+func (c *controller) PauseServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.pauseInstance(ID)
+}
+
+func (c *controller) UnpauseServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.unpauseInstance(ID)
+}
+
+func (c *controller) SuspendServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.suspendInstance(ID)
+}
+
+func (c *controller) ResumeServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.resumeInstance(ID)
+}
+
+func (c *controller) MigrateServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.migrateInstance(ID)
+}
+
+func (c *controller) EvacuateServer(tenant string, ID string, force bool) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.rebuildInstance(ID, force)
+}
+
+func (c *controller) ResizeServer(tenant string, ID string, workloadID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.resizeInstance(ID, workloadID)
+}
+
+func (c *controller) ConsoleLog(tenant string, ID string, lines int) (string, error) {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return "", err
+	}
+
+	return c.consoleLog(ID, lines)
+}
+
+func (c *controller) OpenConsole(tenant string, ID string) (io.ReadWriteCloser, error) {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.openConsole(ID)
+}
+
 func (c *controller) createComputeRoutes(r *mux.Router) error {
 	legacyComputeRoutes(c, r)
 
@@ -1057,3 +1057,25 @@ func testTraceData(t *testing.T, httpExpectedStatus int, validToken bool) {
 func TestTraceData(t *testing.T) {
 	testTraceData(t, http.StatusOK, true)
 }
+
+func TestServerPlacementConstraints(t *testing.T) {
+	var server api.CreateServerRequest
+	server.Server.WorkloadID = "db"
+	server.Server.NodeSelector = map[string]string{"rack": "r1", "disk": "ssd"}
+	server.Server.Affinity = []string{"cache"}
+	server.Server.AntiAffinity = []string{""}
+
+	constraints := serverPlacementConstraints(server)
+	setPlacementWorkload(constraints, server.Server.WorkloadID)
+
+	expected := []payloads.RequestedResource{
+		{Type: payloads.NodeSelector, ValueString: "disk=ssd", Mandatory: true},
+		{Type: payloads.NodeSelector, ValueString: "rack=r1", Mandatory: true},
+		{Type: payloads.Affinity, ValueString: "cache", Mandatory: true},
+		{Type: payloads.AntiAffinity, ValueString: "db", Mandatory: true},
+	}
+
+	if !reflect.DeepEqual(constraints, expected) {
+		t.Fatalf("Expected constraints %v, got %v", expected, constraints)
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/ciao-project/ciao/testutil"
 	"github.com/ciao-project/ciao/uuid"
 	jsonpatch "github.com/evanphx/json-patch"
+	yaml "gopkg.in/yaml.v2"
 )
 
 func addTestWorkload(tenantID string) error {
@@ -507,6 +508,27 @@ func TestRestartInstance(t *testing.T) {
 	}
 }
 
+func TestResourcesDelta(t *testing.T) {
+	from := []payloads.RequestedResource{
+		{Type: payloads.VCPUs, Value: 2},
+		{Type: payloads.MemMB, Value: 1024},
+		{Type: payloads.Instance, Value: 1},
+	}
+	to := []payloads.RequestedResource{
+		{Type: payloads.VCPUs, Value: 4},
+		{Type: payloads.MemMB, Value: 512},
+		{Type: payloads.Instance, Value: 1},
+	}
+
+	grow, shrink := resourcesDelta(from, to)
+	if len(grow) != 1 || grow[0].Type != payloads.VCPUs || grow[0].Value != 2 {
+		t.Errorf("Unexpected resources to consume: %v", grow)
+	}
+	if len(shrink) != 1 || shrink[0].Type != payloads.MemMB || shrink[0].Value != 512 {
+		t.Errorf("Unexpected resources to release: %v", shrink)
+	}
+}
+
 func TestEvacuateNode(t *testing.T) {
 	client, err := testutil.NewSsntpTestClientConnection("EvacuateNode", ssntp.AGENT, testutil.AgentUUID)
 	if err != nil {
@@ -555,6 +577,91 @@ func TestRestoreNode(t *testing.T) {
 	}
 }
 
+func TestRebuildNode(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	// The node is now seen as disconnected but may still be running
+	// its instances.
+
+	err := ctl.ds.DeleteNode(client.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctl.RebuildNode(client.UUID, false)
+	if err != types.ErrNodeNotFenced {
+		t.Fatalf("expected %v, got %v", types.ErrNodeNotFenced, err)
+	}
+
+	serverCh := server.AddCmdChan(ssntp.START)
+
+	err = ctl.RebuildNode(client.UUID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.START)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InstanceUUID != instances[0].ID {
+		t.Fatal("Did not get correct Instance ID")
+	}
+
+	rebuilt := ctl.ds.RebuiltInstances(client.UUID)
+	if len(rebuilt) != 1 || rebuilt[0] != instances[0].ID {
+		t.Fatalf("expected rebuilt instance %s, got %v", instances[0].ID, rebuilt)
+	}
+
+	// When the node reconnects it must be told to delete its copy.
+
+	y, err := yaml.Marshal(payloads.NodeConnected{
+		Connected: payloads.NodeConnectedEvent{
+			NodeUUID: client.UUID,
+			NodeType: payloads.ComputeNode,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverCh = server.AddCmdChan(ssntp.DELETE)
+
+	wrappedClient.EventNotify(ssntp.NodeConnected, &ssntp.Frame{Payload: y})
+
+	result, err = server.GetCmdChanResult(serverCh, ssntp.DELETE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InstanceUUID != instances[0].ID || result.NodeUUID != client.UUID {
+		t.Fatalf("expected DELETE of %s on %s, got %s on %s", instances[0].ID,
+			client.UUID, result.InstanceUUID, result.NodeUUID)
+	}
+
+	// Deleting the copy must not delete the rebuilt instance.
+
+	controllerCh := wrappedClient.addEventChan(ssntp.InstanceDeleted)
+	go client.SendDeleteEvent(instances[0].ID)
+	err = wrappedClient.getEventChan(controllerCh, ssntp.InstanceDeleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.ds.GetInstance(instances[0].ID)
+	if err != nil {
+		t.Fatalf("Rebuilt instance deleted: %v", err)
+	}
+
+	if rebuilt := ctl.ds.RebuiltInstances(client.UUID); len(rebuilt) != 0 {
+		t.Fatalf("expected no rebuilt instances, got %v", rebuilt)
+	}
+}
+
 func TestAttachVolume(t *testing.T) {
 	client, err := testutil.NewSsntpTestClientConnection("AttachVolume", ssntp.AGENT, testutil.AgentUUID)
 	if err != nil {
@@ -715,66 +822,98 @@ func doDetachVolumeCommand(t *testing.T, fail bool) {
 	}
 
 	if data.State != types.InUse {
-		t.Fatalf("expected state %s, got %s\n", types.Detaching, data.State)
+		t.Fatalf("expected state %s, got %s\n", types.InUse, data.State)
 	}
 
-	if fail {
-		err := ctl.DetachVolume(tenantID, volume, "")
-		if err == nil {
-			t.Fatal("Expected error when detaching volume from active instance")
+	serverCh := server.AddCmdChan(ssntp.DetachVolume)
+	agentCh := client.AddCmdChan(ssntp.DetachVolume)
+	var serverErrorCh chan testutil.Result
+	var controllerCh chan struct{}
 
-		}
+	if fail == true {
+		serverErrorCh = server.AddErrorChan(ssntp.DetachVolumeFailure)
+		controllerCh = wrappedClient.addErrorChan(ssntp.DetachVolumeFailure)
+		client.DetachFail = true
+		client.DetachVolumeFailReason = payloads.DetachVolumeDetachFailure
 
-		data, err := ctl.ds.GetBlockDevice(volume)
-		if err != nil {
-			t.Fatal(err)
+		defer func() {
+			client.DetachFail = false
+			client.DetachVolumeFailReason = ""
+		}()
+	}
 
-		}
+	err = ctl.DetachVolume(tenantID, volume, "")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		if data.State != types.InUse {
-			t.Fatalf("expected state %s, got %s\n", types.Detaching, data.State)
+	result, err := server.GetCmdChanResult(serverCh, ssntp.DetachVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.InstanceUUID != instanceID ||
+		result.NodeUUID != client.UUID ||
+		result.VolumeUUID != volume {
+		t.Fatalf("expected %s %s %s, got %s %s %s", instanceID, client.UUID, volume, result.InstanceUUID, result.NodeUUID, result.VolumeUUID)
+	}
+
+	if fail == true {
+		_, err = client.GetCmdChanResult(agentCh, ssntp.DetachVolume)
+		if err == nil {
+			t.Fatal("Success when Failure expected")
 		}
-	} else {
-		serverCh := server.AddCmdChan(ssntp.DELETE)
-		clientCh := client.AddCmdChan(ssntp.DELETE)
 
-		err := ctl.stopInstance(instanceID)
+		_, err = server.GetErrorChanResult(serverErrorCh, ssntp.DetachVolumeFailure)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		result, err := server.GetCmdChanResult(serverCh, ssntp.DELETE)
+		err = wrappedClient.getErrorChan(controllerCh, ssntp.DetachVolumeFailure)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		_, err = client.GetCmdChanResult(clientCh, ssntp.DELETE)
+		// the volume is still attached to the instance.
+		data, err := ctl.ds.GetBlockDevice(volume)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if result.InstanceUUID != instanceID {
-			t.Fatal("Did not get correct Instance ID")
+		if data.State != types.InUse {
+			t.Fatalf("expected state %s, got %s\n", types.InUse, data.State)
 		}
+		return
+	}
 
-		err = sendStopEvent(client, instanceID)
-		if err != nil {
-			t.Fatal(err)
-		}
+	_, err = client.GetCmdChanResult(agentCh, ssntp.DetachVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		err = ctl.DetachVolume(tenantID, volume, "")
-		if err != nil {
-			t.Fatal(err)
-		}
+	data, err = ctl.ds.GetBlockDevice(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		data, err := ctl.ds.GetBlockDevice(volume)
-		if err != nil {
-			t.Fatal(err)
-		}
+	if data.State != types.Detaching {
+		t.Fatalf("expected state %s, got %s\n", types.Detaching, data.State)
+	}
 
-		if data.State != types.Available {
-			t.Fatalf("expected state %s, got %s\n", types.Detaching, data.State)
-		}
+	// the detach completes once the agent no longer reports the volume.
+	sendStatsCmd(client, t)
+
+	data, err = ctl.ds.GetBlockDevice(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.State != types.Available {
+		t.Fatalf("expected state %s, got %s\n", types.Available, data.State)
+	}
+
+	if len(ctl.ds.GetStorageAttachments(instanceID)) != 0 {
+		t.Fatal("expected attachment to be deleted")
 	}
 }
 
@@ -786,15 +925,136 @@ func TestDetachVolumeFailure(t *testing.T) {
 	doDetachVolumeCommand(t, true)
 }
 
+func TestDetachVolumeExited(t *testing.T) {
+	client, tenantID, volume, instanceID := doAttachVolumeCommand(t, false)
+	defer client.Ssntp.Close()
+
+	sendStatsCmd(client, t)
+
+	data, err := ctl.ds.GetBlockDevice(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.State != types.InUse {
+		t.Fatalf("expected state %s, got %s\n", types.InUse, data.State)
+	}
+
+	serverCh := server.AddCmdChan(ssntp.DELETE)
+	clientCh := client.AddCmdChan(ssntp.DELETE)
+
+	err = ctl.stopInstance(instanceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.DELETE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetCmdChanResult(clientCh, ssntp.DELETE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.InstanceUUID != instanceID {
+		t.Fatal("Did not get correct Instance ID")
+	}
+
+	err = sendStopEvent(client, instanceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctl.DetachVolume(tenantID, volume, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = ctl.ds.GetBlockDevice(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.State != types.Available {
+		t.Fatalf("expected state %s, got %s\n", types.Available, data.State)
+	}
+
+	if len(ctl.ds.GetStorageAttachments(instanceID)) != 0 {
+		t.Fatal("expected attachment to be deleted")
+	}
+}
+
 func TestDetachVolumeByAttachment(t *testing.T) {
-	tenant, err := addTestTenant()
+	client, tenantID, volume, instanceID := doAttachVolumeCommand(t, false)
+	defer client.Ssntp.Close()
+
+	sendStatsCmd(client, t)
+
+	err := ctl.DetachVolume(tenantID, volume, "invalidAttachment")
+	if err != api.ErrVolumeNotAttached {
+		t.Fatalf("expected %v, got %v", api.ErrVolumeNotAttached, err)
+	}
+
+	attachments := ctl.ds.GetStorageAttachments(instanceID)
+	if len(attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(attachments))
+	}
+
+	serverCh := server.AddCmdChan(ssntp.DetachVolume)
+
+	err = ctl.DetachVolume(tenantID, volume, attachments[0].ID)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	err = ctl.DetachVolume(tenant.ID, "invalidVolume", "attachmentID")
-	if err == nil {
-		t.Fatal("Detach by attachment ID not supported yet")
+	result, err := server.GetCmdChanResult(serverCh, ssntp.DetachVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.InstanceUUID != instanceID || result.VolumeUUID != volume {
+		t.Fatalf("expected %s %s, got %s %s", instanceID, volume, result.InstanceUUID, result.VolumeUUID)
+	}
+}
+
+func TestExtendVolumeCommand(t *testing.T) {
+	client, tenantID, volume, instanceID := doAttachVolumeCommand(t, false)
+	defer client.Ssntp.Close()
+
+	sendStatsCmd(client, t)
+
+	serverCh := server.AddCmdChan(ssntp.ExtendVolume)
+
+	data, err := ctl.ds.GetBlockDevice(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctl.ExtendVolume(tenantID, volume, data.Size+10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.ExtendVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.InstanceUUID != instanceID ||
+		result.NodeUUID != client.UUID ||
+		result.VolumeUUID != volume {
+		t.Fatalf("expected %s %s %s, got %s %s %s", instanceID, client.UUID, volume, result.InstanceUUID, result.NodeUUID, result.VolumeUUID)
+	}
+
+	data2, err := ctl.ds.GetBlockDevice(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data2.Size != data.Size+10 || data2.State != types.InUse {
+		t.Fatalf("expected size %d and state %s, got %d %s", data.Size+10, types.InUse, data2.Size, data2.State)
 	}
 }
 
@@ -1467,6 +1727,139 @@ func TestDeleteVolume(t *testing.T) {
 	}
 }
 
+func TestVolumeSnapshot(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volID := createTestVolume(tenant.ID, 20, t)
+
+	req := api.RequestedSnapshot{
+		VolumeID: volID,
+		Name:     "test-snapshot",
+	}
+
+	snapshot, err := ctl.CreateVolumeSnapshot(tenant.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot.VolumeID != volID || snapshot.TenantID != tenant.ID ||
+		snapshot.Size != 20 || snapshot.Name != req.Name {
+		t.Fatalf("incorrect snapshot returned\n")
+	}
+
+	snapshots, err := ctl.ListVolumeSnapshots(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 1 || snapshots[0].ID != snapshot.ID {
+		t.Fatalf("expected snapshot to be listed\n")
+	}
+
+	// other tenants cannot see the snapshot
+	tenant2, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.ShowVolumeSnapshot(tenant2.ID, snapshot.ID)
+	if err != types.ErrSnapshotNotFound {
+		t.Fatalf("expected %v, got %v", types.ErrSnapshotNotFound, err)
+	}
+
+	// volumes with snapshots cannot be deleted
+	err = ctl.DeleteVolume(tenant.ID, volID)
+	if err != types.ErrVolumeHasSnapshots {
+		t.Fatalf("expected %v, got %v", types.ErrVolumeHasSnapshots, err)
+	}
+
+	vol, err := ctl.CreateVolume(tenant.ID, api.RequestedVolume{SnapshotID: snapshot.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.ds.GetBlockDevice(vol.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctl.DeleteVolumeSnapshot(tenant.ID, snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.ShowVolumeSnapshot(tenant.ID, snapshot.ID)
+	if err != types.ErrSnapshotNotFound {
+		t.Fatalf("expected %v, got %v", types.ErrSnapshotNotFound, err)
+	}
+
+	err = ctl.DeleteVolume(tenant.ID, volID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtendVolume(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volID := createTestVolume(tenant.ID, 20, t)
+
+	err = ctl.ExtendVolume(tenant.ID, volID, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := ctl.ShowVolumeDetails(tenant.ID, volID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vol.Size != 30 {
+		t.Fatalf("expected size 30, got %d", vol.Size)
+	}
+
+	// volumes cannot be shrunk
+	err = ctl.ExtendVolume(tenant.ID, volID, 10)
+	if err != types.ErrBadRequest {
+		t.Fatalf("expected %v, got %v", types.ErrBadRequest, err)
+	}
+
+	quotas := []types.QuotaDetails{
+		{Name: "tenant-storage-quota", Value: 40},
+	}
+	ctl.qs.Update(tenant.ID, quotas)
+
+	err = ctl.ExtendVolume(tenant.ID, volID, 50)
+	if err != types.ErrQuota {
+		t.Fatalf("expected %v, got %v", types.ErrQuota, err)
+	}
+
+	vol, err = ctl.ShowVolumeDetails(tenant.ID, volID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if vol.Size != 30 {
+		t.Fatalf("expected size 30, got %d", vol.Size)
+	}
+
+	quotas = []types.QuotaDetails{
+		{Name: "tenant-storage-quota", Value: -1},
+	}
+	ctl.qs.Update(tenant.ID, quotas)
+
+	err = ctl.DeleteVolume(tenant.ID, volID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestShowVolumeDetails(t *testing.T) {
 	tenant, err := addTestTenant()
 	if err != nil {
@@ -2061,6 +2454,78 @@ func TestDeleteTenant(t *testing.T) {
 	}
 }
 
+// cloneTrackingDriver fails to delete the snapshots from which block
+// devices that have not been flattened were cloned, as Ceph does.
+type cloneTrackingDriver struct {
+	*storage.NoopDriver
+	clones map[string]string
+}
+
+func (d *cloneTrackingDriver) CreateBlockDeviceFromSnapshot(volumeUUID string, snapshotID string) (storage.BlockDevice, error) {
+	bd, err := d.NoopDriver.CreateBlockDeviceFromSnapshot(volumeUUID, snapshotID)
+	if err == nil {
+		d.clones[bd.ID] = volumeUUID + "@" + snapshotID
+	}
+	return bd, err
+}
+
+func (d *cloneTrackingDriver) FlattenBlockDevice(volumeUUID string) error {
+	delete(d.clones, volumeUUID)
+	return nil
+}
+
+func (d *cloneTrackingDriver) DeleteBlockDevice(volumeUUID string) error {
+	delete(d.clones, volumeUUID)
+	return nil
+}
+
+func (d *cloneTrackingDriver) DeleteBlockDeviceSnapshot(volumeUUID string, snapshotID string) error {
+	for clone, parent := range d.clones {
+		if parent == volumeUUID+"@"+snapshotID {
+			return fmt.Errorf("Snapshot %s has clone %s", parent, clone)
+		}
+	}
+	return nil
+}
+
+func TestDeleteTenantVolumeFromSnapshot(t *testing.T) {
+	driver := ctl.BlockDriver
+	defer func() { ctl.BlockDriver = driver }()
+	ctl.BlockDriver = &cloneTrackingDriver{
+		NoopDriver: &storage.NoopDriver{},
+		clones:     make(map[string]string),
+	}
+
+	config := types.TenantConfig{
+		Name:       "deleteTenantVolumeFromSnapshot",
+		SubnetBits: 24,
+	}
+
+	ID := uuid.Generate().String()
+
+	_, err := ctl.CreateTenant(ID, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volID := createTestVolume(ID, 20, t)
+
+	snapshot, err := ctl.CreateVolumeSnapshot(ID, api.RequestedSnapshot{VolumeID: volID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.CreateVolume(ID, api.RequestedVolume{SnapshotID: snapshot.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctl.DeleteTenant(ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 var ctl *controller
 var server *testutil.SsntpTestServer
 var wrappedClient *ssntpClientWrapper
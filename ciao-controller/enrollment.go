@@ -0,0 +1,334 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/ciao-project/ciao/ssntp/certs"
+	"github.com/ciao-project/ciao/uuid"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// enrollmentTokenLifetime is how long a join token can be used to submit
+// a CSR for.
+const enrollmentTokenLifetime = 24 * time.Hour
+
+type enrollmentToken struct {
+	types.EnrollmentToken
+	role ssntp.Role
+
+	// requestID is the enrollment request the token was used for. A
+	// token can only be used for a single CSR.
+	requestID string
+}
+
+type enrollmentRequest struct {
+	types.EnrollmentRequest
+	role ssntp.Role
+	csr  []byte
+	cert []byte
+}
+
+// enrollment keeps track of the join tokens and of the CSRs submitted
+// by joining nodes. Nothing is persisted: pending requests and unused
+// tokens are lost when the controller restarts.
+type enrollment struct {
+	sync.Mutex
+	anchorCert []byte
+	caCert     []byte
+	tokens     map[string]*enrollmentToken
+	requests   map[string]*enrollmentRequest
+}
+
+func newEnrollment(anchorCertPath string) (*enrollment, error) {
+	anchorCert, err := ioutil.ReadFile(anchorCertPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading anchor certificate")
+	}
+
+	block, _ := pem.Decode(anchorCert)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.Errorf("No certificate found in %s", anchorCertPath)
+	}
+
+	return &enrollment{
+		anchorCert: anchorCert,
+		caCert:     pem.EncodeToMemory(block),
+		tokens:     make(map[string]*enrollmentToken),
+		requests:   make(map[string]*enrollmentRequest),
+	}, nil
+}
+
+// enrollmentRole parses the role a join token grants. Enrollment is only
+// meant for compute and network nodes, other roles must be provisioned
+// by hand.
+func enrollmentRole(value string) (ssntp.Role, error) {
+	var role ssntp.Role
+
+	err := role.Set(value)
+	if err != nil {
+		return ssntp.UNKNOWN, err
+	}
+
+	switch role {
+	case ssntp.AGENT, ssntp.NETAGENT, ssntp.AGENT | ssntp.NETAGENT:
+		return role, nil
+	}
+
+	return ssntp.UNKNOWN, errors.Errorf("Role %s can not be enrolled", value)
+}
+
+func (e *enrollment) createToken(req types.EnrollmentTokenRequest) (types.EnrollmentToken, error) {
+	role, err := enrollmentRole(req.Role)
+	if err != nil {
+		glog.Warningf("Invalid enrollment token request: %v", err)
+		return types.EnrollmentToken{}, types.ErrBadRequest
+	}
+
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		return types.EnrollmentToken{}, errors.Wrap(err, "Error generating enrollment token")
+	}
+
+	token := &enrollmentToken{
+		EnrollmentToken: types.EnrollmentToken{
+			Token:       hex.EncodeToString(b),
+			Role:        req.Role,
+			AutoApprove: req.AutoApprove,
+			Expiry:      time.Now().Add(enrollmentTokenLifetime),
+		},
+		role: role,
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	e.purgeExpiredTokens()
+	e.tokens[token.Token] = token
+
+	return token.EnrollmentToken, nil
+}
+
+// purgeExpiredTokens forgets the tokens that expired before being used.
+// Tokens bound to a request are kept so that the node can keep polling
+// for its certificate.
+func (e *enrollment) purgeExpiredTokens() {
+	now := time.Now()
+	for t, token := range e.tokens {
+		if token.requestID == "" && now.After(token.Expiry) {
+			delete(e.tokens, t)
+		}
+	}
+}
+
+func (e *enrollment) listRequests() []types.EnrollmentRequest {
+	e.Lock()
+	defer e.Unlock()
+
+	requests := make([]types.EnrollmentRequest, 0, len(e.requests))
+	for _, r := range e.requests {
+		requests = append(requests, r.EnrollmentRequest)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Created.Before(requests[j].Created)
+	})
+
+	return requests
+}
+
+func (e *enrollment) sign(r *enrollmentRequest) error {
+	var cert bytes.Buffer
+
+	err := certs.CreateCertFromCSR(r.role, r.csr, e.anchorCert, &cert)
+	if err != nil {
+		return errors.Wrapf(err, "Error signing enrollment request %s", r.ID)
+	}
+
+	r.cert = cert.Bytes()
+	r.Status = types.EnrollmentApproved
+
+	glog.Infof("Enrollment request %s for %v approved", r.ID, r.Hosts)
+
+	return nil
+}
+
+func (e *enrollment) updateRequest(ID string, status types.EnrollmentStatus) error {
+	e.Lock()
+	defer e.Unlock()
+
+	r, ok := e.requests[ID]
+	if !ok {
+		return types.ErrEnrollmentNotFound
+	}
+
+	if r.Status != types.EnrollmentPending {
+		return types.ErrBadRequest
+	}
+
+	switch status {
+	case types.EnrollmentApproved:
+		return e.sign(r)
+	case types.EnrollmentRejected:
+		r.Status = types.EnrollmentRejected
+		glog.Infof("Enrollment request %s for %v rejected", r.ID, r.Hosts)
+		return nil
+	}
+
+	return types.ErrBadRequest
+}
+
+func (e *enrollment) response(r *enrollmentRequest) (types.EnrollResponse, error) {
+	resp := types.EnrollResponse{
+		ID:     r.ID,
+		Status: r.Status,
+	}
+
+	switch r.Status {
+	case types.EnrollmentRejected:
+		return resp, types.ErrEnrollmentRejected
+	case types.EnrollmentApproved:
+		resp.Cert = string(r.cert)
+		resp.CACert = string(e.caCert)
+	}
+
+	return resp, nil
+}
+
+func parseEnrollmentCSR(csr string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(csr))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("No CSR found")
+	}
+
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = request.CheckSignature()
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func (e *enrollment) enroll(req types.EnrollRequest) (types.EnrollResponse, error) {
+	request, err := parseEnrollmentCSR(req.CSR)
+	if err != nil {
+		glog.Warningf("Invalid enrollment CSR: %v", err)
+		return types.EnrollResponse{}, types.ErrBadRequest
+	}
+	fingerprint := certs.FingerPrint(request)
+
+	e.Lock()
+	defer e.Unlock()
+
+	token, ok := e.tokens[req.Token]
+	if !ok {
+		return types.EnrollResponse{}, types.ErrInvalidEnrollmentToken
+	}
+
+	if token.requestID != "" {
+		r := e.requests[token.requestID]
+		if r.Fingerprint != fingerprint {
+			glog.Warningf("Enrollment token for request %s reused for another CSR", r.ID)
+			return types.EnrollResponse{}, types.ErrInvalidEnrollmentToken
+		}
+		return e.response(r)
+	}
+
+	if time.Now().After(token.Expiry) {
+		delete(e.tokens, req.Token)
+		return types.EnrollResponse{}, types.ErrInvalidEnrollmentToken
+	}
+
+	r := &enrollmentRequest{
+		EnrollmentRequest: types.EnrollmentRequest{
+			ID:          uuid.Generate().String(),
+			Role:        token.Role,
+			Hosts:       request.DNSNames,
+			IPs:         []string{},
+			Fingerprint: fingerprint,
+			Status:      types.EnrollmentPending,
+			Created:     time.Now(),
+		},
+		role: token.role,
+		csr:  []byte(req.CSR),
+	}
+	for _, ip := range request.IPAddresses {
+		r.IPs = append(r.IPs, ip.String())
+	}
+
+	if token.AutoApprove {
+		err = e.sign(r)
+		if err != nil {
+			return types.EnrollResponse{}, err
+		}
+	} else {
+		glog.Infof("Enrollment request %s for %v waiting for approval", r.ID, r.Hosts)
+	}
+
+	token.requestID = r.ID
+	e.requests[r.ID] = r
+
+	return e.response(r)
+}
+
+func (c *controller) CreateEnrollmentToken(req types.EnrollmentTokenRequest) (types.EnrollmentToken, error) {
+	if c.enrollment == nil {
+		return types.EnrollmentToken{}, types.ErrEnrollmentDisabled
+	}
+
+	return c.enrollment.createToken(req)
+}
+
+func (c *controller) ListEnrollmentRequests() ([]types.EnrollmentRequest, error) {
+	if c.enrollment == nil {
+		return nil, types.ErrEnrollmentDisabled
+	}
+
+	return c.enrollment.listRequests(), nil
+}
+
+func (c *controller) UpdateEnrollmentRequest(ID string, status types.EnrollmentStatus) error {
+	if c.enrollment == nil {
+		return types.ErrEnrollmentDisabled
+	}
+
+	return c.enrollment.updateRequest(ID, status)
+}
+
+func (c *controller) Enroll(req types.EnrollRequest) (types.EnrollResponse, error) {
+	if c.enrollment == nil {
+		return types.EnrollResponse{}, types.ErrEnrollmentDisabled
+	}
+
+	return c.enrollment.enroll(req)
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/ciao-project/ciao/ssntp/certs"
+)
+
+func newTestEnrollment(t *testing.T) (*enrollment, []byte) {
+	var anchorCert, caCert bytes.Buffer
+
+	template, err := certs.CreateCertTemplate(ssntp.SERVER, "ACME Corp", "", []string{"localhost"}, nil)
+	if err != nil {
+		t.Fatalf("Unable to create anchor cert template: %v", err)
+	}
+
+	err = certs.CreateAnchorCert(template, &anchorCert, &caCert)
+	if err != nil {
+		t.Fatalf("Unable to create anchor cert: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "enrollment_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	anchorCertPath := path.Join(dir, "anchor.pem")
+	err = ioutil.WriteFile(anchorCertPath, anchorCert.Bytes(), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := newEnrollment(anchorCertPath)
+	if err != nil {
+		t.Fatalf("Unable to create enrollment: %v", err)
+	}
+
+	return e, caCert.Bytes()
+}
+
+func newTestCSR(t *testing.T) string {
+	var csr, key bytes.Buffer
+
+	request := certs.CreateCertificateRequest("ACME Corp", "", []string{"node1"}, []string{"198.51.100.1"})
+	err := certs.CreateCSR(request, &csr, &key)
+	if err != nil {
+		t.Fatalf("Unable to create CSR: %v", err)
+	}
+
+	return csr.String()
+}
+
+func TestEnrollmentAutoApprove(t *testing.T) {
+	e, caCert := newTestEnrollment(t)
+
+	token, err := e.createToken(types.EnrollmentTokenRequest{Role: "agent", AutoApprove: true})
+	if err != nil {
+		t.Fatalf("Unable to create token: %v", err)
+	}
+
+	csr := newTestCSR(t)
+	resp, err := e.enroll(types.EnrollRequest{Token: token.Token, CSR: csr})
+	if err != nil {
+		t.Fatalf("Unable to enroll: %v", err)
+	}
+
+	if resp.Status != types.EnrollmentApproved {
+		t.Fatalf("Expected %s status, got %s", types.EnrollmentApproved, resp.Status)
+	}
+
+	if resp.CACert != string(caCert) {
+		t.Fatalf("Unexpected CA certificate %s", resp.CACert)
+	}
+
+	err = certs.VerifyCert(caCert, []byte(resp.Cert))
+	if err != nil {
+		t.Fatalf("Invalid enrollment certificate: %v", err)
+	}
+
+	// The same CSR can be submitted again, but not another one
+	resp2, err := e.enroll(types.EnrollRequest{Token: token.Token, CSR: csr})
+	if err != nil || resp2.Cert != resp.Cert {
+		t.Fatalf("Unable to get enrollment certificate again: %v", err)
+	}
+
+	_, err = e.enroll(types.EnrollRequest{Token: token.Token, CSR: newTestCSR(t)})
+	if err != types.ErrInvalidEnrollmentToken {
+		t.Fatalf("Expected %v error, got %v", types.ErrInvalidEnrollmentToken, err)
+	}
+}
+
+func TestEnrollmentApproval(t *testing.T) {
+	e, _ := newTestEnrollment(t)
+
+	token, err := e.createToken(types.EnrollmentTokenRequest{Role: "agent,netagent"})
+	if err != nil {
+		t.Fatalf("Unable to create token: %v", err)
+	}
+
+	csr := newTestCSR(t)
+	resp, err := e.enroll(types.EnrollRequest{Token: token.Token, CSR: csr})
+	if err != nil {
+		t.Fatalf("Unable to enroll: %v", err)
+	}
+
+	if resp.Status != types.EnrollmentPending || resp.Cert != "" {
+		t.Fatalf("Expected pending enrollment request, got %+v", resp)
+	}
+
+	requests := e.listRequests()
+	if len(requests) != 1 || requests[0].ID != resp.ID ||
+		requests[0].Hosts[0] != "node1" || requests[0].IPs[0] != "198.51.100.1" {
+		t.Fatalf("Unexpected enrollment requests %+v", requests)
+	}
+
+	err = e.updateRequest(resp.ID, types.EnrollmentApproved)
+	if err != nil {
+		t.Fatalf("Unable to approve enrollment request: %v", err)
+	}
+
+	resp, err = e.enroll(types.EnrollRequest{Token: token.Token, CSR: csr})
+	if err != nil || resp.Status != types.EnrollmentApproved || resp.Cert == "" {
+		t.Fatalf("Expected approved enrollment request, got %+v (%v)", resp, err)
+	}
+
+	err = e.updateRequest(resp.ID, types.EnrollmentRejected)
+	if err != types.ErrBadRequest {
+		t.Fatalf("Expected %v error, got %v", types.ErrBadRequest, err)
+	}
+}
+
+func TestEnrollmentRejection(t *testing.T) {
+	e, _ := newTestEnrollment(t)
+
+	_, err := e.createToken(types.EnrollmentTokenRequest{Role: "controller"})
+	if err != types.ErrBadRequest {
+		t.Fatalf("Expected %v error, got %v", types.ErrBadRequest, err)
+	}
+
+	token, err := e.createToken(types.EnrollmentTokenRequest{Role: "agent"})
+	if err != nil {
+		t.Fatalf("Unable to create token: %v", err)
+	}
+
+	_, err = e.enroll(types.EnrollRequest{Token: "invalid", CSR: newTestCSR(t)})
+	if err != types.ErrInvalidEnrollmentToken {
+		t.Fatalf("Expected %v error, got %v", types.ErrInvalidEnrollmentToken, err)
+	}
+
+	csr := newTestCSR(t)
+	resp, err := e.enroll(types.EnrollRequest{Token: token.Token, CSR: csr})
+	if err != nil {
+		t.Fatalf("Unable to enroll: %v", err)
+	}
+
+	err = e.updateRequest(resp.ID, types.EnrollmentRejected)
+	if err != nil {
+		t.Fatalf("Unable to reject enrollment request: %v", err)
+	}
+
+	_, err = e.enroll(types.EnrollRequest{Token: token.Token, CSR: csr})
+	if err != types.ErrEnrollmentRejected {
+		t.Fatalf("Expected %v error, got %v", types.ErrEnrollmentRejected, err)
+	}
+}
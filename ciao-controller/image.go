@@ -15,10 +15,21 @@
 package main
 
 import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ciao-project/ciao/ciao-controller/api"
@@ -28,6 +39,51 @@ import (
 	"github.com/golang/glog"
 )
 
+// defaultChecksumAlgorithm is used for checksums supplied without an
+// algorithm and to checksum uploads when no checksum was supplied.
+const defaultChecksumAlgorithm = "sha256"
+
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// parseDiskFormat checks that format is one we know how to convert to raw.
+// An empty format is probed from the uploaded data.
+func parseDiskFormat(format types.DiskFormat) (types.DiskFormat, error) {
+	switch format {
+	case "", types.Raw, types.QCOW2, types.VMDK, types.VHDX:
+		return format, nil
+	}
+
+	return "", api.ErrBadDiskFormat
+}
+
+// parseChecksum validates a checksum of the form algorithm:hex and returns
+// it in canonical form.  Checksums without an algorithm are sha256 ones.
+func parseChecksum(checksum string) (string, error) {
+	algorithm := defaultChecksumAlgorithm
+	digest := strings.ToLower(checksum)
+	if i := strings.Index(digest, ":"); i != -1 {
+		algorithm = digest[:i]
+		digest = digest[i+1:]
+	}
+
+	newHash, ok := checksumAlgorithms[algorithm]
+	if !ok {
+		return "", api.ErrBadChecksum
+	}
+
+	b, err := hex.DecodeString(digest)
+	if err != nil || len(b) != newHash().Size() {
+		return "", api.ErrBadChecksum
+	}
+
+	return algorithm + ":" + digest, nil
+}
+
 // CreateImage will create an empty image in the image datastore.
 func (c *controller) CreateImage(tenantID string, req api.CreateImageRequest) (types.Image, error) {
 	// create an ImageInfo struct and store it in our image
@@ -44,6 +100,19 @@ func (c *controller) CreateImage(tenantID string, req api.CreateImageRequest) (t
 		}
 	}
 
+	format, err := parseDiskFormat(req.DiskFormat)
+	if err != nil {
+		return types.Image{}, err
+	}
+
+	var checksum string
+	if req.Checksum != "" {
+		checksum, err = parseChecksum(req.Checksum)
+		if err != nil {
+			return types.Image{}, err
+		}
+	}
+
 	i := types.Image{
 		ID:         id,
 		TenantID:   tenantID,
@@ -51,9 +120,11 @@ func (c *controller) CreateImage(tenantID string, req api.CreateImageRequest) (t
 		Name:       req.Name,
 		CreateTime: time.Now(),
 		Visibility: req.Visibility,
+		DiskFormat: format,
+		Checksum:   checksum,
 	}
 
-	err := c.ds.AddImage(i)
+	err = c.ds.AddImage(i)
 	if err != nil {
 		glog.Errorf("Error adding image to datastore: %v", err)
 		return types.Image{}, err
@@ -81,40 +152,207 @@ func (c *controller) ListImages(tenant string) ([]types.Image, error) {
 	return c.ds.GetImages(tenant, false)
 }
 
-func (c *controller) uploadImage(imageID string, body io.Reader) error {
+type qemuImgInfo struct {
+	Format          string `json:"format"`
+	VirtualSize     uint64 `json:"virtual-size"`
+	BackingFilename string `json:"backing-filename"`
+}
+
+// getQemuImgInfo returns the format and virtual size of the image at path.
+// If format is empty qemu-img probes the format of the image.
+func getQemuImgInfo(path string, format types.DiskFormat) (qemuImgInfo, error) {
+	args := []string{"info", "--output=json"}
+	if format != "" {
+		args = append(args, "-f", string(format))
+	}
+	args = append(args, path)
+
+	cmd := exec.Command("qemu-img", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return qemuImgInfo{}, fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, stderr.Bytes())
+	}
+
+	var info qemuImgInfo
+	err = json.Unmarshal(out, &info)
+	if err != nil {
+		return qemuImgInfo{}, fmt.Errorf("Unable to parse qemu-img info output: %v", err)
+	}
+
+	return info, nil
+}
+
+// checkQemuImg checks the consistency of the image at path.  Leaked
+// clusters are harmless and not all formats support checking so neither
+// is reported as an error.
+func checkQemuImg(path string, format types.DiskFormat) error {
+	cmd := exec.Command("qemu-img", "check", "-f", string(format), path)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			switch status.ExitStatus() {
+			case 3, 63:
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, out)
+}
+
+// convertToRaw converts the image at src to a raw image at dst.
+func convertToRaw(src string, format types.DiskFormat, dst string) error {
+	cmd := exec.Command("qemu-img", "convert", "-f", string(format), "-O", "raw", src, dst)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, out)
+	}
+
+	return nil
+}
+
+// receiveImage streams body into a temporary file, returning its path and
+// the checksum of the data using the algorithm of expected, which is
+// either empty or in canonical form.
+func receiveImage(body io.Reader, expected string) (string, string, error) {
+	algorithm := defaultChecksumAlgorithm
+	if expected != "" {
+		algorithm = expected[:strings.Index(expected, ":")]
+	}
+	h := checksumAlgorithms[algorithm]()
+
 	f, err := ioutil.TempFile("", "ciao-image")
 	if err != nil {
-		return fmt.Errorf("Error creating temporary image file: %v", err)
+		return "", "", fmt.Errorf("Error creating temporary image file: %v", err)
 	}
-	defer func() { _ = os.Remove(f.Name()) }()
 
 	buf := make([]byte, 1<<16)
-	_, err = io.CopyBuffer(f, body, buf)
+	_, err = io.CopyBuffer(io.MultiWriter(f, h), body, buf)
 	if err != nil {
 		_ = f.Close()
-		return fmt.Errorf("Error writing to temporary image file: %v", err)
+		_ = os.Remove(f.Name())
+		return "", "", fmt.Errorf("Error writing to temporary image file: %v", err)
 	}
 
 	err = f.Close()
 	if err != nil {
-		return fmt.Errorf("Error closing temporary image file: %v", err)
+		_ = os.Remove(f.Name())
+		return "", "", fmt.Errorf("Error closing temporary image file: %v", err)
+	}
+
+	checksum := algorithm + ":" + hex.EncodeToString(h.Sum(nil))
+	if expected != "" && checksum != expected {
+		_ = os.Remove(f.Name())
+		glog.Errorf("Image checksum mismatch: expected %s got %s", expected, checksum)
+		return "", "", api.ErrChecksumMismatch
+	}
+
+	return f.Name(), checksum, nil
+}
+
+// uploadImage verifies the uploaded data against the checksum and disk
+// format of image, converts it to raw and stores it in a block device.  The
+// disk format is probed when image has none.  The checksum, disk format and
+// virtual size of the upload are recorded in image.
+func (c *controller) uploadImage(image *types.Image, body io.Reader) error {
+	format, err := parseDiskFormat(image.DiskFormat)
+	if err != nil {
+		return err
+	}
+
+	path, checksum, err := receiveImage(body, image.Checksum)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(path) }()
+
+	if format == "" {
+		probed, err := getQemuImgInfo(path, "")
+		if err != nil {
+			glog.Errorf("Unable to probe image format: %v", err)
+			return api.ErrImageCorrupt
+		}
+
+		format, err = parseDiskFormat(types.DiskFormat(probed.Format))
+		if err != nil || format == "" {
+			glog.Errorf("Image probes as unsupported format %q", probed.Format)
+			return api.ErrBadDiskFormat
+		}
 	}
 
-	_, err = c.CreateBlockDevice(imageID, f.Name(), 0)
+	info, err := getQemuImgInfo(path, format)
+	if err != nil {
+		glog.Errorf("Unable to read %s image: %v", format, err)
+		return api.ErrImageCorrupt
+	}
+
+	if info.BackingFilename != "" {
+		glog.Errorf("Rejecting image with backing file %s", info.BackingFilename)
+		return api.ErrImageCorrupt
+	}
+
+	if format != types.Raw {
+		err = checkQemuImg(path, format)
+		if err != nil {
+			glog.Errorf("Image check failed: %v", err)
+			return api.ErrImageCorrupt
+		}
+
+		f, err := ioutil.TempFile("", "ciao-image-raw")
+		if err != nil {
+			return fmt.Errorf("Error creating temporary image file: %v", err)
+		}
+		rawPath := f.Name()
+		_ = f.Close()
+		defer func() { _ = os.Remove(rawPath) }()
+
+		err = convertToRaw(path, format, rawPath)
+		if err != nil {
+			glog.Errorf("Image conversion failed: %v", err)
+			return api.ErrImageCorrupt
+		}
+		path = rawPath
+	}
+
+	// Block drivers probe the format of the images they are given so
+	// make sure the raw data we hand them is not mistaken for another
+	// format.
+	rawInfo, err := getQemuImgInfo(path, "")
+	if err != nil {
+		return fmt.Errorf("Unable to probe converted image: %v", err)
+	}
+
+	if rawInfo.Format != string(types.Raw) {
+		glog.Errorf("Raw image data probes as %s", rawInfo.Format)
+		return api.ErrImageCorrupt
+	}
+
+	_, err = c.CreateBlockDevice(image.ID, path, 0)
 	if err != nil {
 		return fmt.Errorf("Error creating block device: %v", err)
 	}
 
-	err = c.CreateBlockDeviceSnapshot(imageID, "ciao-image")
+	err = c.CreateBlockDeviceSnapshot(image.ID, "ciao-image")
 	if err != nil {
-		_ = c.DeleteBlockDevice(imageID)
+		_ = c.DeleteBlockDevice(image.ID)
 		return fmt.Errorf("Unable to create snapshot: %v", err)
 	}
 
+	image.DiskFormat = format
+	image.Checksum = checksum
+	image.VirtualSize = info.VirtualSize
+
 	return nil
 }
 
-// UploadImage will upload a raw image data and update its status.
+// UploadImage will upload image data, convert it to raw and update its
+// status.
 func (c *controller) UploadImage(tenantID, imageID string, body io.Reader) error {
 	glog.Infof("Uploading image: %v", imageID)
 
@@ -133,11 +371,16 @@ func (c *controller) UploadImage(tenantID, imageID string, body io.Reader) error
 		return err
 	}
 
-	err = c.uploadImage(imageID, body)
+	err = c.uploadImage(&image, body)
 	if err != nil {
 		glog.Errorf("Error uploading image: %v", err)
 		image.State = types.Killed
 		_ = c.ds.UpdateImage(image)
+
+		switch err {
+		case api.ErrChecksumMismatch, api.ErrImageCorrupt, api.ErrBadDiskFormat:
+			return err
+		}
 		return api.ErrImageSaving
 	}
 
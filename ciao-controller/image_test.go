@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+)
+
+func TestParseDiskFormat(t *testing.T) {
+	tests := []struct {
+		format   types.DiskFormat
+		expected types.DiskFormat
+		err      error
+	}{
+		{"", "", nil},
+		{types.Raw, types.Raw, nil},
+		{types.QCOW2, types.QCOW2, nil},
+		{types.VMDK, types.VMDK, nil},
+		{types.VHDX, types.VHDX, nil},
+		{"vdi", "", api.ErrBadDiskFormat},
+	}
+
+	for _, test := range tests {
+		format, err := parseDiskFormat(test.format)
+		if err != test.err || format != test.expected {
+			t.Errorf("parseDiskFormat(%q) = %q, %v expected %q, %v",
+				test.format, format, err, test.expected, test.err)
+		}
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sha256 := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	md5 := "098f6bcd4621d373cade4e832627b4f6"
+
+	tests := []struct {
+		checksum string
+		expected string
+		err      error
+	}{
+		{sha256, "sha256:" + sha256, nil},
+		{"SHA256:9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08", "sha256:" + sha256, nil},
+		{"md5:" + md5, "md5:" + md5, nil},
+		{md5, "", api.ErrBadChecksum},
+		{"crc32:" + md5, "", api.ErrBadChecksum},
+		{"sha256:not-hex", "", api.ErrBadChecksum},
+	}
+
+	for _, test := range tests {
+		checksum, err := parseChecksum(test.checksum)
+		if err != test.err || checksum != test.expected {
+			t.Errorf("parseChecksum(%q) = %q, %v expected %q, %v",
+				test.checksum, checksum, err, test.expected, test.err)
+		}
+	}
+}
+
+func TestReceiveImage(t *testing.T) {
+	data := []byte("test")
+
+	path, checksum, err := receiveImage(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Remove(path)
+
+	expected := "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	if checksum != expected {
+		t.Errorf("Unexpected checksum %s expected %s", checksum, expected)
+	}
+
+	path, checksum, err = receiveImage(bytes.NewReader(data), "md5:098f6bcd4621d373cade4e832627b4f6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Remove(path)
+
+	if checksum != "md5:098f6bcd4621d373cade4e832627b4f6" {
+		t.Errorf("Unexpected checksum %s", checksum)
+	}
+
+	_, _, err = receiveImage(bytes.NewReader(data), "md5:00000000000000000000000000000000")
+	if err != api.ErrChecksumMismatch {
+		t.Errorf("Expected %v got %v", api.ErrChecksumMismatch, err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -415,6 +416,61 @@ func storageConfig(ctl *controller, tenant *types.Tenant, instanceID string, vol
 	return storage, nil
 }
 
+// placementNodes returns the nodes hosting instances of the tenant's
+// workloads named by the affinity and anti-affinity requested resources.
+func placementNodes(ctl *controller, tenantID string, resources []payloads.RequestedResource) ([]string, []string, error) {
+	affinity := make(map[string]bool)
+	antiAffinity := make(map[string]bool)
+
+	for _, r := range resources {
+		if r.Type == payloads.Affinity {
+			affinity[r.ValueString] = true
+		} else if r.Type == payloads.AntiAffinity {
+			antiAffinity[r.ValueString] = true
+		}
+	}
+
+	if len(affinity) == 0 && len(antiAffinity) == 0 {
+		return nil, nil, nil
+	}
+
+	instances, err := ctl.ds.GetAllInstancesFromTenant(tenantID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error getting tenant instances")
+	}
+
+	affinityNodes := make(map[string]bool)
+	antiAffinityNodes := make(map[string]bool)
+	for _, i := range instances {
+		if i.NodeID == "" {
+			continue
+		}
+
+		if affinity[i.WorkloadID] {
+			affinityNodes[i.NodeID] = true
+		}
+		if antiAffinity[i.WorkloadID] {
+			antiAffinityNodes[i.NodeID] = true
+		}
+	}
+
+	return sortedKeys(affinityNodes), sortedKeys(antiAffinityNodes), nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
 func newConfig(ctl *controller, wl *types.Workload, instanceID string, tenantID string,
 	volumes []storage.BlockDevice, name string, IPaddr net.IP) (config, error) {
 	var metaData userData
@@ -459,6 +515,11 @@ func newConfig(ctl *controller, wl *types.Workload, instanceID string, tenantID
 		storage = append(storage, workloadStorage)
 	}
 
+	affinityNodes, antiAffinityNodes, err := placementNodes(ctl, tenantID, defaults)
+	if err != nil {
+		return config, err
+	}
+
 	// hardcode persistence until changes can be made to workload
 	// template datastore.  Estimated resources can be blank
 	// for now because we don't support it yet.
@@ -471,6 +532,9 @@ func newConfig(ctl *controller, wl *types.Workload, instanceID string, tenantID
 		RequestedResources:  defaults,
 		Networking:          networking,
 		Storage:             storage,
+		WorkloadUUID:        wl.ID,
+		AffinityNodes:       affinityNodes,
+		AntiAffinityNodes:   antiAffinityNodes,
 	}
 
 	if wl.VMType == payloads.Docker {
@@ -129,6 +129,9 @@ type persistentStore interface {
 	addStorageAttachment(a types.StorageAttachment) error
 	getAllStorageAttachments() (map[string]types.StorageAttachment, error)
 	deleteStorageAttachment(ID string) error
+	updateVolumeSnapshot(snapshot types.VolumeSnapshot) error
+	deleteVolumeSnapshot(ID string) error
+	getVolumeSnapshots() ([]types.VolumeSnapshot, error)
 
 	// external IP interfaces
 	addPool(pool types.Pool) error
@@ -148,6 +151,18 @@ type persistentStore interface {
 	updateImage(i types.Image) error
 	deleteImage(ID string) error
 	getImages() ([]types.Image, error)
+
+	// rebuilt instances
+	addRebuiltInstance(r rebuiltInstance) error
+	deleteRebuiltInstance(r rebuiltInstance) error
+	getRebuiltInstances() ([]rebuiltInstance, error)
+}
+
+// rebuiltInstance identifies an instance that has been rebuilt away from a
+// node that may still hold a copy of it.
+type rebuiltInstance struct {
+	instanceID string
+	nodeID     string
 }
 
 // Datastore provides context for the datastore package.
@@ -171,12 +186,20 @@ type Datastore struct {
 	instances     map[string]*types.Instance
 	instancesLock *sync.RWMutex
 
+	// rebuiltInstances holds the instances that have been rebuilt away
+	// from nodes that have not yet deleted their copy.  It is protected
+	// by instancesLock.
+	rebuiltInstances map[rebuiltInstance]bool
+
 	tenantUsage     map[string][]types.CiaoUsage
 	tenantUsageLock *sync.RWMutex
 
 	blockDevices map[string]types.Volume
 	bdLock       *sync.RWMutex
 
+	snapshots    map[string]types.VolumeSnapshot
+	snapshotLock *sync.RWMutex
+
 	attachments     map[string]types.StorageAttachment
 	instanceVolumes map[attachment]string
 	attachLock      *sync.RWMutex
@@ -215,6 +238,20 @@ func (ds *Datastore) initExternalIPs() {
 	ds.mappedIPs = ds.db.getMappedIPs()
 }
 
+func (ds *Datastore) initVolumeSnapshots() error {
+	ds.snapshotLock = &sync.RWMutex{}
+	ds.snapshots = make(map[string]types.VolumeSnapshot)
+	snapshots, err := ds.db.getVolumeSnapshots()
+	if err != nil {
+		return errors.Wrap(err, "error getting volume snapshots from database")
+	}
+	for _, s := range snapshots {
+		ds.snapshots[s.ID] = s
+	}
+
+	return nil
+}
+
 func (ds *Datastore) initImages() error {
 	ds.imageLock = &sync.RWMutex{}
 	ds.images = make(map[string]types.Image)
@@ -278,6 +315,7 @@ func (ds *Datastore) Init(config Config) error {
 	// cache all our instances prior to getting tenants
 	ds.instancesLock = &sync.RWMutex{}
 	ds.instances = make(map[string]*types.Instance)
+	ds.rebuiltInstances = make(map[rebuiltInstance]bool)
 
 	instances, err := ds.db.getInstances()
 	if err != nil {
@@ -288,6 +326,15 @@ func (ds *Datastore) Init(config Config) error {
 		ds.instances[instances[i].ID] = instances[i]
 	}
 
+	rebuilt, err := ds.db.getRebuiltInstances()
+	if err != nil {
+		return errors.Wrap(err, "error getting rebuilt instances from database")
+	}
+
+	for _, r := range rebuilt {
+		ds.rebuiltInstances[r] = true
+	}
+
 	// cache our current tenants into a map that we can
 	// quickly index
 	tenants, err := ds.db.getTenants()
@@ -340,6 +387,11 @@ func (ds *Datastore) Init(config Config) error {
 
 	ds.bdLock = &sync.RWMutex{}
 
+	err = ds.initVolumeSnapshots()
+	if err != nil {
+		return err
+	}
+
 	ds.attachments, err = ds.db.getAllStorageAttachments()
 	if err != nil {
 		return errors.Wrap(err, "error getting storage attachments from database")
@@ -1043,7 +1095,17 @@ func (ds *Datastore) StartFailure(instanceID string, reason payloads.StartFailur
 		glog.Warning("CNCI ", instanceID, " Failed to start")
 	}
 
-	if reason.IsFatal() && !migration {
+	// A failure to start the target of a live migration does not affect
+	// the instance, which is still running on its original node.
+
+	if i.State == payloads.Migrating {
+		ds.instancesLock.Lock()
+		i.State = payloads.Running
+		ds.instancesLock.Unlock()
+		if err := ds.updateInstanceStatus(payloads.Running, instanceID); err != nil {
+			return errors.Wrap(err, "Error aborting instance migration")
+		}
+	} else if reason.IsFatal() && !migration {
 		if _, err := ds.deleteInstance(instanceID); err != nil {
 			return errors.Wrap(err, "Error deleting instance")
 		}
@@ -1112,6 +1174,140 @@ func (ds *Datastore) AttachVolumeFailure(instanceID string, volumeID string, rea
 	return errors.Wrap(ds.db.logEvent(e), "Error logging event")
 }
 
+// ExtendVolumeFailure logs that a running instance could not be told about
+// the new size of one of its volumes.  The volume itself has already been
+// extended so there is no state to roll back.
+func (ds *Datastore) ExtendVolumeFailure(instanceID string, volumeID string, reason payloads.ExtendVolumeFailureReason) error {
+	i, err := ds.GetInstance(instanceID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting instance (%v)", instanceID)
+	}
+
+	ds.nodesLock.Lock()
+	n, ok := ds.nodes[i.NodeID]
+	if ok {
+		n.TotalFailures++
+	}
+	ds.nodesLock.Unlock()
+
+	msg := fmt.Sprintf("Extend Volume Failure %s of %s: %s", volumeID, instanceID, reason.String())
+	e := types.LogEntry{
+		TenantID:  i.TenantID,
+		EventType: string(userError),
+		Message:   msg,
+		NodeID:    i.NodeID,
+	}
+
+	return errors.Wrap(ds.db.logEvent(e), "Error logging event")
+}
+
+// DetachVolumeFailure is called when a launcher was unable to detach a volume
+// from an instance.  If the launcher reports that the volume was not attached
+// in the first place the detach is completed, otherwise the volume is
+// returned to the in use state.
+func (ds *Datastore) DetachVolumeFailure(instanceID string, volumeID string, reason payloads.DetachVolumeFailureReason) error {
+	if reason == payloads.DetachVolumeNotAttached {
+		err := ds.completeVolumeDetach(instanceID, volumeID)
+		if err != nil {
+			return errors.Wrapf(err, "error completing detach of volume (%v)", volumeID)
+		}
+	} else {
+		data, err := ds.GetBlockDevice(volumeID)
+		if err != nil {
+			return errors.Wrapf(err, "error getting block device for volume (%v)", volumeID)
+		}
+
+		data.State = types.InUse
+		err = ds.UpdateBlockDevice(data)
+		if err != nil {
+			return errors.Wrapf(err, "error updating block device for volume (%v)", volumeID)
+		}
+	}
+
+	i, err := ds.GetInstance(instanceID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting instance (%v)", instanceID)
+	}
+
+	ds.nodesLock.Lock()
+	n, ok := ds.nodes[i.NodeID]
+	if ok {
+		n.TotalFailures++
+	}
+	ds.nodesLock.Unlock()
+
+	msg := fmt.Sprintf("Detach Volume Failure %s from %s: %s", volumeID, instanceID, reason.String())
+	e := types.LogEntry{
+		TenantID:  i.TenantID,
+		EventType: string(userError),
+		Message:   msg,
+		NodeID:    i.NodeID,
+	}
+
+	return errors.Wrap(ds.db.logEvent(e), "Error logging event")
+}
+
+// completeVolumeDetach removes the attachment of a volume to an instance and
+// marks the volume as available once it is no longer attached to anything.
+func (ds *Datastore) completeVolumeDetach(instanceID string, volumeID string) error {
+	a, err := ds.getStorageAttachment(instanceID, volumeID)
+	if err != nil {
+		return err
+	}
+
+	err = ds.DeleteStorageAttachment(a.ID)
+	if err != nil {
+		return err
+	}
+
+	attachments, err := ds.GetVolumeAttachments(volumeID)
+	if err != nil {
+		return err
+	}
+
+	if len(attachments) > 0 {
+		return nil
+	}
+
+	data, err := ds.GetBlockDevice(volumeID)
+	if err != nil {
+		return err
+	}
+
+	data.State = types.Available
+	return ds.UpdateBlockDevice(data)
+}
+
+// updateDetachingVolumes completes the detach of any volume that is being
+// detached from an instance and that the launcher no longer reports as
+// attached to that instance.
+func (ds *Datastore) updateDetachingVolumes(stat payloads.InstanceStat) {
+	for _, a := range ds.GetStorageAttachments(stat.InstanceUUID) {
+		data, err := ds.GetBlockDevice(a.BlockID)
+		if err != nil || data.State != types.Detaching {
+			continue
+		}
+
+		attached := false
+		for _, v := range stat.Volumes {
+			if v == a.BlockID {
+				attached = true
+				break
+			}
+		}
+
+		if attached {
+			continue
+		}
+
+		err = ds.completeVolumeDetach(a.InstanceID, a.BlockID)
+		if err != nil {
+			glog.Warningf("error completing detach of volume (%v) from instance (%v): %v",
+				a.BlockID, a.InstanceID, err)
+		}
+	}
+}
+
 func (ds *Datastore) deleteInstance(instanceID string) (string, error) {
 	if err := ds.db.deleteInstance(instanceID); err != nil {
 		glog.Warningf("error deleting instance (%v): %v", instanceID, err)
@@ -1249,6 +1445,203 @@ func (ds *Datastore) InstanceStopped(instanceID string) error {
 	return nil
 }
 
+// InstanceRebuilding removes the link between an instance and a node that
+// has failed so that the instance can be restarted on another node.  The
+// old node is remembered until it confirms that it has deleted its copy of
+// the instance, see RebuiltInstanceDeleted.  Any statistics it reports for
+// the instance are ignored until then.
+func (ds *Datastore) InstanceRebuilding(instanceID string) error {
+	ds.instancesLock.Lock()
+	i, ok := ds.instances[instanceID]
+	if !ok {
+		ds.instancesLock.Unlock()
+		return types.ErrInstanceNotFound
+	}
+	oldNodeID := i.NodeID
+	i.NodeID = ""
+	i.State = payloads.Exited
+	tenantID := i.TenantID
+
+	if oldNodeID != "" {
+		r := rebuiltInstance{instanceID: instanceID, nodeID: oldNodeID}
+		if err := ds.db.addRebuiltInstance(r); err != nil {
+			ds.instancesLock.Unlock()
+			return errors.Wrap(err, "Error adding rebuilt instance to database")
+		}
+		ds.rebuiltInstances[r] = true
+	}
+	ds.instancesLock.Unlock()
+
+	if oldNodeID != "" {
+		ds.nodesLock.Lock()
+		if n, ok := ds.nodes[oldNodeID]; ok {
+			delete(n.instances, instanceID)
+		}
+		ds.nodesLock.Unlock()
+	}
+
+	err := ds.updateInstanceStatus(payloads.Exited, instanceID)
+	if err != nil {
+		return errors.Wrap(err, "Error marking instance as rebuilding")
+	}
+
+	msg := fmt.Sprintf("Rebuilding instance %s from node %s", instanceID, oldNodeID)
+	return errors.Wrap(ds.LogEvent(tenantID, msg), "Error logging event")
+}
+
+// RebuiltInstances returns the IDs of the instances that have been rebuilt
+// away from nodeID and that nodeID has not yet deleted.
+func (ds *Datastore) RebuiltInstances(nodeID string) []string {
+	var ids []string
+
+	ds.instancesLock.RLock()
+	for r := range ds.rebuiltInstances {
+		if r.nodeID == nodeID {
+			ids = append(ids, r.instanceID)
+		}
+	}
+	ds.instancesLock.RUnlock()
+
+	return ids
+}
+
+// RebuiltInstanceDeleted is called when nodeID reports that an instance is
+// deleted or unknown.  It returns true if the instance had been rebuilt away
+// from nodeID, in which case only the copy left on nodeID is gone and the
+// instance itself must be kept.
+func (ds *Datastore) RebuiltInstanceDeleted(instanceID string, nodeID string) (bool, error) {
+	r := rebuiltInstance{instanceID: instanceID, nodeID: nodeID}
+
+	ds.instancesLock.Lock()
+	defer ds.instancesLock.Unlock()
+
+	if !ds.rebuiltInstances[r] {
+		return false, nil
+	}
+
+	if err := ds.db.deleteRebuiltInstance(r); err != nil {
+		return true, errors.Wrap(err, "Error deleting rebuilt instance from database")
+	}
+	delete(ds.rebuiltInstances, r)
+
+	return true, nil
+}
+
+// InstanceResized assigns a new workload to a stopped instance.  The
+// instance will be given the resources of this workload when it is next
+// started.
+func (ds *Datastore) InstanceResized(instanceID string, workloadID string) error {
+	ds.instancesLock.Lock()
+	i, ok := ds.instances[instanceID]
+	if !ok {
+		ds.instancesLock.Unlock()
+		return types.ErrInstanceNotFound
+	}
+	oldWorkloadID := i.WorkloadID
+	i.WorkloadID = workloadID
+	ds.instancesLock.Unlock()
+
+	err := ds.db.updateInstance(i)
+	if err != nil {
+		ds.instancesLock.Lock()
+		i.WorkloadID = oldWorkloadID
+		ds.instancesLock.Unlock()
+		return errors.Wrap(err, "Error updating instance workload")
+	}
+
+	msg := fmt.Sprintf("Instance %s resized from workload %s to %s",
+		instanceID, oldWorkloadID, workloadID)
+	return errors.Wrap(ds.LogEvent(i.TenantID, msg), "Error logging event")
+}
+
+// InstanceMigrating marks a running instance as being live migrated.  The
+// state and node of a migrating instance are not updated from node
+// statistics until the migration has completed or failed.
+func (ds *Datastore) InstanceMigrating(instanceID string) error {
+	ds.instancesLock.Lock()
+	i, ok := ds.instances[instanceID]
+	if !ok {
+		ds.instancesLock.Unlock()
+		return types.ErrInstanceNotFound
+	}
+	if i.State != payloads.Running {
+		state := i.State
+		ds.instancesLock.Unlock()
+		return fmt.Errorf("Instance must be %s, not %s", payloads.Running, state)
+	}
+	i.State = payloads.Migrating
+	ds.instancesLock.Unlock()
+
+	err := ds.updateInstanceStatus(payloads.Migrating, instanceID)
+	return errors.Wrap(err, "Error marking instance as migrating")
+}
+
+// InstanceMigrated moves a migrating instance to the node it has been
+// migrated to.
+func (ds *Datastore) InstanceMigrated(instanceID string, nodeID string) error {
+	ds.instancesLock.Lock()
+	i, ok := ds.instances[instanceID]
+	if !ok {
+		ds.instancesLock.Unlock()
+		return types.ErrInstanceNotFound
+	}
+	oldNodeID := i.NodeID
+	i.NodeID = nodeID
+	i.State = payloads.Running
+	ds.instancesLock.Unlock()
+
+	ds.nodesLock.Lock()
+	if n, ok := ds.nodes[oldNodeID]; ok {
+		delete(n.instances, instanceID)
+	}
+	if n, ok := ds.nodes[nodeID]; ok {
+		n.instances[instanceID] = i
+	}
+	ds.nodesLock.Unlock()
+
+	err := ds.updateInstanceStatus(payloads.Running, instanceID)
+	if err != nil {
+		return errors.Wrap(err, "Error marking instance as migrated")
+	}
+
+	msg := fmt.Sprintf("Instance %s migrated from %s to %s", instanceID, oldNodeID, nodeID)
+	return errors.Wrap(ds.LogEvent(i.TenantID, msg), "Error logging event")
+}
+
+// MigrateFailure aborts the migration of an instance, which keeps running
+// on its original node, and logs the reason of the failure.  Both nodes
+// involved in a migration may report its failure, so failures reported for
+// instances that are no longer migrating are ignored.
+func (ds *Datastore) MigrateFailure(instanceID string, reason payloads.MigrateFailureReason, nodeID string) error {
+	ds.instancesLock.Lock()
+	i, ok := ds.instances[instanceID]
+	if !ok {
+		ds.instancesLock.Unlock()
+		return types.ErrInstanceNotFound
+	}
+	if i.State != payloads.Migrating {
+		ds.instancesLock.Unlock()
+		return nil
+	}
+	i.State = payloads.Running
+	tenantID := i.TenantID
+	ds.instancesLock.Unlock()
+
+	err := ds.updateInstanceStatus(payloads.Running, instanceID)
+	if err != nil {
+		return errors.Wrap(err, "Error aborting instance migration")
+	}
+
+	msg := fmt.Sprintf("Migrate Failure %s: %s", instanceID, reason.String())
+	e := types.LogEntry{
+		TenantID:  tenantID,
+		EventType: string(userError),
+		Message:   msg,
+		NodeID:    nodeID,
+	}
+	return errors.Wrap(ds.db.logEvent(e), "Error logging event")
+}
+
 // DeleteNode removes a node from the node cache.
 func (ds *Datastore) DeleteNode(nodeID string) error {
 	ds.nodesLock.Lock()
@@ -1503,7 +1896,30 @@ func reduceToZero(v int) int {
 	return v
 }
 
+// filterRebuiltInstanceStats removes the statistics reported by nodeID for
+// instances that have been rebuilt on another node.
+func (ds *Datastore) filterRebuiltInstanceStats(stats []payloads.InstanceStat, nodeID string) []payloads.InstanceStat {
+	ds.instancesLock.RLock()
+	defer ds.instancesLock.RUnlock()
+
+	if len(ds.rebuiltInstances) == 0 {
+		return stats
+	}
+
+	filtered := make([]payloads.InstanceStat, 0, len(stats))
+	for _, stat := range stats {
+		if ds.rebuiltInstances[rebuiltInstance{instanceID: stat.InstanceUUID, nodeID: nodeID}] {
+			continue
+		}
+		filtered = append(filtered, stat)
+	}
+
+	return filtered
+}
+
 func (ds *Datastore) addInstanceStats(stats []payloads.InstanceStat, nodeID string) error {
+	stats = ds.filterRebuiltInstanceStats(stats, nodeID)
+
 	for index := range stats {
 		stat := stats[index]
 
@@ -1538,7 +1954,13 @@ func (ds *Datastore) addInstanceStats(stats []payloads.InstanceStat, nodeID stri
 
 		ds.instancesLock.Lock()
 		instance, ok := ds.instances[stat.InstanceUUID]
-		if ok {
+
+		// Both the source and the target node of a live migration
+		// report the instance while it is being migrated.  Controller
+		// decides where it runs once the migration is over.
+
+		if ok && instance.State != payloads.Migrating &&
+			stat.State != payloads.Migrating {
 			instance.State = stat.State
 			instance.NodeID = nodeID
 			instance.SSHIP = stat.SSHIP
@@ -1548,6 +1970,10 @@ func (ds *Datastore) addInstanceStats(stats []payloads.InstanceStat, nodeID stri
 			ds.nodesLock.Unlock()
 		}
 		ds.instancesLock.Unlock()
+
+		if ok {
+			ds.updateDetachingVolumes(stat)
+		}
 	}
 
 	return errors.Wrapf(ds.db.addInstanceStats(stats, nodeID), "error adding instance stats to database")
@@ -1744,6 +2170,88 @@ func (ds *Datastore) DeleteBlockDevice(ID string) error {
 	return nil
 }
 
+// AddVolumeSnapshot will store information about a new volume snapshot
+// into the datastore.
+func (ds *Datastore) AddVolumeSnapshot(snapshot types.VolumeSnapshot) error {
+	ds.snapshotLock.Lock()
+	defer ds.snapshotLock.Unlock()
+
+	if _, ok := ds.snapshots[snapshot.ID]; ok {
+		return api.ErrAlreadyExists
+	}
+
+	err := ds.db.updateVolumeSnapshot(snapshot)
+	if err != nil {
+		return errors.Wrap(err, "Error adding volume snapshot to database")
+	}
+
+	ds.snapshots[snapshot.ID] = snapshot
+
+	return nil
+}
+
+// GetVolumeSnapshot will return information about a volume snapshot.
+func (ds *Datastore) GetVolumeSnapshot(ID string) (types.VolumeSnapshot, error) {
+	ds.snapshotLock.RLock()
+	defer ds.snapshotLock.RUnlock()
+
+	snapshot, ok := ds.snapshots[ID]
+	if !ok {
+		return types.VolumeSnapshot{}, types.ErrSnapshotNotFound
+	}
+
+	return snapshot, nil
+}
+
+// GetVolumeSnapshots will return all the volume snapshots owned by a tenant.
+func (ds *Datastore) GetVolumeSnapshots(tenant string) ([]types.VolumeSnapshot, error) {
+	ds.snapshotLock.RLock()
+	defer ds.snapshotLock.RUnlock()
+
+	snapshots := []types.VolumeSnapshot{}
+	for _, s := range ds.snapshots {
+		if s.TenantID == tenant {
+			snapshots = append(snapshots, s)
+		}
+	}
+
+	return snapshots, nil
+}
+
+// GetSnapshotsOfVolume will return all the snapshots taken of a volume.
+func (ds *Datastore) GetSnapshotsOfVolume(volume string) ([]types.VolumeSnapshot, error) {
+	ds.snapshotLock.RLock()
+	defer ds.snapshotLock.RUnlock()
+
+	snapshots := []types.VolumeSnapshot{}
+	for _, s := range ds.snapshots {
+		if s.VolumeID == volume {
+			snapshots = append(snapshots, s)
+		}
+	}
+
+	return snapshots, nil
+}
+
+// DeleteVolumeSnapshot will delete a volume snapshot from the datastore.
+func (ds *Datastore) DeleteVolumeSnapshot(ID string) error {
+	ds.snapshotLock.Lock()
+	defer ds.snapshotLock.Unlock()
+
+	if _, ok := ds.snapshots[ID]; !ok {
+		return types.ErrSnapshotNotFound
+	}
+
+	err := ds.db.deleteVolumeSnapshot(ID)
+	if err != nil {
+		return errors.Wrap(err, "Error deleting volume snapshot from database")
+	}
+
+	delete(ds.snapshots, ID)
+
+	return nil
+}
+
 // GetBlockDevices will return all the BlockDevices associated with a tenant.
 func (ds *Datastore) GetBlockDevices(tenant string) ([]types.Volume, error) {
 	var devices []types.Volume
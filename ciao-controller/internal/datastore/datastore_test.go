@@ -1245,6 +1245,209 @@ func TestStartFailureFullCloud(t *testing.T) {
 	}
 }
 
+func TestInstanceMigration(t *testing.T) {
+	instances, stat := addTestInstanceStats(t)
+	instance := instances[0]
+
+	err := ds.InstanceMigrating(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.InstanceMigrating(instance.ID)
+	if err == nil {
+		t.Fatal("Expected migration of a migrating instance to fail")
+	}
+
+	// stats from either node must not alter a migrating instance
+
+	targetID := uuid.Generate().String()
+	ds.AddNode(targetID, payloads.ComputeNode)
+	targetStats := []payloads.InstanceStat{
+		{
+			InstanceUUID: instance.ID,
+			State:        payloads.Migrating,
+		},
+	}
+	err = ds.addInstanceStats(targetStats, targetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ds.addInstanceStats(stat.Instances, stat.NodeUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, err := ds.GetInstance(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.State != payloads.Migrating || i.NodeID != stat.NodeUUID {
+		t.Fatalf("Migrating instance updated by stats: %s on %s", i.State, i.NodeID)
+	}
+
+	err = ds.InstanceMigrated(instance.ID, targetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, err = ds.GetInstance(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.State != payloads.Running || i.NodeID != targetID {
+		t.Fatalf("Migrated instance is %s on %s", i.State, i.NodeID)
+	}
+
+	nodeInstances, err := ds.GetAllInstancesByNode(stat.NodeUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ni := range nodeInstances {
+		if ni.ID == instance.ID {
+			t.Fatal("Migrated instance still assigned to source node")
+		}
+	}
+
+	nodeInstances, err = ds.GetAllInstancesByNode(targetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeInstances) != 1 || nodeInstances[0].ID != instance.ID {
+		t.Fatal("Migrated instance not assigned to target node")
+	}
+}
+
+func TestMigrateFailure(t *testing.T) {
+	instances, stat := addTestInstanceStats(t)
+	instance := instances[0]
+
+	err := ds.InstanceMigrating(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.MigrateFailure(instance.ID, payloads.MigrateTransferFailure, stat.NodeUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, err := ds.GetInstance(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.State != payloads.Running || i.NodeID != stat.NodeUUID {
+		t.Fatalf("Instance is %s on %s after failed migration", i.State, i.NodeID)
+	}
+}
+
+func TestInstanceResized(t *testing.T) {
+	instances, _ := addTestInstanceStats(t)
+	instance := instances[0]
+
+	workloadID := uuid.Generate().String()
+	err := ds.InstanceResized(instance.ID, workloadID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, err := ds.GetInstance(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.WorkloadID != workloadID {
+		t.Fatalf("Expected workload %s, got %s", workloadID, i.WorkloadID)
+	}
+
+	err = ds.InstanceResized("bogus", workloadID)
+	if err != types.ErrInstanceNotFound {
+		t.Fatalf("Expected ErrInstanceNotFound, got %v", err)
+	}
+}
+
+func TestInstanceRebuilding(t *testing.T) {
+	instances, stat := addTestInstanceStats(t)
+	instance := instances[0]
+
+	err := ds.InstanceRebuilding(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, err := ds.GetInstance(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.State != payloads.Exited || i.NodeID != "" {
+		t.Fatalf("Rebuilding instance is %s on %s", i.State, i.NodeID)
+	}
+
+	// stats reported by the old node must be ignored
+
+	err = ds.addInstanceStats(stat.Instances, stat.NodeUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, err = ds.GetInstance(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.State != payloads.Exited || i.NodeID != "" {
+		t.Fatalf("Rebuilding instance updated by stats: %s on %s", i.State, i.NodeID)
+	}
+
+	// stats reported by its new node must not
+
+	nodeID := uuid.Generate().String()
+	ds.AddNode(nodeID, payloads.ComputeNode)
+	newStats := []payloads.InstanceStat{
+		{
+			InstanceUUID: instance.ID,
+			State:        payloads.Running,
+		},
+	}
+	err = ds.addInstanceStats(newStats, nodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, err = ds.GetInstance(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.State != payloads.Running || i.NodeID != nodeID {
+		t.Fatalf("Rebuilt instance is %s on %s", i.State, i.NodeID)
+	}
+
+	// the old node is remembered until it has deleted its copy
+
+	rebuilt := ds.RebuiltInstances(stat.NodeUUID)
+	if len(rebuilt) != 1 || rebuilt[0] != instance.ID {
+		t.Fatalf("Expected %s to be rebuilt from %s, got %v", instance.ID,
+			stat.NodeUUID, rebuilt)
+	}
+
+	deleted, err := ds.RebuiltInstanceDeleted(instance.ID, nodeID)
+	if err != nil || deleted {
+		t.Fatalf("Deletion by the new node reported as a rebuilt copy: %v", err)
+	}
+
+	deleted, err = ds.RebuiltInstanceDeleted(instance.ID, stat.NodeUUID)
+	if err != nil || !deleted {
+		t.Fatalf("Deletion by the old node not reported as a rebuilt copy: %v", err)
+	}
+
+	if rebuilt := ds.RebuiltInstances(stat.NodeUUID); len(rebuilt) != 0 {
+		t.Fatalf("Rebuilt instances left on %s: %v", stat.NodeUUID, rebuilt)
+	}
+
+	err = ds.InstanceRebuilding("bogus")
+	if err != types.ErrInstanceNotFound {
+		t.Fatalf("Expected ErrInstanceNotFound, got %v", err)
+	}
+}
+
 func TestAttachVolumeFailure(t *testing.T) {
 	newTenant, err := addTestTenant()
 	if err != nil {
@@ -1304,6 +1507,216 @@ func TestAttachVolumeFailure(t *testing.T) {
 	}
 }
 
+func TestExtendVolumeFailure(t *testing.T) {
+	newTenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls, err := ds.GetWorkloads(newTenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := addTestInstance(newTenant, wls[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := types.Volume{
+		BlockDevice: storage.BlockDevice{ID: "extendID", Size: 10},
+		State:       types.InUse,
+		TenantID:    newTenant.ID,
+		CreateTime:  time.Now(),
+	}
+
+	err = ds.AddBlockDevice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new size of the volume is stored by UpdateBlockDevice.
+	data.Size = 20
+	err = ds.UpdateBlockDevice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.ExtendVolumeFailure(instance.ID, data.ID, payloads.ExtendVolumeResizeFailure)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the failure to notify the instance leaves the volume untouched.
+	bd, err := ds.GetBlockDevice(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bd.Size != 20 || bd.State != types.InUse {
+		t.Fatalf("unexpected volume size %d or state %s", bd.Size, bd.State)
+	}
+
+	err = ds.ExtendVolumeFailure("invalidID", data.ID, payloads.ExtendVolumeResizeFailure)
+	if err == nil {
+		t.Fatal("expected error for unknown instance")
+	}
+}
+
+func addDetachingTestVolume(t *testing.T, tenant *types.Tenant, instanceID string, ID string) types.Volume {
+	data := types.Volume{
+		BlockDevice: storage.BlockDevice{ID: ID},
+		State:       types.Available,
+		TenantID:    tenant.ID,
+		CreateTime:  time.Now(),
+	}
+
+	err := ds.AddBlockDevice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.CreateStorageAttachment(instanceID, payloads.StorageResource{ID: ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = ds.GetBlockDevice(ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data.State = types.Detaching
+	err = ds.UpdateBlockDevice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestDetachVolumeFailure(t *testing.T) {
+	newTenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls, err := ds.GetWorkloads(newTenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := addTestInstance(newTenant, wls[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := addDetachingTestVolume(t, newTenant, instance.ID, "detachFailureID")
+
+	// a failure to unplug the volume leaves it attached.
+	err = ds.DetachVolumeFailure(instance.ID, data.ID, payloads.DetachVolumeDetachFailure)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bd, err := ds.GetBlockDevice(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bd.State != types.InUse {
+		t.Fatalf("expected state: %s, got %s\n", types.InUse, bd.State)
+	}
+
+	if len(ds.GetStorageAttachments(instance.ID)) != 1 {
+		t.Fatal("expected attachment to be kept")
+	}
+
+	// a volume the launcher does not know about is simply detached.
+	err = ds.DetachVolumeFailure(instance.ID, data.ID, payloads.DetachVolumeNotAttached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bd, err = ds.GetBlockDevice(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bd.State != types.Available {
+		t.Fatalf("expected state: %s, got %s\n", types.Available, bd.State)
+	}
+
+	if len(ds.GetStorageAttachments(instance.ID)) != 0 {
+		t.Fatal("expected attachment to be deleted")
+	}
+}
+
+func TestDetachVolumeStats(t *testing.T) {
+	newTenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls, err := ds.GetWorkloads(newTenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := addTestInstance(newTenant, wls[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := addDetachingTestVolume(t, newTenant, instance.ID, "detachStatsID")
+
+	stat := payloads.Stat{
+		NodeUUID:     uuid.Generate().String(),
+		NodeHostName: "test",
+		Instances: []payloads.InstanceStat{
+			{
+				InstanceUUID: instance.ID,
+				State:        payloads.ComputeStatusRunning,
+				Volumes:      []string{data.ID},
+			},
+		},
+	}
+
+	// the volume is still reported so the detach is not complete.
+	err = ds.HandleStats(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bd, err := ds.GetBlockDevice(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bd.State != types.Detaching {
+		t.Fatalf("expected state: %s, got %s\n", types.Detaching, bd.State)
+	}
+
+	stat.Instances[0].Volumes = nil
+	err = ds.HandleStats(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bd, err = ds.GetBlockDevice(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bd.State != types.Available {
+		t.Fatalf("expected state: %s, got %s\n", types.Available, bd.State)
+	}
+
+	if len(ds.GetStorageAttachments(instance.ID)) != 0 {
+		t.Fatal("expected attachment to be deleted")
+	}
+}
+
 func testAllocateTenantIPs(t *testing.T, nIPs int) {
 	newTenant, err := addTestTenant()
 	if err != nil {
@@ -1520,6 +1933,73 @@ func TestUpdateBlockDevice(t *testing.T) {
 	}
 }
 
+func TestAddDeleteVolumeSnapshot(t *testing.T) {
+	newTenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := types.VolumeSnapshot{
+		ID:       uuid.Generate().String(),
+		VolumeID: uuid.Generate().String(),
+		TenantID: newTenant.ID,
+		Size:     10,
+		Name:     "test-snapshot",
+	}
+
+	err = ds.AddVolumeSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.AddVolumeSnapshot(snapshot)
+	if err != api.ErrAlreadyExists {
+		t.Fatalf("expecting %s error, received %v", api.ErrAlreadyExists, err)
+	}
+
+	s, err := ds.GetVolumeSnapshot(snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(s, snapshot) {
+		t.Fatal("Snapshot retrieval by ID expected to match")
+	}
+
+	snapshots, err := ds.GetVolumeSnapshots(newTenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 1 || snapshots[0].ID != snapshot.ID {
+		t.Fatalf("Expected one snapshot for tenant, got %v", snapshots)
+	}
+
+	snapshots, err = ds.GetSnapshotsOfVolume(snapshot.VolumeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 1 || snapshots[0].ID != snapshot.ID {
+		t.Fatalf("Expected one snapshot for volume, got %v", snapshots)
+	}
+
+	err = ds.DeleteVolumeSnapshot(snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.GetVolumeSnapshot(snapshot.ID)
+	if err != types.ErrSnapshotNotFound {
+		t.Fatalf("expecting %s error, received %v", types.ErrSnapshotNotFound, err)
+	}
+
+	err = ds.DeleteVolumeSnapshot(snapshot.ID)
+	if err != types.ErrSnapshotNotFound {
+		t.Fatalf("expecting %s error, received %v", types.ErrSnapshotNotFound, err)
+	}
+}
+
 func TestGetBlockDevicesErr(t *testing.T) {
 	// confirm that sending a bad tenant id results in error
 	_, err := ds.GetBlockDevices("badID")
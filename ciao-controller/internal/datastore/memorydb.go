@@ -261,3 +261,27 @@ func (db *MemoryDB) updateImage(i types.Image) error {
 func (db *MemoryDB) deleteImage(ID string) error {
 	return nil
 }
+
+func (db *MemoryDB) updateVolumeSnapshot(snapshot types.VolumeSnapshot) error {
+	return nil
+}
+
+func (db *MemoryDB) deleteVolumeSnapshot(ID string) error {
+	return nil
+}
+
+func (db *MemoryDB) getVolumeSnapshots() ([]types.VolumeSnapshot, error) {
+	return []types.VolumeSnapshot{}, nil
+}
+
+func (db *MemoryDB) addRebuiltInstance(r rebuiltInstance) error {
+	return nil
+}
+
+func (db *MemoryDB) deleteRebuiltInstance(r rebuiltInstance) error {
+	return nil
+}
+
+func (db *MemoryDB) getRebuiltInstances() ([]rebuiltInstance, error) {
+	return []rebuiltInstance{}, nil
+}
@@ -231,6 +231,25 @@ func (d workloadResourceData) Init() error {
 	return d.ds.exec(d.db, cmd)
 }
 
+// workload placement constraints, which unlike the other workload
+// resources carry a string value and may be repeated.
+type workloadPlacementData struct {
+	namedData
+}
+
+func (d workloadPlacementData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS workload_placement
+		(
+		workload_id varchar(32),
+		resource_type string,
+		value_string string,
+		mandatory int,
+		foreign key(workload_id) references workload_template(id)
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
 // workload template data
 type workloadTemplateData struct {
 	namedData
@@ -431,6 +450,56 @@ func (d imageData) Init() error {
 	return d.ds.exec(d.db, cmd)
 }
 
+type imageFormatData struct {
+	namedData
+}
+
+func (d imageFormatData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS image_formats
+		(
+			image_id varchar(32) primary key,
+			disk_format string,
+			checksum string,
+			virtual_size int
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
+type volumeSnapshotData struct {
+	namedData
+}
+
+func (d volumeSnapshotData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS volume_snapshots
+		(
+			id varchar(32) primary key,
+			volume_id varchar(32),
+			tenant_id string,
+			createtime DATETIME,
+			size int,
+			name string,
+			description string
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
+type rebuiltInstanceData struct {
+	namedData
+}
+
+func (d rebuiltInstanceData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS rebuilt_instances
+		(
+			instance_id varchar(32),
+			node_id varchar(32),
+			primary key(instance_id, node_id)
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
 func (ds *sqliteDB) exec(db *sql.DB, cmd string) error {
 	glog.V(2).Info("exec: ", cmd)
 
@@ -515,6 +584,7 @@ func (ds *sqliteDB) init(config Config) error {
 		instanceData{namedData{ds: ds, name: "instances", db: ds.db}},
 		workloadTemplateData{namedData{ds: ds, name: "workload_template", db: ds.db}},
 		workloadResourceData{namedData{ds: ds, name: "workload_resources", db: ds.db}},
+		workloadPlacementData{namedData{ds: ds, name: "workload_placement", db: ds.db}},
 		nodeStatisticsData{namedData{ds: ds, name: "node_statistics", db: ds.db}},
 		logData{namedData{ds: ds, name: "log", db: ds.db}},
 		subnetData{namedData{ds: ds, name: "tenant_network", db: ds.db}},
@@ -530,6 +600,9 @@ func (ds *sqliteDB) init(config Config) error {
 		mappedIPData{namedData{ds: ds, name: "mapped_ips", db: ds.db}},
 		quotaData{namedData{ds: ds, name: "quotas", db: ds.db}},
 		imageData{namedData{ds: ds, name: "images", db: ds.db}},
+		imageFormatData{namedData{ds: ds, name: "image_formats", db: ds.db}},
+		volumeSnapshotData{namedData{ds: ds, name: "volume_snapshots", db: ds.db}},
+		rebuiltInstanceData{namedData{ds: ds, name: "rebuilt_instances", db: ds.db}},
 	}
 
 	ds.workloadsPath = config.InitWorkloadsPath
@@ -672,11 +745,64 @@ func (ds *sqliteDB) getWorkloadDefaults(ID string) ([]payloads.RequestedResource
 		defaults = append(defaults, r)
 	}
 
-	return defaults, nil
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	placement, err := ds.getWorkloadPlacement(ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(defaults, placement...), nil
+}
+
+func (ds *sqliteDB) getWorkloadPlacement(ID string) ([]payloads.RequestedResource, error) {
+	query := `SELECT resource_type, value_string, mandatory FROM workload_placement
+	     WHERE workload_id = ? ORDER BY resource_type, value_string `
+
+	db := ds.getTableDB("workload_placement")
+
+	rows, err := db.Query(query, ID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var placement []payloads.RequestedResource
+
+	for rows.Next() {
+		var rname string
+		var value string
+		var mandatory bool
+
+		err = rows.Scan(&rname, &value, &mandatory)
+		if err != nil {
+			return nil, err
+		}
+		r := payloads.RequestedResource{
+			Type:        payloads.Resource(rname),
+			ValueString: value,
+			Mandatory:   mandatory,
+		}
+		placement = append(placement, r)
+	}
+
+	return placement, rows.Err()
+}
+
+func isPlacementResource(r payloads.Resource) bool {
+	return r == payloads.NodeSelector || r == payloads.Affinity || r == payloads.AntiAffinity
 }
 
 // lock must be held by caller
 func (ds *sqliteDB) createWorkloadDefault(tx *sql.Tx, workloadID string, resource payloads.RequestedResource) error {
+	if isPlacementResource(resource.Type) {
+		_, err := tx.Exec("INSERT INTO workload_placement (workload_id, resource_type, value_string, mandatory) VALUES (?, ?, ?, ?)", workloadID, string(resource.Type), resource.ValueString, resource.Mandatory)
+
+		return err
+	}
+
 	_, err := tx.Exec("INSERT INTO workload_resources (workload_id, resource_type, default_value, estimated_value, mandatory) VALUES (?, ?, ?, ?, ?)", workloadID, string(resource.Type), resource.Value, resource.Value, resource.Mandatory)
 
 	return err
@@ -685,6 +811,11 @@ func (ds *sqliteDB) createWorkloadDefault(tx *sql.Tx, workloadID string, resourc
 // lock must be held by caller
 func (ds *sqliteDB) deleteWorkloadDefault(tx *sql.Tx, workloadID string) error {
 	_, err := tx.Exec("DELETE FROM workload_resources WHERE workload_id = ?", workloadID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM workload_placement WHERE workload_id = ?", workloadID)
 
 	return err
 }
@@ -1328,7 +1459,7 @@ func (ds *sqliteDB) updateInstance(instance *types.Instance) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	_, err := db.Exec("UPDATE instances SET mac_address = ?, ip = ? WHERE id = ?", instance.MACAddress, instance.IPAddress, instance.ID)
+	_, err := db.Exec("UPDATE instances SET mac_address = ?, ip = ?, workload_id = ? WHERE id = ?", instance.MACAddress, instance.IPAddress, instance.WorkloadID, instance.ID)
 
 	return err
 }
@@ -1751,7 +1882,7 @@ func (ds *sqliteDB) updateBlockData(data types.Volume) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	_, err := db.Exec("UPDATE block_data SET state = ? WHERE id = ?", string(data.State), data.ID)
+	_, err := db.Exec("UPDATE block_data SET state = ?, size = ? WHERE id = ?", string(data.State), data.Size, data.ID)
 
 	return err
 }
@@ -2242,7 +2373,14 @@ func (ds *sqliteDB) getQuotas(tenantID string) ([]types.QuotaDetails, error) {
 func (ds *sqliteDB) getImages() ([]types.Image, error) {
 	images := []types.Image{}
 
-	query := `SELECT id, state, tenant_id, name, createtime, size, visibility FROM images`
+	query := `SELECT images.id, images.state, images.tenant_id, images.name,
+			 images.createtime, images.size, images.visibility,
+			 COALESCE(image_formats.disk_format, ''),
+			 COALESCE(image_formats.checksum, ''),
+			 COALESCE(image_formats.virtual_size, 0)
+		  FROM images
+		  LEFT JOIN image_formats
+		  ON images.id = image_formats.image_id`
 
 	db := ds.getTableDB("images")
 	ds.dbLock.Lock()
@@ -2256,15 +2394,16 @@ func (ds *sqliteDB) getImages() ([]types.Image, error) {
 
 	for rows.Next() {
 		i := types.Image{}
-		var state, visibility string
+		var state, visibility, format string
 
-		err = rows.Scan(&i.ID, &state, &i.TenantID, &i.Name, &i.CreateTime, &i.Size, &visibility)
+		err = rows.Scan(&i.ID, &state, &i.TenantID, &i.Name, &i.CreateTime, &i.Size, &visibility, &format, &i.Checksum, &i.VirtualSize)
 		if err != nil {
 			return []types.Image{}, errors.Wrap(err, "error reading image row from database")
 		}
 
 		i.State = types.ImageState(state)
 		i.Visibility = types.Visibility(visibility)
+		i.DiskFormat = types.DiskFormat(format)
 
 		images = append(images, i)
 	}
@@ -2279,9 +2418,24 @@ func (ds *sqliteDB) updateImage(i types.Image) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	_, err := db.Exec(query, i.ID, i.State, i.TenantID, i.Name, i.CreateTime, i.Size, i.Visibility)
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Error updating image into database")
+	}
+
+	_, err = tx.Exec(query, i.ID, i.State, i.TenantID, i.Name, i.CreateTime, i.Size, i.Visibility)
+	if err != nil {
+		_ = tx.Rollback()
+		return errors.Wrap(err, "Error updatiing image into database")
+	}
 
-	return errors.Wrap(err, "Error updatiing image into database")
+	_, err = tx.Exec("REPLACE INTO image_formats (image_id, disk_format, checksum, virtual_size) VALUES (?, ?, ?, ?)", i.ID, string(i.DiskFormat), i.Checksum, i.VirtualSize)
+	if err != nil {
+		_ = tx.Rollback()
+		return errors.Wrap(err, "Error updating image format into database")
+	}
+
+	return errors.Wrap(tx.Commit(), "Error updating image into database")
 }
 
 func (ds *sqliteDB) deleteImage(ID string) error {
@@ -2291,7 +2445,128 @@ func (ds *sqliteDB) deleteImage(ID string) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Error deleting image from database")
+	}
+
+	_, err = tx.Exec(query, ID)
+	if err != nil {
+		_ = tx.Rollback()
+		return errors.Wrap(err, "Error deleting image from database")
+	}
+
+	_, err = tx.Exec("DELETE FROM image_formats WHERE image_id = ?", ID)
+	if err != nil {
+		_ = tx.Rollback()
+		return errors.Wrap(err, "Error deleting image format from database")
+	}
+
+	return errors.Wrap(tx.Commit(), "Error deleting image from database")
+}
+
+func (ds *sqliteDB) getVolumeSnapshots() ([]types.VolumeSnapshot, error) {
+	snapshots := []types.VolumeSnapshot{}
+
+	query := `SELECT id, volume_id, tenant_id, createtime, size, name, description FROM volume_snapshots`
+
+	db := ds.getTableDB("volume_snapshots")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return snapshots, errors.Wrap(err, "error getting volume snapshots from database")
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		s := types.VolumeSnapshot{}
+
+		err = rows.Scan(&s.ID, &s.VolumeID, &s.TenantID, &s.CreateTime, &s.Size, &s.Name, &s.Description)
+		if err != nil {
+			return []types.VolumeSnapshot{}, errors.Wrap(err, "error reading volume snapshot row from database")
+		}
+
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots, nil
+}
+
+func (ds *sqliteDB) updateVolumeSnapshot(s types.VolumeSnapshot) error {
+	query := `REPLACE INTO volume_snapshots (id, volume_id, tenant_id, createtime, size, name, description) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	db := ds.getTableDB("volume_snapshots")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, s.ID, s.VolumeID, s.TenantID, s.CreateTime, s.Size, s.Name, s.Description)
+
+	return errors.Wrap(err, "Error updating volume snapshot in database")
+}
+
+func (ds *sqliteDB) deleteVolumeSnapshot(ID string) error {
+	query := `DELETE FROM volume_snapshots WHERE id = ?`
+
+	db := ds.getTableDB("volume_snapshots")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
 	_, err := db.Exec(query, ID)
 
-	return errors.Wrap(err, "Error deleting image from database")
+	return errors.Wrap(err, "Error deleting volume snapshot from database")
+}
+
+func (ds *sqliteDB) getRebuiltInstances() ([]rebuiltInstance, error) {
+	rebuilt := []rebuiltInstance{}
+
+	query := `SELECT instance_id, node_id FROM rebuilt_instances`
+
+	db := ds.getTableDB("rebuilt_instances")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return rebuilt, errors.Wrap(err, "error getting rebuilt instances from database")
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var r rebuiltInstance
+
+		err = rows.Scan(&r.instanceID, &r.nodeID)
+		if err != nil {
+			return []rebuiltInstance{}, errors.Wrap(err, "error reading rebuilt instance row from database")
+		}
+
+		rebuilt = append(rebuilt, r)
+	}
+
+	return rebuilt, nil
+}
+
+func (ds *sqliteDB) addRebuiltInstance(r rebuiltInstance) error {
+	query := `REPLACE INTO rebuilt_instances (instance_id, node_id) VALUES (?, ?)`
+
+	db := ds.getTableDB("rebuilt_instances")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, r.instanceID, r.nodeID)
+
+	return errors.Wrap(err, "Error adding rebuilt instance to database")
+}
+
+func (ds *sqliteDB) deleteRebuiltInstance(r rebuiltInstance) error {
+	query := `DELETE FROM rebuilt_instances WHERE instance_id = ? AND node_id = ?`
+
+	db := ds.getTableDB("rebuilt_instances")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, r.instanceID, r.nodeID)
+
+	return errors.Wrap(err, "Error deleting rebuilt instance from database")
 }
@@ -1016,6 +1016,88 @@ users:
 	db.disconnect()
 }
 
+func TestSQLiteDBWorkloadPlacement(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tn := createTestTenant(db, t)
+
+	mem := payloads.RequestedResource{
+		Type:  payloads.MemMB,
+		Value: 512,
+	}
+
+	wl := types.Workload{
+		ID:          uuid.Generate().String(),
+		TenantID:    tn.ID,
+		Description: "testWorkload",
+		FWType:      string(payloads.EFI),
+		VMType:      payloads.QEMU,
+		Config:      "---\n#cloud-config\n...\n",
+		Storage:     []types.StorageResource{},
+	}
+
+	antiAffinity := payloads.RequestedResource{
+		Type:        payloads.AntiAffinity,
+		ValueString: wl.ID,
+		Mandatory:   true,
+	}
+
+	ssd := payloads.RequestedResource{
+		Type:        payloads.NodeSelector,
+		ValueString: "disk=ssd",
+		Mandatory:   true,
+	}
+
+	rack := payloads.RequestedResource{
+		Type:        payloads.NodeSelector,
+		ValueString: "rack=r1",
+		Mandatory:   true,
+	}
+
+	wl.Defaults = []payloads.RequestedResource{mem, antiAffinity, ssd, rack}
+
+	filename := fmt.Sprintf("%s/%s_config.yaml", *workloadsPath, wl.ID)
+	defer func() { _ = os.Remove(filename) }()
+
+	err = db.updateWorkload(wl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err := db.getTenant(tn.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tenant.workloads) != 1 {
+		t.Fatal("Expected a workload associated with tenant")
+	}
+
+	defaults := tenant.workloads[0].Defaults
+	if !reflect.DeepEqual(defaults, wl.Defaults) {
+		t.Fatalf("Expected defaults %v, got %v", wl.Defaults, defaults)
+	}
+
+	err = db.deleteWorkload(wl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults, err = db.(*sqliteDB).getWorkloadDefaults(wl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(defaults) != 0 {
+		t.Fatalf("Expected no defaults for deleted workload, got %v", defaults)
+	}
+
+	db.disconnect()
+}
+
 func findQuota(qds []types.QuotaDetails, name string, value int) bool {
 	for _, qd := range qds {
 		if qd.Name == name && qd.Value == value {
@@ -1432,4 +1514,67 @@ func TestSQLiteDBUpdateImage(t *testing.T) {
 	if !reflect.DeepEqual(images[0], i) {
 		t.Fatalf("Returned image not as expected %v vs %v", images[0], i)
 	}
+
+	i.DiskFormat = types.QCOW2
+	i.Checksum = "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	i.VirtualSize = 10 * 1024 * 1024 * 1024
+
+	err = db.updateImage(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images, err = db.getImages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(images) != 1 {
+		t.Fatalf("Unexpected image count: %d vs 1", len(images))
+	}
+
+	if !reflect.DeepEqual(images[0], i) {
+		t.Fatalf("Returned image not as expected %v vs %v", images[0], i)
+	}
+}
+
+func TestSQLiteDBRebuiltInstances(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instanceID := uuid.Generate().String()
+	oldNodes := []string{uuid.Generate().String(), uuid.Generate().String()}
+
+	for _, nodeID := range oldNodes {
+		err = db.addRebuiltInstance(rebuiltInstance{instanceID: instanceID, nodeID: nodeID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rebuilt, err := db.getRebuiltInstances()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rebuilt) != 2 {
+		t.Fatalf("Unexpected rebuilt instance count: %d vs 2", len(rebuilt))
+	}
+
+	err = db.deleteRebuiltInstance(rebuiltInstance{instanceID: instanceID, nodeID: oldNodes[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rebuilt, err = db.getRebuiltInstances()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []rebuiltInstance{{instanceID: instanceID, nodeID: oldNodes[1]}}
+	if !reflect.DeepEqual(rebuilt, expected) {
+		t.Fatalf("Returned rebuilt instances not as expected %v vs %v", rebuilt, expected)
+	}
 }
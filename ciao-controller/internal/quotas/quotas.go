@@ -81,6 +81,7 @@ var supportedResources = [...]payloads.Resource{
 	payloads.VCPUs,
 	payloads.MemMB,
 	payloads.Volume,
+	payloads.Snapshot,
 	payloads.SharedDiskGiB,
 	payloads.Instance,
 	payloads.Image,
@@ -190,6 +191,8 @@ func quotaNameToResource(name string) payloads.Resource {
 		return payloads.SharedDiskGiB
 	case "tenant-volumes-quota":
 		return payloads.Volume
+	case "tenant-snapshots-quota":
+		return payloads.Snapshot
 	case "tenant-instances-quota":
 		return payloads.Instance
 	case "tenant-images-quota":
@@ -209,6 +212,8 @@ func resourceToQuotaName(r payloads.Resource) string {
 		return "tenant-mem-quota"
 	case payloads.Volume:
 		return "tenant-volumes-quota"
+	case payloads.Snapshot:
+		return "tenant-snapshots-quota"
 	case payloads.SharedDiskGiB:
 		return "tenant-storage-quota"
 	case payloads.Instance:
@@ -162,6 +162,7 @@ func TestResourceQuotaMapping(t *testing.T) {
 		payloads.MemMB,
 		payloads.SharedDiskGiB,
 		payloads.Volume,
+		payloads.Snapshot,
 		payloads.Instance,
 		payloads.Image,
 		payloads.ExternalIP,
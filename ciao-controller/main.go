@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -35,6 +36,7 @@ import (
 	"github.com/ciao-project/ciao/clogger/gloginterface"
 	"github.com/ciao-project/ciao/database"
 	"github.com/ciao-project/ciao/osprepare"
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/ssntp"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
@@ -54,12 +56,15 @@ type controller struct {
 	tenantReadinessLock sync.Mutex
 	qs                  *quotas.Quotas
 	httpServers         []*http.Server
+	enrollment          *enrollment
 }
 
 var cert = flag.String("cert", "", "Client certificate")
 var caCert = flag.String("cacert", "", "CA certificate")
-var serverURL = flag.String("url", "", "Server URL")
+var serverURL = flag.String("url", "", "Server URL, or comma separated list of server URLs to fail over between")
 var controllerAPIPort = api.Port
+var enrollAPIPort = api.EnrollPort
+var anchorCert = flag.String("anchor_cert", "", "SSNTP anchor certificate to sign node enrollment requests with, enrollment is disabled if empty")
 var httpsCAcert = "/etc/pki/ciao/ciao-controller-cacert.pem"
 var httpsKey = "/etc/pki/ciao/ciao-controller-key.pem"
 var workloadsPath = flag.String("workloads_path", "/var/lib/ciao/data/controller/workloads", "path to yaml files")
@@ -70,6 +75,8 @@ var clientCertCAPath = "/etc/pki/ciao/auth-CA.pem"
 
 var cephID = flag.String("ceph_id", "", "ceph client id")
 
+var metricsAddr = flag.String("metrics", "", "host:port to serve Prometheus metrics on, disabled if empty")
+
 var adminSSHKey = ""
 
 // default password set to "ciao"
@@ -144,8 +151,10 @@ func main() {
 		return
 	}
 
+	serverURLs := strings.Split(*serverURL, ",")
 	config := &ssntp.Config{
-		URI:    *serverURL,
+		URI:    serverURLs[0],
+		URIs:   serverURLs[1:],
 		CAcert: *caCert,
 		Cert:   *cert,
 		Log:    ssntp.Log,
@@ -195,6 +204,18 @@ func main() {
 	osprepare.InstallDeps(context.TODO(), controllerDeps, logger)
 
 	ctl.BlockDriver = func() storage.BlockDriver {
+		storageConfig := clusterConfig.Configure.Storage
+		if storageConfig.Driver == payloads.LocalStorage {
+			driver := storage.LocalDriver{
+				Path: storageConfig.LocalPath,
+			}
+			if driver.Path == "" {
+				driver.Path = storage.DefaultLocalPath
+			}
+			glog.Infof("Storing volumes in %s", driver.Path)
+			return driver
+		}
+
 		driver := storage.CephDriver{
 			ID: *cephID,
 		}
@@ -221,6 +242,18 @@ func main() {
 	}
 	ctl.httpServers = append(ctl.httpServers, server)
 
+	if *anchorCert != "" {
+		ctl.enrollment, err = newEnrollment(*anchorCert)
+		if err != nil {
+			glog.Fatalf("Error enabling node enrollment: %v", err)
+		}
+		ctl.httpServers = append(ctl.httpServers, ctl.createEnrollServer())
+	}
+
+	if *metricsAddr != "" {
+		go ctl.serveMetrics(*metricsAddr)
+	}
+
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, syscall.SIGTERM, syscall.SIGINT)
 	go func() {
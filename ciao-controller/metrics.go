@@ -0,0 +1,198 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/metrics"
+	"github.com/golang/glog"
+)
+
+var commandsSent = metrics.NewCounterVec("ciao_controller_commands_sent_total",
+	"Number of SSNTP commands sent by the controller", "command")
+
+var commandFailures = metrics.NewCounterVec("ciao_controller_command_failures_total",
+	"Number of SSNTP command failures reported to the controller", "command", "reason")
+
+var instancesDeleted = metrics.NewCounterVec("ciao_controller_instances_deleted_total",
+	"Number of instance deletions confirmed to the controller")
+
+func (c *controller) nodeMetrics() []metrics.Family {
+	memTotal := metrics.NewFamily("ciao_node_memory_total_megabytes",
+		"Total memory of the node", metrics.Gauge)
+	memAvailable := metrics.NewFamily("ciao_node_memory_available_megabytes",
+		"Memory available on the node", metrics.Gauge)
+	diskTotal := metrics.NewFamily("ciao_node_disk_total_megabytes",
+		"Total disk space of the node", metrics.Gauge)
+	diskAvailable := metrics.NewFamily("ciao_node_disk_available_megabytes",
+		"Disk space available on the node", metrics.Gauge)
+	load := metrics.NewFamily("ciao_node_load",
+		"Load average of the node", metrics.Gauge)
+	cpusOnline := metrics.NewFamily("ciao_node_cpus_online",
+		"Number of online CPUs of the node", metrics.Gauge)
+	startFailures := metrics.NewFamily("ciao_node_start_failures_total",
+		"Number of instances that failed to start on the node", metrics.Counter)
+	deleteFailures := metrics.NewFamily("ciao_node_delete_failures_total",
+		"Number of instances that failed to be deleted on the node", metrics.Counter)
+	attachFailures := metrics.NewFamily("ciao_node_attach_volume_failures_total",
+		"Number of volumes that failed to be attached on the node", metrics.Counter)
+
+	cpuUsage := metrics.NewFamily("ciao_instance_cpu_usage_percent",
+		"CPU usage of the instance", metrics.Gauge)
+	memUsage := metrics.NewFamily("ciao_instance_memory_usage_megabytes",
+		"Memory used by the instance", metrics.Gauge)
+	diskUsage := metrics.NewFamily("ciao_instance_disk_usage_megabytes",
+		"Disk space used by the instance", metrics.Gauge)
+
+	nodes := c.ds.GetNodeLastStats().Nodes
+	sort.Sort(types.SortedNodesByID(nodes))
+
+	for _, n := range nodes {
+		labels := metrics.Labels{"node": n.ID, "hostname": n.Hostname}
+
+		memTotal.Add(float64(n.MemTotal), labels)
+		memAvailable.Add(float64(n.MemAvailable), labels)
+		diskTotal.Add(float64(n.DiskTotal), labels)
+		diskAvailable.Add(float64(n.DiskAvailable), labels)
+		load.Add(float64(n.Load), labels)
+		cpusOnline.Add(float64(n.OnlineCPUs), labels)
+
+		if node, err := c.ds.GetNode(n.ID); err == nil {
+			startFailures.Add(float64(node.StartFailures), labels)
+			deleteFailures.Add(float64(node.DeleteFailures), labels)
+			attachFailures.Add(float64(node.AttachVolumeFailures), labels)
+		}
+
+		servers := c.ds.GetInstanceLastStats(n.ID).Servers
+		sort.Slice(servers, func(i, j int) bool { return servers[i].ID < servers[j].ID })
+
+		for _, s := range servers {
+			labels := metrics.Labels{"instance": s.ID, "tenant": s.TenantID, "node": n.ID}
+
+			cpuUsage.Add(float64(s.VCPUUsage), labels)
+			memUsage.Add(float64(s.MemUsage), labels)
+			diskUsage.Add(float64(s.DiskUsage), labels)
+		}
+	}
+
+	return []metrics.Family{*memTotal, *memAvailable, *diskTotal, *diskAvailable,
+		*load, *cpusOnline, *startFailures, *deleteFailures, *attachFailures,
+		*cpuUsage, *memUsage, *diskUsage}
+}
+
+func (c *controller) quotaMetrics() []metrics.Family {
+	usage := metrics.NewFamily("ciao_tenant_quota_usage",
+		"Resources consumed by the tenant", metrics.Gauge)
+	limit := metrics.NewFamily("ciao_tenant_quota_limit",
+		"Resources the tenant is allowed to consume, absent if unlimited", metrics.Gauge)
+
+	tenants, err := c.ds.GetAllTenants()
+	if err != nil {
+		glog.Warningf("Unable to get tenants for metrics: %v", err)
+		return []metrics.Family{*usage, *limit}
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+
+	for _, t := range tenants {
+		qds := c.qs.DumpQuotas(t.ID)
+		sort.Slice(qds, func(i, j int) bool { return qds[i].Name < qds[j].Name })
+
+		for _, qd := range qds {
+			if !strings.HasSuffix(qd.Name, "-quota") {
+				continue
+			}
+
+			labels := metrics.Labels{"tenant": t.ID, "quota": qd.Name}
+			usage.Add(float64(qd.Usage), labels)
+			if qd.Value != -1 {
+				limit.Add(float64(qd.Value), labels)
+			}
+		}
+	}
+
+	return []metrics.Family{*usage, *limit}
+}
+
+// frameMetrics reports the traced frames of all batches together, weighting
+// the average latency of each batch by its number of frames, so that the
+// number of series does not grow with the number of batches.
+func (c *controller) frameMetrics() []metrics.Family {
+	frames := metrics.NewFamily("ciao_ssntp_traced_frames",
+		"Number of traced SSNTP frames", metrics.Gauge)
+	latency := metrics.NewFamily("ciao_ssntp_frame_latency_seconds",
+		"Average time spent processing traced SSNTP frames per component", metrics.Gauge)
+
+	summaries, err := c.ds.GetBatchFrameSummary()
+	if err != nil {
+		glog.Warningf("Unable to get frame summary for metrics: %v", err)
+		return []metrics.Family{*frames, *latency}
+	}
+
+	components := []string{"total", "controller", "scheduler", "launcher"}
+	elapsed := make([]float64, len(components))
+	count := 0
+
+	for _, s := range summaries {
+		stats, err := c.ds.GetBatchFrameStatistics(s.BatchID)
+		if err != nil {
+			glog.Warningf("Unable to get frame statistics for %s: %v", s.BatchID, err)
+			continue
+		}
+
+		for _, stat := range stats {
+			n := float64(stat.NumInstances)
+			elapsed[0] += stat.AverageElapsed * n
+			elapsed[1] += stat.AverageControllerElapsed * n
+			elapsed[2] += stat.AverageSchedulerElapsed * n
+			elapsed[3] += stat.AverageLauncherElapsed * n
+			count += stat.NumInstances
+		}
+	}
+
+	frames.Add(float64(count), nil)
+	if count > 0 {
+		for i, component := range components {
+			latency.Add(elapsed[i]/float64(count),
+				metrics.Labels{"component": component})
+		}
+	}
+
+	return []metrics.Family{*frames, *latency}
+}
+
+func (c *controller) collectMetrics() []metrics.Family {
+	families := c.nodeMetrics()
+	families = append(families, c.quotaMetrics()...)
+	families = append(families, c.frameMetrics()...)
+
+	return append(families, commandsSent.Family(), commandFailures.Family(),
+		instancesDeleted.Family())
+}
+
+func (c *controller) serveMetrics(addr string) {
+	glog.Infof("Serving metrics on http://%s/metrics", addr)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(c.collectMetrics))
+
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		glog.Errorf("Unable to serve metrics: %v", err)
+	}
+}
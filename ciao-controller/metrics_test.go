@@ -0,0 +1,54 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ciao-project/ciao/metrics"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
+)
+
+func TestCollectMetrics(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	var b bytes.Buffer
+	err := metrics.Write(&b, ctl.collectMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	expected := []string{
+		`ciao_node_memory_total_megabytes{hostname="` + client.Name + `",node="` + client.UUID + `"}`,
+		`ciao_instance_memory_usage_megabytes{instance="` + instances[0].ID + `"`,
+		`ciao_tenant_quota_usage{quota="tenant-instances-quota",tenant="` + instances[0].TenantID + `"}`,
+		`ciao_controller_commands_sent_total{command="` + ssntp.START.String() + `"}`,
+		"\nciao_ssntp_traced_frames ",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("Missing %s in metrics:\n%s", e, out)
+		}
+	}
+}
@@ -14,18 +14,168 @@
 
 package main
 
-import "github.com/golang/glog"
+import (
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+)
+
+// evacuateMigrationTimeout is the maximum amount of time EvacuateNode waits
+// for the live migrations it initiates to complete before it asks the
+// launcher to evacuate.  Any instances still on the node at that point
+// will be stopped by the launcher.
+const evacuateMigrationTimeout = 15 * time.Minute
+
+// evacuateStopTimeout is the maximum amount of time RebuildNode waits for
+// the instances of an evacuated node to be stopped.
+const evacuateStopTimeout = 5 * time.Minute
+
+const evacuatePollPeriod = 5 * time.Second
+
+// migrateNodeInstances live migrates all the running VM instances off nodeID.
+// It returns the IDs of the instances whose migrations were successfully
+// initiated.
+func (c *controller) migrateNodeInstances(nodeID string) []string {
+	instances, err := c.ds.GetAllInstancesByNode(nodeID)
+	if err != nil {
+		glog.Warningf("Unable to retrieve instances for node %s: %v", nodeID, err)
+		return nil
+	}
+
+	var migrating []string
+	for _, i := range instances {
+		if i.CNCI || i.State != payloads.Running {
+			continue
+		}
+
+		if err := c.migrateInstance(i.ID); err != nil {
+			glog.Warningf("Unable to migrate instance %s: %v", i.ID, err)
+			continue
+		}
+		migrating = append(migrating, i.ID)
+	}
+
+	return migrating
+}
+
+// waitForInstances blocks until pending returns false for all the instances
+// identified by ids or until timeout expires, in which case it returns
+// false.
+func (c *controller) waitForInstances(ids []string, timeout time.Duration,
+	pending func(i *types.Instance) bool) bool {
+	timeoutCh := time.After(timeout)
+	for len(ids) > 0 {
+		var waiting []string
+		for _, id := range ids {
+			i, err := c.ds.GetInstance(id)
+			if err == nil && pending(i) {
+				waiting = append(waiting, id)
+			}
+		}
+		ids = waiting
+		if len(ids) == 0 {
+			break
+		}
+
+		select {
+		case <-timeoutCh:
+			glog.Warningf("Timed out waiting for %d instances", len(ids))
+			return false
+		case <-time.After(evacuatePollPeriod):
+		}
+	}
+
+	return true
+}
+
+// evacuateNode live migrates what it can off nodeID before placing the node
+// into maintenance mode.
+func (c *controller) evacuateNode(nodeID string) {
+	c.waitForInstances(c.migrateNodeInstances(nodeID), evacuateMigrationTimeout,
+		func(i *types.Instance) bool {
+			return i.State == payloads.Migrating
+		})
+	if err := c.client.EvacuateNode(nodeID); err != nil {
+		glog.Warningf("Error evacuating node")
+	}
+}
 
 func (c *controller) EvacuateNode(nodeID string) error {
 	// should I bother to see if nodeID is valid?
+	go c.evacuateNode(nodeID)
+	return nil
+}
+
+// RebuildNode restarts the instances assigned to nodeID on other nodes.  If
+// the node is still connected it is evacuated first.  Instances that can be
+// live migrated are migrated and the remaining ones are rebuilt once the
+// launcher has stopped them.  Otherwise the node may just be partitioned from
+// the cluster with its instances still running, so force must be set to
+// confirm that the node is down before its instances are rebuilt straight
+// away.
+func (c *controller) RebuildNode(nodeID string, force bool) error {
+	_, err := c.ds.GetNode(nodeID)
+	connected := err == nil
+	if !connected && !force {
+		return types.ErrNodeNotFenced
+	}
+
+	instances, err := c.ds.GetAllInstances()
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, i := range instances {
+		if i.NodeID == nodeID && !i.CNCI {
+			ids = append(ids, i.ID)
+		}
+	}
+
 	go func() {
-		if err := c.client.EvacuateNode(nodeID); err != nil {
-			glog.Warningf("Error evacuating node")
+		if connected {
+			c.evacuateNode(nodeID)
+			c.waitForInstances(ids, evacuateStopTimeout,
+				func(i *types.Instance) bool {
+					return i.NodeID == nodeID
+				})
+		}
+
+		for _, id := range ids {
+			i, err := c.ds.GetInstance(id)
+			if err != nil {
+				continue
+			}
+
+			// Skip the instances that have been migrated.
+
+			if i.NodeID != nodeID && i.State != payloads.Exited {
+				continue
+			}
+
+			if err := c.rebuildInstance(id, force); err != nil {
+				glog.Warningf("Unable to rebuild instance %s: %v", id, err)
+			}
 		}
 	}()
+
 	return nil
 }
 
+// deleteRebuiltInstances asks a node that has just connected to delete its
+// copies of the instances that have been rebuilt on other nodes while it was
+// disconnected.
+func (c *controller) deleteRebuiltInstances(nodeID string) {
+	for _, id := range c.ds.RebuiltInstances(nodeID) {
+		glog.Infof("Deleting copy of rebuilt instance %s from node %s", id, nodeID)
+		if err := c.client.DeleteInstance(id, nodeID); err != nil {
+			glog.Warningf("Unable to delete copy of rebuilt instance %s: %v", id, err)
+		}
+	}
+}
+
 func (c *controller) RestoreNode(nodeID string) error {
 	go func() {
 		if err := c.client.RestoreNode(nodeID); err != nil {
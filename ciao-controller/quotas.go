@@ -64,9 +64,20 @@ func populateQuotasFromDatastore(qs *quotas.Quotas, ds *datastore.Datastore) err
 			size += bd.Size
 			count++
 		}
+
+		// Populate volume snapshot usage
+		snapshots, err := ds.GetVolumeSnapshots(t.ID)
+		if err != nil {
+			return errors.Wrapf(err, "error getting volume snapshots for tenant %s", t.ID)
+		}
+		for _, s := range snapshots {
+			size += s.Size
+		}
+
 		// With initial population we disregard the result of consumption
 		<-qs.Consume(t.ID,
 			payloads.RequestedResource{Type: payloads.Volume, Value: count},
+			payloads.RequestedResource{Type: payloads.Snapshot, Value: len(snapshots)},
 			payloads.RequestedResource{Type: payloads.SharedDiskGiB, Value: size})
 
 		instances, err := ds.GetAllInstancesFromTenant(t.ID)
@@ -126,6 +126,18 @@ func (c *controller) createCiaoServer() (*http.Server, error) {
 	return server, nil
 }
 
+// createEnrollServer creates the HTTPS server joining nodes submit their
+// CSR to. It does not ask for client certificates as joining nodes do not
+// have one yet.
+func (c *controller) createEnrollServer() *http.Server {
+	config := api.Config{URL: c.apiURL, CiaoService: c}
+
+	return &http.Server{
+		Handler: api.EnrollRoutes(config, nil),
+		Addr:    fmt.Sprintf(":%d", enrollAPIPort),
+	}
+}
+
 func (c *controller) ShutdownHTTPServers() {
 	glog.Warning("Shutting down HTTP servers")
 	var wg sync.WaitGroup
@@ -233,6 +233,25 @@ func (c *controller) DeleteTenant(tenantID string) error {
 		}
 	}
 
+	// remove any volume snapshots for this tenant, the storage media
+	// will not remove volumes that still have snapshots.
+	snapshots, err := c.ds.GetVolumeSnapshots(tenantID)
+	if err != nil {
+		return errors.Wrap(err, "Unable to remove tenant")
+	}
+
+	for _, s := range snapshots {
+		err := c.DeleteBlockDeviceSnapshot(s.VolumeID, s.ID)
+		if err != nil {
+			return errors.Wrap(err, "Unable to remove tenant")
+		}
+
+		err = c.ds.DeleteVolumeSnapshot(s.ID)
+		if err != nil {
+			return errors.Wrap(err, "Unable to remove tenant")
+		}
+	}
+
 	// remove any storage for this tenant.
 	bds, err := c.ds.GetBlockDevices(tenantID)
 	if err != nil {
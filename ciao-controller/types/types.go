@@ -107,6 +107,10 @@ type WorkloadRequest struct {
 	Volumes    []storage.BlockDevice
 	Name       string
 	Subnet     string
+
+	// Constraints are placement requested resources applying to
+	// these instances on top of the workload defaults.
+	Constraints []payloads.RequestedResource
 }
 
 // Instance contains information about an instance of a workload.
@@ -290,6 +294,18 @@ type Volume struct {
 	Internal    bool       `json:"internal"`    // whether this storage should be shown to the user
 }
 
+// VolumeSnapshot represents a point in time copy of a volume. New volumes
+// can be created from a snapshot.
+type VolumeSnapshot struct {
+	ID          string    `json:"id"`          // the snapshot UUID
+	VolumeID    string    `json:"volume_id"`   // the volume this snapshot was taken of
+	TenantID    string    `json:"tenant_id"`   // the tenant who owns this snapshot
+	CreateTime  time.Time `json:"created"`     // when we took the snapshot
+	Size        int       `json:"size"`        // size of the volume in GiB
+	Name        string    `json:"name"`        // a human readable name for this snapshot
+	Description string    `json:"description"` // some text to describe this snapshot
+}
+
 // StorageAttachment represents a link between a block device and
 // an instance.
 type StorageAttachment struct {
@@ -606,6 +622,31 @@ var (
 
 	// ErrWorkloadInUse is returned by DeleteWorkload when an instance of a workload is still active.
 	ErrWorkloadInUse = errors.New("Workload definition still in use")
+
+	// ErrEnrollmentDisabled is returned when the controller was started
+	// without an anchor certificate to sign enrollment requests with.
+	ErrEnrollmentDisabled = errors.New("Node enrollment is disabled")
+
+	// ErrInvalidEnrollmentToken is returned when a join token is unknown,
+	// expired or was already used for another CSR.
+	ErrInvalidEnrollmentToken = errors.New("Invalid enrollment token")
+
+	// ErrEnrollmentNotFound is returned when an enrollment request ID is unknown.
+	ErrEnrollmentNotFound = errors.New("Enrollment request not found")
+
+	// ErrEnrollmentRejected is returned when an admin rejected an enrollment request.
+	ErrEnrollmentRejected = errors.New("Enrollment request rejected")
+
+	// ErrSnapshotNotFound is returned when a volume snapshot ID is unknown.
+	ErrSnapshotNotFound = errors.New("Volume snapshot not found")
+
+	// ErrVolumeHasSnapshots is returned when a volume cannot be deleted
+	// because snapshots of it still exist.
+	ErrVolumeHasSnapshots = errors.New("Delete the volume snapshots prior to deletion")
+
+	// ErrNodeNotFenced is returned when asked to rebuild the instances of
+	// a disconnected node without being told that the node is down.
+	ErrNodeNotFenced = errors.New("Node is disconnected but its instances may still be running, force the rebuild once the node is known to be down")
 )
 
 // Link provides a url and relationship for a resource.
@@ -825,13 +866,105 @@ const (
 	Internal Visibility = "internal"
 )
 
+// DiskFormat is the format of the data uploaded for an image.  Images are
+// always stored as raw data.
+type DiskFormat string
+
+const (
+	// Raw indicates that the image data is a raw disk image.
+	Raw DiskFormat = "raw"
+
+	// QCOW2 indicates that the image data is a QEMU copy on write v2 image.
+	QCOW2 DiskFormat = "qcow2"
+
+	// VMDK indicates that the image data is a VMware disk image.
+	VMDK DiskFormat = "vmdk"
+
+	// VHDX indicates that the image data is a Hyper-V disk image.
+	VHDX DiskFormat = "vhdx"
+)
+
 // Image contains the information that ciao will store about the image
 type Image struct {
-	ID         string     `json:"id"`
-	State      ImageState `json:"state"`
-	TenantID   string     `json:"tenant_id"`
-	Name       string     `json:"name"`
-	CreateTime time.Time  `json:"create_time"`
-	Size       uint64     `json:"size"`
-	Visibility Visibility `json:"visibility"`
+	ID          string     `json:"id"`
+	State       ImageState `json:"state"`
+	TenantID    string     `json:"tenant_id"`
+	Name        string     `json:"name"`
+	CreateTime  time.Time  `json:"create_time"`
+	Size        uint64     `json:"size"`
+	Visibility  Visibility `json:"visibility"`
+	DiskFormat  DiskFormat `json:"disk_format,omitempty"`
+	Checksum    string     `json:"checksum,omitempty"`
+	VirtualSize uint64     `json:"virtual_size,omitempty"`
+}
+
+// EnrollmentStatus is the state of a node enrollment request.
+type EnrollmentStatus string
+
+const (
+	// EnrollmentPending means the request waits for an admin decision.
+	EnrollmentPending EnrollmentStatus = "pending"
+
+	// EnrollmentApproved means a certificate was issued for the request.
+	EnrollmentApproved EnrollmentStatus = "approved"
+
+	// EnrollmentRejected means an admin refused to issue a certificate.
+	EnrollmentRejected EnrollmentStatus = "rejected"
+)
+
+// EnrollmentTokenRequest is the request to create a one-time join token.
+// Role is a comma separated list of SSNTP roles, e.g. "agent,netagent".
+// When AutoApprove is set, CSRs submitted with the token are signed without
+// waiting for an admin to approve them.
+type EnrollmentTokenRequest struct {
+	Role        string `json:"role"`
+	AutoApprove bool   `json:"auto_approve"`
+}
+
+// EnrollmentToken is a one-time token a node uses to join the cluster.
+type EnrollmentToken struct {
+	Token       string    `json:"token"`
+	Role        string    `json:"role"`
+	AutoApprove bool      `json:"auto_approve"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// EnrollmentRequest describes a CSR submitted by a joining node.
+type EnrollmentRequest struct {
+	ID          string           `json:"id"`
+	Role        string           `json:"role"`
+	Hosts       []string         `json:"hosts"`
+	IPs         []string         `json:"ips"`
+	Fingerprint string           `json:"fingerprint"`
+	Status      EnrollmentStatus `json:"status"`
+	Created     time.Time        `json:"created"`
+}
+
+// EnrollmentRequestsResponse is the response to a list enrollment requests
+// query.
+type EnrollmentRequestsResponse struct {
+	Requests []EnrollmentRequest `json:"requests"`
+}
+
+// EnrollmentRequestStatus is used to approve or reject an enrollment request.
+type EnrollmentRequestStatus struct {
+	Status EnrollmentStatus `json:"status"`
+}
+
+// EnrollRequest is sent by a joining node. CSR is a PEM encoded certificate
+// signing request. Sending the same token and CSR again returns the current
+// state of the enrollment.
+type EnrollRequest struct {
+	Token string `json:"token"`
+	CSR   string `json:"csr"`
+}
+
+// EnrollResponse is returned to a joining node. Cert and CACert, the PEM
+// encoded node certificate and SSNTP CA certificate, are only set once the
+// request is approved.
+type EnrollResponse struct {
+	ID     string           `json:"id"`
+	Status EnrollmentStatus `json:"status"`
+	Cert   string           `json:"cert,omitempty"`
+	CACert string           `json:"ca_cert,omitempty"`
 }
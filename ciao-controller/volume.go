@@ -15,13 +15,13 @@
 package main
 
 import (
-	"errors"
 	"time"
 
 	"github.com/ciao-project/ciao/ciao-controller/api"
 	"github.com/ciao-project/ciao/ciao-controller/types"
 	"github.com/ciao-project/ciao/ciao-storage"
 	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/uuid"
 	"github.com/golang/glog"
 )
 
@@ -39,6 +39,28 @@ func (c *controller) CreateVolume(tenant string, req api.RequestedVolume) (types
 		// create bootable volume
 		bd, err = c.CreateBlockDeviceFromSnapshot(req.ImageRef, "ciao-image")
 		bd.Bootable = true
+	} else if req.SnapshotID != "" {
+		// create volume from a snapshot of another volume
+		var snapshot types.VolumeSnapshot
+		snapshot, err = c.ds.GetVolumeSnapshot(req.SnapshotID)
+		if err != nil {
+			return types.Volume{}, err
+		}
+
+		if snapshot.TenantID != tenant {
+			return types.Volume{}, types.ErrSnapshotNotFound
+		}
+
+		bd, err = c.CreateBlockDeviceFromSnapshot(snapshot.VolumeID, snapshot.ID)
+
+		// The new volume must not depend on the snapshot, which
+		// could then never be deleted.
+		if err == nil {
+			err = c.FlattenBlockDevice(bd.ID)
+			if err != nil {
+				_ = c.DeleteBlockDevice(bd.ID)
+			}
+		}
 	} else if req.SourceVolID != "" {
 		// copy existing volume
 		bd, err = c.CopyBlockDevice(req.SourceVolID)
@@ -113,6 +135,16 @@ func (c *controller) DeleteVolume(tenant string, volume string) error {
 		return api.ErrVolumeNotAvailable
 	}
 
+	// the storage media will not remove a volume that still has snapshots.
+	snapshots, err := c.ds.GetSnapshotsOfVolume(volume)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) > 0 {
+		return types.ErrVolumeHasSnapshots
+	}
+
 	// remove the block data from our datastore.
 	err = c.ds.DeleteBlockDevice(volume)
 	if err != nil {
@@ -199,23 +231,32 @@ func (c *controller) AttachVolume(tenant string, volume string, instance string,
 	return nil
 }
 
+// DetachVolume detaches a volume from all the instances it is attached to,
+// or only from the given attachment if one is specified.  The volume is
+// hot-unplugged from running instances and becomes available again once
+// the launchers report that it is no longer attached.
 func (c *controller) DetachVolume(tenant string, volume string, attachment string) error {
 	err := c.confirmTenant(tenant)
 	if err != nil {
 		return err
 	}
 
-	// we don't support detaching by attachment ID yet.
-	if attachment != "" {
-		return errors.New("Detaching by attachment ID not implemented")
-	}
-
 	// get attachment info
 	attachments, err := c.ds.GetVolumeAttachments(volume)
 	if err != nil {
 		return err
 	}
 
+	if attachment != "" {
+		var matched []types.StorageAttachment
+		for _, a := range attachments {
+			if a.ID == attachment {
+				matched = append(matched, a)
+			}
+		}
+		attachments = matched
+	}
+
 	if len(attachments) == 0 {
 		return api.ErrVolumeNotAttached
 	}
@@ -245,16 +286,144 @@ func (c *controller) DetachVolume(tenant string, volume string, attachment strin
 		}
 	}
 
-	var retval error
+	var offline []types.StorageAttachment
+	instances := make([]*types.Instance, 0, len(attachments))
+	for _, a := range attachments {
+		i, err := c.ds.GetTenantInstance(tenant, a.InstanceID)
+		if err != nil {
+			return api.ErrInstanceNotFound
+		}
+
+		i.StateLock.RLock()
+		state := i.State
+		i.StateLock.RUnlock()
+
+		// exited instances are not running anywhere so there is
+		// no launcher to tell about the detach.
+		if state == payloads.Exited && i.NodeID == "" {
+			offline = append(offline, a)
+			continue
+		}
+
+		if i.NodeID == "" {
+			return types.ErrInstanceNotAssigned
+		}
+
+		instances = append(instances, i)
+	}
+
+	for _, a := range offline {
+		err = c.ds.DeleteStorageAttachment(a.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(instances) == 0 {
+		remaining, err := c.ds.GetVolumeAttachments(volume)
+		if err != nil {
+			return err
+		}
+
+		if len(remaining) == 0 {
+			info.State = types.Available
+			return c.ds.UpdateBlockDevice(info)
+		}
+
+		return nil
+	}
+
+	// update volume state to detaching
+	info.State = types.Detaching
+
+	err = c.ds.UpdateBlockDevice(info)
+	if err != nil {
+		return err
+	}
+
+	// send command to detach volume from each instance.
+	for _, i := range instances {
+		err = c.client.detachVolume(volume, i.ID, i.NodeID)
+		if err != nil {
+			info.State = types.InUse
+			dsErr := c.ds.UpdateBlockDevice(info)
+			if dsErr != nil {
+				glog.Error(dsErr)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExtendVolume grows a volume to size GiB.  Running QEMU instances to which
+// the volume is attached are told about the new size so that their guests
+// can use it straight away.
+func (c *controller) ExtendVolume(tenant string, volume string, size int) error {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return err
+	}
+
+	// get the block device information
+	info, err := c.ds.GetBlockDevice(volume)
+	if err != nil {
+		return err
+	}
+
+	// check that the block device is owned by the tenant.
+	if info.TenantID != tenant || info.Internal {
+		return api.ErrVolumeOwner
+	}
+
+	// volumes can only grow.
+	if size <= info.Size {
+		return types.ErrBadRequest
+	}
+
+	// check that the block device is not being attached or detached.
+	if info.State != types.Available && info.State != types.InUse {
+		return api.ErrVolumeNotAvailable
+	}
+
+	res := <-c.qs.Consume(tenant,
+		payloads.RequestedResource{Type: payloads.SharedDiskGiB, Value: size - info.Size})
+
+	if !res.Allowed() {
+		c.qs.Release(tenant, res.Resources()...)
+		return types.ErrQuota
+	}
+
+	newSize, err := c.Resize(volume, size)
+	if err != nil {
+		c.qs.Release(tenant, res.Resources()...)
+		return err
+	}
+
+	oldSize := info.Size
+	info.Size = newSize
+
+	err = c.ds.UpdateBlockDevice(info)
+	if err != nil {
+		// the storage media cannot shrink the volume back.
+		glog.Errorf("Unable to record new size of volume %s: %v", volume, err)
+		c.qs.Release(tenant, res.Resources()...)
+		return err
+	}
+
+	glog.Infof("Volume %s extended from %d to %d GiB", volume, oldSize, newSize)
+
+	attachments, err := c.ds.GetVolumeAttachments(volume)
+	if err != nil {
+		return err
+	}
 
-	// detach everything for this volume
 	for _, a := range attachments {
-		// get instance info
 		i, err := c.ds.GetTenantInstance(tenant, a.InstanceID)
 		if err != nil {
-			glog.Error(api.ErrInstanceNotFound)
-			// keep going
-			retval = err
+			glog.Warningf("Unable to find instance %s of volume %s: %v",
+				a.InstanceID, volume, err)
 			continue
 		}
 
@@ -262,21 +431,25 @@ func (c *controller) DetachVolume(tenant string, volume string, attachment strin
 		state := i.State
 		i.StateLock.RUnlock()
 
-		if state != payloads.Exited {
-			retval = errors.New("Can only detach from exited instances")
+		// instances that are not running will see the new size when
+		// they are next started.
+		if state != payloads.Running {
 			continue
 		}
 
-		// update volume state to detaching
-		info.State = types.Available
+		w, err := c.ds.GetWorkload(i.TenantID, i.WorkloadID)
+		if err != nil || w.VMType != payloads.QEMU {
+			continue
+		}
 
-		err = c.ds.UpdateBlockDevice(info)
+		err = c.client.extendVolume(volume, i.ID, i.NodeID, newSize)
 		if err != nil {
-			return err
+			glog.Warningf("Unable to notify instance %s of new size of volume %s: %v",
+				i.ID, volume, err)
 		}
 	}
 
-	return retval
+	return nil
 }
 
 func (c *controller) ListVolumesDetail(tenant string) ([]types.Volume, error) {
@@ -320,3 +493,108 @@ func (c *controller) ShowVolumeDetails(tenant string, volume string) (types.Volu
 
 	return vol, nil
 }
+
+// CreateVolumeSnapshot takes a point in time copy of a volume. The snapshot
+// is accounted against the tenant's snapshot and storage quotas.
+func (c *controller) CreateVolumeSnapshot(tenant string, req api.RequestedSnapshot) (types.VolumeSnapshot, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.VolumeSnapshot{}, err
+	}
+
+	info, err := c.ds.GetBlockDevice(req.VolumeID)
+	if err != nil {
+		return types.VolumeSnapshot{}, err
+	}
+
+	// check that the block device is owned by the tenant.
+	if info.TenantID != tenant || info.Internal {
+		return types.VolumeSnapshot{}, api.ErrVolumeOwner
+	}
+
+	res := <-c.qs.Consume(tenant,
+		payloads.RequestedResource{Type: payloads.Snapshot, Value: 1},
+		payloads.RequestedResource{Type: payloads.SharedDiskGiB, Value: info.Size})
+
+	if !res.Allowed() {
+		c.qs.Release(tenant, res.Resources()...)
+		return types.VolumeSnapshot{}, types.ErrQuota
+	}
+
+	snapshot := types.VolumeSnapshot{
+		ID:          uuid.Generate().String(),
+		VolumeID:    info.ID,
+		TenantID:    tenant,
+		CreateTime:  time.Now(),
+		Size:        info.Size,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	err = c.CreateBlockDeviceSnapshot(info.ID, snapshot.ID)
+	if err != nil {
+		c.qs.Release(tenant, res.Resources()...)
+		return types.VolumeSnapshot{}, err
+	}
+
+	err = c.ds.AddVolumeSnapshot(snapshot)
+	if err != nil {
+		_ = c.DeleteBlockDeviceSnapshot(info.ID, snapshot.ID)
+		c.qs.Release(tenant, res.Resources()...)
+		return types.VolumeSnapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+func (c *controller) ListVolumeSnapshots(tenant string) ([]types.VolumeSnapshot, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return []types.VolumeSnapshot{}, err
+	}
+
+	return c.ds.GetVolumeSnapshots(tenant)
+}
+
+func (c *controller) ShowVolumeSnapshot(tenant string, snapshot string) (types.VolumeSnapshot, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.VolumeSnapshot{}, err
+	}
+
+	s, err := c.ds.GetVolumeSnapshot(snapshot)
+	if err != nil {
+		return types.VolumeSnapshot{}, err
+	}
+
+	if s.TenantID != tenant {
+		return types.VolumeSnapshot{}, types.ErrSnapshotNotFound
+	}
+
+	return s, nil
+}
+
+func (c *controller) DeleteVolumeSnapshot(tenant string, snapshot string) error {
+	s, err := c.ShowVolumeSnapshot(tenant, snapshot)
+	if err != nil {
+		return err
+	}
+
+	// tell the underlying storage media to remove the snapshot first,
+	// it will refuse if volumes created from it still depend on it.
+	err = c.DeleteBlockDeviceSnapshot(s.VolumeID, s.ID)
+	if err != nil {
+		return err
+	}
+
+	err = c.ds.DeleteVolumeSnapshot(s.ID)
+	if err != nil {
+		return err
+	}
+
+	c.qs.Release(s.TenantID,
+		payloads.RequestedResource{Type: payloads.Snapshot, Value: 1},
+		payloads.RequestedResource{Type: payloads.SharedDiskGiB, Value: s.Size})
+
+	return nil
+}
@@ -15,6 +15,8 @@
 package main
 
 import (
+	"strings"
+
 	"github.com/golang/glog"
 
 	"github.com/ciao-project/ciao/ciao-controller/types"
@@ -115,6 +117,47 @@ func (c *controller) validateWorkloadStorage(req types.Workload) error {
 	return nil
 }
 
+// validateWorkloadPlacement checks the node selector, affinity and
+// anti-affinity resources of a workload. Affinity rules must name an
+// existing workload, or be left blank to refer to the workload itself.
+func (c *controller) validateWorkloadPlacement(tenantID string, resources []payloads.RequestedResource) error {
+	for _, r := range resources {
+		switch r.Type {
+		case payloads.NodeSelector:
+			label := strings.SplitN(r.ValueString, "=", 2)
+			if len(label) != 2 || label[0] == "" {
+				return types.ErrBadRequest
+			}
+		case payloads.Affinity, payloads.AntiAffinity:
+			if r.ValueString == "" {
+				continue
+			}
+
+			_, err := c.ds.GetWorkload(tenantID, r.ValueString)
+			if err != nil {
+				return types.ErrBadRequest
+			}
+		}
+	}
+
+	return nil
+}
+
+// setPlacementWorkload makes the blank affinity and anti-affinity rules
+// refer to the workload with the given ID.
+func setPlacementWorkload(resources []payloads.RequestedResource, workloadID string) {
+	for i := range resources {
+		if resources[i].Type != payloads.Affinity &&
+			resources[i].Type != payloads.AntiAffinity {
+			continue
+		}
+
+		if resources[i].ValueString == "" {
+			resources[i].ValueString = workloadID
+		}
+	}
+}
+
 // this is probably an insufficient amount of checking.
 func (c *controller) validateWorkloadRequest(req types.Workload) error {
 	// ID must be blank.
@@ -157,6 +200,12 @@ func (c *controller) validateWorkloadRequest(req types.Workload) error {
 		}
 	}
 
+	err := c.validateWorkloadPlacement(req.TenantID, req.Defaults)
+	if err != nil {
+		glog.V(2).Info("Invalid workload request: invalid placement constraints")
+		return err
+	}
+
 	return nil
 }
 
@@ -172,6 +221,7 @@ func (c *controller) CreateWorkload(req types.Workload) (types.Workload, error)
 	}
 
 	req.ID = uuid.Generate().String()
+	setPlacementWorkload(req.Defaults, req.ID)
 
 	err = c.ds.AddWorkload(req)
 	return req, err
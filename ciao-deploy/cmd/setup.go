@@ -113,6 +113,8 @@ func init() {
 
 	// For configuration file generation
 	setupCmd.Flags().StringVar(&clusterConf.CephID, "ceph-id", "admin", "The ceph id for the storage cluster")
+	setupCmd.Flags().StringVar(&clusterConf.StorageDriver, "storage-driver", "ceph", "Block driver for volumes, ceph or local")
+	setupCmd.Flags().StringVar(&clusterConf.LocalStoragePath, "local-storage-path", "", "Directory in which the local block driver stores volumes")
 	setupCmd.Flags().StringVar(&clusterConf.HTTPSCaCertPath, "https-ca-cert", "", "Path to CA certificate for HTTP service")
 	setupCmd.Flags().StringVar(&clusterConf.HTTPSCertPath, "https-cert", "", "Path to certificate for HTTPS service")
 	setupCmd.Flags().StringVar(&clusterConf.AdminSSHKeyPath, "admin-ssh-key", "", "Path to SSH public key for accessing CNCI")
@@ -35,6 +35,8 @@ import (
 // ClusterConfiguration provides cluster setup information
 type ClusterConfiguration struct {
 	CephID            string
+	StorageDriver     string
+	LocalStoragePath  string
 	HTTPSCaCertPath   string
 	HTTPSCertPath     string
 	AdminSSHKeyPath   string
@@ -68,6 +70,8 @@ func createConfigurationFile(ctx context.Context, clusterConf *ClusterConfigurat
 	config.Configure.Scheduler.ConfigStorageURI = ciaoConfigPath
 
 	config.Configure.Storage.CephID = clusterConf.CephID
+	config.Configure.Storage.Driver = payloads.StorageDriver(clusterConf.StorageDriver)
+	config.Configure.Storage.LocalPath = clusterConf.LocalStoragePath
 
 	// TODO: Generate certs if not supplied
 	config.Configure.Controller.HTTPSCACert = clusterConf.HTTPSCaCertPath
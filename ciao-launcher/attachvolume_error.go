@@ -28,6 +28,8 @@ type attachVolumeError struct {
 }
 
 func (ave *attachVolumeError) send(conn serverConn, instance, volume string) {
+	commandFailures.Inc(ssntp.AttachVolume.String(), string(ave.code))
+
 	if !conn.isConnected() {
 		return
 	}
@@ -88,11 +88,17 @@ func processAttachVolume(storageDriver storage.BlockDriver, monitorCh chan inter
 
 	err := cfg.save(instanceDir)
 	if err != nil {
-		// TODO: should we detach and unmap here?
 		cfg.removeVolume(volumeUUID)
 		attachErr := &attachVolumeError{err, payloads.AttachVolumeStateFailure}
 		glog.Errorf("Unable to persist instance %s state [%s]: %v",
 			instance, string(attachErr.code), err)
+		if monitorCh != nil {
+			if unplugErr := unplugVolume(monitorCh, volumeUUID); unplugErr != nil {
+				glog.Warningf("Unable to detach %s : %v", volumeUUID, unplugErr)
+			} else if unmapErr := storageDriver.UnmapVolumeFromNode(volumeUUID); unmapErr != nil {
+				glog.Warningf("Unable to unmap %s : %v", volumeUUID, unmapErr)
+			}
+		}
 		return attachErr
 	}
 
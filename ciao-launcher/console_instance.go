@@ -0,0 +1,336 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/golang/glog"
+)
+
+// The first serial port of every VM is connected to a unix socket in the
+// instance directory.  QEMU copies everything the guest writes to it to
+// the console log, whether or not a client is connected to the socket.
+
+const (
+	consoleSocket  = "console.sock"
+	consoleLogFile = "console.log"
+
+	// The console log is rotated once it grows beyond consoleLogMax
+	// bytes.  Only one rotated log, console.log.1, is kept.
+	consoleLogMax = 1 << 20
+
+	// consoleTailMax bounds the size of the log sent in a ConsoleLog
+	// event.
+	consoleTailMax = 64 << 10
+
+	// consoleConnectTimeout is the time given to controller to connect
+	// to an interactive console and to send its token.
+	consoleConnectTimeout = 30 * time.Second
+)
+
+func consoleParams(instanceDir string) []string {
+	chardev := fmt.Sprintf("socket,id=console0,path=%s,server,nowait,logfile=%s,logappend=on",
+		path.Join(instanceDir, consoleSocket), path.Join(instanceDir, consoleLogFile))
+	return []string{"-chardev", chardev, "-device", "isa-serial,chardev=console0"}
+}
+
+// rotateConsoleLog is called periodically by the instance go routine.  QEMU
+// opens the log in append mode so it carries on writing at the end of the
+// file once it has been truncated.  Anything written between the copy and
+// the truncation is lost, which is acceptable for a log of this nature.
+
+func rotateConsoleLog(instanceDir string) error {
+	logPath := path.Join(instanceDir, consoleLogFile)
+	fi, err := os.Stat(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if fi.Size() <= consoleLogMax {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(logPath+".1", data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Truncate(logPath, 0)
+}
+
+func readFileTail(filePath string, max int64) ([]byte, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.Size() > max {
+		_, err = f.Seek(fi.Size()-max, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ioutil.ReadAll(io.LimitReader(f, max))
+}
+
+func tailLines(data []byte, lines int) []byte {
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+
+	for i := end - 1; i >= 0; i-- {
+		if data[i] == '\n' {
+			lines--
+			if lines == 0 {
+				return data[i+1:]
+			}
+		}
+	}
+
+	return data
+}
+
+// readConsoleLog returns at most consoleTailMax bytes from the end of the
+// console log, including the rotated log if needed, further limited to the
+// last lines lines if lines is not 0.
+
+func readConsoleLog(instanceDir string, lines int) (string, error) {
+	logPath := path.Join(instanceDir, consoleLogFile)
+
+	current, err := readFileTail(logPath, consoleTailMax)
+	if err != nil {
+		return "", err
+	}
+
+	var log []byte
+	if len(current) < consoleTailMax {
+		rotated, err := readFileTail(logPath+".1", int64(consoleTailMax-len(current)))
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		log = append(rotated, current...)
+	} else {
+		log = current
+	}
+
+	if lines > 0 {
+		log = tailLines(log, lines)
+	}
+
+	return string(log), nil
+}
+
+func newConsoleToken() (string, error) {
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// proxyConsole waits for a single connection on ln.  If the first line
+// received on that connection is the expected token, the rest of the
+// connection is proxied to the serial console of the instance until either
+// side closes it.
+
+func proxyConsole(instance, token string, ln net.Listener, console net.Conn) {
+	defer func() { _ = console.Close() }()
+
+	timer := time.AfterFunc(consoleConnectTimeout, func() { _ = ln.Close() })
+	conn, err := ln.Accept()
+	timer.Stop()
+	_ = ln.Close()
+	if err != nil {
+		glog.Warningf("No connection to the console of %s: %v", instance, err)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetReadDeadline(time.Now().Add(consoleConnectTimeout))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(line)),
+		[]byte(token)) != 1 {
+		glog.Warningf("Rejecting connection to the console of %s from %s",
+			instance, conn.RemoteAddr())
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	glog.Infof("Console of %s connected to %s", instance, conn.RemoteAddr())
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(console, r)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, console)
+		done <- struct{}{}
+	}()
+	<-done
+
+	glog.Infof("Console of %s disconnected", instance)
+}
+
+// startConsoleProxy connects to the serial console of the instance, so that
+// the console is known to be available before controller is told where to
+// connect, and starts listening for the connection from controller.  The
+// connection is TLS protected and only controllers presenting a certificate
+// signed by our CA are accepted.
+
+func startConsoleProxy(instance, instanceDir string) (string, string, error) {
+	token, err := newConsoleToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	console, err := net.Dial("unix", path.Join(instanceDir, consoleSocket))
+	if err != nil {
+		return "", "", err
+	}
+
+	config := &ssntp.Config{CAcert: serverCertPath, Cert: clientCertPath}
+	tlsConfig, err := ssntp.PeerTLSConfig(config, true, ssntp.Controller)
+	if err != nil {
+		_ = console.Close()
+		return "", "", err
+	}
+
+	ln, err := tls.Listen("tcp", net.JoinHostPort(getNodeIPAddress(), "0"), tlsConfig)
+	if err != nil {
+		_ = console.Close()
+		return "", "", err
+	}
+
+	go proxyConsole(instance, token, ln, console)
+
+	return ln.Addr().String(), token, nil
+}
+
+func (id *instanceData) sendConsoleLogEvent(frame *ssntp.Frame, log string) {
+	var event payloads.EventConsoleLog
+
+	event.ConsoleLog.InstanceUUID = id.instance
+	event.ConsoleLog.NodeUUID = id.ac.conn.UUID()
+	event.ConsoleLog.Log = log
+
+	payload, err := yaml.Marshal(&event)
+	if err != nil {
+		glog.Errorf("Unable to Marshall ConsoleLog %v", err)
+		return
+	}
+	_, err = id.ac.conn.SendEventReply(frame, ssntp.ConsoleLog, payload)
+	if err != nil {
+		glog.Errorf("Failed to send event command %v", err)
+		return
+	}
+}
+
+func (id *instanceData) sendConsoleReadyEvent(frame *ssntp.Frame, address, token string) {
+	var event payloads.EventConsoleReady
+
+	event.ConsoleReady.InstanceUUID = id.instance
+	event.ConsoleReady.NodeUUID = id.ac.conn.UUID()
+	event.ConsoleReady.Address = address
+	event.ConsoleReady.Token = token
+
+	payload, err := yaml.Marshal(&event)
+	if err != nil {
+		glog.Errorf("Unable to Marshall ConsoleReady %v", err)
+		return
+	}
+	_, err = id.ac.conn.SendEventReply(frame, ssntp.ConsoleReady, payload)
+	if err != nil {
+		glog.Errorf("Failed to send event command %v", err)
+		return
+	}
+}
+
+func (id *instanceData) processConsole(cmd *insConsoleCmd) *consoleError {
+	if id.cfg.Container {
+		err := fmt.Errorf("Containers do not have a serial console")
+		return &consoleError{err, payloads.ConsoleNotSupported}
+	}
+
+	if cmd.consoleType == payloads.ConsoleTypeLog {
+		log, err := readConsoleLog(id.instanceDir, cmd.lines)
+		if err != nil {
+			return &consoleError{err, payloads.ConsoleUnavailable}
+		}
+		id.sendConsoleLogEvent(cmd.frame, log)
+		return nil
+	}
+
+	if !id.running() {
+		err := fmt.Errorf("Instance %s is not running", id.instance)
+		return &consoleError{err, payloads.ConsoleInvalidState}
+	}
+
+	address, token, err := startConsoleProxy(id.instance, id.instanceDir)
+	if err != nil {
+		return &consoleError{err, payloads.ConsoleUnavailable}
+	}
+	glog.Infof("Console of %s waiting for connection on %s", id.instance, address)
+	id.sendConsoleReadyEvent(cmd.frame, address, token)
+	return nil
+}
+
+func (id *instanceData) consoleCommand(cmd *insConsoleCmd) {
+	consoleErr := id.processConsole(cmd)
+	if consoleErr != nil {
+		glog.Errorf("Unable to access console of instance %s [%s]: %v", id.instance,
+			string(consoleErr.code), consoleErr.err)
+		consoleErr.send(id.ac.conn, id.instance, cmd.frame)
+		return
+	}
+	commandSuccesses.Inc(ssntp.CONSOLE.String())
+}
@@ -0,0 +1,167 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestTailLines(t *testing.T) {
+	var tailTests = []struct {
+		data     string
+		lines    int
+		expected string
+	}{
+		{"", 1, ""},
+		{"one\ntwo\nthree\n", 1, "three\n"},
+		{"one\ntwo\nthree\n", 2, "two\nthree\n"},
+		{"one\ntwo\nthree", 2, "two\nthree"},
+		{"one\ntwo\nthree\n", 3, "one\ntwo\nthree\n"},
+		{"one\ntwo\nthree\n", 10, "one\ntwo\nthree\n"},
+	}
+
+	for _, test := range tailTests {
+		tail := string(tailLines([]byte(test.data), test.lines))
+		if tail != test.expected {
+			t.Errorf("Tail of %q, %d lines: expected %q, got %q", test.data,
+				test.lines, test.expected, tail)
+		}
+	}
+}
+
+// Checks that the console log is rotated once it grows too large and
+// that readConsoleLog returns the tail of the rotated and current logs.
+func TestConsoleLog(t *testing.T) {
+	instanceDir, err := ioutil.TempDir("", "console-log")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(instanceDir) }()
+
+	_, err = readConsoleLog(instanceDir, 0)
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected missing console log, got %v", err)
+	}
+
+	if err := rotateConsoleLog(instanceDir); err != nil {
+		t.Fatalf("Unable to rotate missing console log: %v", err)
+	}
+
+	logPath := path.Join(instanceDir, consoleLogFile)
+	var buf bytes.Buffer
+	for i := 0; buf.Len() <= consoleLogMax; i++ {
+		fmt.Fprintf(&buf, "line %d\n", i)
+	}
+	if err := ioutil.WriteFile(logPath, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("Unable to write console log: %v", err)
+	}
+
+	if err := rotateConsoleLog(instanceDir); err != nil {
+		t.Fatalf("Unable to rotate console log: %v", err)
+	}
+
+	if fi, err := os.Stat(logPath); err != nil || fi.Size() != 0 {
+		t.Fatalf("Console log not truncated")
+	}
+
+	rotated, err := ioutil.ReadFile(logPath + ".1")
+	if err != nil || !bytes.Equal(rotated, buf.Bytes()) {
+		t.Fatalf("Console log not rotated")
+	}
+
+	if err := ioutil.WriteFile(logPath, []byte("login:\n"), 0600); err != nil {
+		t.Fatalf("Unable to write console log: %v", err)
+	}
+
+	log, err := readConsoleLog(instanceDir, 0)
+	if err != nil {
+		t.Fatalf("Unable to read console log: %v", err)
+	}
+	if len(log) != consoleTailMax {
+		t.Errorf("Expected %d bytes of console log, got %d", consoleTailMax, len(log))
+	}
+
+	log, err = readConsoleLog(instanceDir, 2)
+	if err != nil {
+		t.Fatalf("Unable to read console log: %v", err)
+	}
+	lines := bytes.Split(buf.Bytes(), []byte("\n"))
+	expected := string(lines[len(lines)-2]) + "\nlogin:\n"
+	if log != expected {
+		t.Errorf("Expected %q, got %q", expected, log)
+	}
+}
+
+// Checks that proxyConsole rejects connections presenting the wrong token
+// and proxies the console for connections presenting the right one.
+func TestProxyConsole(t *testing.T) {
+	startProxy := func() (net.Conn, net.Listener) {
+		console, consoleEnd := net.Pipe()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Unable to listen: %v", err)
+		}
+		go proxyConsole("testInstance", "token", ln, consoleEnd)
+		return console, ln
+	}
+
+	console, ln := startProxy()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Unable to connect to console proxy: %v", err)
+	}
+	_, _ = conn.Write([]byte("wrong\n"))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Connection with wrong token not closed")
+	}
+	_ = conn.Close()
+	_ = console.Close()
+
+	console, ln = startProxy()
+	defer func() { _ = console.Close() }()
+	conn, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Unable to connect to console proxy: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, _ = conn.Write([]byte("token\nroot\n"))
+	_ = console.SetReadDeadline(time.Now().Add(5 * time.Second))
+	input := make([]byte, 5)
+	if _, err := console.Read(input); err != nil || string(input) != "root\n" {
+		t.Fatalf("Expected console input root, got %q: %v", input, err)
+	}
+
+	go func() { _, _ = console.Write([]byte("Password:")) }()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	output := make([]byte, 9)
+	if _, err := conn.Read(output); err != nil || string(output) != "Password:" {
+		t.Fatalf("Expected console output Password:, got %q: %v", output, err)
+	}
+
+	if _, err := net.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Errorf("Console proxy accepted a second connection")
+	}
+}
@@ -28,6 +28,8 @@ type deleteError struct {
 }
 
 func (de *deleteError) send(conn serverConn, instance string) {
+	commandFailures.Inc(ssntp.DELETE.String(), string(de.code))
+
 	if !conn.isConnected() {
 		return
 	}
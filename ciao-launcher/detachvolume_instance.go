@@ -0,0 +1,101 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	storage "github.com/ciao-project/ciao/ciao-storage"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+)
+
+func unplugVolume(monitorCh chan interface{}, volumeUUID string) error {
+	responseCh := make(chan error)
+
+	monitorCh <- virtualizerDetachCmd{
+		responseCh: responseCh,
+		volumeUUID: volumeUUID,
+	}
+
+	return <-responseCh
+}
+
+func processDetachVolume(storageDriver storage.BlockDriver, monitorCh chan interface{}, cfg *vmConfig,
+	instance, instanceDir, volumeUUID string) *detachVolumeError {
+
+	vol := cfg.findVolume(volumeUUID)
+	if vol == nil {
+		detachErr := &detachVolumeError{nil, payloads.DetachVolumeNotAttached}
+		glog.Errorf("%s is not attached to instance %s [%s]",
+			volumeUUID, instance, string(detachErr.code))
+		return detachErr
+	}
+
+	if vol.Bootable {
+		detachErr := &detachVolumeError{nil, payloads.DetachVolumeNotSupported}
+		glog.Errorf("Cannot detach boot volume %s from instance %s [%s]",
+			volumeUUID, instance, string(detachErr.code))
+		return detachErr
+	}
+
+	if monitorCh != nil {
+		if cfg.Container {
+			detachErr := &detachVolumeError{nil, payloads.DetachVolumeNotSupported}
+			glog.Errorf("Cannot detach a volume from a running container [%s]",
+				string(detachErr.code))
+			return detachErr
+		}
+
+		err := unplugVolume(monitorCh, volumeUUID)
+		if err != nil {
+			detachErr := &detachVolumeError{err, payloads.DetachVolumeDetachFailure}
+			glog.Errorf("Unable to detach volume %s from instance %s [%s]: %v",
+				volumeUUID, instance, string(detachErr.code), err)
+			return detachErr
+		}
+	} else if cfg.Container {
+		err := unmountContainerVolume(dockerMounter{}, instanceDir, volumeUUID)
+		if err != nil {
+			detachErr := &detachVolumeError{err, payloads.DetachVolumeDetachFailure}
+			glog.Errorf("Unable to unmount volume %s of instance %s [%s]: %v",
+				volumeUUID, instance, string(detachErr.code), err)
+			return detachErr
+		}
+	}
+
+	// Volumes hot plugged into VMs and volumes of containers are mapped
+	// on the node.  UnmapVolumeFromNode fails for the other volumes, or
+	// if the volume is also mapped for another instance.  We don't treat
+	// this as an error.
+
+	if err := storageDriver.UnmapVolumeFromNode(volumeUUID); err == nil {
+		glog.Infof("Unmapped volume %s", volumeUUID)
+	}
+
+	volumes := append([]volumeConfig(nil), cfg.Volumes...)
+	cfg.removeVolume(volumeUUID)
+
+	err := cfg.save(instanceDir)
+	if err != nil {
+		cfg.Volumes = volumes
+		detachErr := &detachVolumeError{err, payloads.DetachVolumeStateFailure}
+		glog.Errorf("Unable to persist instance %s state [%s]: %v",
+			instance, string(detachErr.code), err)
+		return detachErr
+	}
+
+	return nil
+}
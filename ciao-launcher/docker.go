@@ -44,6 +44,10 @@ import (
 
 const volumesDir = "volumes"
 
+// dockerRestartTimeout is the number of seconds docker waits for a container
+// to stop during a soft reboot before killing it.
+const dockerRestartTimeout = 10
+
 type dockerMounter struct{}
 
 func (m dockerMounter) Mount(source, destination string) error {
@@ -257,6 +261,24 @@ func (d *docker) umountVolumes(vols []volumeConfig) {
 	}
 }
 
+// unmountContainerVolume unmounts a volume of a container that is not running
+// and removes its mount point.  Volumes are normally unmounted when their
+// container exits so it's not an error for the volume not to be mounted.
+func unmountContainerVolume(m mounter, instanceDir, volumeUUID string) error {
+	vd := path.Join(instanceDir, volumesDir, volumeUUID)
+	err := m.Unmount(vd, 0)
+	if err != nil && err != syscall.EINVAL && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to unmount %s: %v", vd, err)
+	}
+
+	err = os.Remove(vd)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to remove %s: %v", vd, err)
+	}
+
+	return nil
+}
+
 func (d *docker) unmapVolumes() {
 	for _, vol := range d.cfg.Volumes {
 		if err := d.storageDriver.UnmapVolumeFromNode(vol.UUID); err != nil {
@@ -395,7 +417,7 @@ func (d *docker) startVM(vnicName, ipAddress, cephID string) error {
 	return nil
 }
 
-func dockerCommandLoop(cli containerManager, dockerChannel chan interface{}, instance, dockerID string) {
+func dockerWait(cli containerManager, instance, dockerID string) (chan struct{}, context.CancelFunc) {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	lostContainerCh := make(chan struct{})
 	go func() {
@@ -405,6 +427,25 @@ func dockerCommandLoop(cli containerManager, dockerChannel chan interface{}, ins
 			instance, dockerID, ret, err)
 	}()
 
+	return lostContainerCh, cancelFunc
+}
+
+func dockerRestart(cli containerManager, cmd virtualizerRebootCmd, instance, dockerID string) error {
+	timeout := dockerRestartTimeout
+	if cmd.hard {
+		timeout = 0
+	}
+
+	err := cli.ContainerRestart(context.Background(), dockerID, timeout)
+	if err != nil {
+		glog.Errorf("Unable to restart instance %s:%s: %v", instance, dockerID, err)
+	}
+	return err
+}
+
+func dockerCommandLoop(cli containerManager, dockerChannel chan interface{}, instance, dockerID string) {
+	lostContainerCh, cancelFunc := dockerWait(cli, instance, dockerID)
+
 DONE:
 	for {
 		select {
@@ -426,6 +467,28 @@ DONE:
 			case virtualizerAttachCmd:
 				err := fmt.Errorf("Live Attach of volumes not supported for containers")
 				cmd.responseCh <- err
+			case virtualizerDetachCmd:
+				err := fmt.Errorf("Live Detach of volumes not supported for containers")
+				cmd.responseCh <- err
+			case virtualizerResizeCmd:
+				cmd.responseCh <- errNotSupported
+			case virtualizerRebootCmd:
+				// The container stops while it is being restarted
+				// so we need to stop waiting on it until the restart
+				// has completed.
+				cancelFunc()
+				_ = <-lostContainerCh
+				err := dockerRestart(cli, cmd, instance, dockerID)
+				lostContainerCh, cancelFunc = dockerWait(cli, instance, dockerID)
+				cmd.responseCh <- err
+			case virtualizerPauseCmd:
+				cmd.responseCh <- cli.ContainerPause(context.Background(), dockerID)
+			case virtualizerUnpauseCmd:
+				cmd.responseCh <- cli.ContainerUnpause(context.Background(), dockerID)
+			case virtualizerSuspendCmd:
+				cmd.responseCh <- errNotSupported
+			case virtualizerMigrateCmd:
+				cmd.responseCh <- errNotSupported
 			}
 		}
 	}
@@ -99,6 +99,10 @@ func (s dockerTestStorage) GetVolumeMapping() (map[string][]string, error) {
 	return nil, nil
 }
 
+func (s dockerTestStorage) FlattenBlockDevice(volumeUUID string) error {
+	return nil
+}
+
 func (s dockerTestStorage) CopyBlockDevice(volumeUUID string) (storage.BlockDevice, error) {
 	return storage.BlockDevice{}, nil
 }
@@ -207,6 +211,18 @@ func (d *dockerTestClient) ContainerKill(context.Context, string, string) error
 	return nil
 }
 
+func (d *dockerTestClient) ContainerPause(context.Context, string) error {
+	return d.err
+}
+
+func (d *dockerTestClient) ContainerUnpause(context.Context, string) error {
+	return d.err
+}
+
+func (d *dockerTestClient) ContainerRestart(context.Context, string, int) error {
+	return d.err
+}
+
 func (d *dockerTestClient) ContainerWait(ctx context.Context, id string) (int, error) {
 	select {
 	case <-d.containerWaitCh:
@@ -298,6 +314,49 @@ func TestDockerMountUnmount(t *testing.T) {
 	}
 }
 
+// Checks that a volume of a container that is not running can be unmounted.
+//
+// We mount a volume, unmount it with unmountContainerVolume and then call
+// unmountContainerVolume a second time.
+//
+// The volume should be unmounted and its mount point removed.  The second
+// call should succeed as there is nothing left to unmount.
+func TestDockerUnmountContainerVolume(t *testing.T) {
+	root, err := ioutil.TempDir("", "mount-unmount")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(root) }()
+
+	volumeUUID := "92a1e4fa-8448-4260-adb1-4d2dd816cc7c"
+	vd := path.Join(root, volumesDir, volumeUUID)
+	err = os.MkdirAll(vd, 0777)
+	if err != nil {
+		t.Fatalf("Unable to create volume directory: %v", err)
+	}
+
+	mounts := map[string]string{volumeUUID: "/dev/rbd0"}
+	m := dockerTestMounter{mounts: mounts}
+
+	err = unmountContainerVolume(m, root, volumeUUID)
+	if err != nil {
+		t.Fatalf("Unable to unmount volume: %v", err)
+	}
+
+	if len(mounts) != 0 {
+		t.Fatalf("Volume has not been unmounted")
+	}
+
+	if _, err = os.Stat(vd); !os.IsNotExist(err) {
+		t.Fatalf("Volume directory has not been removed")
+	}
+
+	err = unmountContainerVolume(m, root, volumeUUID)
+	if err != nil {
+		t.Fatalf("Unmounting a volume twice failed: %v", err)
+	}
+}
+
 // Checks that everything is cleaned up correctly when a call to
 // docker.mountVolumes fails.
 //
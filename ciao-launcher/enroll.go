@@ -0,0 +1,197 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/ssntp/certs"
+	"github.com/golang/glog"
+)
+
+const enrollRetryInterval = 10 * time.Second
+
+type enrollment struct {
+	client   *http.Client
+	url      string
+	token    string
+	interval time.Duration
+}
+
+func newEnrollment(URL, token, caCertPath string) (*enrollment, error) {
+	tlsConfig := &tls.Config{}
+
+	if caCertPath != "" {
+		caCert, err := ioutil.ReadFile(caCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load enrollment CA certificate: %v", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("No certificate found in %s", caCertPath)
+		}
+	}
+
+	return &enrollment{
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			Timeout:   time.Minute,
+		},
+		url:      strings.TrimSuffix(URL, "/") + "/enroll",
+		token:    token,
+		interval: enrollRetryInterval,
+	}, nil
+}
+
+// submit sends the CSR to the controller. It returns a nil response if the
+// enrollment request is still waiting for approval.
+func (e *enrollment) submit(csr []byte) (*types.EnrollResponse, error) {
+	body, err := json.Marshal(&types.EnrollRequest{
+		Token: e.token,
+		CSR:   string(csr),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, fmt.Errorf("Enrollment failed: %s: %s", resp.Status,
+			strings.TrimSpace(string(body)))
+	}
+
+	var enrollResp types.EnrollResponse
+	err = json.Unmarshal(body, &enrollResp)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid enrollment response: %v", err)
+	}
+
+	return &enrollResp, nil
+}
+
+func writeEnrollmentFile(filePath string, data []byte, perm os.FileMode) error {
+	err := os.MkdirAll(path.Dir(filePath), 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, data, perm)
+}
+
+// enroll gets a certificate for this node from the controller enrollment
+// endpoint. It generates a private key and a CSR, submits the CSR until an
+// admin approves it and stores the resulting certificate and the SSNTP CA
+// certificate to certPath and caCertPath. Nothing is done if certPath
+// already exists.
+func (e *enrollment) enroll(certPath, caCertPath string) error {
+	if _, err := os.Stat(certPath); err == nil {
+		glog.Infof("%s exists, skipping enrollment", certPath)
+		return nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("Unable to get hostname: %v", err)
+	}
+
+	var csr, key bytes.Buffer
+	request := certs.CreateCertificateRequest(hostname, "", []string{hostname}, nil)
+	err = certs.CreateCSR(request, &csr, &key)
+	if err != nil {
+		return fmt.Errorf("Unable to create CSR: %v", err)
+	}
+
+	glog.Infof("Enrolling %s with %s", hostname, e.url)
+
+	var resp *types.EnrollResponse
+	for {
+		resp, err = e.submit(csr.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if resp != nil {
+			break
+		}
+
+		glog.Infof("Enrollment request waiting for approval")
+		time.Sleep(e.interval)
+	}
+
+	var cert bytes.Buffer
+	err = certs.AddPrivateKeyToCert(strings.NewReader(resp.Cert), &key, &cert)
+	if err != nil {
+		return fmt.Errorf("Unable to add private key to certificate: %v", err)
+	}
+
+	err = writeEnrollmentFile(caCertPath, []byte(resp.CACert), 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to write CA certificate: %v", err)
+	}
+
+	err = writeEnrollmentFile(certPath, cert.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to write certificate: %v", err)
+	}
+
+	glog.Infof("Enrollment request %s approved", resp.ID)
+
+	return nil
+}
+
+func enrollNode() error {
+	if clientCertPath == "" || serverCertPath == "" {
+		return fmt.Errorf("-enroll requires -cert and -cacert")
+	}
+
+	if enrollToken == "" {
+		return fmt.Errorf("-enroll requires -enroll-token")
+	}
+
+	e, err := newEnrollment(enrollURL, enrollToken, enrollCACertPath)
+	if err != nil {
+		return err
+	}
+
+	return e.enroll(clientCertPath, serverCertPath)
+}
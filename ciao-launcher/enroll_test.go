@@ -0,0 +1,162 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/ciao-project/ciao/ssntp/certs"
+)
+
+// TestEnroll checks that the launcher keeps submitting its CSR while the
+// enrollment request is pending and stores a usable SSNTP certificate
+// once it is approved.
+func TestEnroll(t *testing.T) {
+	var anchorCert, caCert bytes.Buffer
+
+	template, err := certs.CreateCertTemplate(ssntp.SERVER, "ACME Corp", "", []string{"localhost"}, nil)
+	if err != nil {
+		t.Fatalf("Unable to create anchor cert template: %v", err)
+	}
+
+	err = certs.CreateAnchorCert(template, &anchorCert, &caCert)
+	if err != nil {
+		t.Fatalf("Unable to create anchor cert: %v", err)
+	}
+
+	submissions := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.EnrollRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		submissions++
+		if submissions == 1 {
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(&types.EnrollResponse{ID: "id", Status: types.EnrollmentPending})
+			return
+		}
+
+		var cert bytes.Buffer
+		err := certs.CreateCertFromCSR(ssntp.AGENT, []byte(req.CSR), anchorCert.Bytes(), &cert)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(&types.EnrollResponse{
+			ID:     "id",
+			Status: types.EnrollmentApproved,
+			Cert:   cert.String(),
+			CACert: caCert.String(),
+		})
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "enroll_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	certPath := path.Join(dir, "cert.pem")
+	caCertPath := path.Join(dir, "CAcert.pem")
+
+	e, err := newEnrollment(ts.URL, "token", "")
+	if err != nil {
+		t.Fatalf("Unable to create enrollment: %v", err)
+	}
+	e.interval = time.Millisecond
+
+	err = e.enroll(certPath, caCertPath)
+	if err != nil {
+		t.Fatalf("Unable to enroll: %v", err)
+	}
+
+	if submissions != 2 {
+		t.Errorf("Expected 2 CSR submissions, got %d", submissions)
+	}
+
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		t.Fatalf("Unable to read certificate: %v", err)
+	}
+
+	_, err = tls.X509KeyPair(certPEM, certPEM)
+	if err != nil {
+		t.Fatalf("Invalid certificate: %v", err)
+	}
+
+	caCertPEM, err := ioutil.ReadFile(caCertPath)
+	if err != nil {
+		t.Fatalf("Unable to read CA certificate: %v", err)
+	}
+
+	err = certs.VerifyCert(caCertPEM, certPEM)
+	if err != nil {
+		t.Fatalf("Unable to verify certificate: %v", err)
+	}
+
+	// Enrolling again is a no-op
+	err = e.enroll(certPath, caCertPath)
+	if err != nil || submissions != 2 {
+		t.Fatalf("Unexpected enrollment of an enrolled node: %v", err)
+	}
+}
+
+// TestEnrollRejected checks that enrollment fails when the controller
+// refuses the CSR.
+func TestEnrollRejected(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "enroll_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	e, err := newEnrollment(ts.URL, "token", "")
+	if err != nil {
+		t.Fatalf("Unable to create enrollment: %v", err)
+	}
+
+	certPath := path.Join(dir, "cert.pem")
+	err = e.enroll(certPath, path.Join(dir, "CAcert.pem"))
+	if err == nil {
+		t.Fatalf("Enrollment expected to fail")
+	}
+
+	if _, err := os.Stat(certPath); err == nil {
+		t.Fatalf("Certificate written for rejected enrollment")
+	}
+}
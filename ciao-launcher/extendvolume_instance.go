@@ -0,0 +1,64 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+)
+
+func processExtendVolume(monitorCh chan interface{}, cfg *vmConfig,
+	instance, volumeUUID string, sizeGiB int) *extendVolumeError {
+
+	if cfg.Container {
+		extendErr := &extendVolumeError{nil, payloads.ExtendVolumeNotSupported}
+		glog.Errorf("Cannot extend a volume of a container [%s]", string(extendErr.code))
+		return extendErr
+	}
+
+	if cfg.findVolume(volumeUUID) == nil {
+		extendErr := &extendVolumeError{nil, payloads.ExtendVolumeNotAttached}
+		glog.Errorf("%s is not attached to instance %s [%s]",
+			volumeUUID, instance, string(extendErr.code))
+		return extendErr
+	}
+
+	// If the VM is not running there's nothing to do.  The guest will
+	// see the new size of the volume the next time it boots.
+
+	if monitorCh == nil {
+		return nil
+	}
+
+	responseCh := make(chan error)
+
+	monitorCh <- virtualizerResizeCmd{
+		responseCh: responseCh,
+		volumeUUID: volumeUUID,
+		size:       uint64(sizeGiB) << 30,
+	}
+
+	err := <-responseCh
+	if err != nil {
+		extendErr := &extendVolumeError{err, payloads.ExtendVolumeResizeFailure}
+		glog.Errorf("Unable to resize volume %s of instance %s [%s]: %v",
+			volumeUUID, instance, string(extendErr.code), err)
+		return extendErr
+	}
+
+	return nil
+}
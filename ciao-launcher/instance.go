@@ -45,9 +45,15 @@ type instanceData struct {
 	vm             virtualizer
 	instanceDir    string
 	shuttingDown   bool
+	paused         bool
+	rebooting      bool
 	rcvStamp       time.Time
 	st             *startTimes
 	storageDriver  storage.BlockDriver
+	migration      *insMigrateCmd
+	migrationCh    chan error
+	migrationTimer <-chan time.Time
+	migrated       bool
 }
 
 type insStartCmd struct {
@@ -84,6 +90,48 @@ type insAttachVolumeCmd struct {
 	volumeUUID string
 }
 
+type insDetachVolumeCmd struct {
+	volumeUUID string
+}
+
+type insExtendVolumeCmd struct {
+	volumeUUID string
+
+	// The new size of the volume in GiB.
+	sizeGiB int
+}
+
+type insPowerCmd struct {
+	// The SSNTP command to execute, i.e., REBOOT, PAUSE, UNPAUSE,
+	// SUSPEND or RESUME.
+	cmd ssntp.Command
+
+	// Indicates whether a REBOOT should reset the instance rather than
+	// ask the guest to reboot.
+	hard bool
+}
+
+type insMigrateCmd struct {
+	// The UUID of the node the instance is migrated to.
+	target string
+
+	// The URI on which the VM of the target node waits for the state
+	// of the instance.
+	uri string
+}
+
+type insConsoleCmd struct {
+	// The CONSOLE command frame, which our reply must be correlated with.
+	frame *ssntp.Frame
+
+	// Whether the console log or an interactive console is requested.
+	consoleType payloads.ConsoleType
+
+	// The maximum number of lines of the console log to return, 0 for
+	// no limit.
+	lines int
+}
+
 /*
 This functions asks the server loop to kill the instance.  An instance
 needs to request that the server loop kill it if Start fails completly.
@@ -147,6 +195,7 @@ func (id *instanceData) startCommand(cmd *insStartCmd) {
 		return
 	}
 	id.st = st
+	commandSuccesses.Inc(ssntp.START.String())
 
 	id.connectedCh = make(chan struct{})
 	id.monitorCloseCh = make(chan struct{})
@@ -155,9 +204,19 @@ func (id *instanceData) startCommand(cmd *insStartCmd) {
 	if cmd.frame != nil && cmd.frame.PathTrace() {
 		id.ovsCh <- &ovsTraceFrame{cmd.frame}
 	}
+
+	if id.cfg.MigrationPort != 0 {
+		id.waitForIncomingMigration()
+	}
 }
 
 func (id *instanceData) monitorCommand(cmd *insMonitorCmd) {
+	if id.cfg.Suspended {
+		glog.Infof("Instance %s is suspended", id.instance)
+		id.ovsCh <- &ovsStateChange{id.instance, ovsSuspended}
+		return
+	}
+
 	id.connectedCh = make(chan struct{})
 	id.monitorCloseCh = make(chan struct{})
 	id.monitorCh = id.vm.monitorVM(id.monitorCloseCh, id.connectedCh, &id.instanceWg, true)
@@ -212,10 +271,18 @@ func (id *instanceData) deleteCommand(cmd *insDeleteCmd) bool {
 		id.vm.lostVM()
 	}
 
+	if id.cfg.MigrationPort != 0 {
+		migrationPortGrabber.releasePort(id.cfg.MigrationPort)
+	}
+
 	_ = processDelete(id.vm, id.instanceDir, id.ac.conn, cmd.running)
 
 	id.unmapVolumes()
 
+	if !cmd.suicide && !cmd.stop {
+		commandSuccesses.Inc(ssntp.DELETE.String())
+	}
+
 	if !cmd.skipDeleteEvent {
 		if cmd.stop {
 			id.sendInstanceStoppedEvent()
@@ -228,7 +295,7 @@ func (id *instanceData) deleteCommand(cmd *insDeleteCmd) bool {
 }
 
 func (id *instanceData) attachVolumeCommand(cmd *insAttachVolumeCmd) {
-	if id.shuttingDown {
+	if id.shuttingDown || id.cfg.Suspended || id.migrating() {
 		attachErr := &attachVolumeError{nil, payloads.AttachVolumeInstanceFailure}
 		glog.Errorf("Unable to attach instance[%s]", string(attachErr.code))
 		attachErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
@@ -241,12 +308,80 @@ func (id *instanceData) attachVolumeCommand(cmd *insAttachVolumeCmd) {
 		attachErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
 		return
 	}
+	commandSuccesses.Inc(ssntp.AttachVolume.String())
+
 	d, m, c := id.vm.stats()
 	id.ovsCh <- &ovsStatsUpdateCmd{id.instance, m, d, c, id.getVolumes()}
 
 	glog.Infof("Volume %s attached to instance %s", cmd.volumeUUID, id.instance)
 }
 
+func (id *instanceData) detachVolumeCommand(cmd *insDetachVolumeCmd) {
+	if id.shuttingDown || id.cfg.Suspended || id.migrating() {
+		detachErr := &detachVolumeError{nil, payloads.DetachVolumeInstanceFailure}
+		glog.Errorf("Unable to detach volume from instance[%s]", string(detachErr.code))
+		detachErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
+		return
+	}
+
+	detachErr := processDetachVolume(id.storageDriver, id.monitorCh, id.cfg, id.instance,
+		id.instanceDir, cmd.volumeUUID)
+	if detachErr != nil {
+		detachErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
+		return
+	}
+	commandSuccesses.Inc(ssntp.DetachVolume.String())
+
+	d, m, c := id.vm.stats()
+	id.ovsCh <- &ovsStatsUpdateCmd{id.instance, m, d, c, id.getVolumes()}
+
+	glog.Infof("Volume %s detached from instance %s", cmd.volumeUUID, id.instance)
+}
+
+func (id *instanceData) extendVolumeCommand(cmd *insExtendVolumeCmd) {
+	if id.shuttingDown || id.migrating() {
+		extendErr := &extendVolumeError{nil, payloads.ExtendVolumeResizeFailure}
+		glog.Errorf("Unable to extend volume of instance[%s]", string(extendErr.code))
+		extendErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
+		return
+	}
+
+	extendErr := processExtendVolume(id.monitorCh, id.cfg, id.instance, cmd.volumeUUID,
+		cmd.sizeGiB)
+	if extendErr != nil {
+		extendErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
+		return
+	}
+	commandSuccesses.Inc(ssntp.ExtendVolume.String())
+
+	glog.Infof("Volume %s of instance %s extended to %d GiB", cmd.volumeUUID,
+		id.instance, cmd.sizeGiB)
+}
+
+func (id *instanceData) lostVM() {
+	if id.migrated || id.cfg.MigrationPort != 0 {
+		id.lostMigratedVM()
+		return
+	}
+
+	if id.rebooting {
+		id.rebooting = false
+		glog.Infof("Relaunching rebooted instance: %s", id.instance)
+		err := id.relaunchVM()
+		if err == nil {
+			return
+		}
+		glog.Errorf("Unable to relaunch instance %s: %v", id.instance, err)
+	} else if id.cfg.Suspended {
+		id.ovsCh <- &ovsStateChange{id.instance, ovsSuspended}
+		return
+	}
+
+	id.ovsCh <- &ovsStateChange{id.instance, ovsStopped}
+	killMe(id.instance, false, true, id.doneCh, id.ac, &id.instanceWg)
+	id.shuttingDown = true
+}
+
 func (id *instanceData) logStartTrace() {
 	if id.st == nil {
 		return
@@ -284,6 +419,16 @@ func (id *instanceData) instanceCommand(cmd interface{}) bool {
 		id.monitorCommand(cmd)
 	case *insAttachVolumeCmd:
 		id.attachVolumeCommand(cmd)
+	case *insDetachVolumeCmd:
+		id.detachVolumeCommand(cmd)
+	case *insExtendVolumeCmd:
+		id.extendVolumeCommand(cmd)
+	case *insPowerCmd:
+		id.powerCommand(cmd)
+	case *insMigrateCmd:
+		id.migrateCommand(cmd)
+	case *insConsoleCmd:
+		id.consoleCommand(cmd)
 	case *insDeleteCmd:
 		if id.deleteCommand(cmd) {
 			return false
@@ -333,6 +478,9 @@ DONE:
 		case <-id.statsTimer:
 			d, m, c := id.vm.stats()
 			id.ovsCh <- &ovsStatsUpdateCmd{id.instance, m, d, c, id.getVolumes()}
+			if err := rotateConsoleLog(id.instanceDir); err != nil {
+				glog.Warningf("Unable to rotate console log of %s: %v", id.instance, err)
+			}
 			id.statsTimer = time.After(time.Second * resourcePeriod)
 		case cmd := <-id.cmdCh:
 			if !id.instanceCommand(cmd) {
@@ -350,13 +498,22 @@ DONE:
 			close(id.monitorCh)
 			id.monitorCh = nil
 			id.statsTimer = nil
-			id.ovsCh <- &ovsStateChange{id.instance, ovsStopped}
 			id.st = nil
-			killMe(id.instance, false, true, id.doneCh, id.ac, &id.instanceWg)
-			id.shuttingDown = true
+			id.paused = false
+			if id.migrationCh != nil {
+				id.migrationDone(<-id.migrationCh)
+			}
+			id.lostVM()
+		case err := <-id.migrationCh:
+			id.migrationDone(err)
+		case <-id.migrationTimer:
+			id.incomingMigrationTimeout()
 		case <-id.connectedCh:
 			id.logStartTrace()
 			id.connectedCh = nil
+			if id.cfg.MigrationPort != 0 {
+				id.incomingMigrationDone()
+			}
 			id.vm.connected()
 			id.ovsCh <- &ovsStateChange{id.instance, ovsRunning}
 			d, m, c := id.vm.stats()
@@ -396,12 +553,22 @@ func startInstanceWithVM(instance string, cfg *vmConfig, wg *sync.WaitGroup, don
 	return id.cmdCh
 }
 
-func startInstance(instance string, cfg *vmConfig, wg *sync.WaitGroup, doneCh chan struct{},
-	ac *agentClient, ovsCh chan<- interface{}) chan<- interface{} {
+func newStorageDriver() storage.BlockDriver {
+	if storageDriverName == payloads.LocalStorage {
+		return storage.LocalDriver{
+			Path: localStoragePath,
+		}
+	}
 
-	storageDriver := storage.CephDriver{
+	return storage.CephDriver{
 		ID: cephID,
 	}
+}
+
+func startInstance(instance string, cfg *vmConfig, wg *sync.WaitGroup, doneCh chan struct{},
+	ac *agentClient, ovsCh chan<- interface{}) chan<- interface{} {
+
+	storageDriver := newStorageDriver()
 
 	var vm virtualizer
 	if simulate == true {
@@ -59,9 +59,14 @@ type instanceTestState struct {
 	stf             payloads.ErrorStartFailure
 	df              payloads.ErrorDeleteFailure
 	avf             payloads.ErrorAttachVolumeFailure
+	dvf             payloads.ErrorDetachVolumeFailure
+	evf             payloads.ErrorExtendVolumeFailure
+	pf              payloads.ErrorPowerFailure
+	mf              payloads.ErrorMigrateFailure
 	deMigration     bool
 	de              payloads.EventInstanceDeleted
 	se              payloads.EventInstanceStopped
+	me              payloads.EventInstanceMigrated
 	connect         bool
 	monitorCh       chan interface{}
 	errorCh         chan struct{}
@@ -142,6 +147,27 @@ func (v *instanceTestState) SendError(error ssntp.Error, payload []byte) (int, e
 		if err != nil {
 			v.t.Fatalf("Failed to unmarshall attach volume error %v", err)
 		}
+	case ssntp.DetachVolumeFailure:
+		err := yaml.Unmarshal(payload, &v.dvf)
+		if err != nil {
+			v.t.Fatalf("Failed to unmarshall detach volume error %v", err)
+		}
+	case ssntp.ExtendVolumeFailure:
+		err := yaml.Unmarshal(payload, &v.evf)
+		if err != nil {
+			v.t.Fatalf("Failed to unmarshall extend volume error %v", err)
+		}
+	case ssntp.RebootFailure, ssntp.PauseFailure, ssntp.UnpauseFailure,
+		ssntp.SuspendFailure, ssntp.ResumeFailure:
+		err := yaml.Unmarshal(payload, &v.pf)
+		if err != nil {
+			v.t.Fatalf("Failed to unmarshall power error %v", err)
+		}
+	case ssntp.MigrateFailure:
+		err := yaml.Unmarshal(payload, &v.mf)
+		if err != nil {
+			v.t.Fatalf("Failed to unmarshall migrate error %v", err)
+		}
 	}
 
 	if v.errorCh != nil {
@@ -165,6 +191,11 @@ func (v *instanceTestState) SendEvent(event ssntp.Event, payload []byte) (int, e
 		if err != nil {
 			v.t.Fatalf("Failed to unmarshall instanceStopped event %v", err)
 		}
+	case ssntp.InstanceMigrated:
+		err := yaml.Unmarshal(payload, &v.me)
+		if err != nil {
+			v.t.Fatalf("Failed to unmarshall instanceMigrated event %v", err)
+		}
 	}
 
 	if v.eventCh != nil {
@@ -174,6 +205,14 @@ func (v *instanceTestState) SendEvent(event ssntp.Event, payload []byte) (int, e
 	return 0, nil
 }
 
+func (v *instanceTestState) SendErrorReply(frame *ssntp.Frame, error ssntp.Error, payload []byte) (int, error) {
+	return v.SendError(error, payload)
+}
+
+func (v *instanceTestState) SendEventReply(frame *ssntp.Frame, event ssntp.Event, payload []byte) (int, error) {
+	return v.SendEvent(event, payload)
+}
+
 func (v *instanceTestState) Dial(config *ssntp.Config, ntf ssntp.ClientNotifier) error {
 	return nil
 }
@@ -349,6 +388,10 @@ func (v *instanceTestState) ClusterConfiguration() (payloads.Configure, error) {
 	return payloads.Configure{}, nil
 }
 
+func (v *instanceTestState) PayloadEncoding() payloads.Encoding {
+	return payloads.YAML
+}
+
 func cleanupShutdownFail(t *testing.T, instance string, doneCh chan struct{}, ovsCh chan interface{}, wg *sync.WaitGroup) {
 	_ = os.RemoveAll(path.Join(testInstancesDir, instance))
 
@@ -903,6 +946,398 @@ func TestAttachExistingVolumeToInstance(t *testing.T) {
 	wg.Wait()
 }
 
+// Check we can detach a volume from a running instance
+//
+// We start the instance loop, add a volume, detach it, detach it a second
+// time and then delete the instance.
+//
+// The instanceLoop and then instance should start correctly.  The volume should
+// be correctly attached and then hot unplugged, the stats command verifying
+// both operations.  The second attempt to detach the volume should fail.  The
+// instance should be correctly deleted.
+func TestDetachVolumeFromInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	select {
+	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending attach volume command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		monCmd.(virtualizerAttachCmd).responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for attach volume command result")
+	}
+
+	_ = state.expectStatsUpdateWithVolumes(t, ovsCh, []string{testutil.VolumeUUID})
+
+	select {
+	case cmdCh <- &insDetachVolumeCmd{testutil.VolumeUUID}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending detach volume command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		detachCmd := monCmd.(virtualizerDetachCmd)
+		if detachCmd.volumeUUID != testutil.VolumeUUID {
+			t.Errorf("Unexpected volume detached %s", detachCmd.volumeUUID)
+		}
+		detachCmd.responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for detach volume command result")
+	}
+
+	_ = state.expectStatsUpdateWithVolumes(t, ovsCh, []string{})
+
+	select {
+	case <-state.errorCh:
+		t.Error("Volume detach failed")
+	case cmdCh <- &insDetachVolumeCmd{testutil.VolumeUUID}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending detach volume command")
+	}
+
+	select {
+	case <-state.errorCh:
+		if state.dvf.Reason != payloads.DetachVolumeNotAttached {
+			t.Errorf("Unexpected error.  Expected %s got %s",
+				payloads.DetachVolumeNotAttached, state.dvf.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for detach to fail")
+	}
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check we can extend a volume attached to an instance
+//
+// We start the instance loop, add a volume, extend it, try to extend a volume
+// that is not attached to the instance and then delete the instance.
+//
+// The instanceLoop and then instance should start correctly.  The volume should
+// be correctly attached and the new size of the volume should be passed to the
+// virtualizer.  Extending the unattached volume should fail.  The instance
+// should be correctly deleted.
+func TestExtendVolumeOfInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	select {
+	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending attach volume command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		monCmd.(virtualizerAttachCmd).responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for attach volume command result")
+	}
+
+	_ = state.expectStatsUpdateWithVolumes(t, ovsCh, []string{testutil.VolumeUUID})
+
+	select {
+	case cmdCh <- &insExtendVolumeCmd{testutil.VolumeUUID, 20}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending extend volume command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		resizeCmd := monCmd.(virtualizerResizeCmd)
+		if resizeCmd.volumeUUID != testutil.VolumeUUID || resizeCmd.size != 20<<30 {
+			t.Errorf("Unexpected resize command %s %d", resizeCmd.volumeUUID,
+				resizeCmd.size)
+		}
+		resizeCmd.responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for extend volume command result")
+	}
+
+	select {
+	case <-state.errorCh:
+		t.Error("Volume extend failed")
+	case cmdCh <- &insExtendVolumeCmd{testutil.InstanceUUID, 20}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending extend volume command")
+	}
+
+	select {
+	case <-state.errorCh:
+		if state.evf.Reason != payloads.ExtendVolumeNotAttached {
+			t.Errorf("Unexpected error.  Expected %s got %s",
+				payloads.ExtendVolumeNotAttached, state.evf.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for extend to fail")
+	}
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check we can pause and unpause an instance
+//
+// We start the instance loop, pause the instance, unpause the instance
+// and then delete it.
+//
+// The instanceLoop and then instance should start correctly.  The PAUSE and
+// UNPAUSE commands should be forwarded to the virtualizer and the overseer
+// should be informed of the paused and running states.  The instance should
+// be correctly deleted.
+func TestPauseUnpauseInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	select {
+	case cmdCh <- &insPowerCmd{cmd: ssntp.PAUSE}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending pause command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		monCmd.(virtualizerPauseCmd).responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for pause command")
+	}
+
+	_ = waitForStateChange(t, ovsPaused, ovsCh)
+
+	select {
+	case cmdCh <- &insPowerCmd{cmd: ssntp.UNPAUSE}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending unpause command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		monCmd.(virtualizerUnpauseCmd).responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for unpause command")
+	}
+
+	_ = waitForStateChange(t, ovsRunning, ovsCh)
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check that unpausing a running instance fails
+//
+// We start the instance loop, unpause the running instance and then delete it.
+//
+// The instanceLoop and then instance should start correctly.  The UNPAUSE
+// command should fail with an invalid_state error.  The instance should be
+// correctly deleted.
+func TestUnpauseRunningInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	state.errorCh = make(chan struct{})
+
+	select {
+	case cmdCh <- &insPowerCmd{cmd: ssntp.UNPAUSE}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending unpause command")
+	}
+
+	select {
+	case <-state.errorCh:
+		if state.pf.Reason != payloads.PowerInvalidState {
+			t.Errorf("Unexpected error.  Expected %s got %s",
+				payloads.PowerInvalidState, state.pf.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for unpause to fail")
+	}
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check that containers cannot be suspended
+//
+// We start the instance loop with a container, suspend it and then delete it.
+//
+// The instanceLoop and then instance should start correctly.  The SUSPEND
+// command should fail with a not_supported error.  The instance should be
+// correctly deleted.
+func TestSuspendContainer(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	cfg.Container = true
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	state.errorCh = make(chan struct{})
+
+	select {
+	case cmdCh <- &insPowerCmd{cmd: ssntp.SUSPEND}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending suspend command")
+	}
+
+	select {
+	case <-state.errorCh:
+		if state.pf.Reason != payloads.PowerNotSupported {
+			t.Errorf("Unexpected error.  Expected %s got %s",
+				payloads.PowerNotSupported, state.pf.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for suspend to fail")
+	}
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check that containers cannot be live migrated
+//
+// We start the instance loop with a container, migrate it and then delete it.
+//
+// The instanceLoop and then instance should start correctly.  The MIGRATE
+// command should fail with a not_supported error.  The instance should be
+// correctly deleted.
+func TestMigrateContainer(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	cfg.Container = true
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	state.errorCh = make(chan struct{})
+
+	select {
+	case cmdCh <- &insMigrateCmd{testutil.NetAgentUUID, testutil.MigrateURI}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending migrate command")
+	}
+
+	select {
+	case <-state.errorCh:
+		if state.mf.Reason != payloads.MigrateNotSupported {
+			t.Errorf("Unexpected error.  Expected %s got %s",
+				payloads.MigrateNotSupported, state.mf.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for migrate to fail")
+	}
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check we can live migrate an instance
+//
+// We start the instance loop, migrate the instance and simulate the VM
+// quitting once the migration has completed.  We then forward the resulting
+// suicide command back to the instance.
+//
+// The instanceLoop and then instance should start correctly.  The MIGRATE
+// command should be forwarded to the virtualizer and the overseer should be
+// informed of the migrating state.  An InstanceMigrated event should be sent
+// and the instance should delete itself without sending an InstanceDeleted
+// event when its VM goes away.
+func TestLiveMigrateInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	state.eventCh = make(chan struct{})
+
+	select {
+	case cmdCh <- &insMigrateCmd{testutil.NetAgentUUID, testutil.MigrateURI}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending migrate command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		monCmd.(virtualizerMigrateCmd).responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for migrate command")
+	}
+
+	_ = waitForStateChange(t, ovsMigrating, ovsCh)
+
+	select {
+	case <-state.eventCh:
+		state.eventCh = nil
+		if state.me.InstanceMigrated.TargetNodeUUID != testutil.NetAgentUUID {
+			t.Errorf("Unexpected migration target.  Expected %s got %s",
+				testutil.NetAgentUUID, state.me.InstanceMigrated.TargetNodeUUID)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for InstanceMigrated event")
+	}
+
+	close(state.monitorClosedCh)
+	state.monitorCh = nil
+
+	timeout := time.After(time.Second * 5)
+	var cmd *cmdWrapper
+DONE:
+	for {
+		select {
+		case <-ovsCh:
+		case cmd = <-state.ac.cmdCh:
+			break DONE
+		case <-timeout:
+			t.Error("Timedout waiting for delete cmd")
+			shutdownInstanceLoop(doneCh, ovsCh, &wg, t)
+			t.FailNow()
+		}
+	}
+
+	delCmd := cmd.cmd.(*insDeleteCmd)
+	if !delCmd.skipDeleteEvent || delCmd.stop {
+		t.Errorf("Unexpected delete command for migrated instance")
+	}
+
+	state.errorCh = make(chan struct{})
+	select {
+	case cmdCh <- delCmd:
+	case <-time.After(time.Second):
+		shutdownInstanceLoop(doneCh, ovsCh, &wg, t)
+		t.Fatal("Timed out sending suicide command")
+	}
+	wg.Wait()
+
+	select {
+	case <-state.errorCh:
+		t.Error("Suicide Delete failed unexpectedly")
+	default:
+	}
+}
+
 func TestMain(m *testing.M) {
 	flag.Parse()
 	var err error
@@ -26,10 +26,13 @@ import (
 	"os/signal"
 	"path"
 	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	storage "github.com/ciao-project/ciao/ciao-storage"
 	"github.com/ciao-project/ciao/clogger/gloginterface"
 	"github.com/ciao-project/ciao/networking/libsnnet"
 	"github.com/ciao-project/ciao/osprepare"
@@ -75,7 +78,32 @@ func (f *qemuVirtualisationFlag) Set(val string) error {
 	return nil
 }
 
+type labelsFlag map[string]string
+
+func (f labelsFlag) String() string {
+	labels := make([]string, 0, len(f))
+	for k, v := range f {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+
+	return strings.Join(labels, ",")
+}
+
+func (f labelsFlag) Set(val string) error {
+	for _, label := range strings.Split(val, ",") {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("key=value expected, got %s", label)
+		}
+		f[kv[0]] = kv[1]
+	}
+
+	return nil
+}
+
 var netConfig networkConfig
+var serverURL string
 var serverCertPath string
 var clientCertPath string
 var networking bool
@@ -83,16 +111,29 @@ var hardReset bool
 var diskLimit bool
 var memLimit bool
 var cephID string
+var storageDriverName payloads.StorageDriver
+var localStoragePath string
 var simulate bool
 var maxInstances = int(math.MaxInt32)
+var nodeLabels = labelsFlag{}
+var metricsAddr string
+var enrollURL string
+var enrollToken string
+var enrollCACertPath string
 
 func init() {
+	flag.StringVar(&serverURL, "server", "", "Comma separated list of SSNTP server URLs to fail over between, the CA certificate ones are tried next")
 	flag.StringVar(&serverCertPath, "cacert", "", "Client certificate")
 	flag.StringVar(&clientCertPath, "cert", "", "CA certificate")
 	flag.BoolVar(&networking, "network", true, "Enable networking")
 	flag.BoolVar(&hardReset, "hard-reset", false, "Kill and delete all instances, reset networking and exit")
 	flag.BoolVar(&simulate, "simulation", false, "Launcher simulation")
 	flag.StringVar(&cephID, "ceph_id", "", "ceph client id")
+	flag.Var(nodeLabels, "labels", "Comma separated key=value labels advertised by the node")
+	flag.StringVar(&metricsAddr, "metrics", "", "host:port to serve Prometheus metrics on, disabled if empty")
+	flag.StringVar(&enrollURL, "enroll", "", "Controller enrollment URL to get the -cert and -cacert certificates from, if -cert does not exist")
+	flag.StringVar(&enrollToken, "enroll-token", "", "One-time join token for -enroll")
+	flag.StringVar(&enrollCACertPath, "enroll-cacert", "", "CA certificate of the -enroll HTTPS server, the system ones are used if empty")
 }
 
 const (
@@ -104,6 +145,7 @@ const (
 	maintenanceFile = dataDir + "/maintenance"
 	networkFile     = dataDir + "/network"
 	instanceState   = "state"
+	suspendState    = "suspend"
 	lockFile        = "client-agent.lock"
 	statsPeriod     = 6
 	resourcePeriod  = 30
@@ -237,6 +279,46 @@ func processInstanceCommand(conn serverConn, cmd *cmdWrapper, ovsCh chan<- inter
 		}
 		delCmd = insCmd
 		delCmd.running = insState.running
+	case *insPowerCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			pe := powerError{nil, payloads.PowerNoInstance}
+			pe.send(conn, insCmd.cmd, cmd.instance)
+			return
+		}
+	case *insMigrateCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			me := migrateError{nil, payloads.MigrateNoInstance}
+			me.send(conn, cmd.instance)
+			return
+		}
+	case *insDetachVolumeCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			dve := detachVolumeError{nil, payloads.DetachVolumeNoInstance}
+			dve.send(conn, cmd.instance, insCmd.volumeUUID)
+			return
+		}
+	case *insExtendVolumeCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			eve := extendVolumeError{nil, payloads.ExtendVolumeNoInstance}
+			eve.send(conn, cmd.instance, insCmd.volumeUUID)
+			return
+		}
+	case *insConsoleCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			ce := consoleError{nil, payloads.ConsoleNoInstance}
+			ce.send(conn, cmd.instance, insCmd.frame)
+			return
+		}
 	default:
 		target = insCmdChannel(cmd.instance, ovsCh)
 	}
@@ -289,6 +371,11 @@ func loadClusterConfig(conn serverConn) error {
 	if cephID == "" {
 		cephID = clusterConfig.Configure.Storage.CephID
 	}
+	storageDriverName = clusterConfig.Configure.Storage.Driver
+	localStoragePath = clusterConfig.Configure.Storage.LocalPath
+	if localStoragePath == "" {
+		localStoragePath = storage.DefaultLocalPath
+	}
 
 	if err := netConfig.Save(); err != nil {
 		glog.Warningf("Unable to save networking config: %v", err)
@@ -304,7 +391,11 @@ func printClusterConfig() {
 	glog.Infof("Management Network:   %v", netConfig.MgmtNet)
 	glog.Infof("Disk Limit:           %v", diskLimit)
 	glog.Infof("Memory Limit:         %v", memLimit)
-	glog.Infof("Ceph ID:              %v", cephID)
+	if storageDriverName == payloads.LocalStorage {
+		glog.Infof("Volume Path:          %v", localStoragePath)
+	} else {
+		glog.Infof("Ceph ID:              %v", cephID)
+	}
 }
 
 func connectToServer(doneCh chan struct{}, statusCh chan struct{}) {
@@ -315,8 +406,9 @@ func connectToServer(doneCh chan struct{}, statusCh chan struct{}) {
 
 	var wg sync.WaitGroup
 
-	cfg := &ssntp.Config{CAcert: serverCertPath, Cert: clientCertPath,
-		Log: ssntp.Log}
+	serverURLs := strings.Split(serverURL, ",")
+	cfg := &ssntp.Config{URI: serverURLs[0], URIs: serverURLs[1:],
+		CAcert: serverCertPath, Cert: clientCertPath, Log: ssntp.Log}
 	client := &agentClient{
 		conn:  &ssntpConn{},
 		cmdCh: make(chan *cmdWrapper),
@@ -493,6 +585,10 @@ func startLauncher() int {
 	timeoutCh := make(chan struct{})
 	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
+	if metricsAddr != "" {
+		go serveMetrics(metricsAddr)
+	}
+
 	go connectToServer(doneCh, statusCh)
 
 DONE:
@@ -559,6 +655,12 @@ func main() {
 			glog.Fatalf("Unable to create mandatory dirs: %v", err)
 		}
 
+		if enrollURL != "" {
+			if err := enrollNode(); err != nil {
+				glog.Fatalf("Unable to enroll node: %v", err)
+			}
+		}
+
 		exitCode = startLauncher()
 	}
 
@@ -0,0 +1,140 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/ciao-project/ciao/metrics"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/golang/glog"
+)
+
+var commandSuccesses = metrics.NewCounterVec("ciao_launcher_command_successes_total",
+	"Number of SSNTP commands successfully processed by the launcher", "command")
+
+var commandFailures = metrics.NewCounterVec("ciao_launcher_command_failures_total",
+	"Number of SSNTP commands the launcher failed to process", "command", "reason")
+
+/*
+The overseer owns the node and instance statistics and cannot be queried from
+the HTTP server go routines, as the overseer might be gone by the time the
+query is sent. Instead it publishes a snapshot of these metrics every time it
+computes the node statistics.
+*/
+var overseerMetrics struct {
+	sync.Mutex
+	families []metrics.Family
+}
+
+func (ovs *overseer) updateMetrics(cns *cnStats, status ssntp.Status) {
+	node := ovs.ac.conn.UUID()
+	labels := metrics.Labels{"node": node}
+
+	ready := metrics.NewFamily("ciao_launcher_node_ready",
+		"Whether the node is ready to accept workloads", metrics.Gauge)
+	memTotal := metrics.NewFamily("ciao_launcher_node_memory_total_megabytes",
+		"Total memory of the node", metrics.Gauge)
+	memAvailable := metrics.NewFamily("ciao_launcher_node_memory_available_megabytes",
+		"Memory available for new instances on the node", metrics.Gauge)
+	diskTotal := metrics.NewFamily("ciao_launcher_node_disk_total_megabytes",
+		"Total disk space of the node", metrics.Gauge)
+	diskAvailable := metrics.NewFamily("ciao_launcher_node_disk_available_megabytes",
+		"Disk space available for new instances on the node", metrics.Gauge)
+	load := metrics.NewFamily("ciao_launcher_node_load",
+		"Load average of the node", metrics.Gauge)
+	cpusOnline := metrics.NewFamily("ciao_launcher_node_cpus_online",
+		"Number of online CPUs of the node", metrics.Gauge)
+	vcpusAllocated := metrics.NewFamily("ciao_launcher_node_vcpus_allocated",
+		"Number of VCPUs allocated to instances on the node", metrics.Gauge)
+	instances := metrics.NewFamily("ciao_launcher_node_instances",
+		"Number of instances on the node", metrics.Gauge)
+
+	readyValue := 0.0
+	if status == ssntp.READY {
+		readyValue = 1
+	}
+
+	ready.Add(readyValue, labels)
+	memTotal.Add(float64(cns.totalMemMB), labels)
+	memAvailable.Add(float64(ovs.memoryAvailable), labels)
+	diskTotal.Add(float64(cns.totalDiskMB), labels)
+	diskAvailable.Add(float64(ovs.diskSpaceAvailable), labels)
+	load.Add(float64(cns.load), labels)
+	cpusOnline.Add(float64(cns.cpusOnline), labels)
+	vcpusAllocated.Add(float64(ovs.vcpusAllocated), labels)
+	instances.Add(float64(len(ovs.instances)), labels)
+
+	cpuUsage := metrics.NewFamily("ciao_launcher_instance_cpu_usage_percent",
+		"CPU usage of the instance", metrics.Gauge)
+	memUsage := metrics.NewFamily("ciao_launcher_instance_memory_usage_megabytes",
+		"Memory used by the instance", metrics.Gauge)
+	diskUsage := metrics.NewFamily("ciao_launcher_instance_disk_usage_megabytes",
+		"Disk space used by the instance", metrics.Gauge)
+
+	uuids := make([]string, 0, len(ovs.instances))
+	for uuid := range ovs.instances {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	for _, uuid := range uuids {
+		state := ovs.instances[uuid]
+		labels := metrics.Labels{"node": node, "instance": uuid}
+
+		// -1 means that no statistics were received yet.
+		if state.CPUUsage != -1 {
+			cpuUsage.Add(float64(state.CPUUsage), labels)
+		}
+		if state.memoryUsageMB != -1 {
+			memUsage.Add(float64(state.memoryUsageMB), labels)
+		}
+		if state.diskUsageMB != -1 {
+			diskUsage.Add(float64(state.diskUsageMB), labels)
+		}
+	}
+
+	families := []metrics.Family{*ready, *memTotal, *memAvailable, *diskTotal,
+		*diskAvailable, *load, *cpusOnline, *vcpusAllocated, *instances,
+		*cpuUsage, *memUsage, *diskUsage}
+
+	overseerMetrics.Lock()
+	overseerMetrics.families = families
+	overseerMetrics.Unlock()
+}
+
+func collectMetrics() []metrics.Family {
+	overseerMetrics.Lock()
+	families := append([]metrics.Family(nil), overseerMetrics.families...)
+	overseerMetrics.Unlock()
+
+	return append(families, commandSuccesses.Family(), commandFailures.Family())
+}
+
+func serveMetrics(addr string) {
+	glog.Infof("Serving metrics on http://%s/metrics", addr)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(collectMetrics))
+
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		glog.Errorf("Unable to serve metrics: %v", err)
+	}
+}
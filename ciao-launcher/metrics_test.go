@@ -0,0 +1,86 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ciao-project/ciao/metrics"
+	"github.com/ciao-project/ciao/payloads"
+)
+
+// Check the launcher metrics
+//
+// Start the overseer with a stats interval of 300ms and wait for a stats
+// command.  Report a START failure.  Collect the metrics.
+//
+// The node metrics published by the overseer and the START failure should
+// be present in the collected metrics.
+func TestCollectMetrics(t *testing.T) {
+	diskLimit = false
+
+	instancesDir, err := ioutil.TempDir("", "overseer-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory")
+	}
+	defer func() { _ = os.RemoveAll(instancesDir) }()
+
+	var wg sync.WaitGroup
+	state := &overseerTestState{
+		t:       t,
+		statsCh: make(chan *payloads.Stat),
+	}
+	state.ac = &agentClient{conn: state, cmdCh: make(chan *cmdWrapper)}
+
+	ovsCh := startOverseerFull(instancesDir, &wg, state.ac, time.Millisecond*300,
+		fakeDeviceInfo{})
+
+	select {
+	case <-state.statsCh:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for Stats")
+	}
+
+	shutdownOverseer(ovsCh, state)
+	wg.Wait()
+
+	se := &startError{nil, payloads.LaunchFailure, false}
+	se.send(state, "")
+
+	var b bytes.Buffer
+	err = metrics.Write(&b, collectMetrics())
+	if err != nil {
+		t.Fatalf("Unable to write metrics: %v", err)
+	}
+
+	expected := []string{
+		`ciao_launcher_node_ready{node="test-uuid"} 1`,
+		`ciao_launcher_node_instances{node="test-uuid"} 0`,
+		`ciao_launcher_command_failures_total{command="START",reason="launch_failure"}`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(b.String(), e) {
+			t.Errorf("Missing %s in metrics:\n%s", e, b.String())
+		}
+	}
+}
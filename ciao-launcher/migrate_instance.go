@@ -0,0 +1,204 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/golang/glog"
+)
+
+const (
+	migrationTimeout    = 10 * time.Minute
+	migrationPollPeriod = 500 * time.Millisecond
+
+	// The target of a migration is started before the source is asked
+	// to migrate, so it waits a little longer than the source before
+	// giving up.
+	incomingMigrationTimeout = migrationTimeout + time.Minute
+)
+
+func migrationURI(ipAddress string, port int) string {
+	return fmt.Sprintf("tcp:%s:%d", ipAddress, port)
+}
+
+func (id *instanceData) migrating() bool {
+	return id.migration != nil || id.cfg.MigrationPort != 0
+}
+
+func (id *instanceData) sendMigrationTargetReadyEvent() {
+	var event payloads.EventMigrationTargetReady
+
+	event.MigrationTargetReady.InstanceUUID = id.instance
+	event.MigrationTargetReady.NodeUUID = id.ac.conn.UUID()
+	event.MigrationTargetReady.URI = migrationURI(getNodeIPAddress(), id.cfg.MigrationPort)
+
+	payload, err := yaml.Marshal(&event)
+	if err != nil {
+		glog.Errorf("Unable to Marshall MigrationTargetReady %v", err)
+		return
+	}
+	_, err = id.ac.conn.SendEvent(ssntp.MigrationTargetReady, payload)
+	if err != nil {
+		glog.Errorf("Failed to send event command %v", err)
+		return
+	}
+}
+
+func (id *instanceData) sendInstanceMigratedEvent(target string) {
+	var event payloads.EventInstanceMigrated
+
+	event.InstanceMigrated.InstanceUUID = id.instance
+	event.InstanceMigrated.NodeUUID = id.ac.conn.UUID()
+	event.InstanceMigrated.TargetNodeUUID = target
+
+	payload, err := yaml.Marshal(&event)
+	if err != nil {
+		glog.Errorf("Unable to Marshall InstanceMigrated %v", err)
+		return
+	}
+	_, err = id.ac.conn.SendEvent(ssntp.InstanceMigrated, payload)
+	if err != nil {
+		glog.Errorf("Failed to send event command %v", err)
+		return
+	}
+}
+
+// waitForIncomingMigration is called once the VM of a migration target has
+// been launched.  The VM does not run until it has received the state of
+// the instance from the source node.
+
+func (id *instanceData) waitForIncomingMigration() {
+	glog.Infof("Instance %s waiting for incoming migration from %s", id.instance,
+		id.cfg.MigrationSource)
+	id.ovsCh <- &ovsStateChange{id.instance, ovsMigrating}
+	id.migrationTimer = time.After(incomingMigrationTimeout)
+	id.sendMigrationTargetReadyEvent()
+}
+
+func (id *instanceData) incomingMigrationDone() {
+	glog.Infof("Instance %s migrated from %s", id.instance, id.cfg.MigrationSource)
+
+	migrationPortGrabber.releasePort(id.cfg.MigrationPort)
+	id.migrationTimer = nil
+	id.cfg.MigrationPort = 0
+	id.cfg.MigrationSource = ""
+	if err := id.cfg.save(id.instanceDir); err != nil {
+		glog.Warningf("Unable to persist state of %s: %v", id.instance, err)
+	}
+}
+
+func (id *instanceData) incomingMigrationTimeout() {
+	id.migrationTimer = nil
+	if id.shuttingDown || id.cfg.MigrationPort == 0 {
+		return
+	}
+
+	err := fmt.Errorf("Timed out waiting for migration of %s", id.instance)
+	glog.Error(err)
+	me := &migrateError{err, payloads.MigrateTimeout}
+	me.send(id.ac.conn, id.instance)
+
+	killMe(id.instance, true, false, id.doneCh, id.ac, &id.instanceWg)
+	id.shuttingDown = true
+}
+
+// lostMigratedVM is called when the VM of an instance that has been migrated
+// away from this node, or that failed to migrate to this node, stops.  The
+// instance is deleted without notifying controller, which already knows
+// where the instance now runs.
+
+func (id *instanceData) lostMigratedVM() {
+	if !id.migrated {
+		err := fmt.Errorf("Incoming migration of %s failed", id.instance)
+		glog.Error(err)
+		me := &migrateError{err, payloads.MigrateTransferFailure}
+		me.send(id.ac.conn, id.instance)
+	}
+
+	glog.Infof("Removing instance %s from node", id.instance)
+	killMe(id.instance, true, false, id.doneCh, id.ac, &id.instanceWg)
+	id.shuttingDown = true
+}
+
+func (id *instanceData) startMigration(cmd *insMigrateCmd) *migrateError {
+	if id.cfg.Container {
+		err := fmt.Errorf("Containers cannot be migrated")
+		return &migrateError{err, payloads.MigrateNotSupported}
+	}
+
+	if !id.running() || id.paused {
+		err := fmt.Errorf("Instance %s is not running", id.instance)
+		return &migrateError{err, payloads.MigrateInvalidState}
+	}
+
+	// The migration is carried out asynchronously by the monitor go
+	// routine so that the instance go routine can continue to process
+	// commands and to report statistics while it is in progress.
+
+	id.migration = cmd
+	id.migrationCh = make(chan error, 1)
+	id.monitorCh <- virtualizerMigrateCmd{id.migrationCh, cmd.uri}
+	id.ovsCh <- &ovsStateChange{id.instance, ovsMigrating}
+
+	glog.Infof("Migrating instance %s to %s", id.instance, cmd.target)
+	return nil
+}
+
+func (id *instanceData) migrateCommand(cmd *insMigrateCmd) {
+	migrateErr := id.startMigration(cmd)
+	if migrateErr != nil {
+		glog.Errorf("Unable to migrate instance %s [%s]: %v", id.instance,
+			string(migrateErr.code), migrateErr.err)
+		migrateErr.send(id.ac.conn, id.instance)
+	}
+}
+
+func (id *instanceData) migrationDone(err error) {
+	cmd := id.migration
+	id.migration = nil
+	id.migrationCh = nil
+
+	if err != nil {
+		var code payloads.MigrateFailureReason = payloads.MigrateTransferFailure
+		if err == errNotSupported {
+			code = payloads.MigrateNotSupported
+		}
+		glog.Errorf("Unable to migrate instance %s [%s]: %v", id.instance,
+			string(code), err)
+		me := &migrateError{err, code}
+		me.send(id.ac.conn, id.instance)
+		if id.monitorCh != nil {
+			id.ovsCh <- &ovsStateChange{id.instance, ovsRunning}
+		}
+		return
+	}
+
+	commandSuccesses.Inc(ssntp.MIGRATE.String())
+	glog.Infof("Instance %s migrated to %s", id.instance, cmd.target)
+
+	// The VM will quit shortly.  When it does, lostVM removes the
+	// instance from this node.
+
+	id.migrated = true
+	id.sendInstanceMigratedEvent(cmd.target)
+}
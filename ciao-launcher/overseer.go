@@ -131,6 +131,9 @@ const (
 	ovsPending ovsRunningState = iota
 	ovsRunning
 	ovsStopped
+	ovsPaused
+	ovsSuspended
+	ovsMigrating
 )
 
 const (
@@ -275,13 +278,15 @@ func (ovs *overseer) sendReadyStatusCommand(cns *cnStats) {
 	s.MemTotalMB, s.MemAvailableMB = cns.totalMemMB, cns.availableMemMB
 	s.Load = cns.load
 	s.CpusOnline = cns.cpusOnline
+	s.VCPUsAllocated = ovs.vcpusAllocated
 	s.DiskTotalMB, s.DiskAvailableMB = cns.totalDiskMB, cns.availableDiskMB
 	s.Networks = make([]payloads.NetworkStat, len(nicInfo))
 	for i, nic := range nicInfo {
 		s.Networks[i] = *nic
 	}
+	s.Labels = nodeLabels
 
-	payload, err := yaml.Marshal(&s)
+	payload, err := payloads.Marshal(ovs.ac.conn.PayloadEncoding(), &s)
 	if err != nil {
 		glog.Errorf("Unable to Marshall status payload %v", err)
 		return
@@ -327,15 +332,23 @@ func (ovs *overseer) sendStats(cns *cnStats, status ssntp.Status) {
 	for i, nic := range nicInfo {
 		s.Networks[i] = *nic
 	}
+	s.Labels = nodeLabels
 	s.Instances = make([]payloads.InstanceStat, len(ovs.instances))
 	i := 0
 	for uuid, state := range ovs.instances {
 		s.Instances[i].InstanceUUID = uuid
-		if state.running == ovsRunning {
+		switch state.running {
+		case ovsRunning:
 			s.Instances[i].State = payloads.Running
-		} else if state.running == ovsStopped {
+		case ovsStopped:
 			s.Instances[i].State = payloads.Exited
-		} else {
+		case ovsPaused:
+			s.Instances[i].State = payloads.Paused
+		case ovsSuspended:
+			s.Instances[i].State = payloads.Suspended
+		case ovsMigrating:
+			s.Instances[i].State = payloads.Migrating
+		default:
 			s.Instances[i].State = payloads.Pending
 		}
 		s.Instances[i].MemoryUsageMB = state.memoryUsageMB
@@ -347,7 +360,7 @@ func (ovs *overseer) sendStats(cns *cnStats, status ssntp.Status) {
 		i++
 	}
 
-	payload, err := yaml.Marshal(&s)
+	payload, err := payloads.Marshal(ovs.ac.conn.PayloadEncoding(), &s)
 	if err != nil {
 		glog.Errorf("Unable to Marshall STATS %v", err)
 		return
@@ -622,6 +635,7 @@ DONE:
 			cns := getStats(ovs.instancesDir)
 			ovs.updateAvailableResources(cns)
 			status := ovs.computeStatus()
+			ovs.updateMetrics(cns, status)
 			ovs.sendStatusCommand(cns, status)
 			ovs.sendStats(cns, status)
 			ovs.sendTraceReport()
@@ -25,7 +25,6 @@ import (
 	"testing"
 	"time"
 
-	"gopkg.in/yaml.v2"
 
 	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/ssntp"
@@ -69,6 +68,14 @@ func (v *overseerTestState) SendEvent(event ssntp.Event, payload []byte) (int, e
 	return 0, nil
 }
 
+func (v *overseerTestState) SendErrorReply(frame *ssntp.Frame, error ssntp.Error, payload []byte) (int, error) {
+	return v.SendError(error, payload)
+}
+
+func (v *overseerTestState) SendEventReply(frame *ssntp.Frame, event ssntp.Event, payload []byte) (int, error) {
+	return v.SendEvent(event, payload)
+}
+
 func (v *overseerTestState) Dial(config *ssntp.Config, ntf ssntp.ClientNotifier) error {
 	return nil
 }
@@ -80,7 +87,7 @@ func (v *overseerTestState) SendStatus(status ssntp.Status, payload []byte) (int
 	switch status {
 	case ssntp.READY:
 		ready := &payloads.Ready{}
-		err := yaml.Unmarshal(payload, ready)
+		err := payloads.Unmarshal(payload, ready)
 		if err != nil {
 			v.t.Errorf("Failed to unmarshall READY status %v", err)
 		}
@@ -102,7 +109,7 @@ func (v *overseerTestState) SendCommand(cmd ssntp.Command, payload []byte) (int,
 			return 0, nil
 		}
 		stats := &payloads.Stat{}
-		err := yaml.Unmarshal(payload, stats)
+		err := payloads.Unmarshal(payload, stats)
 		if err != nil {
 			v.t.Errorf("Failed to unmarshall Stats %v", err)
 		}
@@ -136,6 +143,10 @@ func (v *overseerTestState) ClusterConfiguration() (payloads.Configure, error) {
 	return payloads.Configure{}, nil
 }
 
+func (v *overseerTestState) PayloadEncoding() payloads.Encoding {
+	return payloads.Gob
+}
+
 func shutdownOverseer(ovsCh chan<- interface{}, state *overseerTestState) {
 	close(ovsCh)
 
@@ -26,6 +26,7 @@ import (
 
 	"github.com/ciao-project/ciao/networking/libsnnet"
 	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
 	"github.com/golang/glog"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -118,7 +119,7 @@ func parseVMTtype(start *payloads.StartCmd) (bool, error) {
 func parseStartPayload(data []byte) (*vmConfig, *payloadError) {
 	var clouddata payloads.Start
 
-	err := yaml.Unmarshal(data, &clouddata)
+	err := payloads.Unmarshal(data, &clouddata)
 	if err != nil {
 		return nil, &payloadError{err, payloads.InvalidPayload}
 	}
@@ -146,6 +147,12 @@ func parseStartPayload(data []byte) (*vmConfig, *payloadError) {
 		return nil, &payloadError{err, payloads.InvalidData}
 	}
 
+	migrationSource := strings.TrimSpace(start.MigrationSource)
+	if container && migrationSource != "" {
+		err = fmt.Errorf("Containers cannot be live migrated")
+		return nil, &payloadError{err, payloads.InvalidData}
+	}
+
 	for i := range start.RequestedResources {
 		switch start.RequestedResources[i].Type {
 		case payloads.VCPUs:
@@ -192,6 +199,8 @@ func parseStartPayload(data []byte) (*vmConfig, *payloadError) {
 		SSHPort:     sshPort,
 		Volumes:     volumes,
 		Restart:     clouddata.Start.Restart,
+
+		MigrationSource: migrationSource,
 	}, nil
 }
 
@@ -224,6 +233,53 @@ func generateAttachVolumeError(node, instance, volume string, ave *attachVolumeE
 	return yaml.Marshal(avf)
 }
 
+func generateDetachVolumeError(node, instance, volume string, dve *detachVolumeError) (out []byte, err error) {
+	dvf := &payloads.ErrorDetachVolumeFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		VolumeUUID:   volume,
+		Reason:       dve.code,
+	}
+	return yaml.Marshal(dvf)
+}
+
+func generateExtendVolumeError(node, instance, volume string, eve *extendVolumeError) (out []byte, err error) {
+	evf := &payloads.ErrorExtendVolumeFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		VolumeUUID:   volume,
+		Reason:       eve.code,
+	}
+	return yaml.Marshal(evf)
+}
+
+func generatePowerError(node, instance string, pe *powerError) (out []byte, err error) {
+	pf := &payloads.ErrorPowerFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		Reason:       pe.code,
+	}
+	return yaml.Marshal(pf)
+}
+
+func generateMigrateError(node, instance string, me *migrateError) (out []byte, err error) {
+	mf := &payloads.ErrorMigrateFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		Reason:       me.code,
+	}
+	return yaml.Marshal(mf)
+}
+
+func generateConsoleError(node, instance string, ce *consoleError) (out []byte, err error) {
+	cf := &payloads.ErrorConsoleFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		Reason:       ce.code,
+	}
+	return yaml.Marshal(cf)
+}
+
 func generateNetEventPayload(ssntpEvent *libsnnet.SsntpEventInfo, agentUUID string) ([]byte, error) {
 	var event interface{}
 	var eventData *payloads.TenantAddedEvent
@@ -296,6 +352,167 @@ func parseAttachVolumePayload(data []byte) (string, string, *payloadError) {
 	return extractVolumeInfo(&clouddata.Attach, payloads.AttachVolumeInvalidData)
 }
 
+func parseDetachVolumePayload(data []byte) (string, string, *payloadError) {
+	var clouddata payloads.DetachVolume
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", "", &payloadError{err, payloads.DetachVolumeInvalidPayload}
+	}
+
+	return extractVolumeInfo(&clouddata.Detach, payloads.DetachVolumeInvalidData)
+}
+
+func parseExtendVolumePayload(data []byte) (string, *insExtendVolumeCmd, *payloadError) {
+	var clouddata payloads.ExtendVolume
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", nil, &payloadError{err, payloads.ExtendVolumeInvalidPayload}
+	}
+
+	volumeCmd := payloads.VolumeCmd{
+		InstanceUUID: clouddata.Extend.InstanceUUID,
+		VolumeUUID:   clouddata.Extend.VolumeUUID,
+	}
+	instance, volume, payloadErr := extractVolumeInfo(&volumeCmd, payloads.ExtendVolumeInvalidData)
+	if payloadErr != nil {
+		return "", nil, payloadErr
+	}
+
+	if clouddata.Extend.SizeGiB <= 0 {
+		err = fmt.Errorf("Invalid volume size received: %d", clouddata.Extend.SizeGiB)
+		return "", nil, &payloadError{err, payloads.ExtendVolumeInvalidData}
+	}
+
+	return instance, &insExtendVolumeCmd{volume, clouddata.Extend.SizeGiB}, nil
+}
+
+func extractPowerInstance(instance string) (string, *payloadError) {
+	instance = strings.TrimSpace(instance)
+	if !uuidRegexp.MatchString(instance) {
+		err := fmt.Errorf("Invalid instance id received: %s", instance)
+		return "", &payloadError{err, payloads.PowerInvalidData}
+	}
+	return instance, nil
+}
+
+func parseRebootPayload(data []byte) (string, bool, *payloadError) {
+	var clouddata payloads.Reboot
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", false, &payloadError{err, payloads.PowerInvalidPayload}
+	}
+
+	instance, payloadErr := extractPowerInstance(clouddata.Reboot.InstanceUUID)
+	if payloadErr != nil {
+		return "", false, payloadErr
+	}
+	return instance, clouddata.Reboot.Hard, nil
+}
+
+func parsePowerPayload(cmd ssntp.Command, data []byte) (string, *payloadError) {
+	var err error
+	var powerCmd *payloads.PowerCmd
+
+	switch cmd {
+	case ssntp.PAUSE:
+		var clouddata payloads.Pause
+		err = yaml.Unmarshal(data, &clouddata)
+		powerCmd = &clouddata.Pause
+	case ssntp.UNPAUSE:
+		var clouddata payloads.Unpause
+		err = yaml.Unmarshal(data, &clouddata)
+		powerCmd = &clouddata.Unpause
+	case ssntp.SUSPEND:
+		var clouddata payloads.Suspend
+		err = yaml.Unmarshal(data, &clouddata)
+		powerCmd = &clouddata.Suspend
+	case ssntp.RESUME:
+		var clouddata payloads.Resume
+		err = yaml.Unmarshal(data, &clouddata)
+		powerCmd = &clouddata.Resume
+	default:
+		err = fmt.Errorf("Unexpected power command %s", cmd)
+	}
+
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", &payloadError{err, payloads.PowerInvalidPayload}
+	}
+
+	return extractPowerInstance(powerCmd.InstanceUUID)
+}
+
+func parseMigratePayload(data []byte) (string, string, string, *payloadError) {
+	var clouddata payloads.Migrate
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", "", "", &payloadError{err, payloads.MigrateInvalidPayload}
+	}
+
+	instance := strings.TrimSpace(clouddata.Migrate.InstanceUUID)
+	if !uuidRegexp.MatchString(instance) {
+		err = fmt.Errorf("Invalid instance id received: %s", instance)
+		return "", "", "", &payloadError{err, payloads.MigrateInvalidData}
+	}
+
+	target := strings.TrimSpace(clouddata.Migrate.TargetAgentUUID)
+	if target == "" {
+		err = fmt.Errorf("Missing migration target for instance %s", instance)
+		return "", "", "", &payloadError{err, payloads.MigrateInvalidData}
+	}
+
+	uri := strings.TrimSpace(clouddata.Migrate.URI)
+	if !strings.HasPrefix(uri, "tcp:") {
+		err = fmt.Errorf("Invalid migration URI received: %s", uri)
+		return "", "", "", &payloadError{err, payloads.MigrateInvalidData}
+	}
+
+	return instance, target, uri, nil
+}
+
+func parseConsolePayload(data []byte) (string, *insConsoleCmd, *payloadError) {
+	var clouddata payloads.Console
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", nil, &payloadError{err, payloads.ConsoleInvalidPayload}
+	}
+
+	instance := strings.TrimSpace(clouddata.Console.InstanceUUID)
+	if !uuidRegexp.MatchString(instance) {
+		err = fmt.Errorf("Invalid instance id received: %s", instance)
+		return "", nil, &payloadError{err, payloads.ConsoleInvalidData}
+	}
+
+	cmd := &insConsoleCmd{
+		consoleType: clouddata.Console.Type,
+		lines:       clouddata.Console.Lines,
+	}
+
+	switch cmd.consoleType {
+	case payloads.ConsoleTypeLog, payloads.ConsoleTypeInteractive:
+	default:
+		err = fmt.Errorf("Invalid console type received: %s", cmd.consoleType)
+		return "", nil, &payloadError{err, payloads.ConsoleInvalidData}
+	}
+
+	if cmd.lines < 0 {
+		err = fmt.Errorf("Invalid number of console lines received: %d", cmd.lines)
+		return "", nil, &payloadError{err, payloads.ConsoleInvalidData}
+	}
+
+	return instance, cmd, nil
+}
+
 func linesToBytes(doc []string, buf *bytes.Buffer) {
 	for _, line := range doc {
 		_, _ = buf.WriteString(line)
@@ -18,12 +18,14 @@ package main
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/ciao-project/ciao/networking/libsnnet"
 	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
 	"github.com/ciao-project/ciao/testutil"
 )
 
@@ -181,6 +183,152 @@ func TestParseAttachVolumePayload(t *testing.T) {
 	}
 }
 
+// Verify the parseDetachVolumePayload function.
+//
+// The function is passed one valid payload and two invalid payloads.
+//
+// No error should be returned for the valid payload and the returned instance
+// and volume UUIDs should match what is in the payload.  Errors should be
+// returned for the invalid payloads.
+func TestParseDetachVolumePayload(t *testing.T) {
+	instance, volume, err := parseDetachVolumePayload([]byte(testutil.DetachVolumeYaml))
+	if err != nil {
+		t.Fatalf("parseDetachVolumePayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID || volume != testutil.VolumeUUID {
+		t.Fatalf("VolumeUUID or InstanceUUID is invalid")
+	}
+
+	_, _, err = parseDetachVolumePayload([]byte("  -"))
+	if err == nil || err.code != payloads.DetachVolumeInvalidPayload {
+		t.Fatalf("DetachVolumeInvalidPayload error expected")
+	}
+
+	_, _, err = parseDetachVolumePayload([]byte(testutil.BadDetachVolumeYaml))
+	if err == nil || err.code != payloads.DetachVolumeInvalidData {
+		t.Fatalf("DetachVolumeInvalidData error expected")
+	}
+}
+
+// Verify the parseExtendVolumePayload function.
+//
+// The function is passed one valid payload and three invalid payloads.
+//
+// No error should be returned for the valid payload and the returned instance,
+// volume UUID and size should match what is in the payload.  Errors should be
+// returned for the invalid payloads.
+func TestParseExtendVolumePayload(t *testing.T) {
+	instance, cmd, err := parseExtendVolumePayload([]byte(testutil.ExtendVolumeYaml))
+	if err != nil {
+		t.Fatalf("parseExtendVolumePayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID || cmd.volumeUUID != testutil.VolumeUUID {
+		t.Fatalf("VolumeUUID or InstanceUUID is invalid")
+	}
+	if cmd.sizeGiB != 20 {
+		t.Fatalf("Unexpected size %d", cmd.sizeGiB)
+	}
+
+	_, _, err = parseExtendVolumePayload([]byte("  -"))
+	if err == nil || err.code != payloads.ExtendVolumeInvalidPayload {
+		t.Fatalf("ExtendVolumeInvalidPayload error expected")
+	}
+
+	_, _, err = parseExtendVolumePayload([]byte(testutil.BadAttachVolumeYaml))
+	if err == nil || err.code != payloads.ExtendVolumeInvalidData {
+		t.Fatalf("ExtendVolumeInvalidData error expected")
+	}
+
+	noSize := strings.Replace(testutil.ExtendVolumeYaml, "size_gib: 20", "size_gib: 0", 1)
+	_, _, err = parseExtendVolumePayload([]byte(noSize))
+	if err == nil || err.code != payloads.ExtendVolumeInvalidData {
+		t.Fatalf("ExtendVolumeInvalidData error expected for invalid size")
+	}
+}
+
+// Verify the parseRebootPayload and parsePowerPayload functions.
+//
+// The functions are passed valid payloads and an invalid payload.
+//
+// No error should be returned for the valid payloads and the returned instance
+// UUIDs and reboot type should match what is in the payloads.  An error should
+// be returned for the invalid payload.
+func TestParsePowerPayload(t *testing.T) {
+	instance, hard, err := parseRebootPayload([]byte(testutil.RebootYaml))
+	if err != nil {
+		t.Fatalf("parseRebootPayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID || !hard {
+		t.Fatalf("InstanceUUID or hard flag is invalid")
+	}
+
+	instance, err = parsePowerPayload(ssntp.SUSPEND, []byte(testutil.SuspendYaml))
+	if err != nil {
+		t.Fatalf("parsePowerPayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID {
+		t.Fatalf("InstanceUUID is invalid")
+	}
+
+	_, err = parsePowerPayload(ssntp.PAUSE, []byte(testutil.SuspendYaml))
+	if err == nil || err.code != payloads.PowerInvalidData {
+		t.Fatalf("PowerInvalidData error expected")
+	}
+
+	_, err = parsePowerPayload(ssntp.PAUSE, []byte("  -"))
+	if err == nil || err.code != payloads.PowerInvalidPayload {
+		t.Fatalf("PowerInvalidPayload error expected")
+	}
+}
+
+func TestParseMigratePayload(t *testing.T) {
+	instance, target, uri, err := parseMigratePayload([]byte(testutil.LiveMigrateYaml))
+	if err != nil {
+		t.Fatalf("parseMigratePayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID || target != testutil.NetAgentUUID ||
+		uri != testutil.MigrateURI {
+		t.Fatalf("InstanceUUID, target or URI is invalid")
+	}
+
+	_, _, _, err = parseMigratePayload([]byte(testutil.PauseYaml))
+	if err == nil || err.code != payloads.MigrateInvalidData {
+		t.Fatalf("MigrateInvalidData error expected")
+	}
+
+	_, _, _, err = parseMigratePayload([]byte("  -"))
+	if err == nil || err.code != payloads.MigrateInvalidPayload {
+		t.Fatalf("MigrateInvalidPayload error expected")
+	}
+}
+
+func TestParseConsolePayload(t *testing.T) {
+	instance, cmd, err := parseConsolePayload([]byte(testutil.ConsoleLogCmdYaml))
+	if err != nil {
+		t.Fatalf("parseConsolePayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID ||
+		cmd.consoleType != payloads.ConsoleTypeLog || cmd.lines != 20 {
+		t.Fatalf("InstanceUUID, type or lines is invalid")
+	}
+
+	_, _, err = parseConsolePayload([]byte(testutil.PauseYaml))
+	if err == nil || err.code != payloads.ConsoleInvalidData {
+		t.Fatalf("ConsoleInvalidData error expected")
+	}
+
+	badType := strings.Replace(testutil.ConsoleLogCmdYaml, "type: log", "type: vnc", 1)
+	_, _, err = parseConsolePayload([]byte(badType))
+	if err == nil || err.code != payloads.ConsoleInvalidData {
+		t.Fatalf("ConsoleInvalidData error expected")
+	}
+
+	_, _, err = parseConsolePayload([]byte("  -"))
+	if err == nil || err.code != payloads.ConsoleInvalidPayload {
+		t.Fatalf("ConsoleInvalidPayload error expected")
+	}
+}
+
 // Verify the parseStartPayload function.
 //
 // The function is passed one valid payload and a number of invalid payloads.
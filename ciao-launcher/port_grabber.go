@@ -27,6 +27,13 @@ const (
 	portGrabberMax   = 6900
 )
 
+// Incoming live migrations are received on ports taken from the dynamic
+// range so that they cannot clash with the spice and netcat ports.
+const (
+	migrationPortStart = 49152
+	migrationPortMax   = 49216
+)
+
 /*
 Just because a port is in the free map doesn't mean it's free.  It could be
 used by some other qemu process or otherwise that is not managed by launcher.
@@ -43,16 +50,24 @@ a port was in use or not, a la libvirt, but there'd still be a race condition.
 
 type portGrabber struct {
 	sync.Mutex
-	free map[int]struct{}
+	start int
+	max   int
+	free  map[int]struct{}
 }
 
-var uiPortGrabber = portGrabber{}
+var uiPortGrabber = newPortGrabber(portGrabberStart, portGrabberMax)
+var migrationPortGrabber = newPortGrabber(migrationPortStart, migrationPortMax)
 
-func init() {
-	uiPortGrabber.free = make(map[int]struct{})
-	for i := portGrabberStart; i < portGrabberMax; i++ {
-		uiPortGrabber.free[i] = struct{}{}
+func newPortGrabber(start, max int) *portGrabber {
+	pg := &portGrabber{
+		start: start,
+		max:   max,
+		free:  make(map[int]struct{}),
+	}
+	for i := start; i < max; i++ {
+		pg.free[i] = struct{}{}
 	}
+	return pg
 }
 
 func (pg *portGrabber) grabPort() int {
@@ -77,7 +92,7 @@ func (pg *portGrabber) grabPort() int {
 func (pg *portGrabber) releasePort(port int) {
 	glog.Infof("Releasing port: %d", port)
 
-	if port < portGrabberStart || port >= portGrabberMax {
+	if port < pg.start || port >= pg.max {
 		glog.Warningf("Unable to release invalid port number %d", port)
 		return
 	}
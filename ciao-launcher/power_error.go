@@ -0,0 +1,55 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/golang/glog"
+)
+
+var powerFailures = map[ssntp.Command]ssntp.Error{
+	ssntp.REBOOT:  ssntp.RebootFailure,
+	ssntp.PAUSE:   ssntp.PauseFailure,
+	ssntp.UNPAUSE: ssntp.UnpauseFailure,
+	ssntp.SUSPEND: ssntp.SuspendFailure,
+	ssntp.RESUME:  ssntp.ResumeFailure,
+}
+
+type powerError struct {
+	err  error
+	code payloads.PowerFailureReason
+}
+
+func (pe *powerError) send(conn serverConn, cmd ssntp.Command, instance string) {
+	commandFailures.Inc(cmd.String(), string(pe.code))
+
+	if !conn.isConnected() {
+		return
+	}
+
+	payload, err := generatePowerError(conn.UUID(), instance, pe)
+	if err != nil {
+		glog.Errorf("Unable to generate payload for %s failure: %v", cmd, err)
+		return
+	}
+
+	_, err = conn.SendError(powerFailures[cmd], payload)
+	if err != nil {
+		glog.Errorf("Unable to send %s failure: %v", cmd, err)
+	}
+}
@@ -0,0 +1,183 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/golang/glog"
+)
+
+func (id *instanceData) running() bool {
+	return !id.shuttingDown && id.monitorCh != nil && id.connectedCh == nil &&
+		!id.migrating()
+}
+
+func (id *instanceData) executeMonitorCmd(cmd interface{}, responseCh chan error) *powerError {
+	id.monitorCh <- cmd
+	err := <-responseCh
+	if err == errNotSupported {
+		return &powerError{err, payloads.PowerNotSupported}
+	} else if err != nil {
+		return &powerError{err, payloads.PowerActionFailure}
+	}
+	return nil
+}
+
+// relaunchVM boots the VM of an existing instance whose VM is no longer
+// running, e.g., after a soft reboot or when resuming a suspended instance.
+func (id *instanceData) relaunchVM() error {
+	err := id.vm.startVM(id.cfg.VnicName, getNodeIPAddress(), cephID)
+	if err != nil {
+		return err
+	}
+
+	id.connectedCh = make(chan struct{})
+	id.monitorCloseCh = make(chan struct{})
+	id.monitorCh = id.vm.monitorVM(id.monitorCloseCh, id.connectedCh, &id.instanceWg, false)
+	return nil
+}
+
+func (id *instanceData) rebootCommand(hard bool) *powerError {
+	if !id.running() || id.paused {
+		err := fmt.Errorf("Instance %s is not running", id.instance)
+		return &powerError{err, payloads.PowerInvalidState}
+	}
+
+	// VMs are soft rebooted by powering them down through ACPI and
+	// relaunching them once they have stopped.  This is taken care of
+	// by the instance loop when it notices that the VM has gone away.
+
+	if !hard && !id.cfg.Container {
+		glog.Infof("Powering down %s for reboot", id.instance)
+		id.rebooting = true
+		id.monitorCh <- virtualizerStopCmd{}
+		return nil
+	}
+
+	responseCh := make(chan error)
+	return id.executeMonitorCmd(virtualizerRebootCmd{responseCh, hard}, responseCh)
+}
+
+func (id *instanceData) pauseCommand() *powerError {
+	if !id.running() || id.paused {
+		err := fmt.Errorf("Instance %s is not running", id.instance)
+		return &powerError{err, payloads.PowerInvalidState}
+	}
+
+	responseCh := make(chan error)
+	powerErr := id.executeMonitorCmd(virtualizerPauseCmd{responseCh}, responseCh)
+	if powerErr != nil {
+		return powerErr
+	}
+
+	id.paused = true
+	id.ovsCh <- &ovsStateChange{id.instance, ovsPaused}
+	return nil
+}
+
+func (id *instanceData) unpauseCommand() *powerError {
+	if !id.running() || !id.paused {
+		err := fmt.Errorf("Instance %s is not paused", id.instance)
+		return &powerError{err, payloads.PowerInvalidState}
+	}
+
+	responseCh := make(chan error)
+	powerErr := id.executeMonitorCmd(virtualizerUnpauseCmd{responseCh}, responseCh)
+	if powerErr != nil {
+		return powerErr
+	}
+
+	id.paused = false
+	id.ovsCh <- &ovsStateChange{id.instance, ovsRunning}
+	return nil
+}
+
+func (id *instanceData) suspendCommand() *powerError {
+	if id.cfg.Container {
+		err := fmt.Errorf("Containers cannot be suspended")
+		return &powerError{err, payloads.PowerNotSupported}
+	}
+
+	if !id.running() {
+		err := fmt.Errorf("Instance %s is not running", id.instance)
+		return &powerError{err, payloads.PowerInvalidState}
+	}
+
+	responseCh := make(chan error)
+	powerErr := id.executeMonitorCmd(virtualizerSuspendCmd{responseCh}, responseCh)
+	if powerErr != nil {
+		return powerErr
+	}
+
+	// The VM is about to go away.  Persisting the suspended state
+	// prevents the instance loop, or a restarted launcher, from
+	// deleting it.
+
+	id.cfg.Suspended = true
+	if err := id.cfg.save(id.instanceDir); err != nil {
+		glog.Warningf("Unable to persist suspended state of %s: %v", id.instance, err)
+	}
+	return nil
+}
+
+func (id *instanceData) resumeCommand() *powerError {
+	if id.shuttingDown || !id.cfg.Suspended {
+		err := fmt.Errorf("Instance %s is not suspended", id.instance)
+		return &powerError{err, payloads.PowerInvalidState}
+	}
+
+	err := id.relaunchVM()
+	if err != nil {
+		return &powerError{err, payloads.PowerActionFailure}
+	}
+
+	id.cfg.Suspended = false
+	if err := id.cfg.save(id.instanceDir); err != nil {
+		glog.Warningf("Unable to persist state of %s: %v", id.instance, err)
+	}
+	return nil
+}
+
+func (id *instanceData) powerCommand(cmd *insPowerCmd) {
+	var powerErr *powerError
+
+	switch cmd.cmd {
+	case ssntp.REBOOT:
+		powerErr = id.rebootCommand(cmd.hard)
+	case ssntp.PAUSE:
+		powerErr = id.pauseCommand()
+	case ssntp.UNPAUSE:
+		powerErr = id.unpauseCommand()
+	case ssntp.SUSPEND:
+		powerErr = id.suspendCommand()
+	case ssntp.RESUME:
+		powerErr = id.resumeCommand()
+	}
+
+	if powerErr != nil {
+		glog.Errorf("Unable to %s instance %s [%s]: %v", cmd.cmd, id.instance,
+			string(powerErr.code), powerErr.err)
+		powerErr.send(id.ac.conn, cmd.cmd, id.instance)
+		return
+	}
+	commandSuccesses.Inc(cmd.cmd.String())
+
+	glog.Infof("%s of instance %s succeeded", cmd.cmd, id.instance)
+}
@@ -31,6 +31,7 @@ import (
 
 	"context"
 
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/qemu"
 	"github.com/golang/glog"
 )
@@ -280,6 +281,16 @@ func launchQemuWithSpice(params []string, fds []*os.File, ipAddress string) (int
 	return port, err
 }
 
+// volumeDriveFile returns the file qemu should open for a volume specified
+// on its command line.
+func volumeDriveFile(volumeUUID, cephID string) string {
+	if storageDriverName == payloads.LocalStorage {
+		return path.Join(localStoragePath, volumeUUID)
+	}
+
+	return fmt.Sprintf("rbd:rbd/%s:id=%s", volumeUUID, cephID)
+}
+
 func generateQEMULaunchParams(cfg *vmConfig, isoPath, instanceDir string,
 	networkParams []string, cephID string) []string {
 	params := make([]string, 0, 32)
@@ -297,8 +308,8 @@ func generateQEMULaunchParams(cfg *vmConfig, isoPath, instanceDir string,
 
 	for _, v := range cfg.Volumes {
 		blockdevID := fmt.Sprintf("drive_%s", v.UUID)
-		volDriveStr := fmt.Sprintf("file=rbd:rbd/%s:id=%s,if=none,id=%s,format=raw",
-			v.UUID, cephID, blockdevID)
+		volDriveStr := fmt.Sprintf("file=%s,if=none,id=%s,format=raw",
+			volumeDriveFile(v.UUID, cephID), blockdevID)
 		params = append(params, "-drive", volDriveStr)
 		volDeviceStr :=
 			fmt.Sprintf("virtio-blk-pci,scsi=off,bus=pci.0,addr=0x%x,id=device_%s,drive=%s",
@@ -337,6 +348,8 @@ func generateQEMULaunchParams(cfg *vmConfig, isoPath, instanceDir string,
 	qmpParam := fmt.Sprintf("unix:%s,server,nowait", qmpSocket)
 	params = append(params, "-qmp", qmpParam)
 
+	params = append(params, consoleParams(instanceDir)...)
+
 	if cfg.Mem > 0 {
 		memoryParam := fmt.Sprintf("%d", cfg.Mem)
 		params = append(params, "-m", memoryParam)
@@ -386,6 +399,25 @@ func (q *qemuV) startVM(vnicName, ipAddress, cephID string) error {
 
 	params := generateQEMULaunchParams(q.cfg, q.isoPath, q.instanceDir, networkParams, cephID)
 
+	// The saved state of a suspended instance is removed once it has been
+	// read so that it does not get loaded again on a later reboot.
+
+	statePath := path.Join(q.instanceDir, suspendState)
+	if _, err := os.Stat(statePath); err == nil {
+		glog.Infof("Resuming instance from %s", statePath)
+		params = append(params, "-incoming",
+			fmt.Sprintf("exec:cat %s && rm -f %s", statePath, statePath))
+	}
+
+	// The VM of a live migration target is started paused, waiting for
+	// the state of the instance to arrive from the source node.
+
+	if q.cfg.MigrationPort != 0 {
+		uri := migrationURI(ipAddress, q.cfg.MigrationPort)
+		glog.Infof("Waiting for incoming migration on %s", uri)
+		params = append(params, "-incoming", uri)
+	}
+
 	var err error
 
 	if !launchWithUI.Enabled() {
@@ -424,18 +456,23 @@ func (q *qemuV) lostVM() {
 	q.prevCPUTime = -1
 }
 
-func qmpAttach(cmd virtualizerAttachCmd, q *qemu.QMP) {
-	glog.Info("Attach command received")
-
+func hotplugBlockdevID(volumeUUID string) string {
 	// Versions of qemu 2.9 and greater have a 31 byte limit on the size of
 	// IDs used to identify block devices.  We form our ID by appending the
 	// the volumeUUID with the '-'s and the final 3 characters removed, to the
 	// constant string "d_".  Drive names are not allowed to start with numbers.
 
-	blockdevID := fmt.Sprintf("d_%s", strings.Replace(cmd.volumeUUID, "-", "", -1))
+	blockdevID := fmt.Sprintf("d_%s", strings.Replace(volumeUUID, "-", "", -1))
 	if len(blockdevID) > 31 {
 		blockdevID = blockdevID[:31]
 	}
+	return blockdevID
+}
+
+func qmpAttach(cmd virtualizerAttachCmd, q *qemu.QMP) {
+	glog.Info("Attach command received")
+
+	blockdevID := hotplugBlockdevID(cmd.volumeUUID)
 	err := q.ExecuteBlockdevAdd(context.Background(), cmd.device, blockdevID)
 	if err != nil {
 		glog.Errorf("Failed to execute blockdev-add: %v", err)
@@ -453,8 +490,184 @@ func qmpAttach(cmd virtualizerAttachCmd, q *qemu.QMP) {
 	cmd.responseCh <- err
 }
 
+func qmpDetach(cmd virtualizerDetachCmd, q *qemu.QMP) {
+	glog.Info("Detach command received")
+
+	// device_del only completes once the guest has released the device,
+	// which it may never do.
+
+	devID := fmt.Sprintf("device_%s", cmd.volumeUUID)
+	ctx, cancelFN := context.WithTimeout(context.Background(), time.Second*30)
+	err := q.ExecuteDeviceDel(ctx, devID)
+	cancelFN()
+	if err != nil {
+		glog.Errorf("Failed to execute device_del: %v", err)
+		cmd.responseCh <- err
+		return
+	}
+
+	// Drives specified on the QEMU command line are deleted along with
+	// their devices.  Only the block devices we hot plugged need to be
+	// removed.
+
+	err = q.ExecuteBlockdevDel(context.Background(), hotplugBlockdevID(cmd.volumeUUID))
+	if err != nil {
+		glog.Infof("No hot plugged block device to remove for %s: %v",
+			cmd.volumeUUID, err)
+	}
+	cmd.responseCh <- nil
+}
+
+func qmpResize(cmd virtualizerResizeCmd, q *qemu.QMP) {
+	glog.Info("Resize command received")
+
+	// Volumes attached when the VM was launched are known by the ids of
+	// their drives.  Volumes hot plugged into the VM are known by the
+	// ids we passed to blockdev-add.
+
+	err := q.ExecuteBlockResize(context.Background(),
+		fmt.Sprintf("drive_%s", cmd.volumeUUID), cmd.size)
+	if err != nil {
+		err = q.ExecuteBlockResize(context.Background(),
+			hotplugBlockdevID(cmd.volumeUUID), cmd.size)
+		if err != nil {
+			glog.Errorf("Failed to execute block_resize: %v", err)
+		}
+	}
+	cmd.responseCh <- err
+}
+
+func qmpReboot(cmd virtualizerRebootCmd, q *qemu.QMP) {
+	glog.Info("Reboot command received")
+
+	// Soft reboots are implemented by the instance go routine, which
+	// powers down the VM through ACPI and then relaunches it.
+
+	if !cmd.hard {
+		cmd.responseCh <- errNotSupported
+		return
+	}
+
+	err := q.ExecuteSystemReset(context.Background())
+	if err != nil {
+		glog.Errorf("Failed to execute system_reset: %v", err)
+	}
+	cmd.responseCh <- err
+}
+
+func qmpSuspend(cmd virtualizerSuspendCmd, q *qemu.QMP, instanceDir string) {
+	glog.Info("Suspend command received")
+
+	// The state of the VM is migrated to a file in the instance directory.
+	// Once the migration has completed we can quit qemu.  The file is
+	// picked up by startVM the next time the instance is launched.
+
+	statePath := path.Join(instanceDir, suspendState)
+
+	err := q.ExecuteStop(context.Background())
+	if err != nil {
+		glog.Errorf("Failed to execute stop: %v", err)
+		cmd.responseCh <- err
+		return
+	}
+
+	err = q.ExecuteMigrationEvents(context.Background())
+	if err == nil {
+		ctx, cancelFN := context.WithTimeout(context.Background(), time.Minute*5)
+		err = q.ExecuteMigrate(ctx, fmt.Sprintf("exec:cat > %s", statePath))
+		cancelFN()
+	}
+
+	if err != nil {
+		glog.Errorf("Failed to save instance state: %v", err)
+		_ = os.Remove(statePath)
+		if err := q.ExecuteCont(context.Background()); err != nil {
+			glog.Warningf("Failed to execute cont: %v", err)
+		}
+		cmd.responseCh <- err
+		return
+	}
+
+	err = q.ExecuteQuit(context.Background())
+	if err != nil {
+		glog.Warningf("Failed to execute quit instance: %v", err)
+	}
+	cmd.responseCh <- nil
+}
+
+func qmpMigrate(cmd virtualizerMigrateCmd, q *qemu.QMP) {
+	glog.Infof("Migrate command received: %s", cmd.uri)
+
+	err := q.ExecuteMigrateStart(context.Background(), cmd.uri)
+	if err != nil {
+		glog.Errorf("Failed to execute migrate: %v", err)
+		cmd.responseCh <- err
+		return
+	}
+
+	timeout := time.After(migrationTimeout)
+	for {
+		select {
+		case <-timeout:
+			glog.Errorf("Migration to %s timed out", cmd.uri)
+			if err := q.ExecuteMigrateCancel(context.Background()); err != nil {
+				glog.Warningf("Failed to execute migrate_cancel: %v", err)
+			}
+			cmd.responseCh <- fmt.Errorf("Migration to %s timed out", cmd.uri)
+			return
+		case <-time.After(migrationPollPeriod):
+		}
+
+		status, err := q.ExecuteQueryMigrate(context.Background())
+		if err != nil {
+			glog.Warningf("Failed to execute query-migrate: %v", err)
+			continue
+		}
+
+		switch status.Status {
+		case "completed":
+			glog.Infof("Migration to %s completed in %d ms, downtime %d ms",
+				cmd.uri, status.TotalTime, status.Downtime)
+
+			// The instance now runs on the target node.  There is
+			// nothing left to do here but to quit.
+
+			err = q.ExecuteQuit(context.Background())
+			if err != nil {
+				glog.Warningf("Failed to execute quit instance: %v", err)
+			}
+			cmd.responseCh <- nil
+			return
+		case "failed", "cancelled":
+			err = fmt.Errorf("Migration to %s %s: %s", cmd.uri, status.Status,
+				status.ErrorDesc)
+			glog.Error(err)
+			cmd.responseCh <- err
+			return
+		}
+	}
+}
+
+// qmpWaitForIncoming closes connectedCh when the VM of a live migration
+// target starts running, i.e., when the state of the instance has been
+// received.  It needs to consume all the events generated by the instance,
+// as the QMP loop blocks until they are read.
+
+func qmpWaitForIncoming(eventCh <-chan qemu.QMPEvent, instance string,
+	connectedCh chan struct{}, wg *sync.WaitGroup) {
+	resumed := false
+	for ev := range eventCh {
+		if !resumed && ev.Name == "RESUME" {
+			glog.Infof("Incoming migration of %s completed", instance)
+			resumed = true
+			close(connectedCh)
+		}
+	}
+	wg.Done()
+}
+
 func qmpConnect(qmpChannel chan interface{}, instance, instanceDir string, closedCh chan struct{},
-	connectedCh chan struct{}, wg *sync.WaitGroup, boot bool) {
+	connectedCh chan struct{}, wg *sync.WaitGroup, boot, incoming bool) {
 
 	var q *qemu.QMP
 	defer func() {
@@ -467,12 +680,22 @@ func qmpConnect(qmpChannel chan interface{}, instance, instanceDir string, close
 
 	socket := path.Join(instanceDir, "socket")
 	cfg := qemu.QMPConfig{Logger: qmpGlogLogger{}}
+	var eventCh chan qemu.QMPEvent
+	if incoming {
+		eventCh = make(chan qemu.QMPEvent)
+		cfg.EventCh = eventCh
+	}
 	q, ver, err := qemu.QMPStart(context.Background(), socket, cfg, closedCh)
 	if err != nil {
 		glog.Warningf("Failed to connect to QEMU instance %s: %v", instance, err)
 		return
 	}
 
+	if incoming {
+		wg.Add(1)
+		go qmpWaitForIncoming(eventCh, instance, connectedCh, wg)
+	}
+
 	glog.Infof("Connected to %s.", instance)
 	glog.Infof("QMP version %d.%d.%d", ver.Major, ver.Minor, ver.Micro)
 	glog.Infof("QMP capabilities %s", ver.Capabilities)
@@ -483,7 +706,9 @@ func qmpConnect(qmpChannel chan interface{}, instance, instanceDir string, close
 		return
 	}
 
-	close(connectedCh)
+	if !incoming {
+		close(connectedCh)
+	}
 
 DONE:
 	for {
@@ -505,6 +730,20 @@ DONE:
 			}
 		case virtualizerAttachCmd:
 			qmpAttach(cmd, q)
+		case virtualizerDetachCmd:
+			qmpDetach(cmd, q)
+		case virtualizerResizeCmd:
+			qmpResize(cmd, q)
+		case virtualizerRebootCmd:
+			qmpReboot(cmd, q)
+		case virtualizerPauseCmd:
+			cmd.responseCh <- q.ExecuteStop(context.Background())
+		case virtualizerUnpauseCmd:
+			cmd.responseCh <- q.ExecuteCont(context.Background())
+		case virtualizerSuspendCmd:
+			qmpSuspend(cmd, q, instanceDir)
+		case virtualizerMigrateCmd:
+			qmpMigrate(cmd, q)
 		}
 	}
 }
@@ -520,8 +759,14 @@ DONE:
 func (q *qemuV) monitorVM(closedCh chan struct{}, connectedCh chan struct{},
 	wg *sync.WaitGroup, boot bool) chan interface{} {
 	qmpChannel := make(chan interface{})
+
+	// There's no way of telling whether the incoming migration of an
+	// instance has completed when launcher restarts, so we assume it has.
+
+	incoming := q.cfg.MigrationPort != 0 && !boot
 	wg.Add(1)
-	go qmpConnect(qmpChannel, q.cfg.Instance, q.instanceDir, closedCh, connectedCh, wg, boot)
+	go qmpConnect(qmpChannel, q.cfg.Instance, q.instanceDir, closedCh, connectedCh, wg, boot,
+		incoming)
 	return qmpChannel
 }
 
@@ -26,6 +26,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/ciao-project/ciao/payloads"
 )
 
 func genQEMUParams(networkParams []string) []string {
@@ -35,7 +37,10 @@ func genQEMUParams(networkParams []string) []string {
 	}
 	baseParams = append(baseParams, networkParams...)
 	baseParams = append(baseParams, "-enable-kvm", "-cpu", "host", "-daemonize",
-		"-qmp", "unix:/var/lib/ciao/instance/1/socket,server,nowait")
+		"-qmp", "unix:/var/lib/ciao/instance/1/socket,server,nowait",
+		"-chardev", "socket,id=console0,path=/var/lib/ciao/instance/1/console.sock,server,nowait,"+
+			"logfile=/var/lib/ciao/instance/1/console.log,logappend=on",
+		"-device", "isa-serial,chardev=console0")
 
 	return baseParams
 }
@@ -88,6 +93,27 @@ func TestGenerateQEMULaunchParams(t *testing.T) {
 	}
 }
 
+func TestVolumeDriveFile(t *testing.T) {
+	volumeUUID := "67d86208-b46c-4465-9018-e14187d4010"
+
+	file := volumeDriveFile(volumeUUID, "ciao")
+	if file != "rbd:rbd/"+volumeUUID+":id=ciao" {
+		t.Errorf("Unexpected ceph drive file %s", file)
+	}
+
+	storageDriverName = payloads.LocalStorage
+	localStoragePath = "/var/lib/ciao/volumes"
+	defer func() {
+		storageDriverName = ""
+		localStoragePath = ""
+	}()
+
+	file = volumeDriveFile(volumeUUID, "ciao")
+	if file != "/var/lib/ciao/volumes/"+volumeUUID {
+		t.Errorf("Unexpected local drive file %s", file)
+	}
+}
+
 func TestQmpConnectBadSocket(t *testing.T) {
 	var wg sync.WaitGroup
 	qmpChannel := make(chan interface{})
@@ -97,7 +123,7 @@ func TestQmpConnectBadSocket(t *testing.T) {
 	instanceDir := path.Join("/tmp", instance)
 
 	wg.Add(1)
-	go qmpConnect(qmpChannel, instance, instanceDir, closedCh, connectedCh, &wg, false)
+	go qmpConnect(qmpChannel, instance, instanceDir, closedCh, connectedCh, &wg, false, false)
 	wg.Wait()
 	select {
 	case <-closedCh:
@@ -129,7 +155,7 @@ func setupQmpSocket(t *testing.T, runTest func(net.Conn, *bufio.Scanner, chan in
 	}
 	defer ln.Close()
 	wg.Add(1)
-	go qmpConnect(qmpChannel, instance, instanceDir, closedCh, connectedCh, &wg, false)
+	go qmpConnect(qmpChannel, instance, instanceDir, closedCh, connectedCh, &wg, false, false)
 	fd, err := ln.Accept()
 	if err != nil {
 		t.Fatalf("Unable to accept client %v", err)
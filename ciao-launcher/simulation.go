@@ -75,8 +75,23 @@ VM:
 				s.monitorCh = nil
 				break VM
 			}
-			if _, stopCmd := cmd.(virtualizerStopCmd); stopCmd {
+			switch cmd := cmd.(type) {
+			case virtualizerStopCmd:
 				break VM
+			case virtualizerDetachCmd:
+				cmd.responseCh <- nil
+			case virtualizerResizeCmd:
+				cmd.responseCh <- nil
+			case virtualizerRebootCmd:
+				cmd.responseCh <- nil
+			case virtualizerPauseCmd:
+				cmd.responseCh <- nil
+			case virtualizerUnpauseCmd:
+				cmd.responseCh <- nil
+			case virtualizerSuspendCmd:
+				cmd.responseCh <- errNotSupported
+			case virtualizerMigrateCmd:
+				cmd.responseCh <- errNotSupported
 			}
 		case <-s.killCh:
 			break VM
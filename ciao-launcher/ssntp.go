@@ -39,6 +39,8 @@ type restoreCmd struct{}
 type serverConn interface {
 	SendError(error ssntp.Error, payload []byte) (int, error)
 	SendEvent(event ssntp.Event, payload []byte) (int, error)
+	SendErrorReply(frame *ssntp.Frame, error ssntp.Error, payload []byte) (int, error)
+	SendEventReply(frame *ssntp.Frame, event ssntp.Event, payload []byte) (int, error)
 	Dial(config *ssntp.Config, ntf ssntp.ClientNotifier) error
 	SendStatus(status ssntp.Status, payload []byte) (int, error)
 	SendCommand(cmd ssntp.Command, payload []byte) (int, error)
@@ -48,6 +50,7 @@ type serverConn interface {
 	isConnected() bool
 	setStatus(status bool)
 	ClusterConfiguration() (payloads.Configure, error)
+	PayloadEncoding() payloads.Encoding
 }
 
 // ssntpConn is a concrete implementation of serverConn.  It represents
@@ -136,6 +139,79 @@ func (client *agentClient) CommandNotify(cmd ssntp.Command, frame *ssntp.Frame)
 			return
 		}
 		client.cmdCh <- &cmdWrapper{instance, &insAttachVolumeCmd{volume}}
+	case ssntp.DetachVolume:
+		instance, volume, payloadErr := parseDetachVolumePayload(payload)
+		if payloadErr != nil {
+			detachVolumeError := &detachVolumeError{
+				payloadErr.err,
+				payloads.DetachVolumeFailureReason(payloadErr.code),
+			}
+			detachVolumeError.send(client.conn, "", "")
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, &insDetachVolumeCmd{volume}}
+	case ssntp.ExtendVolume:
+		instance, extendCmd, payloadErr := parseExtendVolumePayload(payload)
+		if payloadErr != nil {
+			extendVolumeError := &extendVolumeError{
+				payloadErr.err,
+				payloads.ExtendVolumeFailureReason(payloadErr.code),
+			}
+			extendVolumeError.send(client.conn, "", "")
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, extendCmd}
+	case ssntp.REBOOT:
+		instance, hard, payloadErr := parseRebootPayload(payload)
+		if payloadErr != nil {
+			powerError := &powerError{
+				payloadErr.err,
+				payloads.PowerFailureReason(payloadErr.code),
+			}
+			powerError.send(client.conn, cmd, "")
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, &insPowerCmd{cmd: cmd, hard: hard}}
+	case ssntp.PAUSE, ssntp.UNPAUSE, ssntp.SUSPEND, ssntp.RESUME:
+		instance, payloadErr := parsePowerPayload(cmd, payload)
+		if payloadErr != nil {
+			powerError := &powerError{
+				payloadErr.err,
+				payloads.PowerFailureReason(payloadErr.code),
+			}
+			powerError.send(client.conn, cmd, "")
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, &insPowerCmd{cmd: cmd}}
+	case ssntp.MIGRATE:
+		instance, target, uri, payloadErr := parseMigratePayload(payload)
+		if payloadErr != nil {
+			migrateError := &migrateError{
+				payloadErr.err,
+				payloads.MigrateFailureReason(payloadErr.code),
+			}
+			migrateError.send(client.conn, "")
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, &insMigrateCmd{target: target, uri: uri}}
+	case ssntp.CONSOLE:
+		instance, consoleCmd, payloadErr := parseConsolePayload(payload)
+		if payloadErr != nil {
+			consoleError := &consoleError{
+				payloadErr.err,
+				payloads.ConsoleFailureReason(payloadErr.code),
+			}
+			consoleError.send(client.conn, "", frame)
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		consoleCmd.frame = frame
+		client.cmdCh <- &cmdWrapper{instance, consoleCmd}
 	case ssntp.EVACUATE:
 		client.cmdCh <- &cmdWrapper{"", &evacuateCmd{}}
 	case ssntp.Restore:
@@ -42,6 +42,14 @@ func (v *ssntpTestState) SendEvent(event ssntp.Event, payload []byte) (int, erro
 	return 0, nil
 }
 
+func (v *ssntpTestState) SendErrorReply(frame *ssntp.Frame, error ssntp.Error, payload []byte) (int, error) {
+	return v.SendError(error, payload)
+}
+
+func (v *ssntpTestState) SendEventReply(frame *ssntp.Frame, event ssntp.Event, payload []byte) (int, error) {
+	return v.SendEvent(event, payload)
+}
+
 func (v *ssntpTestState) Dial(config *ssntp.Config, ntf ssntp.ClientNotifier) error {
 	return nil
 }
@@ -78,6 +86,10 @@ func (v *ssntpTestState) ClusterConfiguration() (payloads.Configure, error) {
 	return payloads.Configure{}, nil
 }
 
+func (v *ssntpTestState) PayloadEncoding() payloads.Encoding {
+	return payloads.YAML
+}
+
 // Verify the behaviour the ConnectNotify and DisconnectNotify methods
 //
 // Call ConnectNotify and wait for the statusCmd command on the cmdCh.
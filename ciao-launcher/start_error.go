@@ -29,6 +29,8 @@ type startError struct {
 }
 
 func (se *startError) send(conn serverConn, instance string) {
+	commandFailures.Inc(ssntp.START.String(), string(se.code))
+
 	if !conn.isConnected() {
 		return
 	}
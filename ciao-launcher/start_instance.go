@@ -112,6 +112,21 @@ func processStart(cmd *insStartCmd, instanceDir string, vm virtualizer, conn ser
 
 	st.networkStamp = time.Now()
 
+	// Remember the name of the vnic so that the instance can be
+	// relaunched after a reboot or a suspend.
+	cfg.VnicName = vnicName
+
+	// The port of an incoming migration is released by the instance
+	// go routine, once the migration is over or the instance deleted.
+
+	if cfg.MigrationSource != "" {
+		cfg.MigrationPort = migrationPortGrabber.grabPort()
+		if cfg.MigrationPort == 0 {
+			err = fmt.Errorf("No port available for incoming migration")
+			return nil, &startError{err, payloads.LaunchFailure, cmd.cfg.Restart}
+		}
+	}
+
 	err = createInstance(vm, instanceDir, cfg, bridge, gatewayIP, cmd.userData,
 		cmd.metaData)
 	if err != nil {
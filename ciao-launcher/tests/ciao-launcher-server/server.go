@@ -101,7 +101,7 @@ func (ts *testServer) DisconnectNotify(uuid string, role ssntp.Role) {
 
 func (ts *testServer) StatusNotify(uuid string, status ssntp.Status, frame *ssntp.Frame) {
 	var ready payloads.Ready
-	err := yaml.Unmarshal(frame.Payload, &ready)
+	err := payloads.Unmarshal(frame.Payload, &ready)
 	if err == nil {
 		server.Lock()
 		if server.clients[uuid] != nil {
@@ -115,7 +115,7 @@ func (ts *testServer) CommandNotify(uuid string, command ssntp.Command, frame *s
 	switch command {
 	case ssntp.STATS:
 		var stats payloads.Stat
-		err := yaml.Unmarshal(frame.Payload, &stats)
+		err := payloads.Unmarshal(frame.Payload, &stats)
 		if err == nil {
 			server.Lock()
 			if server.clients[uuid] != nil {
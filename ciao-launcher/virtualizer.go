@@ -27,8 +27,35 @@ type virtualizerAttachCmd struct {
 	volumeUUID string
 	device     string
 }
+type virtualizerDetachCmd struct {
+	responseCh chan error
+	volumeUUID string
+}
+type virtualizerResizeCmd struct {
+	responseCh chan error
+	volumeUUID string
+	size       uint64
+}
+type virtualizerRebootCmd struct {
+	responseCh chan error
+	hard       bool
+}
+type virtualizerPauseCmd struct {
+	responseCh chan error
+}
+type virtualizerUnpauseCmd struct {
+	responseCh chan error
+}
+type virtualizerSuspendCmd struct {
+	responseCh chan error
+}
+type virtualizerMigrateCmd struct {
+	responseCh chan error
+	uri        string
+}
 
 var errImageNotFound = errors.New("Image Not Found")
+var errNotSupported = errors.New("Not Supported")
 
 //BUG(markus): These methods need to be cancellable
 
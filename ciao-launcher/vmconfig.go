@@ -45,9 +45,18 @@ type vmConfig struct {
 	TenantUUID  string
 	ConcUUID    string
 	VnicUUID    string
+	VnicName    string
 	SSHPort     int
 	Volumes     []volumeConfig
 	Restart     bool
+	Suspended   bool
+
+	// MigrationSource is the UUID of the node an instance is being live
+	// migrated from and MigrationPort the port on which its VM waits
+	// for the incoming migration.  Both are cleared once the migration
+	// has completed.
+	MigrationSource string
+	MigrationPort   int
 }
 
 func loadVMConfig(instanceDir string) (*vmConfig, error) {
@@ -133,9 +133,76 @@ any changes in their node statistics.
 
 Fairness
 
-Ciao-scheduler currently implements an extremely trivial algorithm to
+By default ciao-scheduler implements an extremely trivial algorithm to
 prefer not using the most-recently-used compute node.  This is inexpensive
 and leads to sufficient spread of new workloads across a cluster.
 
+Other placement policies can be selected with the -placement flag, or
+with the scheduler placement_policy entry of the cluster configuration:
+
+	first_fit: the default, most-recently-used avoiding, policy.
+	bin_pack:  fill up the nodes with the least available memory first,
+	           keeping the rest of the cluster as idle as possible.
+	spread:    pick the node with the most free memory and disk, and
+	           the lowest load per cpu.
+	weighted:  like spread, but scoring nodes with the free memory,
+	           free disk and load weights set in placement_weights.
+
+All policies but first_fit walk the whole node list for each workload,
+trading some dispatching speed for a better fit.
+
+Whatever the policy, a workload never lands on a node with fewer CPUs
+online than the workload requests VCPUs, and the VCPUs allocated on a
+node never exceed its online CPUs times the CPU overcommit ratio.  The
+ratio defaults to 16 and can be set with the -cpu-overcommit flag or the
+scheduler cpu_overcommit_ratio entry of the cluster configuration.
+
+Placement constraints
+
+A START command may constrain where its instance runs with node_selector,
+affinity and anti_affinity requested resources.  A node_selector of
+"key=value" restricts the workload to the nodes advertising that label in
+their READY status, as set with the ciao-launcher -labels flag.  Affinity
+and anti_affinity name another workload: the instance must then be placed
+on, respectively must avoid, a node hosting instances of that workload.
+The controller lists the nodes it knows to host such instances in the
+START payload, and ciao-scheduler adds the nodes on which it placed them
+in the last few minutes, before the controller could learn about it.
+
+High availability
+
+A second ciao-scheduler can run as a hot standby of the first one by
+starting it with -peer set to the first scheduler address.  The standby
+stays connected to its peer as an SSNTP client, authenticated with its
+scheduler certificate, and pings it every second.  The peer holds a lease
+on serving that the standby renews for as long as it gets answers.  Once
+the connection is lost, the standby keeps trying to reconnect and only
+starts serving when the 5 seconds lease expired.  SSNTP clients given both
+schedulers, either in their server URL list or in the CA certificate, then
+reconnect to the standby.  Its view of the cluster is rebuilt from these
+connections alone: launchers send their READY status as they connect and
+controllers renegotiate their master, so no state needs to be handed over.
+Starting both schedulers with each other as peer makes a restarted
+scheduler the standby of the one that took over.
+
+Both schedulers end up serving if they start at the same time, or if a
+network partition separates them while their clients still reach them.  A
+serving scheduler started with -peer thus checks every 5 seconds whether
+its peer serves as well.  If so, the scheduler with the higher SSNTP UUID
+stops and exits, its clients reconnecting to the other one.  It must be
+restarted, e.g. by its service manager, to stand by again.
+
+Introspection
+
+When started with -http host:port, ciao-scheduler serves a read-only JSON
+view of its state at /state: the connected controllers and their role,
+the compute and network nodes as last reported with the most recently
+used node index, and the count of START commands dispatched or failed per
+reason along with the last 100 decisions.
+
+The same listener serves /metrics in the Prometheus text exposition format:
+the capacity, load and VCPU allocation of every node, and the START commands
+dispatched or failed per reason.
+
 */
 package main
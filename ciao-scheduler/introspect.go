@@ -0,0 +1,240 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ciao-project/ciao/metrics"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+)
+
+var httpAddr = flag.String("http", "",
+	"host:port to serve the read-only HTTP/JSON scheduler state and the Prometheus metrics on, disabled if empty")
+
+// Number of START decisions kept for introspection.
+const recentStartDecisions = 100
+
+type startDecision struct {
+	Time         time.Time                   `json:"time"`
+	InstanceUUID string                      `json:"instance_uuid"`
+	NodeUUID     string                      `json:"node_uuid,omitempty"`
+	Reason       payloads.StartFailureReason `json:"failure_reason,omitempty"`
+}
+
+// startStats accounts for the START commands processed by the scheduler,
+// either dispatched to a node or failed for a given reason.
+type startStats struct {
+	mutex      sync.Mutex
+	dispatched int
+	failures   map[payloads.StartFailureReason]int
+	recent     []startDecision
+	next       int
+}
+
+func (s *startStats) record(d startDecision) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if d.Reason == "" {
+		s.dispatched++
+	} else {
+		if s.failures == nil {
+			s.failures = make(map[payloads.StartFailureReason]int)
+		}
+		s.failures[d.Reason]++
+	}
+
+	if len(s.recent) < recentStartDecisions {
+		s.recent = append(s.recent, d)
+	} else {
+		s.recent[s.next] = d
+	}
+	s.next = (s.next + 1) % recentStartDecisions
+}
+
+func (sched *ssntpSchedulerServer) recordStartDispatch(instanceUUID string, nodeUUID string) {
+	sched.starts.record(startDecision{
+		Time:         time.Now(),
+		InstanceUUID: instanceUUID,
+		NodeUUID:     nodeUUID,
+	})
+}
+
+func (sched *ssntpSchedulerServer) recordStartFailure(instanceUUID string, reason payloads.StartFailureReason) {
+	sched.starts.record(startDecision{
+		Time:         time.Now(),
+		InstanceUUID: instanceUUID,
+		Reason:       reason,
+	})
+}
+
+type controllerState struct {
+	UUID   string `json:"uuid"`
+	Status string `json:"status"`
+}
+
+type networkState struct {
+	IP  string `json:"ip"`
+	MAC string `json:"mac"`
+}
+
+type nodeState struct {
+	UUID            string            `json:"uuid"`
+	Status          string            `json:"status"`
+	MemTotalMB      int               `json:"mem_total_mb"`
+	MemAvailableMB  int               `json:"mem_available_mb"`
+	DiskTotalMB     int               `json:"disk_total_mb"`
+	DiskAvailableMB int               `json:"disk_available_mb"`
+	Load            int               `json:"load"`
+	CpusOnline      int               `json:"cpus_online"`
+	VCPUsAllocated  int               `json:"vcpus_allocated"`
+	Networks        []networkState    `json:"networks"`
+	Labels          map[string]string `json:"labels,omitempty"`
+}
+
+type startState struct {
+	Dispatched int                                 `json:"dispatched"`
+	Failures   map[payloads.StartFailureReason]int `json:"failures"`
+	Recent     []startDecision                     `json:"recent"`
+}
+
+type schedulerState struct {
+	PlacementPolicy    payloads.PlacementPolicy `json:"placement_policy"`
+	CPUOvercommitRatio float64                  `json:"cpu_overcommit_ratio"`
+	Controllers        []controllerState        `json:"controllers"`
+	ComputeNodes       []nodeState              `json:"compute_nodes"`
+	ComputeMRUIndex    int                      `json:"compute_mru_index"`
+	NetworkNodes       []nodeState              `json:"network_nodes"`
+	NetworkMRUIndex    int                      `json:"network_mru_index"`
+	Starts             startState               `json:"starts"`
+}
+
+func getNodeStates(nodes []*nodeStat) []nodeState {
+	states := make([]nodeState, 0, len(nodes))
+
+	for _, node := range nodes {
+		node.mutex.Lock()
+		state := nodeState{
+			UUID:            node.uuid,
+			Status:          node.status.String(),
+			MemTotalMB:      node.memTotalMB,
+			MemAvailableMB:  node.memAvailMB,
+			DiskTotalMB:     node.diskTotalMB,
+			DiskAvailableMB: node.diskAvailMB,
+			Load:            node.load,
+			CpusOnline:      node.cpus,
+			VCPUsAllocated:  node.vcpusAllocated,
+			Networks:        make([]networkState, 0, len(node.networks)),
+			Labels:          node.labels,
+		}
+		for _, n := range node.networks {
+			state.Networks = append(state.Networks, networkState{IP: n.NodeIP, MAC: n.NodeMAC})
+		}
+		node.mutex.Unlock()
+
+		states = append(states, state)
+	}
+
+	return states
+}
+
+func (sched *ssntpSchedulerServer) getState() schedulerState {
+	state := schedulerState{
+		PlacementPolicy:    sched.placementPolicy().policy(),
+		CPUOvercommitRatio: sched.cpuOvercommitRatio(),
+	}
+
+	sched.controllerMutex.RLock()
+	state.Controllers = make([]controllerState, 0, len(sched.controllerList))
+	for _, controller := range sched.controllerList {
+		controller.mutex.Lock()
+		state.Controllers = append(state.Controllers, controllerState{
+			UUID:   controller.uuid,
+			Status: controller.status.String(),
+		})
+		controller.mutex.Unlock()
+	}
+	sched.controllerMutex.RUnlock()
+
+	sched.cnMutex.RLock()
+	state.ComputeNodes = getNodeStates(sched.cnList)
+	state.ComputeMRUIndex = sched.cnMRUIndex
+	sched.cnMutex.RUnlock()
+
+	sched.nnMutex.RLock()
+	state.NetworkNodes = getNodeStates(sched.nnList)
+	state.NetworkMRUIndex = sched.nnMRUIndex
+	sched.nnMutex.RUnlock()
+
+	sched.starts.mutex.Lock()
+	state.Starts.Dispatched = sched.starts.dispatched
+	state.Starts.Failures = make(map[payloads.StartFailureReason]int)
+	for reason, count := range sched.starts.failures {
+		state.Starts.Failures[reason] = count
+	}
+
+	// most recent first
+	n := len(sched.starts.recent)
+	state.Starts.Recent = make([]startDecision, 0, n)
+	for i := 1; i <= n; i++ {
+		idx := (sched.starts.next - i + recentStartDecisions) % recentStartDecisions
+		state.Starts.Recent = append(state.Starts.Recent, sched.starts.recent[idx])
+	}
+	sched.starts.mutex.Unlock()
+
+	return state
+}
+
+func (sched *ssntpSchedulerServer) stateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	b, err := json.MarshalIndent(sched.getState(), "", "\t")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func (sched *ssntpSchedulerServer) introspectionHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/state", sched.stateHandler)
+	mux.Handle("/metrics", metrics.Handler(sched.collectMetrics))
+
+	return mux
+}
+
+func serveIntrospection(sched *ssntpSchedulerServer, addr string) {
+	glog.Infof("Serving scheduler state on http://%s/state and metrics on http://%s/metrics", addr, addr)
+
+	err := http.ListenAndServe(addr, sched.introspectionHandler())
+	if err != nil {
+		glog.Errorf("Unable to serve scheduler state: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ciao-project/ciao/payloads"
+)
+
+func getTestState(t *testing.T, sched *ssntpSchedulerServer) schedulerState {
+	server := httptest.NewServer(sched.introspectionHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var state schedulerState
+	err = json.NewDecoder(resp.Body).Decode(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return state
+}
+
+func TestIntrospectionState(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	sched.controllerList = append(sched.controllerList,
+		&controllerStat{uuid: "ctl-1", status: controllerMaster})
+	spinUpComputeNodeLarge(sched, 1)
+	spinUpComputeNodeLarge(sched, 2)
+	spinUpNetworkNodeLarge(sched, 3, []payloads.NetworkStat{
+		{NodeIP: "192.168.1.1", NodeMAC: "02:00:15:03:6f:49"},
+	})
+
+	var work = createStartWorkload(1, 256, 10000)
+	resources, err := sched.getWorkloadResources(work)
+	if err != nil {
+		t.Fatal("bad workload resources")
+	}
+
+	node := PickComputeNode(sched, "", &resources, false)
+	if node == nil {
+		t.Fatal("found no compute fit")
+	}
+	node.mutex.Unlock()
+	sched.recordStartDispatch(resources.instanceUUID, node.uuid)
+	sched.recordStartFailure(resources.instanceUUID, payloads.FullCloud)
+
+	state := getTestState(t, sched)
+
+	if len(state.Controllers) != 1 || state.Controllers[0].UUID != "ctl-1" ||
+		state.Controllers[0].Status != "MASTER" {
+		t.Errorf("unexpected controllers %v", state.Controllers)
+	}
+
+	if len(state.ComputeNodes) != 2 || state.ComputeNodes[0].UUID != fmt.Sprintf("%08d", 1) ||
+		state.ComputeNodes[0].MemTotalMB != 141312 || state.ComputeNodes[0].CpusOnline != 4 {
+		t.Errorf("unexpected compute nodes %v", state.ComputeNodes)
+	}
+
+	if state.ComputeMRUIndex != sched.cnMRUIndex || state.NetworkMRUIndex != -1 {
+		t.Errorf("unexpected MRU indexes %d, %d", state.ComputeMRUIndex, state.NetworkMRUIndex)
+	}
+
+	if len(state.NetworkNodes) != 1 || len(state.NetworkNodes[0].Networks) != 1 ||
+		state.NetworkNodes[0].Networks[0].IP != "192.168.1.1" {
+		t.Errorf("unexpected network nodes %v", state.NetworkNodes)
+	}
+
+	if state.Starts.Dispatched != 1 || state.Starts.Failures[payloads.FullCloud] != 1 {
+		t.Errorf("unexpected START counts %v", state.Starts)
+	}
+
+	if len(state.Starts.Recent) != 2 || state.Starts.Recent[0].Reason != payloads.FullCloud ||
+		state.Starts.Recent[1].NodeUUID != node.uuid {
+		t.Errorf("unexpected recent START decisions %v", state.Starts.Recent)
+	}
+}
+
+func TestIntrospectionRecentStarts(t *testing.T) {
+	var stats startStats
+
+	for i := 0; i < recentStartDecisions+10; i++ {
+		stats.record(startDecision{InstanceUUID: fmt.Sprintf("%d", i)})
+	}
+
+	if stats.dispatched != recentStartDecisions+10 || len(stats.recent) != recentStartDecisions {
+		t.Fatalf("unexpected START stats %d, %d", stats.dispatched, len(stats.recent))
+	}
+
+	sched := newSsntpSchedulerServer()
+	sched.starts.recent = stats.recent
+	sched.starts.next = stats.next
+
+	state := sched.getState()
+	if state.Starts.Recent[0].InstanceUUID != fmt.Sprintf("%d", recentStartDecisions+9) ||
+		state.Starts.Recent[recentStartDecisions-1].InstanceUUID != "10" {
+		t.Errorf("unexpected recent START decisions order")
+	}
+}
+
+func TestIntrospectionReadOnly(t *testing.T) {
+	server := httptest.NewServer(newSsntpSchedulerServer().introspectionHandler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/state", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+func TestIntrospectionMetrics(t *testing.T) {
+	sched := newSsntpSchedulerServer()
+	spinUpComputeNodeLarge(sched, 1)
+	spinUpNetworkNodeLarge(sched, 2, nil)
+	sched.recordStartDispatch("instance-1", fmt.Sprintf("%08d", 1))
+	sched.recordStartFailure("instance-2", payloads.FullCloud)
+
+	server := httptest.NewServer(sched.introspectionHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	expected := []string{
+		"# TYPE ciao_scheduler_node_ready gauge\n",
+		`ciao_scheduler_node_ready{node="00000001",role="compute"} 1` + "\n",
+		`ciao_scheduler_node_memory_total_megabytes{node="00000001",role="compute"} 141312` + "\n",
+		`ciao_scheduler_node_cpus_online{node="00000002",role="network"} 4` + "\n",
+		"ciao_scheduler_starts_dispatched_total 1\n",
+		`ciao_scheduler_start_failures_total{reason="full_cloud"} 1` + "\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(body), e) {
+			t.Errorf("missing %q in metrics:\n%s", e, body)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"sort"
+
+	"github.com/ciao-project/ciao/metrics"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
+)
+
+type nodeFamilies struct {
+	ready          *metrics.Family
+	memTotal       *metrics.Family
+	memAvailable   *metrics.Family
+	diskTotal      *metrics.Family
+	diskAvailable  *metrics.Family
+	load           *metrics.Family
+	cpusOnline     *metrics.Family
+	vcpusAllocated *metrics.Family
+}
+
+func newNodeFamilies() *nodeFamilies {
+	return &nodeFamilies{
+		ready: metrics.NewFamily("ciao_scheduler_node_ready",
+			"Whether the node is ready to accept workloads", metrics.Gauge),
+		memTotal: metrics.NewFamily("ciao_scheduler_node_memory_total_megabytes",
+			"Total memory of the node", metrics.Gauge),
+		memAvailable: metrics.NewFamily("ciao_scheduler_node_memory_available_megabytes",
+			"Memory available for new instances on the node", metrics.Gauge),
+		diskTotal: metrics.NewFamily("ciao_scheduler_node_disk_total_megabytes",
+			"Total disk space of the node", metrics.Gauge),
+		diskAvailable: metrics.NewFamily("ciao_scheduler_node_disk_available_megabytes",
+			"Disk space available for new instances on the node", metrics.Gauge),
+		load: metrics.NewFamily("ciao_scheduler_node_load",
+			"Load average of the node", metrics.Gauge),
+		cpusOnline: metrics.NewFamily("ciao_scheduler_node_cpus_online",
+			"Number of online CPUs of the node", metrics.Gauge),
+		vcpusAllocated: metrics.NewFamily("ciao_scheduler_node_vcpus_allocated",
+			"Number of VCPUs allocated to instances on the node", metrics.Gauge),
+	}
+}
+
+func (f *nodeFamilies) add(nodes []nodeState, role string) {
+	for _, n := range nodes {
+		labels := metrics.Labels{"node": n.UUID, "role": role}
+
+		ready := 0.0
+		if n.Status == ssntp.READY.String() {
+			ready = 1
+		}
+
+		f.ready.Add(ready, labels)
+		f.memTotal.Add(float64(n.MemTotalMB), labels)
+		f.memAvailable.Add(float64(n.MemAvailableMB), labels)
+		f.diskTotal.Add(float64(n.DiskTotalMB), labels)
+		f.diskAvailable.Add(float64(n.DiskAvailableMB), labels)
+		f.load.Add(float64(n.Load), labels)
+		f.cpusOnline.Add(float64(n.CpusOnline), labels)
+		f.vcpusAllocated.Add(float64(n.VCPUsAllocated), labels)
+	}
+}
+
+func (f *nodeFamilies) families() []metrics.Family {
+	return []metrics.Family{*f.ready, *f.memTotal, *f.memAvailable, *f.diskTotal,
+		*f.diskAvailable, *f.load, *f.cpusOnline, *f.vcpusAllocated}
+}
+
+func (sched *ssntpSchedulerServer) collectMetrics() []metrics.Family {
+	state := sched.getState()
+
+	controllers := metrics.NewFamily("ciao_scheduler_controllers",
+		"Number of connected controllers", metrics.Gauge)
+	controllers.Add(float64(len(state.Controllers)), nil)
+
+	ratio := metrics.NewFamily("ciao_scheduler_cpu_overcommit_ratio",
+		"Ratio of VCPUs that can be allocated per online CPU", metrics.Gauge)
+	ratio.Add(state.CPUOvercommitRatio, nil)
+
+	nodes := newNodeFamilies()
+	nodes.add(state.ComputeNodes, "compute")
+	nodes.add(state.NetworkNodes, "network")
+
+	dispatched := metrics.NewFamily("ciao_scheduler_starts_dispatched_total",
+		"Number of START commands dispatched to a node", metrics.Counter)
+	dispatched.Add(float64(state.Starts.Dispatched), nil)
+
+	reasons := make([]string, 0, len(state.Starts.Failures))
+	for reason := range state.Starts.Failures {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+
+	failures := metrics.NewFamily("ciao_scheduler_start_failures_total",
+		"Number of START commands the scheduler failed to dispatch", metrics.Counter)
+	for _, reason := range reasons {
+		count := state.Starts.Failures[payloads.StartFailureReason(reason)]
+		failures.Add(float64(count), metrics.Labels{"reason": reason})
+	}
+
+	families := []metrics.Family{*controllers, *ratio}
+	families = append(families, nodes.families()...)
+	return append(families, *dispatched, *failures)
+}
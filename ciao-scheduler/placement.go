@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ciao-project/ciao/payloads"
+)
+
+// placementPolicy chooses which node out of a list of nodes should
+// receive a workload.
+type placementPolicy interface {
+	// pick returns the index in nodes of the node selected for a
+	// workload, or -1 if no node fits. mru and mruIndex describe the
+	// most recently used node, if any. The fits function must be
+	// called with the node mutex held. When a node is selected, pick
+	// returns with its mutex held.
+	pick(nodes []*nodeStat, mru *nodeStat, mruIndex int, fits func(node *nodeStat) bool) int
+
+	// policy returns the placement policy name.
+	policy() payloads.PlacementPolicy
+}
+
+// Scoring policies drop a node's lock between scoring it and selecting
+// it, so the best node may no longer fit once relocked. Only rescan
+// a limited number of times before giving up.
+const maxPlacementRetries = 3
+
+var defaultPlacementWeights = payloads.PlacementWeights{
+	Mem:  1,
+	Disk: 1,
+	Load: 1,
+}
+
+func newPlacementPolicy(policy payloads.PlacementPolicy, weights payloads.PlacementWeights) (placementPolicy, error) {
+	switch policy {
+	case "", payloads.FirstFit:
+		return firstFitPolicy{}, nil
+	case payloads.BinPack:
+		return binPackPolicy{}, nil
+	case payloads.Spread:
+		return weightedPolicy{name: payloads.Spread, weights: defaultPlacementWeights}, nil
+	case payloads.Weighted:
+		if weights == (payloads.PlacementWeights{}) {
+			weights = defaultPlacementWeights
+		}
+		return weightedPolicy{name: payloads.Weighted, weights: weights}, nil
+	}
+
+	return nil, fmt.Errorf("unknown placement policy \"%s\"", policy)
+}
+
+// firstFitPolicy is the historical, and cheapest, ciao placement
+// policy: first try the nodes after the most recently used one, then
+// the whole list including the MRU.
+type firstFitPolicy struct{}
+
+func (p firstFitPolicy) policy() payloads.PlacementPolicy {
+	return payloads.FirstFit
+}
+
+func (p firstFitPolicy) pick(nodes []*nodeStat, mru *nodeStat, mruIndex int, fits func(node *nodeStat) bool) int {
+	/* First try nodes after the MRU */
+	if mruIndex != -1 && mruIndex < len(nodes)-1 {
+		for i, node := range nodes[mruIndex+1:] {
+			node.mutex.Lock()
+			if node == mru {
+				node.mutex.Unlock()
+				continue
+			}
+
+			if fits(node) == true {
+				return mruIndex + 1 + i // locked nodeStat
+			}
+			node.mutex.Unlock()
+		}
+	}
+
+	/* Then try the whole list, including the MRU */
+	for i, node := range nodes {
+		node.mutex.Lock()
+		if fits(node) == true {
+			return i // locked nodeStat
+		}
+		node.mutex.Unlock()
+	}
+
+	return -1
+}
+
+// pickHighestScore walks the whole node list and selects the fitting
+// node with the highest score. Ties go to the node found first.
+func pickHighestScore(nodes []*nodeStat, fits func(node *nodeStat) bool, score func(node *nodeStat) float64) int {
+	for try := 0; try < maxPlacementRetries; try++ {
+		best := -1
+		var bestScore float64
+
+		for i, node := range nodes {
+			node.mutex.Lock()
+			if fits(node) == true {
+				s := score(node)
+				if best == -1 || s > bestScore {
+					best = i
+					bestScore = s
+				}
+			}
+			node.mutex.Unlock()
+		}
+
+		if best == -1 {
+			return -1
+		}
+
+		node := nodes[best]
+		node.mutex.Lock()
+		if fits(node) == true {
+			return best // locked nodeStat
+		}
+		node.mutex.Unlock()
+	}
+
+	return -1
+}
+
+// binPackPolicy fills up nodes before using new ones, by selecting
+// the fitting node with the least available memory and then disk.
+type binPackPolicy struct{}
+
+func (p binPackPolicy) policy() payloads.PlacementPolicy {
+	return payloads.BinPack
+}
+
+func (p binPackPolicy) pick(nodes []*nodeStat, mru *nodeStat, mruIndex int, fits func(node *nodeStat) bool) int {
+	return pickHighestScore(nodes, fits, func(node *nodeStat) float64 {
+		return -(float64(node.memAvailMB) + float64(node.diskAvailMB)/float64(1024*1024))
+	})
+}
+
+// weightedPolicy scores each fitting node from its free memory and
+// disk ratios and its load per cpu, and selects the highest scoring
+// one. The spread policy is a weighted policy giving all three the
+// same weight.
+type weightedPolicy struct {
+	name    payloads.PlacementPolicy
+	weights payloads.PlacementWeights
+}
+
+func (p weightedPolicy) policy() payloads.PlacementPolicy {
+	return p.name
+}
+
+func ratio(n int, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+
+	return float64(n) / float64(total)
+}
+
+func (p weightedPolicy) score(node *nodeStat) float64 {
+	cpus := node.cpus
+	if cpus <= 0 {
+		cpus = 1
+	}
+
+	return p.weights.Mem*ratio(node.memAvailMB, node.memTotalMB) +
+		p.weights.Disk*ratio(node.diskAvailMB, node.diskTotalMB) -
+		p.weights.Load*ratio(node.load, cpus)
+}
+
+func (p weightedPolicy) pick(nodes []*nodeStat, mru *nodeStat, mruIndex int, fits func(node *nodeStat) bool) int {
+	return pickHighestScore(nodes, fits, p.score)
+}
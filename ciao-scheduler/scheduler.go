@@ -22,12 +22,15 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/pprof"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/ciao-project/ciao/clogger/gloginterface"
+	"github.com/ciao-project/ciao/configuration"
 	"github.com/ciao-project/ciao/osprepare"
 	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/ssntp"
@@ -38,16 +41,23 @@ import (
 
 var cert = flag.String("cert", "/etc/pki/ciao/cert-Scheduler-localhost.pem", "Server certificate")
 var cacert = flag.String("cacert", "/etc/pki/ciao/CAcert-server-localhost.pem", "CA certificate")
+var crl = flag.String("crl", "", "Certificate revocation list")
 var cpuprofile = flag.String("cpuprofile", "", "Write cpu profile to file")
 var heartbeat = flag.Bool("heartbeat", false, "Emit status heartbeat text")
 var logDir = "/var/lib/ciao/logs/scheduler"
 var configURI = flag.String("configuration-uri", "file:///etc/ciao/configuration.yaml",
 	"Cluster configuration URI")
+var placement = flag.String("placement", "",
+	"Workload placement policy (first_fit, bin_pack, spread or weighted), overrides the cluster configuration")
+var cpuOvercommit = flag.Float64("cpu-overcommit", 0,
+	"Maximum ratio of allocated VCPUs to online CPUs on a node, overrides the cluster configuration")
 
 type ssntpSchedulerServer struct {
 	// user config overrides ------------------------------------------
-	heartbeat  bool
-	cpuprofile string
+	heartbeat     bool
+	cpuprofile    string
+	placement     string
+	cpuOvercommit float64
 
 	// ssntp ----------------------------------------------------------
 	config *ssntp.Config
@@ -74,6 +84,14 @@ type ssntpSchedulerServer struct {
 	nnMutex    sync.RWMutex // Rlock traversing map, Lock modifying map
 	nnMRU      *nodeStat
 	nnMRUIndex int
+
+	// Workload placement
+	policy      placementPolicy
+	cpuRatio    float64
+	policyMutex sync.RWMutex
+
+	// START decisions, for introspection
+	starts startStats
 }
 
 func newSsntpSchedulerServer() *ssntpSchedulerServer {
@@ -83,21 +101,33 @@ func newSsntpSchedulerServer() *ssntpSchedulerServer {
 		cnMRUIndex:    -1,
 		nnMap:         make(map[string]*nodeStat),
 		nnMRUIndex:    -1,
+		policy:        firstFitPolicy{},
+		cpuRatio:      defaultCPUOvercommitRatio,
 	}
 }
 
+// A node can run instances totaling up to defaultCPUOvercommitRatio
+// times more VCPUs than it has CPUs online, unless configured otherwise.
+const defaultCPUOvercommitRatio = 16.0
+
 type nodeStat struct {
-	mutex       sync.Mutex
-	status      ssntp.Status
-	uuid        string
-	memTotalMB  int
-	memAvailMB  int
-	diskTotalMB int
-	diskAvailMB int
-	load        int
-	cpus        int
-	isNetNode   bool
-	networks    []payloads.NetworkStat
+	mutex          sync.Mutex
+	status         ssntp.Status
+	uuid           string
+	memTotalMB     int
+	memAvailMB     int
+	diskTotalMB    int
+	diskAvailMB    int
+	load           int
+	cpus           int
+	vcpusAllocated int
+	isNetNode      bool
+	networks       []payloads.NetworkStat
+	labels         map[string]string
+
+	// workload UUID to time of the last placement of one of its
+	// instances on this node
+	placements map[string]time.Time
 }
 
 type controllerStatus uint8
@@ -390,6 +420,9 @@ func (sched *ssntpSchedulerServer) ConnectNotify(uuid string, role ssntp.Role) {
 	if role.IsNetAgent() {
 		connectNetworkNode(sched, uuid)
 	}
+	if role.IsScheduler() {
+		glog.Infof("Peer scheduler %s connected", uuid)
+	}
 
 	glog.V(2).Infof("Connect (role 0x%x, uuid=%s)\n", role, uuid)
 }
@@ -404,6 +437,9 @@ func (sched *ssntpSchedulerServer) DisconnectNotify(uuid string, role ssntp.Role
 	if role.IsNetAgent() {
 		disconnectNetworkNode(sched, uuid)
 	}
+	if role.IsScheduler() {
+		glog.Infof("Peer scheduler %s disconnected", uuid)
+	}
 
 	glog.V(2).Infof("Connect (role 0x%x, uuid=%s)\n", role, uuid)
 }
@@ -419,9 +455,9 @@ func (sched *ssntpSchedulerServer) updateNodeStat(node *nodeStat, status ssntp.S
 	case ssntp.READY:
 		//pull in client's READY status frame transmitted statistics
 		var stats payloads.Ready
-		err := yaml.Unmarshal(payload, &stats)
+		err := payloads.Unmarshal(payload, &stats)
 		if err != nil {
-			glog.Errorf("Bad READY yaml for node %s\n", node.uuid)
+			glog.Errorf("Bad READY payload for node %s\n", node.uuid)
 			return
 		}
 		node.memTotalMB = stats.MemTotalMB
@@ -430,7 +466,9 @@ func (sched *ssntpSchedulerServer) updateNodeStat(node *nodeStat, status ssntp.S
 		node.diskAvailMB = stats.DiskAvailableMB
 		node.load = stats.Load
 		node.cpus = stats.CpusOnline
+		node.vcpusAllocated = stats.VCPUsAllocated
 		node.networks = stats.Networks
+		node.labels = stats.Labels
 
 		//any changes to the payloads.Ready struct should be
 		//accompanied by a change here
@@ -470,11 +508,19 @@ func (sched *ssntpSchedulerServer) StatusNotify(uuid string, status ssntp.Status
 }
 
 type workResources struct {
-	instanceUUID string
-	memReqMB     int
-	diskReqMB    int
-	networkNode  bool
-	physNets     []string
+	instanceUUID      string
+	workloadUUID      string
+	vcpusReq          int
+	memReqMB          int
+	diskReqMB         int
+	networkNode       bool
+	physNets          []string
+	nodeSelector      map[string]string
+	affinity          []string
+	antiAffinity      []string
+	affinityNodes     map[string]bool
+	antiAffinityNodes map[string]bool
+	preferredNode     string
 }
 
 func (sched *ssntpSchedulerServer) getWorkloadResources(work *payloads.Start) (workload workResources, err error) {
@@ -484,6 +530,11 @@ func (sched *ssntpSchedulerServer) getWorkloadResources(work *payloads.Start) (w
 		reqValue := work.Start.RequestedResources[idx].Value
 		reqString := work.Start.RequestedResources[idx].ValueString
 
+		// vcpus:
+		if reqType == payloads.VCPUs {
+			workload.vcpusReq = reqValue
+		}
+
 		// memory:
 		if reqType == payloads.MemMB {
 			workload.memReqMB = reqValue
@@ -514,9 +565,51 @@ func (sched *ssntpSchedulerServer) getWorkloadResources(work *payloads.Start) (w
 			}
 		}
 
+		// node labels
+		if reqType == payloads.NodeSelector {
+			label := strings.SplitN(reqString, "=", 2)
+			if len(label) != 2 || label[0] == "" {
+				return workload, fmt.Errorf("invalid start payload resource demand: node_selector (%s) is not key=value", reqString)
+			}
+
+			if workload.nodeSelector == nil {
+				workload.nodeSelector = make(map[string]string)
+			}
+			workload.nodeSelector[label[0]] = label[1]
+		}
+
+		// workload affinity and anti-affinity
+		if reqType == payloads.Affinity || reqType == payloads.AntiAffinity {
+			if reqString == "" {
+				return workload, fmt.Errorf("invalid start payload resource demand: %s has no workload", reqType)
+			}
+
+			if reqType == payloads.Affinity {
+				workload.affinity = append(workload.affinity, reqString)
+			} else {
+				workload.antiAffinity = append(workload.antiAffinity, reqString)
+			}
+		}
+
 		// etc...
 	}
 
+	workload.affinityNodes = make(map[string]bool)
+	for _, node := range work.Start.AffinityNodes {
+		workload.affinityNodes[node] = true
+	}
+
+	workload.antiAffinityNodes = make(map[string]bool)
+	for _, node := range work.Start.AntiAffinityNodes {
+		workload.antiAffinityNodes[node] = true
+	}
+
+	// a migration target must never be the node the instance is
+	// migrating away from
+	if work.Start.MigrationSource != "" {
+		workload.antiAffinityNodes[work.Start.MigrationSource] = true
+	}
+
 	// volumes
 	for _, volume := range work.Start.Storage {
 		if volume.Local {
@@ -528,12 +621,17 @@ func (sched *ssntpSchedulerServer) getWorkloadResources(work *payloads.Start) (w
 	if workload.memReqMB <= 0 {
 		return workload, fmt.Errorf("invalid start payload resource demand: mem_mb (%d) <= 0, must be > 0", workload.memReqMB)
 	}
+	if workload.vcpusReq < 0 {
+		return workload, fmt.Errorf("invalid start payload resource demand: vcpus (%d) < 0, must be >= 0", workload.vcpusReq)
+	}
 	if workload.diskReqMB < 0 {
 		return workload, fmt.Errorf("invalid start payload local disk demand: disk MB (%d) < 0, must be >= 0", workload.diskReqMB)
 	}
 
-	// note the uuid
+	// note the uuids
 	workload.instanceUUID = work.Start.InstanceUUID
+	workload.workloadUUID = work.Start.WorkloadUUID
+	workload.preferredNode = work.Start.PreferredNode
 
 	return workload, nil
 }
@@ -562,13 +660,52 @@ func networkDemandsSatisfied(node *nodeStat, workload *workResources) bool {
 	return true
 }
 
+// Nodes not reporting their online CPUs are not subject to VCPU demands.
+// Otherwise a single workload can not use more VCPUs than there are CPUs
+// online, and all workloads together no more than cpuRatio times that.
+func cpuDemandsSatisfied(node *nodeStat, workload *workResources, cpuRatio float64) bool {
+	if node.cpus <= 0 {
+		return true
+	}
+
+	if workload.vcpusReq > node.cpus {
+		return false
+	}
+
+	vcpusAllocated := node.vcpusAllocated
+	if vcpusAllocated < 0 {
+		vcpusAllocated = 0
+	}
+
+	return float64(vcpusAllocated+workload.vcpusReq) <= float64(node.cpus)*cpuRatio
+}
+
+// A node must advertise all the labels selected by a workload. With
+// affinity rules the node must already host one of the affine workloads,
+// unless none of them has been placed yet. With anti-affinity rules it
+// must not host any of the anti-affine workloads.
+func placementDemandsSatisfied(node *nodeStat, workload *workResources) bool {
+	for key, value := range workload.nodeSelector {
+		if v, ok := node.labels[key]; !ok || v != value {
+			return false
+		}
+	}
+
+	if len(workload.affinityNodes) > 0 && !workload.affinityNodes[node.uuid] {
+		return false
+	}
+
+	return !workload.antiAffinityNodes[node.uuid]
+}
+
 // Check resource demands are satisfiable by the referenced, locked nodeStat object
-func (sched *ssntpSchedulerServer) workloadFits(node *nodeStat, workload *workResources) bool {
-	// simple scheduling policy == first fit
+func (sched *ssntpSchedulerServer) workloadFits(node *nodeStat, workload *workResources, cpuRatio float64) bool {
 	if node.memAvailMB >= workload.memReqMB &&
 		node.diskAvailMB >= workload.diskReqMB &&
 		node.status == ssntp.READY &&
-		networkDemandsSatisfied(node, workload) {
+		cpuDemandsSatisfied(node, workload, cpuRatio) &&
+		networkDemandsSatisfied(node, workload) &&
+		placementDemandsSatisfied(node, workload) {
 
 		return true
 	}
@@ -576,6 +713,8 @@ func (sched *ssntpSchedulerServer) workloadFits(node *nodeStat, workload *workRe
 }
 
 func (sched *ssntpSchedulerServer) sendStartFailureError(clientUUID string, instanceUUID string, reason payloads.StartFailureReason, restart bool) {
+	sched.recordStartFailure(instanceUUID, reason)
+
 	error := payloads.ErrorStartFailure{
 		InstanceUUID: instanceUUID,
 		Reason:       reason,
@@ -676,6 +815,42 @@ func getWorkloadAgentUUID(sched *ssntpSchedulerServer, command ssntp.Command, pa
 		var cmd payloads.AttachVolume
 		err := yaml.Unmarshal(payload, &cmd)
 		return cmd.Attach.InstanceUUID, cmd.Attach.WorkloadAgentUUID, err
+	case ssntp.DetachVolume:
+		var cmd payloads.DetachVolume
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Detach.InstanceUUID, cmd.Detach.WorkloadAgentUUID, err
+	case ssntp.REBOOT:
+		var cmd payloads.Reboot
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Reboot.InstanceUUID, cmd.Reboot.WorkloadAgentUUID, err
+	case ssntp.PAUSE:
+		var cmd payloads.Pause
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Pause.InstanceUUID, cmd.Pause.WorkloadAgentUUID, err
+	case ssntp.UNPAUSE:
+		var cmd payloads.Unpause
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Unpause.InstanceUUID, cmd.Unpause.WorkloadAgentUUID, err
+	case ssntp.SUSPEND:
+		var cmd payloads.Suspend
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Suspend.InstanceUUID, cmd.Suspend.WorkloadAgentUUID, err
+	case ssntp.RESUME:
+		var cmd payloads.Resume
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Resume.InstanceUUID, cmd.Resume.WorkloadAgentUUID, err
+	case ssntp.MIGRATE:
+		var cmd payloads.Migrate
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Migrate.InstanceUUID, cmd.Migrate.WorkloadAgentUUID, err
+	case ssntp.CONSOLE:
+		var cmd payloads.Console
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Console.InstanceUUID, cmd.Console.WorkloadAgentUUID, err
+	case ssntp.ExtendVolume:
+		var cmd payloads.ExtendVolume
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Extend.InstanceUUID, cmd.Extend.WorkloadAgentUUID, err
 	}
 }
 
@@ -698,6 +873,78 @@ func (sched *ssntpSchedulerServer) fwdCmdToComputeNode(command ssntp.Command, pa
 // Decrement resource claims for the referenced locked nodeStat object
 func (sched *ssntpSchedulerServer) decrementResourceUsage(node *nodeStat, workload *workResources) {
 	node.memAvailMB -= workload.memReqMB
+	if node.vcpusAllocated < 0 {
+		node.vcpusAllocated = 0
+	}
+	node.vcpusAllocated += workload.vcpusReq
+
+	if workload.workloadUUID != "" {
+		if node.placements == nil {
+			node.placements = make(map[string]time.Time)
+		}
+		node.placements[workload.workloadUUID] = time.Now()
+	}
+}
+
+// The controller only learns where an instance runs from the node STATS
+// following its start. Until then, the scheduler relies on the placements
+// it made itself to enforce affinity and anti-affinity, e.g., between the
+// instances of a single multi-instance START request.
+const placementMemory = 5 * time.Minute
+
+// Add the nodes on which the scheduler recently placed instances of the
+// workload's affine and anti-affine workloads to its node sets. Must be
+// called with the node list lock held.
+func addRecentPlacements(nodes []*nodeStat, workload *workResources) {
+	if len(workload.affinity) == 0 && len(workload.antiAffinity) == 0 {
+		return
+	}
+
+	if workload.affinityNodes == nil {
+		workload.affinityNodes = make(map[string]bool)
+	}
+	if workload.antiAffinityNodes == nil {
+		workload.antiAffinityNodes = make(map[string]bool)
+	}
+
+	now := time.Now()
+	for _, node := range nodes {
+		node.mutex.Lock()
+		for uuid, t := range node.placements {
+			if now.Sub(t) > placementMemory {
+				delete(node.placements, uuid)
+			}
+		}
+		for _, uuid := range workload.affinity {
+			if _, ok := node.placements[uuid]; ok {
+				workload.affinityNodes[node.uuid] = true
+			}
+		}
+		for _, uuid := range workload.antiAffinity {
+			if _, ok := node.placements[uuid]; ok {
+				workload.antiAffinityNodes[node.uuid] = true
+			}
+		}
+		node.mutex.Unlock()
+	}
+}
+
+// Return the index of the workload's preferred node, with the node locked, if
+// the node is in the list and the workload fits on it, or -1 otherwise.
+func pickPreferredNode(nodes []*nodeStat, workload *workResources, fits func(node *nodeStat) bool) int {
+	if workload.preferredNode == "" {
+		return -1
+	}
+
+	for i, node := range nodes {
+		node.mutex.Lock()
+		if node.uuid == workload.preferredNode && fits(node) {
+			return i // locked nodeStat
+		}
+		node.mutex.Unlock()
+	}
+
+	return -1
 }
 
 // Find suitable compute node, returning referenced to a locked nodeStat if found
@@ -711,33 +958,21 @@ func pickComputeNode(sched *ssntpSchedulerServer, controllerUUID string, workloa
 		return nil
 	}
 
-	/* First try nodes after the MRU */
-	if sched.cnMRUIndex != -1 && sched.cnMRUIndex < len(sched.cnList)-1 {
-		for i, node := range sched.cnList[sched.cnMRUIndex+1:] {
-			node.mutex.Lock()
-			if node == sched.cnMRU {
-				node.mutex.Unlock()
-				continue
-			}
+	addRecentPlacements(sched.cnList, workload)
 
-			if sched.workloadFits(node, workload) == true {
-				sched.cnMRUIndex = sched.cnMRUIndex + 1 + i
-				sched.cnMRU = node
-				return node // locked nodeStat
-			}
-			node.mutex.Unlock()
-		}
+	cpuRatio := sched.cpuOvercommitRatio()
+	fits := func(node *nodeStat) bool {
+		return sched.workloadFits(node, workload, cpuRatio)
 	}
 
-	/* Then try the whole list, including the MRU */
-	for i, node := range sched.cnList {
-		node.mutex.Lock()
-		if sched.workloadFits(node, workload) == true {
-			sched.cnMRUIndex = i
-			sched.cnMRU = node
-			return node // locked nodeStat
-		}
-		node.mutex.Unlock()
+	i := pickPreferredNode(sched.cnList, workload, fits)
+	if i == -1 {
+		i = sched.placementPolicy().pick(sched.cnList, sched.cnMRU, sched.cnMRUIndex, fits)
+	}
+	if i != -1 {
+		sched.cnMRUIndex = i
+		sched.cnMRU = sched.cnList[i]
+		return sched.cnMRU // locked nodeStat
 	}
 
 	sched.sendStartFailureError(controllerUUID, workload.instanceUUID, payloads.FullCloud, restart)
@@ -755,33 +990,18 @@ func pickNetworkNode(sched *ssntpSchedulerServer, controllerUUID string, workloa
 		return nil
 	}
 
-	/* First try nodes after the MRU */
-	if sched.nnMRUIndex != -1 && sched.nnMRUIndex < len(sched.nnList)-1 {
-		for i, node := range sched.nnList[sched.nnMRUIndex+1:] {
-			node.mutex.Lock()
-			if node == sched.nnMRU {
-				node.mutex.Unlock()
-				continue
-			}
+	addRecentPlacements(sched.nnList, workload)
 
-			if sched.workloadFits(node, workload) == true {
-				sched.nnMRUIndex = sched.nnMRUIndex + 1 + i
-				sched.nnMRU = node
-				return node // locked nodeStat
-			}
-			node.mutex.Unlock()
-		}
+	cpuRatio := sched.cpuOvercommitRatio()
+	fits := func(node *nodeStat) bool {
+		return sched.workloadFits(node, workload, cpuRatio)
 	}
 
-	/* Then try the whole list, including the MRU */
-	for i, node := range sched.nnList {
-		node.mutex.Lock()
-		if sched.workloadFits(node, workload) == true {
-			sched.nnMRUIndex = i
-			sched.nnMRU = node
-			return node // locked nodeStat
-		}
-		node.mutex.Unlock()
+	i := sched.placementPolicy().pick(sched.nnList, sched.nnMRU, sched.nnMRUIndex, fits)
+	if i != -1 {
+		sched.nnMRUIndex = i
+		sched.nnMRU = sched.nnList[i]
+		return sched.nnMRU // locked nodeStat
 	}
 
 	sched.sendStartFailureError(controllerUUID, workload.instanceUUID, payloads.NoNetworkNodes, restart)
@@ -790,9 +1010,10 @@ func pickNetworkNode(sched *ssntpSchedulerServer, controllerUUID string, workloa
 
 func startWorkload(sched *ssntpSchedulerServer, controllerUUID string, payload []byte) (dest ssntp.ForwardDestination, instanceUUID string) {
 	var work payloads.Start
-	err := yaml.Unmarshal(payload, &work)
+	err := payloads.Unmarshal(payload, &work)
 	if err != nil {
-		glog.Errorf("Bad START workload yaml from Controller %s: %s\n", controllerUUID, err)
+		glog.Errorf("Bad START workload payload from Controller %s: %s\n", controllerUUID, err)
+		sched.recordStartFailure("", payloads.InvalidPayload)
 		dest.SetDecision(ssntp.Discard)
 		return dest, ""
 	}
@@ -800,6 +1021,7 @@ func startWorkload(sched *ssntpSchedulerServer, controllerUUID string, payload [
 	workload, err := sched.getWorkloadResources(&work)
 	if err != nil {
 		glog.Errorf("Bad START workload resource list from Controller %s: %s\n", controllerUUID, err)
+		sched.recordStartFailure(work.Start.InstanceUUID, payloads.InvalidData)
 		dest.SetDecision(ssntp.Discard)
 		return dest, ""
 	}
@@ -821,6 +1043,7 @@ func startWorkload(sched *ssntpSchedulerServer, controllerUUID string, payload [
 		//	to back on the same targetNode, but also not add latency to dispatch and
 		//	hopefully not queue when all nodes have just started a workload.
 		sched.decrementResourceUsage(targetNode, &workload)
+		sched.recordStartDispatch(instanceUUID, targetNode.uuid)
 
 		dest.AddRecipient(targetNode.uuid)
 		targetNode.mutex.Unlock()
@@ -863,11 +1086,14 @@ func (sched *ssntpSchedulerServer) CommandForward(controllerUUID string, command
 		dest, instanceUUID = startWorkload(sched, controllerUUID, payload)
 	case ssntp.DELETE:
 		fallthrough
-	case ssntp.AttachVolume:
+	case ssntp.AttachVolume, ssntp.DetachVolume:
 		fallthrough
 	case ssntp.EVACUATE:
 		fallthrough
 	case ssntp.Restore:
+		fallthrough
+	case ssntp.REBOOT, ssntp.PAUSE, ssntp.UNPAUSE, ssntp.SUSPEND, ssntp.RESUME, ssntp.MIGRATE,
+		ssntp.CONSOLE, ssntp.ExtendVolume:
 		dest, instanceUUID = sched.fwdCmdToComputeNode(command, payload)
 	case ssntp.AssignPublicIP:
 		fallthrough
@@ -883,10 +1109,87 @@ func (sched *ssntpSchedulerServer) CommandForward(controllerUUID string, command
 	return
 }
 
+func (sched *ssntpSchedulerServer) placementPolicy() placementPolicy {
+	sched.policyMutex.RLock()
+	defer sched.policyMutex.RUnlock()
+
+	return sched.policy
+}
+
+func (sched *ssntpSchedulerServer) cpuOvercommitRatio() float64 {
+	sched.policyMutex.RLock()
+	defer sched.policyMutex.RUnlock()
+
+	return sched.cpuRatio
+}
+
+func (sched *ssntpSchedulerServer) setCPUOvercommitRatio(ratio float64) error {
+	if ratio < 1 {
+		return fmt.Errorf("invalid cpu overcommit ratio %v, must be >= 1", ratio)
+	}
+
+	sched.policyMutex.Lock()
+	sched.cpuRatio = ratio
+	sched.policyMutex.Unlock()
+
+	glog.Infof("Using %v cpu overcommit ratio\n", ratio)
+
+	return nil
+}
+
+func (sched *ssntpSchedulerServer) setPlacementPolicy(policy payloads.PlacementPolicy, weights payloads.PlacementWeights) error {
+	p, err := newPlacementPolicy(policy, weights)
+	if err != nil {
+		return err
+	}
+
+	sched.policyMutex.Lock()
+	sched.policy = p
+	sched.policyMutex.Unlock()
+
+	glog.Infof("Using %s placement policy\n", p.policy())
+
+	return nil
+}
+
+// The placement settings from the command line always win over the
+// cluster configuration ones.
+func (sched *ssntpSchedulerServer) configure(conf *payloads.Configure) {
+	if sched.placement == "" {
+		err := sched.setPlacementPolicy(conf.Configure.Scheduler.PlacementPolicy,
+			conf.Configure.Scheduler.PlacementWeights)
+		if err != nil {
+			glog.Errorf("Bad placement policy configuration: %s\n", err)
+		}
+	}
+
+	if sched.cpuOvercommit == 0 {
+		ratio := conf.Configure.Scheduler.CPUOvercommitRatio
+		if ratio == 0 {
+			ratio = defaultCPUOvercommitRatio
+		}
+
+		err := sched.setCPUOvercommitRatio(ratio)
+		if err != nil {
+			glog.Errorf("Bad cpu overcommit configuration: %s\n", err)
+		}
+	}
+}
+
 func (sched *ssntpSchedulerServer) CommandNotify(uuid string, command ssntp.Command, frame *ssntp.Frame) {
-	// Currently all commands are handled by CommandForward, the SSNTP command forwader,
-	// or directly by role defined forwarding rules.
+	// Currently all other commands are handled by CommandForward, the SSNTP
+	// command forwader, or directly by role defined forwarding rules.
 	glog.V(2).Infof("COMMAND %v from %s\n", command, uuid)
+
+	if command == ssntp.CONFIGURE {
+		conf, err := configuration.Payload(frame.Payload)
+		if err != nil {
+			glog.Errorf("Bad CONFIGURE yaml from %s: %s\n", uuid, err)
+			return
+		}
+
+		sched.configure(&conf)
+	}
 }
 
 func (sched *ssntpSchedulerServer) EventForward(uuid string, event ssntp.Event, frame *ssntp.Frame) (dest ssntp.ForwardDestination) {
@@ -1115,6 +1418,14 @@ func setSSNTPForwardRules(sched *ssntpSchedulerServer) {
 			Operand: ssntp.InstanceStopped,
 			Dest:    ssntp.Controller,
 		},
+		{ // all MigrationTargetReady events go to all Controllers
+			Operand: ssntp.MigrationTargetReady,
+			Dest:    ssntp.Controller,
+		},
+		{ // all InstanceMigrated events go to all Controllers
+			Operand: ssntp.InstanceMigrated,
+			Dest:    ssntp.Controller,
+		},
 		{ // all ConcentratorInstanceAdded events go to all Controllers
 			Operand: ssntp.ConcentratorInstanceAdded,
 			Dest:    ssntp.Controller,
@@ -1183,6 +1494,75 @@ func setSSNTPForwardRules(sched *ssntpSchedulerServer) {
 			Operand: ssntp.AttachVolumeFailure,
 			Dest:    ssntp.Controller,
 		},
+		{ // all DetachVolume command are processed by the Command forwarder
+			Operand:        ssntp.DetachVolume,
+			CommandForward: sched,
+		},
+		{ // all DetachVolumeFailure errors go to all Controllers
+			Operand: ssntp.DetachVolumeFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all REBOOT commands are processed by the Command forwarder
+			Operand:        ssntp.REBOOT,
+			CommandForward: sched,
+		},
+		{ // all PAUSE commands are processed by the Command forwarder
+			Operand:        ssntp.PAUSE,
+			CommandForward: sched,
+		},
+		{ // all UNPAUSE commands are processed by the Command forwarder
+			Operand:        ssntp.UNPAUSE,
+			CommandForward: sched,
+		},
+		{ // all SUSPEND commands are processed by the Command forwarder
+			Operand:        ssntp.SUSPEND,
+			CommandForward: sched,
+		},
+		{ // all RESUME commands are processed by the Command forwarder
+			Operand:        ssntp.RESUME,
+			CommandForward: sched,
+		},
+		{ // all RebootFailure errors go to all Controllers
+			Operand: ssntp.RebootFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all PauseFailure errors go to all Controllers
+			Operand: ssntp.PauseFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all UnpauseFailure errors go to all Controllers
+			Operand: ssntp.UnpauseFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all SuspendFailure errors go to all Controllers
+			Operand: ssntp.SuspendFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all ResumeFailure errors go to all Controllers
+			Operand: ssntp.ResumeFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all MIGRATE commands are processed by the Command forwarder
+			Operand:        ssntp.MIGRATE,
+			CommandForward: sched,
+		},
+		{ // all MigrateFailure errors go to all Controllers
+			Operand: ssntp.MigrateFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all CONSOLE commands are processed by the Command forwarder,
+			// replies are only forwarded to the Controller that sent them.
+			Operand:        ssntp.CONSOLE,
+			CommandForward: sched,
+		},
+		{ // all ExtendVolume commands are processed by the Command forwarder
+			Operand:        ssntp.ExtendVolume,
+			CommandForward: sched,
+		},
+		{ // all ExtendVolumeFailure errors go to all Controllers
+			Operand: ssntp.ExtendVolumeFailure,
+			Dest:    ssntp.Controller,
+		},
 		{ // all AssignPublicIP commands are processed by the Command forwarder
 			Operand:        ssntp.AssignPublicIP,
 			CommandForward: sched,
@@ -1210,20 +1590,62 @@ func initLogger() error {
 	return nil
 }
 
+// The SSNTP server only hands the cluster configuration out to its
+// clients, so load our own copy of it.
+func loadClusterConfiguration(sched *ssntpSchedulerServer) {
+	blob, err := configuration.ExtractBlob(*configURI)
+	if err != nil {
+		glog.Warningf("Unable to load cluster configuration from %s: %s", *configURI, err)
+		return
+	}
+
+	conf, err := configuration.Payload(blob)
+	if err != nil {
+		glog.Warningf("Bad cluster configuration: %s", err)
+		return
+	}
+
+	sched.configure(&conf)
+}
+
 func configSchedulerServer() (sched *ssntpSchedulerServer) {
 	setLimits()
 
 	sched = newSsntpSchedulerServer()
 	sched.cpuprofile = *cpuprofile
 	sched.heartbeat = *heartbeat
+	sched.placement = *placement
+	sched.cpuOvercommit = *cpuOvercommit
 
 	toggleDebug(sched)
 
+	if sched.placement != "" {
+		err := sched.setPlacementPolicy(payloads.PlacementPolicy(sched.placement), payloads.PlacementWeights{})
+		if err != nil {
+			glog.Errorf("%s", err)
+			return nil
+		}
+	}
+
+	if sched.cpuOvercommit != 0 {
+		err := sched.setCPUOvercommitRatio(sched.cpuOvercommit)
+		if err != nil {
+			glog.Errorf("%s", err)
+			return nil
+		}
+	}
+
+	if sched.placement == "" || sched.cpuOvercommit == 0 {
+		loadClusterConfiguration(sched)
+	}
+
 	sched.config = &ssntp.Config{
-		CAcert:    *cacert,
-		Cert:      *cert,
-		ConfigURI: *configURI,
-		Log:       ssntp.Log,
+		CAcert:             *cacert,
+		Cert:               *cert,
+		ConfigURI:          *configURI,
+		CRL:                *crl,
+		Log:                ssntp.Log,
+		AuthorizationRules: ssntp.DefaultAuthorizationRules,
 	}
 
 	setSSNTPForwardRules(sched)
@@ -1251,5 +1673,32 @@ func main() {
 		return
 	}
 
+	if *httpAddr != "" {
+		go serveIntrospection(sched, *httpAddr)
+	}
+
+	if *peer != "" {
+		standby(*peer, sched.config)
+
+		sched.config.SyncChannel = make(chan error, 1)
+		go fencePeer(sched, *peer, sched.config.SyncChannel)
+	}
+
+	go reloadCredentialsOnSIGHUP(sched)
+
 	sched.ssntp.Serve(sched.config, sched)
 }
+
+// SIGHUP makes the scheduler reload its certificate and CRL right away,
+// instead of waiting for SSNTP to notice they changed.
+func reloadCredentialsOnSIGHUP(sched *ssntpSchedulerServer) {
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGHUP)
+
+	for range signalCh {
+		glog.Info("Reloading SSNTP credentials")
+		if err := sched.ssntp.ReloadCredentials(); err != nil {
+			glog.Errorf("Unable to reload SSNTP credentials: %v", err)
+		}
+	}
+}
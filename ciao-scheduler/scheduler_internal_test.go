@@ -174,8 +174,9 @@ func TestPickComputeNode(t *testing.T) {
 	resources, err := sched.getWorkloadResources(work)
 	if err != nil ||
 		resources.instanceUUID != "c73322e8-d5fe-4d57-874c-dcee4fd368cd" ||
+		resources.vcpusReq != 2 ||
 		resources.memReqMB != 256 {
-		t.Fatalf("bad workload resources %s, %d", resources.instanceUUID, resources.memReqMB)
+		t.Fatalf("bad workload resources %s, %d, %d", resources.instanceUUID, resources.vcpusReq, resources.memReqMB)
 	}
 
 	// no compute nodes
@@ -222,6 +223,402 @@ func TestPickComputeNode(t *testing.T) {
 	}
 }
 
+func TestPickComputeNodeVCPUs(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	err := sched.setCPUOvercommitRatio(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// more VCPUs than any node has CPUs online
+	var work = createStartWorkload(64, 256, 10000)
+	resources, err := sched.getWorkloadResources(work)
+	if err != nil {
+		t.Fatal("bad workload resources")
+	}
+
+	spinUpComputeNodeLarge(sched, 1)
+	node := PickComputeNode(sched, "", &resources, false)
+	if node != nil {
+		t.Error("found compute fit for a workload larger than any node")
+	}
+
+	// 4 CPUs, so 8 VCPUs with a 2 overcommit ratio
+	work = createStartWorkload(3, 256, 10000)
+	resources, err = sched.getWorkloadResources(work)
+	if err != nil {
+		t.Fatal("bad workload resources")
+	}
+
+	for i := 0; i < 2; i++ {
+		node = PickComputeNode(sched, "", &resources, false)
+		if node == nil {
+			t.Fatalf("found no compute fit for workload %d", i)
+		}
+		sched.decrementResourceUsage(node, &resources)
+		node.mutex.Unlock()
+	}
+
+	if node.vcpusAllocated != 6 {
+		t.Errorf("expected 6 VCPUs allocated, got %d", node.vcpusAllocated)
+	}
+
+	node = PickComputeNode(sched, "", &resources, false)
+	if node != nil {
+		t.Error("found compute fit on an overcommitted node")
+	}
+
+	// nodes not reporting their CPUs take any VCPU demand
+	sched.cnMap[fmt.Sprintf("%08d", 1)].cpus = -1
+	node = PickComputeNode(sched, "", &resources, false)
+	if node == nil {
+		t.Error("found no compute fit on a node with unknown CPUs")
+	} else {
+		node.mutex.Unlock()
+	}
+
+	err = sched.setCPUOvercommitRatio(0.5)
+	if err == nil {
+		t.Error("expected error setting a cpu overcommit ratio below 1")
+	}
+}
+
+func TestPickComputeNodeSelector(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	var work = createStartWorkload(1, 256, 10000)
+	work.Start.RequestedResources = append(work.Start.RequestedResources,
+		payloads.RequestedResource{Type: payloads.NodeSelector, ValueString: "disk=ssd"})
+	resources, err := sched.getWorkloadResources(work)
+	if err != nil {
+		t.Fatal("bad workload resources")
+	}
+
+	spinUpComputeNodeLarge(sched, 1)
+	spinUpComputeNodeLarge(sched, 2)
+	sched.cnMap[fmt.Sprintf("%08d", 1)].labels = map[string]string{"disk": "hdd"}
+
+	node := PickComputeNode(sched, "", &resources, false)
+	if node != nil {
+		t.Error("found compute fit on a node without the selected label")
+	}
+
+	sched.cnMap[fmt.Sprintf("%08d", 2)].labels = map[string]string{"disk": "ssd"}
+	node = PickComputeNode(sched, "", &resources, false)
+	if node == nil {
+		t.Fatal("found no compute fit on a node with the selected label")
+	}
+	node.mutex.Unlock()
+	if node.uuid != fmt.Sprintf("%08d", 2) {
+		t.Errorf("expected node %08d, got %s", 2, node.uuid)
+	}
+
+	work.Start.RequestedResources = append(work.Start.RequestedResources,
+		payloads.RequestedResource{Type: payloads.NodeSelector, ValueString: "ssd"})
+	_, err = sched.getWorkloadResources(work)
+	if err == nil {
+		t.Error("expected error on a malformed node selector")
+	}
+}
+
+func TestPickComputeNodeAffinity(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	spinUpComputeNodeLarge(sched, 1)
+	spinUpComputeNodeLarge(sched, 2)
+	spinUpComputeNodeLarge(sched, 3)
+
+	// replicas of a workload that must not share a node
+	var work = createStartWorkload(1, 256, 10000)
+	work.Start.WorkloadUUID = "db"
+	work.Start.RequestedResources = append(work.Start.RequestedResources,
+		payloads.RequestedResource{Type: payloads.AntiAffinity, ValueString: "db"})
+	work.Start.AntiAffinityNodes = []string{fmt.Sprintf("%08d", 1)}
+
+	used := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		resources, err := sched.getWorkloadResources(work)
+		if err != nil {
+			t.Fatal("bad workload resources")
+		}
+
+		node := PickComputeNode(sched, "", &resources, false)
+		if node == nil {
+			t.Fatalf("found no compute fit for replica %d", i)
+		}
+		sched.decrementResourceUsage(node, &resources)
+		node.mutex.Unlock()
+
+		if node.uuid == fmt.Sprintf("%08d", 1) || used[node.uuid] {
+			t.Errorf("replica %d placed on already used node %s", i, node.uuid)
+		}
+		used[node.uuid] = true
+	}
+
+	resources, err := sched.getWorkloadResources(work)
+	if err != nil {
+		t.Fatal("bad workload resources")
+	}
+	node := PickComputeNode(sched, "", &resources, false)
+	if node != nil {
+		t.Error("found compute fit for an anti-affine replica on a full cluster")
+	}
+
+	// a workload that must run alongside the replicas
+	work = createStartWorkload(1, 256, 10000)
+	work.Start.WorkloadUUID = "cache"
+	work.Start.RequestedResources = append(work.Start.RequestedResources,
+		payloads.RequestedResource{Type: payloads.Affinity, ValueString: "db"})
+	resources, err = sched.getWorkloadResources(work)
+	if err != nil {
+		t.Fatal("bad workload resources")
+	}
+
+	node = PickComputeNode(sched, "", &resources, false)
+	if node == nil {
+		t.Fatal("found no compute fit for an affine workload")
+	}
+	node.mutex.Unlock()
+	if !used[node.uuid] {
+		t.Errorf("affine workload placed on node %s not hosting its workload", node.uuid)
+	}
+
+	work.Start.RequestedResources = append(work.Start.RequestedResources,
+		payloads.RequestedResource{Type: payloads.Affinity})
+	_, err = sched.getWorkloadResources(work)
+	if err == nil {
+		t.Error("expected error on an affinity rule without workload")
+	}
+}
+
+func TestPickComputeNodeMigrationSource(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	spinUpComputeNodeLarge(sched, 1)
+	spinUpComputeNodeLarge(sched, 2)
+
+	var work = createStartWorkload(1, 256, 10000)
+	work.Start.MigrationSource = fmt.Sprintf("%08d", 1)
+	for i := 0; i < 2; i++ {
+		resources, err := sched.getWorkloadResources(work)
+		if err != nil {
+			t.Fatal("bad workload resources")
+		}
+
+		node := PickComputeNode(sched, "", &resources, false)
+		if node == nil {
+			t.Fatal("found no compute fit for a migration target")
+		}
+		node.mutex.Unlock()
+
+		if node.uuid == work.Start.MigrationSource {
+			t.Errorf("migration target placed on its source node %s", node.uuid)
+		}
+	}
+}
+
+func TestPickComputeNodePreferredNode(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	spinUpComputeNodeLarge(sched, 1)
+	spinUpComputeNodeLarge(sched, 2)
+	spinUpComputeNodeVerySmall(sched, 3)
+
+	var work = createStartWorkload(1, 256, 10000)
+	work.Start.PreferredNode = fmt.Sprintf("%08d", 2)
+	for i := 0; i < 2; i++ {
+		resources, err := sched.getWorkloadResources(work)
+		if err != nil {
+			t.Fatal("bad workload resources")
+		}
+
+		node := PickComputeNode(sched, "", &resources, true)
+		if node == nil {
+			t.Fatal("found no compute fit for a resized instance")
+		}
+		node.mutex.Unlock()
+
+		if node.uuid != work.Start.PreferredNode {
+			t.Errorf("resized instance placed on %s instead of %s",
+				node.uuid, work.Start.PreferredNode)
+		}
+	}
+
+	// the workload does not fit on its preferred node anymore
+
+	work.Start.PreferredNode = fmt.Sprintf("%08d", 3)
+	resources, err := sched.getWorkloadResources(work)
+	if err != nil {
+		t.Fatal("bad workload resources")
+	}
+
+	node := PickComputeNode(sched, "", &resources, true)
+	if node == nil {
+		t.Fatal("found no compute fit for a resized instance")
+	}
+	node.mutex.Unlock()
+
+	if node.uuid == work.Start.PreferredNode {
+		t.Errorf("resized instance placed on a node it does not fit on")
+	}
+}
+
+func testPickComputeNodePolicy(t *testing.T, policy payloads.PlacementPolicy, weights payloads.PlacementWeights, expected string) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	err := sched.setPlacementPolicy(policy, weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var work = createStartWorkload(2, 256, 10000)
+	resources, err := sched.getWorkloadResources(work)
+	if err != nil {
+		t.Fatal("bad workload resources")
+	}
+
+	// a node too small for the workload, an almost full one, an empty
+	// but heavily loaded one and a half full, idle one
+	spinUpComputeNodeVerySmall(sched, 1)
+	spinUpComputeNode(sched, 2, 16384)
+	spinUpComputeNode(sched, 3, 16384)
+	spinUpComputeNode(sched, 4, 16384)
+	sched.cnMap[fmt.Sprintf("%08d", 2)].memAvailMB = 1024
+	sched.cnMap[fmt.Sprintf("%08d", 3)].load = 16
+	sched.cnMap[fmt.Sprintf("%08d", 4)].memAvailMB = 8192
+
+	node := PickComputeNode(sched, "", &resources, false)
+	if node == nil {
+		t.Fatalf("%s found no compute fit when one should exist", policy)
+	}
+	node.mutex.Unlock()
+
+	if node.uuid != expected {
+		t.Errorf("%s picked node %s, expected %s", policy, node.uuid, expected)
+	}
+	if sched.cnMRU != node {
+		t.Errorf("%s did not update the MRU node", policy)
+	}
+}
+
+func TestPickComputeNodePolicies(t *testing.T) {
+	var policyTests = []struct {
+		policy   payloads.PlacementPolicy
+		weights  payloads.PlacementWeights
+		expected string
+	}{
+		{payloads.FirstFit, payloads.PlacementWeights{}, "00000002"},
+		{payloads.BinPack, payloads.PlacementWeights{}, "00000002"},
+		{payloads.Spread, payloads.PlacementWeights{}, "00000004"},
+		{payloads.Weighted, payloads.PlacementWeights{}, "00000004"},
+		{payloads.Weighted, payloads.PlacementWeights{Mem: 1}, "00000003"},
+	}
+
+	for _, test := range policyTests {
+		testPickComputeNodePolicy(t, test.policy, test.weights, test.expected)
+	}
+}
+
+func TestPickNetworkNodePolicies(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	var work payloads.Start
+	err := yaml.Unmarshal([]byte(testutil.CNCIStartYaml), &work)
+	if err != nil {
+		t.Fatalf("bad CNCI workload yaml: %s", err)
+	}
+
+	resources, err := sched.getWorkloadResources(&work)
+	if err != nil {
+		t.Fatalf("bad CNCI workload resources: %s", err)
+	}
+
+	// the largest node does not have the requested networks
+	spinUpNetworkNode(sched, 1, 8192, testutil.MultipleComputeNetworks)
+	spinUpNetworkNode(sched, 2, 141312, testutil.PartialComputeNetworks)
+	spinUpNetworkNode(sched, 3, 16384, testutil.MultipleComputeNetworks)
+
+	var policyTests = []struct {
+		policy   payloads.PlacementPolicy
+		expected string
+	}{
+		{payloads.BinPack, "00000001"},
+		{payloads.Spread, "00000001"},
+	}
+
+	for _, test := range policyTests {
+		err = sched.setPlacementPolicy(test.policy, payloads.PlacementWeights{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		node := PickNetworkNode(sched, "", &resources, false)
+		if node == nil {
+			t.Fatalf("%s found no network fit when one should exist", test.policy)
+		}
+		node.mutex.Unlock()
+
+		if node.uuid != test.expected {
+			t.Errorf("%s picked node %s, expected %s", test.policy, node.uuid, test.expected)
+		}
+	}
+}
+
+func TestSetPlacementPolicy(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+
+	if sched.placementPolicy().policy() != payloads.FirstFit {
+		t.Errorf("expected default %s policy, got %s", payloads.FirstFit, sched.placementPolicy().policy())
+	}
+
+	var conf payloads.Configure
+	conf.Configure.Scheduler.PlacementPolicy = payloads.BinPack
+	sched.configure(&conf)
+	if sched.placementPolicy().policy() != payloads.BinPack {
+		t.Errorf("expected %s policy, got %s", payloads.BinPack, sched.placementPolicy().policy())
+	}
+
+	// an unknown policy keeps the current one
+	conf.Configure.Scheduler.PlacementPolicy = "best_fit"
+	sched.configure(&conf)
+	if sched.placementPolicy().policy() != payloads.BinPack {
+		t.Errorf("expected %s policy, got %s", payloads.BinPack, sched.placementPolicy().policy())
+	}
+
+	// the command line policy wins over the cluster configuration
+	sched.placement = string(payloads.Spread)
+	conf.Configure.Scheduler.PlacementPolicy = payloads.Weighted
+	sched.configure(&conf)
+	if sched.placementPolicy().policy() != payloads.BinPack {
+		t.Errorf("expected %s policy, got %s", payloads.BinPack, sched.placementPolicy().policy())
+	}
+}
+
 func benchmarkPickComputeNode(b *testing.B, nodecount int) {
 	sched = configSchedulerServer()
 	if sched == nil {
@@ -761,6 +1158,13 @@ func TestGetWorkloadAgentUUID(t *testing.T) {
 		{ssntp.EVACUATE, []byte(testutil.EvacuateYaml), "", testutil.AgentUUID},
 		{ssntp.Restore, []byte(testutil.RestoreYaml), "", testutil.AgentUUID},
 		{ssntp.AttachVolume, []byte(testutil.AttachVolumeYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.DetachVolume, []byte(testutil.DetachVolumeYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.REBOOT, []byte(testutil.RebootYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.PAUSE, []byte(testutil.PauseYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.SUSPEND, []byte(testutil.SuspendYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.MIGRATE, []byte(testutil.LiveMigrateYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.CONSOLE, []byte(testutil.ConsoleLogCmdYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.ExtendVolume, []byte(testutil.ExtendVolumeYaml), testutil.InstanceUUID, testutil.AgentUUID},
 	}
 	for _, test := range stringTests {
 		instanceUUID, agentUUID, _ := GetWorkloadAgentUUID(sched, test.cmd, test.yaml)
@@ -0,0 +1,229 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/ciao-project/ciao/uuid"
+	"github.com/golang/glog"
+)
+
+var peer = flag.String("peer", "",
+	"host[:port] of a peer scheduler, stay on standby for as long as it is serving")
+
+const (
+	defaultSSNTPPort = 8888
+
+	// The standby pings its peer every peerKeepaliveInterval and loses
+	// its connection after peerKeepaliveMisses intervals of silence.
+	peerKeepaliveInterval = time.Second
+	peerKeepaliveMisses   = 3
+
+	// peerLease is how long the peer keeps its lease on serving after
+	// the standby last heard from it.  The standby only takes over once
+	// the lease expired without managing to reconnect to the peer.
+	peerLease = 5 * time.Second
+
+	// peerProbeInterval is the interval at which a serving scheduler
+	// checks whether its peer is serving as well.
+	peerProbeInterval = 5 * time.Second
+	peerProbeTimeout  = 2 * time.Second
+)
+
+func peerAddress(peer string) string {
+	if _, _, err := net.SplitHostPort(peer); err == nil {
+		return peer
+	}
+
+	return fmt.Sprintf("%s:%d", peer, defaultSSNTPPort)
+}
+
+// peerLog logs the SSNTP messages of peer connections only when verbose,
+// as failing to connect to a peer that is not serving is expected.
+type peerLog struct{}
+
+func (l peerLog) Errorf(format string, args ...interface{}) {
+	glog.V(2).Infof("Peer SSNTP Error: "+format, args...)
+}
+
+func (l peerLog) Warningf(format string, args ...interface{}) {
+	glog.V(2).Infof("Peer SSNTP Warning: "+format, args...)
+}
+
+func (l peerLog) Infof(format string, args ...interface{}) {
+	glog.V(3).Infof("Peer SSNTP Info: "+format, args...)
+}
+
+// peerClient is an SSNTP connection to the peer scheduler, authenticated
+// with the scheduler certificate.
+type peerClient struct {
+	ssntp        ssntp.Client
+	disconnected chan struct{}
+	once         sync.Once
+}
+
+func (client *peerClient) ConnectNotify() {}
+
+func (client *peerClient) DisconnectNotify() {
+	client.once.Do(func() { close(client.disconnected) })
+}
+
+func (client *peerClient) StatusNotify(status ssntp.Status, frame *ssntp.Frame) {}
+
+func (client *peerClient) CommandNotify(command ssntp.Command, frame *ssntp.Frame) {}
+
+func (client *peerClient) EventNotify(event ssntp.Event, frame *ssntp.Frame) {}
+
+func (client *peerClient) ErrorNotify(error ssntp.Error, frame *ssntp.Frame) {}
+
+func peerConfig(config *ssntp.Config, address string, id string) *ssntp.Config {
+	// SSNTP clients fall back to the hosts of the CA certificate and to
+	// localhost, make sure they are tried on the peer port.
+
+	port := defaultSSNTPPort
+	if _, p, err := net.SplitHostPort(address); err == nil {
+		port, _ = strconv.Atoi(p)
+	}
+
+	return &ssntp.Config{
+		UUID:              id,
+		URIs:              []string{address},
+		Port:              uint32(port),
+		CAcert:            config.CAcert,
+		Cert:              config.Cert,
+		CRL:               config.CRL,
+		Log:               peerLog{},
+		KeepaliveInterval: peerKeepaliveInterval,
+		KeepaliveMisses:   peerKeepaliveMisses,
+	}
+}
+
+// dialPeer connects to the peer scheduler at address, giving up after
+// timeout.
+func dialPeer(config *ssntp.Config, address string, id string, timeout time.Duration) (*peerClient, error) {
+	client := &peerClient{disconnected: make(chan struct{})}
+
+	dialCh := make(chan error, 1)
+	go func() {
+		dialCh <- client.ssntp.Dial(peerConfig(config, address, id), client)
+	}()
+
+	select {
+	case err := <-dialCh:
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case <-time.After(timeout):
+	}
+
+	client.ssntp.Close()
+	<-dialCh
+
+	return nil, fmt.Errorf("Unable to connect to %s", address)
+}
+
+// waitForPeerFailure returns once the lease of the peer scheduler at
+// address expired.  The lease is renewed for as long as the standby is
+// connected to the peer, SSNTP keepalives telling when the peer stops
+// answering.
+func waitForPeerFailure(config *ssntp.Config, address string, lease time.Duration) {
+	id := uuid.Generate().String()
+	expiry := time.Now().Add(lease)
+
+	for {
+		timeout := time.Until(expiry)
+		if timeout <= 0 {
+			return
+		}
+
+		client, err := dialPeer(config, address, id, timeout)
+		if err != nil {
+			glog.V(1).Infof("Peer scheduler %s not serving: %v", address, err)
+			time.Sleep(peerKeepaliveInterval)
+			continue
+		}
+
+		glog.Infof("Connected to peer scheduler %s", address)
+		<-client.disconnected
+		client.ssntp.Close()
+
+		expiry = time.Now().Add(lease)
+		glog.Warningf("Lost peer scheduler %s, its lease expires in %v", address, lease)
+	}
+}
+
+// peerConflict returns true if the peer scheduler at address is serving
+// with a lower UUID than id, our own server UUID.  Both schedulers serve
+// when they started together or took over from each other during a network
+// partition.  The one with the higher UUID must then step down.
+func peerConflict(config *ssntp.Config, address string, id string) bool {
+	client, err := dialPeer(config, address, id, peerProbeTimeout)
+	if err != nil {
+		return false
+	}
+
+	peerID := client.ssntp.ServerUUID()
+	client.ssntp.Close()
+
+	// We may have connected to ourselves.
+
+	if peerID == id {
+		return false
+	}
+
+	glog.Warningf("Peer scheduler %s (%s) is serving as well", address, peerID)
+
+	return id > peerID
+}
+
+func standby(peer string, config *ssntp.Config) {
+	address := peerAddress(peer)
+
+	glog.Infof("Standing by for peer scheduler %s", address)
+	waitForPeerFailure(config, address, peerLease)
+	glog.Warningf("Peer scheduler %s failed, taking over", address)
+}
+
+// fencePeer stops sched and exits, for the scheduler to be restarted as a
+// standby, if the peer scheduler is serving as well and must keep serving.
+// The SSNTP clients of sched then reconnect to the peer.
+func fencePeer(sched *ssntpSchedulerServer, peer string, serving chan error) {
+	if err := <-serving; err != nil {
+		return
+	}
+
+	address := peerAddress(peer)
+	id := sched.ssntp.UUID()
+
+	for !peerConflict(sched.config, address, id) {
+		time.Sleep(peerProbeInterval)
+	}
+
+	glog.Errorf("Stepping down for peer scheduler %s", address)
+	sched.ssntp.Stop()
+	glog.Flush()
+	os.Exit(1)
+}
@@ -0,0 +1,98 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerAddress(t *testing.T) {
+	if a := peerAddress("sched-1"); a != "sched-1:8888" {
+		t.Errorf("expected sched-1:8888, got %s", a)
+	}
+
+	if a := peerAddress("10.0.0.1:9999"); a != "10.0.0.1:9999" {
+		t.Errorf("expected 10.0.0.1:9999, got %s", a)
+	}
+}
+
+const peerTestAddress = "localhost:8889"
+
+func startPeerScheduler(t *testing.T) *ssntpSchedulerServer {
+	peerSched := configSchedulerServer()
+	if peerSched == nil {
+		t.Fatal("unable to configure peer scheduler")
+	}
+
+	peerSched.config.Port = 8889
+	peerSched.config.SyncChannel = make(chan error, 1)
+	go peerSched.ssntp.Serve(peerSched.config, peerSched)
+
+	if err := <-peerSched.config.SyncChannel; err != nil {
+		t.Fatal(err)
+	}
+
+	return peerSched
+}
+
+func TestWaitForPeerFailure(t *testing.T) {
+	peerSched := startPeerScheduler(t)
+
+	done := make(chan struct{})
+	go func() {
+		waitForPeerFailure(peerSched.config, peerTestAddress, time.Second)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("took over from a serving peer")
+	case <-time.After(3 * time.Second):
+	}
+
+	peerSched.ssntp.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("did not take over from a failed peer")
+	}
+}
+
+func TestPeerConflict(t *testing.T) {
+	peerSched := startPeerScheduler(t)
+	peerID := peerSched.ssntp.UUID()
+
+	if peerConflict(peerSched.config, peerTestAddress, peerID) {
+		t.Error("stepping down for ourselves")
+	}
+
+	if peerConflict(peerSched.config, peerTestAddress, "00000000-0000-0000-0000-000000000000") {
+		t.Error("stepping down for a peer with a higher UUID")
+	}
+
+	if !peerConflict(peerSched.config, peerTestAddress, "ffffffff-ffff-ffff-ffff-ffffffffffff") {
+		t.Error("not stepping down for a peer with a lower UUID")
+	}
+
+	peerSched.ssntp.Stop()
+
+	if peerConflict(peerSched.config, peerTestAddress, "ffffffff-ffff-ffff-ffff-ffffffffffff") {
+		t.Error("stepping down for a stopped peer")
+	}
+}
@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ciao-project/ciao/ssntp"
+)
+
+var (
+	frameType    = flag.String("type", "", "Only dump frames of this type [COMMAND, STATUS, EVENT, ERROR]")
+	operand      = flag.String("operand", "", "Only dump frames with this operand, e.g. START")
+	nodeUUID     = flag.String("uuid", "", "Only dump frames sent to or received from this peer UUID")
+	direction    = flag.String("direction", "", "Only dump frames going in this direction [rx, tx]")
+	dumpPayloads = flag.Bool("payload", false, "Dump frame payloads")
+)
+
+func match(f *ssntp.CapturedFrame) bool {
+	if *frameType != "" && !strings.EqualFold(*frameType, f.Type) {
+		return false
+	}
+
+	if *operand != "" && !strings.EqualFold(*operand, f.Operand) {
+		return false
+	}
+
+	if *nodeUUID != "" && *nodeUUID != f.Peer && *nodeUUID != f.Local {
+		return false
+	}
+
+	if *direction != "" && *direction != string(f.Direction) {
+		return false
+	}
+
+	return true
+}
+
+func dumpFrame(w io.Writer, f *ssntp.CapturedFrame) {
+	arrow := "<-"
+	if f.Direction == ssntp.CaptureTx {
+		arrow = "->"
+	}
+
+	fmt.Fprintf(w, "%s %s %s(%s) %s %s(%s) %s %s",
+		f.Timestamp.Format(time.RFC3339Nano), f.Direction,
+		f.Local, f.LocalRole, arrow, f.Peer, f.PeerRole,
+		f.Type, f.Operand)
+
+	if f.CorrelationID != "" {
+		fmt.Fprintf(w, " corr=%s", f.CorrelationID)
+	}
+
+	if f.Label != "" {
+		fmt.Fprintf(w, " label=%s", f.Label)
+	}
+
+	fmt.Fprintln(w)
+
+	if *dumpPayloads && f.Payload != "" {
+		fmt.Fprintf(w, "    [%s]\n", f.Encoding)
+		for _, line := range strings.Split(strings.TrimSuffix(f.Payload, "\n"), "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
+
+func dumpCapture(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	frames, err := ssntp.ReadCapture(file)
+	if err != nil {
+		return fmt.Errorf("Could not read %s: %s", path, err)
+	}
+
+	for i := range frames {
+		if match(&frames[i]) {
+			dumpFrame(os.Stdout, &frames[i])
+		}
+	}
+
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] capture-file...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	for _, path := range flag.Args() {
+		if err := dumpCapture(path); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -28,6 +28,7 @@ type BlockDriver interface {
 	CreateBlockDevice(volumeUUID string, image string, sizeGB int) (BlockDevice, error)
 	CreateBlockDeviceFromSnapshot(volumeUUID string, snapshotID string) (BlockDevice, error)
 	CreateBlockDeviceSnapshot(volumeUUID string, snapshotID string) error
+	FlattenBlockDevice(volumeUUID string) error
 	DeleteBlockDevice(string) error
 	DeleteBlockDeviceSnapshot(volumeUUID string, snapshotID string) error
 	MapVolumeToNode(volumeUUID string) (string, error)
@@ -50,3 +51,14 @@ type BlockDevice struct {
 	Tag       string `json:"-"`          // arbitrary text identifier
 	Size      int    `json:"size"`       // size in GiB
 }
+
+// bytesToGiB converts a size in bytes to GiB, rounding up unless we've got
+// a multiple of 1GiB.
+func bytesToGiB(bytes uint64) int {
+	res := bytes / (1024 * 1024 * 1024)
+	rem := bytes % (1024 * 1024 * 1024)
+	if rem == 0 {
+		return int(res)
+	}
+	return int(res + 1)
+}
@@ -39,13 +39,7 @@ func (d CephDriver) getBlockDeviceSizeGiB(volumeUUID string) (int, error) {
 		return 0, err
 	}
 
-	// When converting to GiB round up unless we've got a multiple of 1GiB
-	res := bytes / (1024 * 1024 * 1024)
-	rem := bytes % (1024 * 1024 * 1024)
-	if rem == 0 {
-		return int(res), nil
-	}
-	return int(res + 1), nil
+	return bytesToGiB(bytes), nil
 }
 
 // CreateBlockDevice will create a rbd image in the ceph cluster.
@@ -102,6 +96,18 @@ func (d CephDriver) CreateBlockDeviceFromSnapshot(volumeUUID string, snapshotID
 	return BlockDevice{ID: ID, Size: size}, nil
 }
 
+// FlattenBlockDevice copies into a block device cloned from a snapshot the
+// data it shares with that snapshot, so that the snapshot can be deleted.
+func (d CephDriver) FlattenBlockDevice(volumeUUID string) error {
+	cmd := exec.Command("rbd", "--id", d.ID, "flatten", volumeUUID)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, out)
+	}
+	return nil
+}
+
 // CreateBlockDeviceSnapshot creates and protects the snapshot with the provided name
 func (d CephDriver) CreateBlockDeviceSnapshot(volumeUUID string, snapshotID string) error {
 	var cmd *exec.Cmd
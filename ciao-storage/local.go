@@ -0,0 +1,425 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ciao-project/ciao/uuid"
+)
+
+// DefaultLocalPath is the directory in which the local driver stores
+// volumes unless another one is configured.
+const DefaultLocalPath = "/var/lib/ciao/volumes"
+
+// LocalDriver maintains context for the local driver interface.  Volumes
+// are stored as sparse raw image files in a directory and are mapped to
+// loop devices, so the driver is only suitable for clusters in which all
+// the nodes can see this directory, e.g., single host clusters.
+type LocalDriver struct {
+	// Path is the directory in which volumes and snapshots are stored
+	Path string
+}
+
+func (d LocalDriver) volumePath(volumeUUID string) (string, error) {
+	_, err := uuid.Parse(volumeUUID)
+	if err != nil {
+		return "", fmt.Errorf("invalid UUID supplied for volume ID")
+	}
+
+	return filepath.Join(d.Path, volumeUUID), nil
+}
+
+func (d LocalDriver) snapshotPath(volumeUUID string, snapshotID string) (string, error) {
+	path, err := d.volumePath(volumeUUID)
+	if err != nil {
+		return "", err
+	}
+
+	if snapshotID == "" || strings.ContainsAny(snapshotID, "/@") {
+		return "", fmt.Errorf("invalid snapshot ID %q", snapshotID)
+	}
+
+	return path + "@" + snapshotID, nil
+}
+
+// copyFile copies src to a new file dst, preserving holes.  On file systems
+// that support it the data is shared between the two files until either is
+// written to.
+func copyFile(src, dst string) error {
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+
+	cmd := exec.Command("cp", "--sparse=always", "--reflink=auto", src, dst)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		_ = os.Remove(dst)
+		return fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, out)
+	}
+
+	// cp gives dst the mode of src, which is read only for snapshots.
+	err = os.Chmod(dst, 0600)
+	if err != nil {
+		_ = os.Remove(dst)
+		return fmt.Errorf("Unable to set mode of %s: %v", dst, err)
+	}
+	return nil
+}
+
+// CreateBlockDevice will create a raw image file in the volume directory.
+// If image is not empty the file is created from the image, which can be
+// in any format understood by qemu-img.
+func (d LocalDriver) CreateBlockDevice(volumeUUID string, image string, size int) (BlockDevice, error) {
+	if volumeUUID == "" {
+		volumeUUID = uuid.Generate().String()
+	}
+
+	path, err := d.volumePath(volumeUUID)
+	if err != nil {
+		return BlockDevice{}, err
+	}
+
+	err = os.MkdirAll(d.Path, 0755)
+	if err != nil {
+		return BlockDevice{}, fmt.Errorf("Unable to create volume directory: %v", err)
+	}
+
+	if image != "" {
+		cmd := exec.Command("qemu-img", "convert", "-O", "raw", image, path)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			_ = os.Remove(path)
+			return BlockDevice{}, fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, out)
+		}
+
+		return BlockDevice{ID: volumeUUID, Size: size}, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return BlockDevice{}, fmt.Errorf("Unable to create volume %s: %v", volumeUUID, err)
+	}
+
+	err = f.Truncate(int64(size) * 1024 * 1024 * 1024)
+	_ = f.Close()
+	if err != nil {
+		_ = os.Remove(path)
+		return BlockDevice{}, fmt.Errorf("Unable to set size of volume %s: %v", volumeUUID, err)
+	}
+
+	return BlockDevice{ID: volumeUUID, Size: size}, nil
+}
+
+// CreateBlockDeviceFromSnapshot will create a block device from a copy of
+// a previously created snapshot.
+func (d LocalDriver) CreateBlockDeviceFromSnapshot(volumeUUID string, snapshotID string) (BlockDevice, error) {
+	snapPath, err := d.snapshotPath(volumeUUID, snapshotID)
+	if err != nil {
+		return BlockDevice{}, err
+	}
+
+	ID := uuid.Generate().String()
+	path, _ := d.volumePath(ID)
+
+	err = copyFile(snapPath, path)
+	if err != nil {
+		return BlockDevice{}, err
+	}
+
+	size, err := d.getBlockDeviceSizeGiB(ID)
+	if err != nil {
+		_ = os.Remove(path)
+		return BlockDevice{}, fmt.Errorf("Error when querying block device size: %v", err)
+	}
+
+	return BlockDevice{ID: ID, Size: size}, nil
+}
+
+// FlattenBlockDevice does nothing as block devices created from snapshots
+// are full copies of them.
+func (d LocalDriver) FlattenBlockDevice(volumeUUID string) error {
+	return nil
+}
+
+// CreateBlockDeviceSnapshot creates a read only copy of the volume with the
+// provided name.
+func (d LocalDriver) CreateBlockDeviceSnapshot(volumeUUID string, snapshotID string) error {
+	snapPath, err := d.snapshotPath(volumeUUID, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	path, _ := d.volumePath(volumeUUID)
+	err = copyFile(path, snapPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(snapPath, 0400)
+	if err != nil {
+		_ = os.Remove(snapPath)
+		return fmt.Errorf("Unable to protect snapshot %s: %v", snapshotID, err)
+	}
+
+	return nil
+}
+
+// CopyBlockDevice will copy an existing volume
+func (d LocalDriver) CopyBlockDevice(volumeUUID string) (BlockDevice, error) {
+	src, err := d.volumePath(volumeUUID)
+	if err != nil {
+		return BlockDevice{}, err
+	}
+
+	ID := uuid.Generate().String()
+	path, _ := d.volumePath(ID)
+
+	err = copyFile(src, path)
+	if err != nil {
+		return BlockDevice{}, err
+	}
+
+	size, err := d.getBlockDeviceSizeGiB(ID)
+	if err != nil {
+		_ = os.Remove(path)
+		return BlockDevice{}, fmt.Errorf("Error when querying block device size: %v", err)
+	}
+
+	return BlockDevice{ID: ID, Size: size}, nil
+}
+
+// DeleteBlockDevice will remove a volume.  As with rbd images, volumes that
+// still have snapshots cannot be deleted.
+func (d LocalDriver) DeleteBlockDevice(volumeUUID string) error {
+	path, err := d.volumePath(volumeUUID)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := filepath.Glob(path + "@*")
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) > 0 {
+		return fmt.Errorf("Volume %s has %d snapshots", volumeUUID, len(snapshots))
+	}
+
+	return os.Remove(path)
+}
+
+// DeleteBlockDeviceSnapshot deletes the snapshot with the provided name
+func (d LocalDriver) DeleteBlockDeviceSnapshot(volumeUUID string, snapshotID string) error {
+	snapPath, err := d.snapshotPath(volumeUUID, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(snapPath)
+}
+
+// GetBlockDeviceSize returns the number of bytes used by the block device
+func (d LocalDriver) GetBlockDeviceSize(volumeUUID string) (uint64, error) {
+	path, err := d.volumePath(volumeUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(fi.Size()), nil
+}
+
+func (d LocalDriver) getBlockDeviceSizeGiB(volumeUUID string) (int, error) {
+	bytes, err := d.GetBlockDeviceSize(volumeUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	return bytesToGiB(bytes), nil
+}
+
+// MapVolumeToNode maps a volume to a loop device on a node.  The path to
+// the new device is returned if the mapping succeeds.
+func (d LocalDriver) MapVolumeToNode(volumeUUID string) (string, error) {
+	path, err := d.volumePath(volumeUUID)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("losetup", "--find", "--show", path)
+	data, err := cmd.Output()
+	if err != nil {
+		if err, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, err.Stderr)
+		}
+		return "", fmt.Errorf("Error when running: %v: %v", cmd.Args, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	if !scanner.Scan() {
+		return "", fmt.Errorf("Unable to determine device name for %s", volumeUUID)
+	}
+	return scanner.Text(), nil
+}
+
+// UnmapVolumeFromNode unmaps a volume from a loop device on a node.  Either
+// the volume UUID or the device can be specified.  As with rbd, a volume
+// that is mapped more than once can only be unmapped by device.
+func (d LocalDriver) UnmapVolumeFromNode(volumeUUID string) error {
+	device := volumeUUID
+	if !strings.HasPrefix(volumeUUID, "/dev/") {
+		vmap, err := d.GetVolumeMapping()
+		if err != nil {
+			return err
+		}
+
+		devices := vmap[volumeUUID]
+		if len(devices) == 0 {
+			return fmt.Errorf("Volume %s is not mapped", volumeUUID)
+		} else if len(devices) > 1 {
+			return fmt.Errorf("Volume %s is mapped more than once", volumeUUID)
+		}
+		device = devices[0]
+	}
+
+	cmd := exec.Command("losetup", "--detach", device)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, out)
+	}
+	return nil
+}
+
+// GetVolumeMapping returns a map of volumeUUID to mapped devices.
+func (d LocalDriver) GetVolumeMapping() (map[string][]string, error) {
+	// losetup reports the canonical path of the backing files.
+	dir, err := filepath.Abs(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	if realDir, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = realDir
+	}
+
+	cmd := exec.Command("losetup", "--list", "--noheadings", "--output", "NAME,BACK-FILE")
+	data, err := cmd.Output()
+	if err != nil {
+		if err, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, err.Stderr)
+		}
+		return nil, fmt.Errorf("Error when running: %v: %v", cmd.Args, err)
+	}
+
+	return parseLoopDevices(data, dir), nil
+}
+
+// parseLoopDevices extracts the loop devices backed by volumes stored in dir
+// from the output of losetup --list.
+func parseLoopDevices(data []byte, dir string) map[string][]string {
+	volumeDevMap := make(map[string][]string)
+
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		backingFile := strings.TrimSpace(fields[1])
+		if filepath.Dir(backingFile) != dir {
+			continue
+		}
+
+		volumeUUID := filepath.Base(backingFile)
+		if _, err := uuid.Parse(volumeUUID); err != nil {
+			continue
+		}
+
+		volumeDevMap[volumeUUID] = append(volumeDevMap[volumeUUID], fields[0])
+	}
+
+	return volumeDevMap
+}
+
+// IsValidSnapshotUUID returns true if the uuid matches the ciao expected
+// form of {UUID}@{UUID}
+func (d LocalDriver) IsValidSnapshotUUID(snapshotUUID string) error {
+	UUIDs := strings.Split(snapshotUUID, "@")
+	if len(UUIDs) != 2 {
+		return fmt.Errorf("missing '@'")
+	}
+	_, e1 := uuid.Parse(UUIDs[0])
+	_, e2 := uuid.Parse(UUIDs[1])
+	if e1 != nil || e2 != nil {
+		return fmt.Errorf("uuid not of form \"{UUID}@{UUID}\"")
+	}
+
+	return nil
+}
+
+// Resize the underlying image file. Only extending is permitted. Loop
+// devices mapped to the volume are told about its new size. Returns the new
+// size in GiB.
+func (d LocalDriver) Resize(volumeUUID string, sizeGiB int) (int, error) {
+	path, err := d.volumePath(volumeUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	oldSize, err := d.GetBlockDeviceSize(volumeUUID)
+	if err != nil {
+		return 0, err
+	}
+
+	newSize := int64(sizeGiB) * 1024 * 1024 * 1024
+	if newSize < int64(oldSize) {
+		return bytesToGiB(oldSize), fmt.Errorf("Volume %s cannot be shrunk", volumeUUID)
+	}
+
+	err = os.Truncate(path, newSize)
+	if err != nil {
+		return bytesToGiB(oldSize), fmt.Errorf("Unable to resize volume %s: %v", volumeUUID, err)
+	}
+
+	// There can be no loop devices to update if losetup is not installed.
+	if _, err := exec.LookPath("losetup"); err != nil {
+		return bytesToGiB(uint64(newSize)), nil
+	}
+
+	vmap, err := d.GetVolumeMapping()
+	if err == nil {
+		for _, device := range vmap[volumeUUID] {
+			cmd := exec.Command("losetup", "--set-capacity", device)
+			out, cerr := cmd.CombinedOutput()
+			if cerr != nil {
+				err = fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, cerr, out)
+			}
+		}
+	}
+
+	size, _ := d.getBlockDeviceSizeGiB(volumeUUID)
+	return size, err
+}
@@ -0,0 +1,255 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/ciao-project/ciao/bat"
+	"github.com/ciao-project/ciao/uuid"
+)
+
+func newLocalTestDriver(t *testing.T) LocalDriver {
+	dir, err := ioutil.TempDir("", "local-driver")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return LocalDriver{Path: dir}
+}
+
+// Check creating a sized locally backed block device works
+//
+// TestLocalCreateSizedBlockDevice creates an empty block device, checks its
+// size and then deletes it.
+func TestLocalCreateSizedBlockDevice(t *testing.T) {
+	d := newLocalTestDriver(t)
+	defer func() { _ = os.RemoveAll(d.Path) }()
+
+	device, err := d.CreateBlockDevice("", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if device.Size != 1 {
+		t.Errorf("Unexpected size: expected 1 got %d", device.Size)
+	}
+
+	size, err := d.GetBlockDeviceSize(device.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != 1024*1024*1024 {
+		t.Errorf("Unexpected block size: expected %d got %d", 1024*1024*1024, size)
+	}
+
+	_, err = d.CreateBlockDevice(device.ID, "", 1)
+	if err == nil {
+		t.Errorf("Expected error when creating existing volume")
+	}
+
+	err = d.DeleteBlockDevice(device.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = d.CreateBlockDevice("not-a-uuid", "", 1)
+	if err == nil {
+		t.Errorf("Expected error for invalid volume UUID")
+	}
+}
+
+// Check creating a locally backed block device from an image works
+//
+// TestLocalCreateBlockDevice creates a block device containing some random
+// data, checks for errors and then deletes it.
+func TestLocalCreateBlockDevice(t *testing.T) {
+	if _, err := exec.LookPath("qemu-img"); err != nil {
+		t.Skip("Skipping test: qemu-img not found")
+	}
+
+	d := newLocalTestDriver(t)
+	defer func() { _ = os.RemoveAll(d.Path) }()
+
+	path, err := bat.CreateRandomFile(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	device, err := d.CreateBlockDevice("", path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := d.GetBlockDeviceSize(device.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != 20*1024*1024 {
+		t.Errorf("Unexpected block size: expected %d got %d", 20*1024*1024, size)
+	}
+
+	err = d.DeleteBlockDevice(device.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check snapshots and copies of locally backed block devices
+//
+// TestLocalSnapshotBlockDevice creates a block device, snapshots it and
+// creates new block devices from the snapshot and from the volume itself.
+// It checks that the volume cannot be deleted while it has snapshots.
+func TestLocalSnapshotBlockDevice(t *testing.T) {
+	d := newLocalTestDriver(t)
+	defer func() { _ = os.RemoveAll(d.Path) }()
+
+	device, err := d.CreateBlockDevice("", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.CreateBlockDeviceSnapshot(device.ID, "ciao-image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.CreateBlockDeviceSnapshot(device.ID, "ciao-image")
+	if err == nil {
+		t.Errorf("Expected error when creating existing snapshot")
+	}
+
+	err = d.CreateBlockDeviceSnapshot(device.ID, "../ciao-image")
+	if err == nil {
+		t.Errorf("Expected error for invalid snapshot ID")
+	}
+
+	err = d.DeleteBlockDevice(device.ID)
+	if err == nil {
+		t.Errorf("Expected error when deleting volume with snapshots")
+	}
+
+	clone, err := d.CreateBlockDeviceFromSnapshot(device.ID, "ciao-image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if clone.Size != 2 {
+		t.Errorf("Unexpected clone size: expected 2 got %d", clone.Size)
+	}
+
+	// clones must be writable even though snapshots are not.
+	err = ioutil.WriteFile(d.Path+"/"+clone.ID, []byte("data"), 0600)
+	if err != nil {
+		t.Error(err)
+	}
+
+	copy, err := d.CopyBlockDevice(device.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if copy.Size != 2 {
+		t.Errorf("Unexpected copy size: expected 2 got %d", copy.Size)
+	}
+
+	for _, ID := range []string{clone.ID, copy.ID} {
+		err = d.DeleteBlockDevice(ID)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	err = d.DeleteBlockDeviceSnapshot(device.ID, "ciao-image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.DeleteBlockDevice(device.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Check resizing a locally backed block device
+//
+// TestLocalResize checks that volumes can be extended but not shrunk.
+func TestLocalResize(t *testing.T) {
+	d := newLocalTestDriver(t)
+	defer func() { _ = os.RemoveAll(d.Path) }()
+
+	device, err := d.CreateBlockDevice("", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := d.Resize(device.ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size != 3 {
+		t.Errorf("Unexpected size: expected 3 got %d", size)
+	}
+
+	size, err = d.Resize(device.ID, 2)
+	if err == nil {
+		t.Errorf("Expected error when shrinking volume")
+	}
+
+	if size != 3 {
+		t.Errorf("Unexpected size: expected 3 got %d", size)
+	}
+}
+
+func TestLocalParseLoopDevices(t *testing.T) {
+	volumeUUID := uuid.Generate().String()
+	data := []byte(`/dev/loop0 /var/lib/ciao/volumes/` + volumeUUID + `
+/dev/loop1 /var/lib/ciao/volumes/` + volumeUUID + `@ciao-image
+/dev/loop2 /var/lib/other/` + volumeUUID + `
+/dev/loop3 /var/lib/ciao/volumes/` + volumeUUID + `
+/dev/loop4
+`)
+
+	vmap := parseLoopDevices(data, "/var/lib/ciao/volumes")
+	if len(vmap) != 1 {
+		t.Fatalf("Expected 1 volume got %d", len(vmap))
+	}
+
+	devices := vmap[volumeUUID]
+	if len(devices) != 2 || devices[0] != "/dev/loop0" || devices[1] != "/dev/loop3" {
+		t.Errorf("Unexpected devices %v", devices)
+	}
+}
+
+func TestLocalIsValidSnapshotUUID(t *testing.T) {
+	d := LocalDriver{}
+
+	err := d.IsValidSnapshotUUID("a@b")
+	if err == nil {
+		t.Errorf("Expected error for invalid snapshot UUID")
+	}
+
+	err = d.IsValidSnapshotUUID("dc1d3e23-e32a-49f5-8c59-402c13031d49@e1f4834b-af32-46d9-8ec3-e4cea3de78cb")
+	if err != nil {
+		t.Errorf("expected nil, got \"%s\"", err)
+	}
+}
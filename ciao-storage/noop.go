@@ -42,6 +42,11 @@ func (d *NoopDriver) CreateBlockDeviceSnapshot(volumeUUID string, snapshotID str
 	return nil
 }
 
+// FlattenBlockDevice pretends to flatten a block device
+func (d *NoopDriver) FlattenBlockDevice(volumeUUID string) error {
+	return nil
+}
+
 // CopyBlockDevice pretends to copy an existing block device
 func (d *NoopDriver) CopyBlockDevice(string) (BlockDevice, error) {
 	return BlockDevice{ID: uuid.Generate().String()}, nil
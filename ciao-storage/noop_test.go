@@ -116,6 +116,11 @@ func TestNoopSnapshots(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	err = noopDriver.FlattenBlockDevice(bd.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	err = noopDriver.DeleteBlockDeviceSnapshot("", "")
 	if err != nil {
 		t.Fatal(err)
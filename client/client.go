@@ -163,6 +163,21 @@ func (client *Client) buildCiaoURL(format string, args ...interface{}) string {
 	return fmt.Sprintf(prefix+format, args...)
 }
 
+func (client *Client) tlsConfig() *tls.Config {
+	tlsConfig := &tls.Config{}
+
+	if client.caCertPool != nil {
+		tlsConfig.RootCAs = client.caCertPool
+	}
+
+	if client.clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*client.clientCert}
+		tlsConfig.BuildNameToCertificate()
+	}
+
+	return tlsConfig
+}
+
 func (client *Client) sendHTTPRequest(method string, url string, values []queryValue, body io.Reader, content string) (*http.Response, error) {
 	req, err := http.NewRequest(method, os.ExpandEnv(url), body)
 	if err != nil {
@@ -188,19 +203,8 @@ func (client *Client) sendHTTPRequest(method string, url string, values []queryV
 		req.Header.Set("Accept", "application/json")
 	}
 
-	tlsConfig := &tls.Config{}
-
-	if client.caCertPool != nil {
-		tlsConfig.RootCAs = client.caCertPool
-	}
-
-	if client.clientCert != nil {
-		tlsConfig.Certificates = []tls.Certificate{*client.clientCert}
-		tlsConfig.BuildNameToCertificate()
-	}
-
 	transport := &http.Transport{
-		TLSClientConfig: tlsConfig,
+		TLSClientConfig: client.tlsConfig(),
 	}
 
 	c := &http.Client{Transport: transport}
@@ -51,21 +51,28 @@ func (client *Client) uploadTenantImage(tenant, image string, data io.Reader) er
 	}
 
 	resp, err := client.sendHTTPRequest("PUT", url, nil, data, fmt.Sprintf("%s/octet-stream", api.ImagesV1))
+	if err != nil {
+		return err
+	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("Unexpected HTTP response code (%d): %s", resp.StatusCode, resp.Status)
 	}
 
-	return err
+	return nil
 }
 
-// CreateImage creates and uploads a new image
-func (client *Client) CreateImage(name string, visibility types.Visibility, ID string, data io.Reader) (string, error) {
+// CreateImage creates and uploads a new image. The data is in the given disk
+// format and, if checksum is not empty, must match it. The controller
+// converts the data to a raw image.
+func (client *Client) CreateImage(name string, visibility types.Visibility, ID string, diskFormat types.DiskFormat, checksum string, data io.Reader) (string, error) {
 	opts := api.CreateImageRequest{
 		Name:       name,
 		ID:         ID,
 		Visibility: visibility,
+		DiskFormat: diskFormat,
+		Checksum:   checksum,
 	}
 
 	var url string
@@ -18,10 +18,15 @@ package client
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
 )
 
@@ -41,8 +46,11 @@ func (client *Client) DeleteInstance(instanceID string) error {
 	return client.deleteResource(url, api.InstancesV1)
 }
 
-func (client *Client) instanceAction(instanceID string, action string) error {
-	actionBytes := []byte(action)
+func (client *Client) instanceAction(instanceID string, action string, args interface{}) error {
+	actionBytes, err := json.Marshal(map[string]interface{}{action: args})
+	if err != nil {
+		return errors.Wrap(err, "Error marshalling action")
+	}
 
 	url := client.buildCiaoURL("%s/instances/%s/action", client.TenantID, instanceID)
 
@@ -60,12 +68,106 @@ func (client *Client) instanceAction(instanceID string, action string) error {
 
 // StopInstance stops the given instance
 func (client *Client) StopInstance(instanceID string) error {
-	return client.instanceAction(instanceID, "os-stop")
+	return client.instanceAction(instanceID, "os-stop", nil)
 }
 
 // StartInstance stops the given instance
 func (client *Client) StartInstance(instanceID string) error {
-	return client.instanceAction(instanceID, "os-start")
+	return client.instanceAction(instanceID, "os-start", nil)
+}
+
+// RebootInstance reboots the given instance.  A hard reboot resets the
+// instance rather than asking its guest to reboot.
+func (client *Client) RebootInstance(instanceID string, hard bool) error {
+	req := api.RebootRequest{Type: api.RebootSoft}
+	if hard {
+		req.Type = api.RebootHard
+	}
+	return client.instanceAction(instanceID, "reboot", &req)
+}
+
+// PauseInstance pauses the given instance
+func (client *Client) PauseInstance(instanceID string) error {
+	return client.instanceAction(instanceID, "pause", nil)
+}
+
+// UnpauseInstance unpauses the given instance
+func (client *Client) UnpauseInstance(instanceID string) error {
+	return client.instanceAction(instanceID, "unpause", nil)
+}
+
+// SuspendInstance suspends the given instance
+func (client *Client) SuspendInstance(instanceID string) error {
+	return client.instanceAction(instanceID, "suspend", nil)
+}
+
+// ResumeInstance resumes the given suspended instance
+func (client *Client) ResumeInstance(instanceID string) error {
+	return client.instanceAction(instanceID, "resume", nil)
+}
+
+// MigrateInstance live migrates the given running instance to another node
+func (client *Client) MigrateInstance(instanceID string) error {
+	return client.instanceAction(instanceID, "os-migrateLive", nil)
+}
+
+// EvacuateInstance restarts on another node an instance whose node has
+// failed.  Only admins may force the evacuation of an instance whose node
+// is disconnected rather than known to be down.
+func (client *Client) EvacuateInstance(instanceID string, force bool) error {
+	return client.instanceAction(instanceID, "evacuate", &api.EvacuateRequest{Force: force})
+}
+
+// ResizeInstance gives the given instance the resources of a workload
+func (client *Client) ResizeInstance(instanceID string, workloadID string) error {
+	return client.instanceAction(instanceID, "resize", &api.ResizeRequest{FlavorRef: workloadID})
+}
+
+// GetInstanceConsoleLog gets the last lines of the console log of the given
+// instance, or as much of the log as is available if lines is 0
+func (client *Client) GetInstanceConsoleLog(instanceID string, lines int) (string, error) {
+	var log api.ConsoleLogResponse
+
+	url := client.buildCiaoURL("%s/instances/%s/console-log", client.TenantID, instanceID)
+
+	var values []queryValue
+	if lines > 0 {
+		values = append(values, queryValue{
+			name:  "lines",
+			value: strconv.Itoa(lines),
+		})
+	}
+
+	err := client.getResource(url, api.InstancesV1, values, &log)
+
+	return log.Output, err
+}
+
+// OpenInstanceConsole opens a websocket connected to the serial console of
+// the given instance.  Console output is received in binary messages and
+// the contents of the messages sent are written to the console.
+func (client *Client) OpenInstanceConsole(instanceID string) (*websocket.Conn, error) {
+	url := client.buildCiaoURL("%s/instances/%s/console", client.TenantID, instanceID)
+	url = "wss" + strings.TrimPrefix(url, "https")
+
+	header := http.Header{}
+	header.Set("Content-Type", fmt.Sprintf("application/%s", api.InstancesV1))
+
+	dialer := websocket.Dialer{
+		TLSClientConfig: client.tlsConfig(),
+	}
+
+	ws, resp, err := dialer.Dial(url, header)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			body, _ := ioutil.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("HTTP Error [%d] for [GET %s]: %s", resp.StatusCode, url, body)
+		}
+		return nil, errors.Wrapf(err, "Error opening console of %s", instanceID)
+	}
+
+	return ws, nil
 }
 
 // ListInstancesByWorkload provides the list of instances for a given tenant and workloadID.
@@ -43,3 +43,25 @@ func (client *Client) ChangeNodeStatus(nodeID string, status types.NodeStatusTyp
 
 	return err
 }
+
+// RebuildNode evacuates a node and restarts on other nodes the instances
+// that could not be live migrated off it.  A disconnected node may still be
+// running its instances, so force must be set to confirm that it is down
+// before they are rebuilt.
+func (client *Client) RebuildNode(nodeID string, force bool) error {
+	if !client.IsPrivileged() {
+		return errors.New("This command is only available to admins")
+	}
+
+	url, err := client.getCiaoResource("node", api.NodeV1)
+	if err != nil {
+		return errors.Wrap(err, "Error getting node resource")
+	}
+
+	url = fmt.Sprintf("%s/%s/evacuate?rebuild=true", url, nodeID)
+	if force {
+		url += "&force=true"
+	}
+
+	return client.postResource(url, api.NodeV1, nil, nil)
+}
@@ -83,8 +83,9 @@ func (client *Client) AttachVolume(volumeID string, instanceID, mountPoint strin
 	return err
 }
 
-// DetachVolume detaches a volume from an instance
-func (client *Client) DetachVolume(volumeID string) error {
+// DetachVolume detaches a volume from the instances it is attached to.  If
+// attachmentID is not empty the volume is only detached from that attachment.
+func (client *Client) DetachVolume(volumeID string, attachmentID string) error {
 	url := client.buildCiaoURL("%s/volumes/%s/action", client.TenantID, volumeID)
 
 	type DetachRequest struct {
@@ -93,10 +94,74 @@ func (client *Client) DetachVolume(volumeID string) error {
 	var detachReq = struct {
 		Detach DetachRequest `json:"detach"`
 	}{
-		Detach: DetachRequest{},
+		Detach: DetachRequest{
+			AttachmentID: attachmentID,
+		},
 	}
 
 	err := client.postResource(url, api.VolumesV1, &detachReq, nil)
 
 	return err
 }
+
+// ExtendVolume grows a volume to size GiB
+func (client *Client) ExtendVolume(volumeID string, size int) error {
+	url := client.buildCiaoURL("%s/volumes/%s/action", client.TenantID, volumeID)
+
+	type ExtendRequest struct {
+		NewSize int `json:"new_size"`
+	}
+	var extendReq = struct {
+		Extend ExtendRequest `json:"extend"`
+	}{
+		Extend: ExtendRequest{
+			NewSize: size,
+		},
+	}
+
+	err := client.postResource(url, api.VolumesV1, &extendReq, nil)
+
+	return err
+}
+
+// CreateVolumeSnapshot takes a snapshot of a volume
+func (client *Client) CreateVolumeSnapshot(req api.RequestedSnapshot) (types.VolumeSnapshot, error) {
+	var snapshot types.VolumeSnapshot
+
+	url := client.buildCiaoURL("%s/snapshots", client.TenantID)
+	err := client.postResource(url, api.VolumesV1, &req, &snapshot)
+
+	return snapshot, err
+}
+
+// ListVolumeSnapshots lists the volume snapshots, optionally only those of
+// the given volume
+func (client *Client) ListVolumeSnapshots(volumeID string) ([]types.VolumeSnapshot, error) {
+	var snapshots []types.VolumeSnapshot
+
+	var query []queryValue
+	if volumeID != "" {
+		query = append(query, queryValue{name: "volume_id", value: volumeID})
+	}
+
+	url := client.buildCiaoURL("%s/snapshots", client.TenantID)
+	err := client.getResource(url, api.VolumesV1, query, &snapshots)
+
+	return snapshots, err
+}
+
+// GetVolumeSnapshot gets the details of a single volume snapshot
+func (client *Client) GetVolumeSnapshot(snapshotID string) (types.VolumeSnapshot, error) {
+	var snapshot types.VolumeSnapshot
+
+	url := client.buildCiaoURL("%s/snapshots/%s", client.TenantID, snapshotID)
+	err := client.getResource(url, api.VolumesV1, nil, &snapshot)
+
+	return snapshot, err
+}
+
+// DeleteVolumeSnapshot deletes a volume snapshot
+func (client *Client) DeleteVolumeSnapshot(snapshotID string) error {
+	url := client.buildCiaoURL("%s/snapshots/%s", client.TenantID, snapshotID)
+	return client.deleteResource(url, api.VolumesV1)
+}
@@ -35,7 +35,8 @@ import (
 //
 // TODO: proper validation of values set in yaml setup
 func validMinConf(conf *payloads.Configure) bool {
-	if conf.Configure.Storage.CephID == "" {
+	if conf.Configure.Storage.CephID == "" &&
+		conf.Configure.Storage.Driver != payloads.LocalStorage {
 		fmt.Printf("Warning, ceph_id not set (will become an error soon)")
 	}
 	return (conf.Configure.Scheduler.ConfigStorageURI != "" &&
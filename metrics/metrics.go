@@ -0,0 +1,227 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package metrics exposes ciao components metrics in the Prometheus text
+// exposition format.
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type is the type of a metric family.
+type Type string
+
+const (
+	// Counter is a cumulative metric that only ever increases.
+	Counter Type = "counter"
+
+	// Gauge is a metric that can arbitrarily go up and down.
+	Gauge Type = "gauge"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Labels maps label names to label values.
+type Labels map[string]string
+
+// Sample is a single value of a metric family, identified by its labels.
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// Family is a set of samples sharing the same metric name.
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Samples []Sample
+}
+
+// NewFamily creates a metric family with no samples.
+func NewFamily(name string, help string, t Type) *Family {
+	return &Family{
+		Name: name,
+		Help: help,
+		Type: t,
+	}
+}
+
+// Add adds a sample to the family.
+func (f *Family) Add(value float64, labels Labels) {
+	f.Samples = append(f.Samples, Sample{Labels: labels, Value: value})
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeSample(w *bufio.Writer, name string, s Sample) {
+	_, _ = w.WriteString(name)
+
+	if len(s.Labels) > 0 {
+		names := make([]string, 0, len(s.Labels))
+		for n := range s.Labels {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		_ = w.WriteByte('{')
+		for i, n := range names {
+			if i > 0 {
+				_ = w.WriteByte(',')
+			}
+			_, _ = w.WriteString(n)
+			_, _ = w.WriteString(`="`)
+			_, _ = w.WriteString(labelEscaper.Replace(s.Labels[n]))
+			_ = w.WriteByte('"')
+		}
+		_ = w.WriteByte('}')
+	}
+
+	_ = w.WriteByte(' ')
+	_, _ = w.WriteString(formatValue(s.Value))
+	_ = w.WriteByte('\n')
+}
+
+// Write writes the families to w in the Prometheus text exposition format.
+// Families are written in order, and the labels of each sample are sorted
+// by name.
+func Write(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+
+	for _, f := range families {
+		if f.Help != "" {
+			_, _ = bw.WriteString("# HELP " + f.Name + " " + helpEscaper.Replace(f.Help) + "\n")
+		}
+		if f.Type != "" {
+			_, _ = bw.WriteString("# TYPE " + f.Name + " " + string(f.Type) + "\n")
+		}
+
+		for _, s := range f.Samples {
+			writeSample(bw, f.Name, s)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Handler returns an HTTP handler serving the families returned by collect
+// on every GET request.
+func Handler(collect func() []Family) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var b bytes.Buffer
+		if err := Write(&b, collect()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = w.Write(b.Bytes())
+	})
+}
+
+// CounterVec is a set of counters sharing the same metric name and label
+// names. It is safe for concurrent use.
+type CounterVec struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mutex  sync.Mutex
+	counts map[string]float64
+	labels map[string][]string
+}
+
+// NewCounterVec creates a set of counters partitioned by labelNames.
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		counts:     make(map[string]float64),
+		labels:     make(map[string][]string),
+	}
+}
+
+// Inc increments the counter identified by labelValues, which are given in
+// the order of the label names the CounterVec was created with.
+func (c *CounterVec) Inc(labelValues ...string) {
+	values := make([]string, len(c.labelNames))
+	copy(values, labelValues)
+	key := strings.Join(values, "\xff")
+
+	c.mutex.Lock()
+	c.counts[key]++
+	c.labels[key] = values
+	c.mutex.Unlock()
+}
+
+// Family returns a snapshot of the counters, sorted by label values.
+func (c *CounterVec) Family() Family {
+	f := Family{
+		Name: c.name,
+		Help: c.help,
+		Type: Counter,
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	keys := make([]string, 0, len(c.counts))
+	for k := range c.counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		labels := make(Labels)
+		for i, n := range c.labelNames {
+			labels[n] = c.labels[k][i]
+		}
+		f.Add(c.counts[k], labels)
+	}
+
+	return f
+}
@@ -0,0 +1,140 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test the text exposition format.
+//
+// This test writes a gauge and a counter family, with escaped help and
+// label values, and special float values.
+//
+// The output matches the expected Prometheus text format, with labels
+// sorted by name.
+func TestWrite(t *testing.T) {
+	gauge := NewFamily("ciao_test_gauge", "A test\\gauge\nwith two lines", Gauge)
+	gauge.Add(1.5, Labels{"node": "n1", "hostname": "host \"one\""})
+	gauge.Add(math.Inf(1), Labels{"node": "n2", "hostname": "back\\slash\n"})
+
+	counter := NewFamily("ciao_test_total", "A test counter", Counter)
+	counter.Add(42, nil)
+
+	var b bytes.Buffer
+	if err := Write(&b, []Family{*gauge, *counter}); err != nil {
+		t.Fatalf("Unable to write families: %v", err)
+	}
+
+	expected := `# HELP ciao_test_gauge A test\\gauge\nwith two lines
+# TYPE ciao_test_gauge gauge
+ciao_test_gauge{hostname="host \"one\"",node="n1"} 1.5
+ciao_test_gauge{hostname="back\\slash\n",node="n2"} +Inf
+# HELP ciao_test_total A test counter
+# TYPE ciao_test_total counter
+ciao_test_total 42
+`
+	if b.String() != expected {
+		t.Fatalf("Unexpected output:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+}
+
+// Test counter vectors.
+//
+// This test increments counters for several label values.
+//
+// The counters are accounted separately and returned sorted by label values,
+// missing label values being empty.
+func TestCounterVec(t *testing.T) {
+	c := NewCounterVec("ciao_test_total", "A test counter", "command", "reason")
+	c.Inc("START", "full")
+	c.Inc("DELETE")
+	c.Inc("START", "full")
+
+	f := c.Family()
+	if f.Type != Counter || f.Name != "ciao_test_total" {
+		t.Fatalf("Unexpected family %s of type %s", f.Name, f.Type)
+	}
+
+	if len(f.Samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(f.Samples))
+	}
+
+	s := f.Samples[0]
+	if s.Labels["command"] != "DELETE" || s.Labels["reason"] != "" || s.Value != 1 {
+		t.Fatalf("Unexpected first sample %v", s)
+	}
+
+	s = f.Samples[1]
+	if s.Labels["command"] != "START" || s.Labels["reason"] != "full" || s.Value != 2 {
+		t.Fatalf("Unexpected second sample %v", s)
+	}
+}
+
+// Test the metrics HTTP handler.
+//
+// This test issues a GET and a POST request to the handler.
+//
+// The GET request returns the collected families with the Prometheus content
+// type, the POST one is rejected.
+func TestHandler(t *testing.T) {
+	collect := func() []Family {
+		f := NewFamily("ciao_test_gauge", "", Gauge)
+		f.Add(1, nil)
+		return []Family{*f}
+	}
+
+	server := httptest.NewServer(Handler(collect))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Unable to get metrics: %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Unable to read metrics: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status %d", resp.StatusCode)
+	}
+
+	if resp.Header.Get("Content-Type") != ContentType {
+		t.Fatalf("Unexpected content type %s", resp.Header.Get("Content-Type"))
+	}
+
+	if string(body) != "# TYPE ciao_test_gauge gauge\nciao_test_gauge 1\n" {
+		t.Fatalf("Unexpected metrics %q", string(body))
+	}
+
+	resp, err = http.Post(server.URL, "text/plain", nil)
+	if err != nil {
+		t.Fatalf("Unable to post metrics: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status %d, got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"path"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -51,7 +52,7 @@ var enableNATssh bool
 var agentUUID string
 
 func init() {
-	flag.StringVar(&serverURL, "server", "", "URL of SSNTP server, Use auto for auto discovery")
+	flag.StringVar(&serverURL, "server", "", "URL of SSNTP server, or comma separated list of server URLs to fail over between. Use auto for auto discovery")
 	flag.StringVar(&serverCertPath, "cacert", "/var/lib/ciao/CAcert-server-localhost.pem", "Client certificate")
 	flag.StringVar(&clientCertPath, "cert", "/var/lib/ciao/cert-client-localhost.pem", "CA certificate")
 	flag.StringVar(&computeNet, "compute-net", "", "Compute Subnet")
@@ -351,8 +352,9 @@ func connectToServer(db *cnciDatabase, doneCh chan struct{}, statusCh chan struc
 		statusCh <- struct{}{}
 	}()
 
-	cfg := &ssntp.Config{UUID: agentUUID, URI: serverURL, CAcert: serverCertPath, Cert: clientCertPath,
-		Log: ssntp.Log, Rand: cnciRand}
+	serverURLs := strings.Split(serverURL, ",")
+	cfg := &ssntp.Config{UUID: agentUUID, URI: serverURLs[0], URIs: serverURLs[1:],
+		CAcert: serverCertPath, Cert: clientCertPath, Log: ssntp.Log, Rand: cnciRand}
 	client := &agentClient{db: db, cmdCh: make(chan *cmdWrapper)}
 
 	dialCh := make(chan error)
@@ -34,10 +34,80 @@ func (s StorageType) String() string {
 	return ""
 }
 
+// StorageDriver is the name of the block driver used for volumes.
+type StorageDriver string
+
+const (
+	// CephStorage stores volumes as rbd images in a ceph cluster.  This is
+	// the default.
+	CephStorage StorageDriver = "ceph"
+
+	// LocalStorage stores volumes as image files in a local directory.
+	// It is only suitable for single host clusters.
+	LocalStorage StorageDriver = "local"
+)
+
+func (s StorageDriver) String() string {
+	switch s {
+	case CephStorage:
+		return "ceph"
+	case LocalStorage:
+		return "local"
+	}
+
+	return ""
+}
+
+// PlacementPolicy is the name of a scheduler workload placement policy.
+type PlacementPolicy string
+
+const (
+	// FirstFit places a workload on the first node that fits it, starting
+	// the search after the most recently used node.
+	FirstFit PlacementPolicy = "first_fit"
+
+	// BinPack places a workload on the fitting node with the least
+	// available resources, to keep as many nodes as possible idle.
+	BinPack PlacementPolicy = "bin_pack"
+
+	// Spread places a workload on the least loaded fitting node.
+	Spread PlacementPolicy = "spread"
+
+	// Weighted places a workload on the fitting node with the highest
+	// score, as computed from the configured PlacementWeights.
+	Weighted PlacementPolicy = "weighted"
+)
+
+func (p PlacementPolicy) String() string {
+	switch p {
+	case FirstFit:
+		return "first_fit"
+	case BinPack:
+		return "bin_pack"
+	case Spread:
+		return "spread"
+	case Weighted:
+		return "weighted"
+	}
+
+	return ""
+}
+
+// PlacementWeights contains the relative weights the weighted placement
+// policy gives to a node's free memory, free disk and load when scoring it.
+type PlacementWeights struct {
+	Mem  float64 `yaml:"mem"`
+	Disk float64 `yaml:"disk"`
+	Load float64 `yaml:"load"`
+}
+
 // ConfigureScheduler contains the unmarshalled configurations for the
 // scheduler service.
 type ConfigureScheduler struct {
-	ConfigStorageURI string `yaml:"storage_uri"`
+	ConfigStorageURI   string           `yaml:"storage_uri"`
+	PlacementPolicy    PlacementPolicy  `yaml:"placement_policy,omitempty"`
+	PlacementWeights   PlacementWeights `yaml:"placement_weights,omitempty"`
+	CPUOvercommitRatio float64          `yaml:"cpu_overcommit_ratio,omitempty"`
 }
 
 // ConfigureController contains the unmarshalled configurations for the
@@ -64,9 +134,11 @@ type ConfigureLauncher struct {
 }
 
 // ConfigureStorage contains the unmarshalled configurations for the
-// Ceph storage driver.
+// storage drivers.
 type ConfigureStorage struct {
-	CephID string `yaml:"ceph_id"`
+	CephID    string        `yaml:"ceph_id"`
+	Driver    StorageDriver `yaml:"driver,omitempty"`
+	LocalPath string        `yaml:"local_path,omitempty"`
 }
 
 // ConfigurePayload is a wrapper to read and unmarshall all posible
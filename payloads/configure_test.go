@@ -89,3 +89,88 @@ func TestConfigureStorageTypeString(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigurePlacementPolicyString(t *testing.T) {
+	var stringTests = []struct {
+		p        PlacementPolicy
+		expected string
+	}{
+		{FirstFit, "first_fit"},
+		{BinPack, "bin_pack"},
+		{Spread, "spread"},
+		{Weighted, "weighted"},
+		{PlacementPolicy("unknown"), ""},
+	}
+	for _, test := range stringTests {
+		out := test.p.String()
+		if out != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, out)
+		}
+	}
+}
+
+func TestConfigurePlacementUnmarshal(t *testing.T) {
+	var cfg Configure
+
+	y := `configure:
+  scheduler:
+    storage_uri: ` + testutil.StorageURI + `
+    placement_policy: weighted
+    placement_weights:
+      mem: 2
+      disk: 1
+      load: 0.5
+`
+	err := yaml.Unmarshal([]byte(y), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Configure.Scheduler.PlacementPolicy != Weighted {
+		t.Errorf("Wrong placement policy %v", cfg.Configure.Scheduler.PlacementPolicy)
+	}
+
+	weights := PlacementWeights{Mem: 2, Disk: 1, Load: 0.5}
+	if cfg.Configure.Scheduler.PlacementWeights != weights {
+		t.Errorf("Wrong placement weights %v", cfg.Configure.Scheduler.PlacementWeights)
+	}
+}
+
+func TestConfigureStorageDriverString(t *testing.T) {
+	var stringTests = []struct {
+		s        StorageDriver
+		expected string
+	}{
+		{CephStorage, "ceph"},
+		{LocalStorage, "local"},
+		{StorageDriver("unknown"), ""},
+	}
+	for _, test := range stringTests {
+		out := test.s.String()
+		if out != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, out)
+		}
+	}
+}
+
+func TestConfigureStorageDriverUnmarshal(t *testing.T) {
+	var cfg Configure
+
+	y := `configure:
+  storage:
+    driver: local
+    local_path: /var/lib/ciao/volumes
+`
+	err := yaml.Unmarshal([]byte(y), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Configure.Storage.Driver != LocalStorage {
+		t.Errorf("Wrong storage driver %v", cfg.Configure.Storage.Driver)
+	}
+
+	if cfg.Configure.Storage.LocalPath != "/var/lib/ciao/volumes" {
+		t.Errorf("Wrong local storage path %v", cfg.Configure.Storage.LocalPath)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// ConsoleType denotes the type of console access requested by an SSNTP
+// CONSOLE command.
+type ConsoleType string
+
+const (
+	// ConsoleTypeLog asks for the tail of the console log of an instance.
+	ConsoleTypeLog ConsoleType = "log"
+
+	// ConsoleTypeInteractive asks for an interactive connection to the
+	// serial console of an instance.
+	ConsoleTypeInteractive = "interactive"
+)
+
+// ConsoleCmd contains the information needed by the node running an
+// instance to give access to the serial console of that instance.
+type ConsoleCmd struct {
+	// InstanceUUID is the UUID of the instance whose console is accessed.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// WorkloadAgentUUID identifies the node on which the instance is
+	// running.  This information is needed by the scheduler to route
+	// the command to the correct CN/NN.
+	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+
+	// Type is the type of console access requested.
+	Type ConsoleType `yaml:"type"`
+
+	// Lines is the maximum number of lines of the console log to return.
+	// Zero means as many lines as the node is willing to send.  It is
+	// ignored for interactive consoles.
+	Lines int `yaml:"lines,omitempty"`
+}
+
+// Console represents the unmarshalled version of the contents of a SSNTP
+// CONSOLE payload.
+type Console struct {
+	Console ConsoleCmd `yaml:"console"`
+}
+
+// ConsoleLogEvent is sent by a node in reply to a CONSOLE command of type
+// ConsoleTypeLog.
+type ConsoleLogEvent struct {
+	// InstanceUUID is the UUID of the instance whose console log is
+	// returned.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// NodeUUID is the UUID of the node running the instance.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// Log is the tail of the console log.
+	Log string `yaml:"log"`
+}
+
+// EventConsoleLog represents the unmarshalled version of the contents of an
+// SSNTP ssntp.ConsoleLog event.
+type EventConsoleLog struct {
+	ConsoleLog ConsoleLogEvent `yaml:"console_log"`
+}
+
+// ConsoleReadyEvent is sent by a node in reply to a CONSOLE command of type
+// ConsoleTypeInteractive.
+type ConsoleReadyEvent struct {
+	// InstanceUUID is the UUID of the instance whose console is ready.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// NodeUUID is the UUID of the node running the instance.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// Address is the TCP address, e.g., 198.51.100.2:34567, on which the
+	// node accepts a single TLS connection to the serial console.  Nodes
+	// only accept clients presenting a Controller certificate.
+	Address string `yaml:"address"`
+
+	// Token must be sent, followed by a newline, as the first line of
+	// the connection to Address.  The node closes connections that do
+	// not start with this token.
+	Token string `yaml:"token"`
+}
+
+// EventConsoleReady represents the unmarshalled version of the contents of
+// an SSNTP ssntp.ConsoleReady event.
+type EventConsoleReady struct {
+	ConsoleReady ConsoleReadyEvent `yaml:"console_ready"`
+}
@@ -0,0 +1,127 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConsoleUnmarshal(t *testing.T) {
+	var console Console
+	err := yaml.Unmarshal([]byte(testutil.ConsoleLogCmdYaml), &console)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if console.Console.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", console.Console.InstanceUUID)
+	}
+
+	if console.Console.WorkloadAgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong Agent UUID field [%s]", console.Console.WorkloadAgentUUID)
+	}
+
+	if console.Console.Type != ConsoleTypeLog {
+		t.Errorf("Wrong type field [%s]", console.Console.Type)
+	}
+
+	if console.Console.Lines != 20 {
+		t.Errorf("Wrong lines field [%d]", console.Console.Lines)
+	}
+}
+
+func TestConsoleMarshal(t *testing.T) {
+	var console Console
+	console.Console.InstanceUUID = testutil.InstanceUUID
+	console.Console.WorkloadAgentUUID = testutil.AgentUUID
+	console.Console.Type = ConsoleTypeLog
+	console.Console.Lines = 20
+
+	y, err := yaml.Marshal(&console)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.ConsoleLogCmdYaml {
+		t.Errorf("CONSOLE marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.ConsoleLogCmdYaml)
+	}
+}
+
+func TestConsoleLogMarshal(t *testing.T) {
+	var event EventConsoleLog
+	event.ConsoleLog.InstanceUUID = testutil.InstanceUUID
+	event.ConsoleLog.NodeUUID = testutil.AgentUUID
+	event.ConsoleLog.Log = "Booting from Hard Disk...\nlogin:\n"
+
+	y, err := yaml.Marshal(&event)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.ConsoleLogYaml {
+		t.Errorf("ConsoleLog marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.ConsoleLogYaml)
+	}
+}
+
+func TestConsoleLogBinaryMarshal(t *testing.T) {
+	var event EventConsoleLog
+	event.ConsoleLog.Log = "\x1b[2J\xff\xfe login:\r\n"
+
+	y, err := yaml.Marshal(&event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event2 EventConsoleLog
+	err = yaml.Unmarshal(y, &event2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event2.ConsoleLog.Log != event.ConsoleLog.Log {
+		t.Errorf("ConsoleLog round trip failed [%q] vs [%q]", event2.ConsoleLog.Log,
+			event.ConsoleLog.Log)
+	}
+}
+
+func TestConsoleReadyUnmarshal(t *testing.T) {
+	var ready EventConsoleReady
+	err := yaml.Unmarshal([]byte(testutil.ConsoleReadyYaml), &ready)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if ready.ConsoleReady.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", ready.ConsoleReady.InstanceUUID)
+	}
+
+	if ready.ConsoleReady.NodeUUID != testutil.AgentUUID {
+		t.Errorf("Wrong node UUID field [%s]", ready.ConsoleReady.NodeUUID)
+	}
+
+	if ready.ConsoleReady.Address != testutil.ConsoleAddress {
+		t.Errorf("Wrong address field [%s]", ready.ConsoleReady.Address)
+	}
+
+	if ready.ConsoleReady.Token != testutil.ConsoleToken {
+		t.Errorf("Wrong token field [%s]", ready.ConsoleReady.Token)
+	}
+}
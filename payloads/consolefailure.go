@@ -0,0 +1,83 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// ConsoleFailureReason denotes the underlying error that prevented an SSNTP
+// CONSOLE command from giving access to the console of an instance.
+type ConsoleFailureReason string
+
+const (
+	// ConsoleNoInstance indicates that the console could not be accessed
+	// as the instance does not exist on the node to which the command was
+	// sent.
+	ConsoleNoInstance ConsoleFailureReason = "no_instance"
+
+	// ConsoleInvalidPayload indicates that the payload of the SSNTP
+	// CONSOLE command was corrupt and could not be unmarshalled.
+	ConsoleInvalidPayload = "invalid_payload"
+
+	// ConsoleInvalidData is returned by ciao-launcher if the contents
+	// of the CONSOLE payload are incorrect, e.g., the type is unknown.
+	ConsoleInvalidData = "invalid_data"
+
+	// ConsoleInvalidState indicates that the instance is not in a state
+	// allowing its console to be accessed, e.g., an interactive console
+	// was requested for an instance that is not running.
+	ConsoleInvalidState = "invalid_state"
+
+	// ConsoleNotSupported indicates that the instance has no serial
+	// console, e.g., it is a container.
+	ConsoleNotSupported = "not_supported"
+
+	// ConsoleUnavailable indicates that the console of the instance
+	// could not be read or connected to.
+	ConsoleUnavailable = "unavailable"
+)
+
+// ErrorConsoleFailure represents the unmarshalled version of the contents of a
+// SSNTP ERROR frame whose type is set to ssntp.ConsoleFailure.
+type ErrorConsoleFailure struct {
+	// NodeUUID is the UUID of the node that generated this error.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// InstanceUUID is the UUID of the instance whose console could not
+	// be accessed.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// Reason provides the reason for the failure, e.g.,
+	// ConsoleNotSupported.
+	Reason ConsoleFailureReason `yaml:"reason"`
+}
+
+func (r ConsoleFailureReason) String() string {
+	switch r {
+	case ConsoleNoInstance:
+		return "Instance does not exist"
+	case ConsoleInvalidPayload:
+		return "YAML payload is corrupt"
+	case ConsoleInvalidData:
+		return "Command section of YAML payload is corrupt or missing required information"
+	case ConsoleInvalidState:
+		return "Instance state does not allow console access"
+	case ConsoleNotSupported:
+		return "Not Supported"
+	case ConsoleUnavailable:
+		return "Console is not available"
+	}
+
+	return ""
+}
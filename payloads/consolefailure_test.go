@@ -0,0 +1,86 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConsoleFailureUnmarshal(t *testing.T) {
+	var error ErrorConsoleFailure
+	err := yaml.Unmarshal([]byte(testutil.ConsoleFailureYaml), &error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if error.NodeUUID != testutil.AgentUUID {
+		t.Error("Wrong Node UUID field")
+	}
+
+	if error.InstanceUUID != testutil.InstanceUUID {
+		t.Error("Wrong Instance UUID field")
+	}
+
+	if error.Reason != ConsoleNotSupported {
+		t.Error("Wrong Error field")
+	}
+}
+
+func TestConsoleFailureMarshal(t *testing.T) {
+	error := ErrorConsoleFailure{
+		NodeUUID:     testutil.AgentUUID,
+		InstanceUUID: testutil.InstanceUUID,
+		Reason:       ConsoleNotSupported,
+	}
+
+	y, err := yaml.Marshal(&error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.ConsoleFailureYaml {
+		t.Errorf("ConsoleFailure marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.ConsoleFailureYaml)
+	}
+}
+
+func TestConsoleFailureString(t *testing.T) {
+	var stringTests = []struct {
+		r        ConsoleFailureReason
+		expected string
+	}{
+		{ConsoleNoInstance, "Instance does not exist"},
+		{ConsoleInvalidPayload, "YAML payload is corrupt"},
+		{ConsoleInvalidData, "Command section of YAML payload is corrupt or missing required information"},
+		{ConsoleInvalidState, "Instance state does not allow console access"},
+		{ConsoleNotSupported, "Not Supported"},
+		{ConsoleUnavailable, "Console is not available"},
+	}
+	error := ErrorConsoleFailure{
+		InstanceUUID: testutil.InstanceUUID,
+	}
+	for _, test := range stringTests {
+		error.Reason = test.r
+		s := error.Reason.String()
+		if s != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, s)
+		}
+	}
+}
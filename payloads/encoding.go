@@ -0,0 +1,103 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Encoding identifies the format an SSNTP payload is serialized with.
+type Encoding uint8
+
+const (
+	// YAML is the default payload encoding. It is understood by all
+	// ciao components.
+	YAML Encoding = iota
+
+	// Gob is a compact binary payload encoding, based on encoding/gob.
+	// It is much cheaper to marshal and unmarshal than YAML and should
+	// be used for large and frequent payloads, e.g. STATS, whenever
+	// the SSNTP peer supports it.
+	Gob
+)
+
+// gobMagic prefixes Gob encoded payloads. A YAML document can not start
+// with a NUL byte so this lets Unmarshal tell both encodings apart.
+const gobMagic = 0x00
+
+func (e Encoding) String() string {
+	switch e {
+	case YAML:
+		return "yaml"
+	case Gob:
+		return "gob"
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint8(e))
+}
+
+// PayloadEncoding returns the encoding of an SSNTP payload.
+func PayloadEncoding(data []byte) Encoding {
+	if len(data) > 0 && data[0] == gobMagic {
+		return Gob
+	}
+
+	return YAML
+}
+
+// Marshal serializes v, a pointer to any of the SSNTP payload structures
+// defined in this package, with the e encoding.
+func Marshal(e Encoding, v interface{}) ([]byte, error) {
+	switch e {
+	case YAML:
+		return yaml.Marshal(v)
+	case Gob:
+		buf := bytes.NewBuffer([]byte{gobMagic})
+		err := gob.NewEncoder(buf).Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("Unsupported payload encoding %s", e)
+}
+
+// Unmarshal parses an SSNTP payload into v, regardless of the encoding
+// it was marshalled with.
+// YAML payloads only overwrite the fields of v they contain, as with
+// yaml.Unmarshal. Gob payloads replace v entirely.
+func Unmarshal(data []byte, v interface{}) error {
+	if PayloadEncoding(data) == YAML {
+		return yaml.Unmarshal(data, v)
+	}
+
+	// gob does not transmit zero values, so we need to start from a
+	// zero value for fields set to zero by the sender to stay that way.
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("Can not unmarshal payload into non pointer %T", v)
+	}
+	rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+
+	return gob.NewDecoder(bytes.NewReader(data[1:])).Decode(v)
+}
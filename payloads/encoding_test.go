@@ -0,0 +1,201 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+var encodingTests = []struct {
+	yaml    string
+	payload interface{}
+}{
+	{testutil.StartYaml, &Start{}},
+	{testutil.CNCIStartYaml, &Start{}},
+	{testutil.RestartYaml, &Restart{}},
+	{testutil.StopYaml, &Stop{}},
+	{testutil.DeleteYaml, &Delete{}},
+	{testutil.EvacuateYaml, &Evacuate{}},
+	{testutil.RestoreYaml, &Restore{}},
+	{testutil.AttachVolumeYaml, &AttachVolume{}},
+	{testutil.AssignIPYaml, &CommandAssignPublicIP{}},
+	{testutil.ReleaseIPYaml, &CommandReleasePublicIP{}},
+	{testutil.ConfigureYaml, &Configure{}},
+	{testutil.ReadyYaml, &Ready{}},
+	{testutil.StatsYaml, &Stat{}},
+	{testutil.NodeOnlyStatsYaml, &Stat{}},
+	{testutil.StartFailureYaml, &ErrorStartFailure{}},
+	{testutil.RestartFailureYaml, &ErrorRestartFailure{}},
+	{testutil.StopFailureYaml, &ErrorStopFailure{}},
+	{testutil.DeleteFailureYaml, &ErrorDeleteFailure{}},
+	{testutil.AttachVolumeFailureYaml, &ErrorAttachVolumeFailure{}},
+	{testutil.CNCIAddedYaml, &EventConcentratorInstanceAdded{}},
+	{testutil.AssignedIPYaml, &EventPublicIPAssigned{}},
+	{testutil.UnassignedIPYaml, &EventPublicIPUnassigned{}},
+	{testutil.TenantAddedYaml, &EventTenantAdded{}},
+	{testutil.TenantRemovedYaml, &EventTenantRemoved{}},
+	{testutil.InsDelYaml, &EventInstanceDeleted{}},
+	{testutil.InsStopYaml, &EventInstanceStopped{}},
+	{testutil.NodeConnectedYaml, &NodeConnected{}},
+}
+
+// Test that all payloads survive a round trip through all encodings
+//
+// Parse the YAML test payloads, marshal them with each encoding and
+// unmarshal the result.
+//
+// The unmarshalled payloads should match the original ones and the
+// marshalled ones should carry the right encoding.
+func TestEncodingRoundTrip(t *testing.T) {
+	for _, test := range encodingTests {
+		err := yaml.Unmarshal([]byte(test.yaml), test.payload)
+		if err != nil {
+			t.Fatalf("Unable to parse %T: %v", test.payload, err)
+		}
+
+		for _, e := range []Encoding{YAML, Gob} {
+			data, err := Marshal(e, test.payload)
+			if err != nil {
+				t.Fatalf("Unable to marshal %T with %s: %v", test.payload, e, err)
+			}
+
+			if PayloadEncoding(data) != e {
+				t.Errorf("Wrong encoding for %T, expected %s got %s",
+					test.payload, e, PayloadEncoding(data))
+			}
+
+			out := reflect.New(reflect.TypeOf(test.payload).Elem()).Interface()
+			err = Unmarshal(data, out)
+			if err != nil {
+				t.Fatalf("Unable to unmarshal %T with %s: %v", test.payload, e, err)
+			}
+
+			// Compare the YAML representations as empty slices and
+			// maps may come back as nil ones.
+			expected, _ := yaml.Marshal(test.payload)
+			got, _ := yaml.Marshal(out)
+			if string(expected) != string(got) {
+				t.Errorf("%T %s round trip failed:\n%s\nvs\n%s",
+					test.payload, e, expected, got)
+			}
+		}
+	}
+}
+
+// Test that Gob payloads overwrite the unmarshalled structure
+//
+// Marshal a Stat payload with zero values and unmarshal it into an
+// initialised Stat structure.
+//
+// The zero values should overwrite the Init() ones.
+func TestEncodingGobZeroValues(t *testing.T) {
+	var in, out Stat
+
+	data, err := Marshal(Gob, &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out.Init()
+	err = Unmarshal(data, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.MemTotalMB != 0 || out.Load != 0 {
+		t.Errorf("Init() values were not overwritten: %+v", out)
+	}
+}
+
+// Test unsupported encodings
+//
+// Try to marshal a payload with an unknown encoding.
+//
+// Marshal should fail.
+func TestEncodingUnsupported(t *testing.T) {
+	_, err := Marshal(Encoding(42), &Stat{})
+	if err == nil {
+		t.Errorf("Marshalling with an unknown encoding should fail")
+	}
+}
+
+func benchmarkStats() *Stat {
+	var networks []NetworkStat
+	var instances []InstanceStat
+
+	for i := 0; i < 200; i++ {
+		instance := testutil.InstanceStat001
+		instance.InstanceUUID = fmt.Sprintf("%s-%d", instance.InstanceUUID, i)
+		instances = append(instances, instance)
+	}
+	networks = append(networks, testutil.NetworkStat001, testutil.NetworkStat002)
+
+	stats := testutil.StatsPayload(testutil.AgentUUID, "test", instances, networks)
+	return &stats
+}
+
+func benchmarkStart(b *testing.B) *Start {
+	var start Start
+
+	err := yaml.Unmarshal([]byte(testutil.StartYaml), &start)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return &start
+}
+
+func benchmarkEncoding(b *testing.B, e Encoding, in interface{}) {
+	out := reflect.New(reflect.TypeOf(in).Elem()).Interface()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := Marshal(e, in)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		err = Unmarshal(data, out)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStatsYAML(b *testing.B) {
+	benchmarkEncoding(b, YAML, benchmarkStats())
+}
+
+func BenchmarkStatsGob(b *testing.B) {
+	benchmarkEncoding(b, Gob, benchmarkStats())
+}
+
+func BenchmarkStartYAML(b *testing.B) {
+	benchmarkEncoding(b, YAML, benchmarkStart(b))
+}
+
+func BenchmarkStartGob(b *testing.B) {
+	benchmarkEncoding(b, Gob, benchmarkStart(b))
+}
@@ -0,0 +1,86 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// ExtendVolumeFailureReason denotes the underlying error that prevented
+// an SSNTP ExtendVolume command from notifying an instance that one of its
+// volumes has been extended.
+type ExtendVolumeFailureReason string
+
+const (
+	// ExtendVolumeNoInstance indicates that the instance could not be
+	// notified as it does not exist on the node to which the ExtendVolume
+	// command was sent.
+	ExtendVolumeNoInstance ExtendVolumeFailureReason = "no_instance"
+
+	// ExtendVolumeInvalidPayload indicates that the payload of the SSNTP
+	// ExtendVolume command was corrupt and could not be unmarshalled.
+	ExtendVolumeInvalidPayload = "invalid_payload"
+
+	// ExtendVolumeInvalidData is returned by ciao-launcher if the contents
+	// of the ExtendVolume payload are incorrect, e.g., the instance_uuid
+	// is missing.
+	ExtendVolumeInvalidData = "invalid_data"
+
+	// ExtendVolumeNotAttached indicates that the volume is not attached
+	// to the instance.
+	ExtendVolumeNotAttached = "not_attached"
+
+	// ExtendVolumeResizeFailure indicates that the instance could not
+	// be notified of the new size of the volume.
+	ExtendVolumeResizeFailure = "resize_failure"
+
+	// ExtendVolumeNotSupported indicates that the extend volume command
+	// is not supported for the given workload type, e.g., a container.
+	ExtendVolumeNotSupported = "not_supported"
+)
+
+// ErrorExtendVolumeFailure represents the unmarshalled version of the contents of a
+// SSNTP ERROR frame whose type is set to ssntp.ExtendVolumeFailure.
+type ErrorExtendVolumeFailure struct {
+	// NodeUUID is the UUID of the node that generated this error.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// InstanceUUID is the UUID of the instance that could not be notified.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// VolumeUUID is the UUID of the volume that has been extended.
+	VolumeUUID string `yaml:"volume_uuid"`
+
+	// Reason provides the reason for the extend failure, e.g.,
+	// ExtendVolumeNoInstance.
+	Reason ExtendVolumeFailureReason `yaml:"reason"`
+}
+
+func (r ExtendVolumeFailureReason) String() string {
+	switch r {
+	case ExtendVolumeNoInstance:
+		return "Instance does not exist"
+	case ExtendVolumeInvalidPayload:
+		return "YAML payload is corrupt"
+	case ExtendVolumeInvalidData:
+		return "Command section of YAML payload is corrupt or missing required information"
+	case ExtendVolumeNotAttached:
+		return "Volume not attached"
+	case ExtendVolumeResizeFailure:
+		return "Failed to resize volume"
+	case ExtendVolumeNotSupported:
+		return "Not Supported"
+	}
+
+	return ""
+}
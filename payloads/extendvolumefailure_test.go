@@ -0,0 +1,92 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestExtendVolumeFailureUnmarshal(t *testing.T) {
+	var error ErrorExtendVolumeFailure
+	err := yaml.Unmarshal([]byte(testutil.ExtendVolumeFailureYaml), &error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if error.NodeUUID != testutil.AgentUUID {
+		t.Error("Wrong Node UUID field")
+	}
+
+	if error.InstanceUUID != testutil.InstanceUUID {
+		t.Error("Wrong Instance UUID field")
+	}
+
+	if error.VolumeUUID != testutil.VolumeUUID {
+		t.Error("Wrong Volume UUID field")
+	}
+
+	if error.Reason != ExtendVolumeResizeFailure {
+		t.Error("Wrong Error field")
+	}
+}
+
+func TestExtendVolumeFailureMarshal(t *testing.T) {
+	error := ErrorExtendVolumeFailure{
+		NodeUUID:     testutil.AgentUUID,
+		InstanceUUID: testutil.InstanceUUID,
+		VolumeUUID:   testutil.VolumeUUID,
+		Reason:       ExtendVolumeResizeFailure,
+	}
+
+	y, err := yaml.Marshal(&error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.ExtendVolumeFailureYaml {
+		t.Errorf("ExtendVolumeFailure marshalling failed\n[%s]\n vs\n[%s]",
+			string(y), testutil.ExtendVolumeFailureYaml)
+	}
+}
+
+func TestExtendVolumeFailureString(t *testing.T) {
+	var stringTests = []struct {
+		r        ExtendVolumeFailureReason
+		expected string
+	}{
+		{ExtendVolumeNoInstance, "Instance does not exist"},
+		{ExtendVolumeInvalidPayload, "YAML payload is corrupt"},
+		{ExtendVolumeInvalidData, "Command section of YAML payload is corrupt or missing required information"},
+		{ExtendVolumeNotAttached, "Volume not attached"},
+		{ExtendVolumeResizeFailure, "Failed to resize volume"},
+		{ExtendVolumeNotSupported, "Not Supported"},
+	}
+	error := ErrorExtendVolumeFailure{
+		InstanceUUID: testutil.InstanceUUID,
+	}
+	for _, test := range stringTests {
+		error.Reason = test.r
+		s := error.Reason.String()
+		if s != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, s)
+		}
+	}
+}
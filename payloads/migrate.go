@@ -0,0 +1,83 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// MigrateCmd contains the information needed by the node currently running
+// an instance to live migrate it to another node.
+type MigrateCmd struct {
+	// InstanceUUID is the UUID of the instance to migrate.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// WorkloadAgentUUID identifies the node on which the instance is
+	// running.  This information is needed by the scheduler to route
+	// the command to the correct CN/NN.
+	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+
+	// TargetAgentUUID identifies the node to which the instance is
+	// migrated.
+	TargetAgentUUID string `yaml:"target_agent_uuid"`
+
+	// URI is the address on which the target node is waiting for the
+	// state of the instance, e.g., tcp:198.51.100.2:49152.
+	URI string `yaml:"uri"`
+}
+
+// Migrate represents the unmarshalled version of the contents of a SSNTP
+// MIGRATE payload.
+type Migrate struct {
+	Migrate MigrateCmd `yaml:"migrate"`
+}
+
+// MigrationTargetReadyEvent is sent by the target node of a live migration
+// once the instance has been started and is waiting for its state.
+type MigrationTargetReadyEvent struct {
+	// InstanceUUID is the UUID of the instance being migrated.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// NodeUUID is the UUID of the target node.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// URI is the address on which the target node is waiting for the
+	// state of the instance.
+	URI string `yaml:"uri"`
+}
+
+// EventMigrationTargetReady represents the unmarshalled version of the
+// contents of an SSNTP ssntp.MigrationTargetReady event.
+type EventMigrationTargetReady struct {
+	MigrationTargetReady MigrationTargetReadyEvent `yaml:"migration_target_ready"`
+}
+
+// InstanceMigratedEvent is sent by the source node of a live migration once
+// the instance is running on the target node and its local state has been
+// deleted.
+type InstanceMigratedEvent struct {
+	// InstanceUUID is the UUID of the migrated instance.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// NodeUUID is the UUID of the source node.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// TargetNodeUUID is the UUID of the node now running the instance.
+	TargetNodeUUID string `yaml:"target_node_uuid"`
+}
+
+// EventInstanceMigrated represents the unmarshalled version of the contents
+// of an SSNTP ssntp.InstanceMigrated event.
+type EventInstanceMigrated struct {
+	InstanceMigrated InstanceMigratedEvent `yaml:"instance_migrated"`
+}
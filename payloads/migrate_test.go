@@ -0,0 +1,102 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMigrateUnmarshal(t *testing.T) {
+	var migrate Migrate
+	err := yaml.Unmarshal([]byte(testutil.LiveMigrateYaml), &migrate)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if migrate.Migrate.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", migrate.Migrate.InstanceUUID)
+	}
+
+	if migrate.Migrate.WorkloadAgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong Agent UUID field [%s]", migrate.Migrate.WorkloadAgentUUID)
+	}
+
+	if migrate.Migrate.TargetAgentUUID != testutil.NetAgentUUID {
+		t.Errorf("Wrong target Agent UUID field [%s]", migrate.Migrate.TargetAgentUUID)
+	}
+
+	if migrate.Migrate.URI != testutil.MigrateURI {
+		t.Errorf("Wrong URI field [%s]", migrate.Migrate.URI)
+	}
+}
+
+func TestMigrateMarshal(t *testing.T) {
+	var migrate Migrate
+	migrate.Migrate.InstanceUUID = testutil.InstanceUUID
+	migrate.Migrate.WorkloadAgentUUID = testutil.AgentUUID
+	migrate.Migrate.TargetAgentUUID = testutil.NetAgentUUID
+	migrate.Migrate.URI = testutil.MigrateURI
+
+	y, err := yaml.Marshal(&migrate)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.LiveMigrateYaml {
+		t.Errorf("MIGRATE marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.LiveMigrateYaml)
+	}
+}
+
+func TestMigrationTargetReadyUnmarshal(t *testing.T) {
+	var ready EventMigrationTargetReady
+	err := yaml.Unmarshal([]byte(testutil.MigrationTargetReadyYaml), &ready)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if ready.MigrationTargetReady.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", ready.MigrationTargetReady.InstanceUUID)
+	}
+
+	if ready.MigrationTargetReady.NodeUUID != testutil.NetAgentUUID {
+		t.Errorf("Wrong node UUID field [%s]", ready.MigrationTargetReady.NodeUUID)
+	}
+
+	if ready.MigrationTargetReady.URI != testutil.MigrateURI {
+		t.Errorf("Wrong URI field [%s]", ready.MigrationTargetReady.URI)
+	}
+}
+
+func TestInstanceMigratedMarshal(t *testing.T) {
+	var migrated EventInstanceMigrated
+	migrated.InstanceMigrated.InstanceUUID = testutil.InstanceUUID
+	migrated.InstanceMigrated.NodeUUID = testutil.AgentUUID
+	migrated.InstanceMigrated.TargetNodeUUID = testutil.NetAgentUUID
+
+	y, err := yaml.Marshal(&migrated)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.InstanceMigratedYaml {
+		t.Errorf("InstanceMigrated marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.InstanceMigratedYaml)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// MigrateFailureReason denotes the underlying error that prevented an SSNTP
+// MIGRATE command from live migrating an instance.
+type MigrateFailureReason string
+
+const (
+	// MigrateNoInstance indicates that the instance could not be migrated
+	// as it does not exist on the node to which the command was sent.
+	MigrateNoInstance MigrateFailureReason = "no_instance"
+
+	// MigrateInvalidPayload indicates that the payload of the SSNTP
+	// MIGRATE command was corrupt and could not be unmarshalled.
+	MigrateInvalidPayload = "invalid_payload"
+
+	// MigrateInvalidData is returned by ciao-launcher if the contents
+	// of the MIGRATE payload are incorrect, e.g., the uri is missing.
+	MigrateInvalidData = "invalid_data"
+
+	// MigrateInvalidState indicates that the instance is not in a state
+	// allowing it to be migrated, e.g., it is paused.
+	MigrateInvalidState = "invalid_state"
+
+	// MigrateNotSupported indicates that the instance cannot be live
+	// migrated, e.g., it is a container.
+	MigrateNotSupported = "not_supported"
+
+	// MigrateTransferFailure indicates that the transfer of the instance
+	// state from the source to the target node failed.
+	MigrateTransferFailure = "transfer_failure"
+
+	// MigrateTimeout is returned by the target node of a migration when
+	// it does not receive the state of the instance in time.
+	MigrateTimeout = "timeout"
+)
+
+// ErrorMigrateFailure represents the unmarshalled version of the contents of a
+// SSNTP ERROR frame whose type is set to ssntp.MigrateFailure.
+type ErrorMigrateFailure struct {
+	// NodeUUID is the UUID of the node that generated this error, i.e.,
+	// either the source or the target node of the migration.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// InstanceUUID is the UUID of the instance that could not be migrated.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// Reason provides the reason for the failure, e.g.,
+	// MigrateTransferFailure.
+	Reason MigrateFailureReason `yaml:"reason"`
+}
+
+func (r MigrateFailureReason) String() string {
+	switch r {
+	case MigrateNoInstance:
+		return "Instance does not exist"
+	case MigrateInvalidPayload:
+		return "YAML payload is corrupt"
+	case MigrateInvalidData:
+		return "Command section of YAML payload is corrupt or missing required information"
+	case MigrateInvalidState:
+		return "Instance state does not allow migration"
+	case MigrateNotSupported:
+		return "Not Supported"
+	case MigrateTransferFailure:
+		return "Failed to transfer instance state"
+	case MigrateTimeout:
+		return "Timed out waiting for instance state"
+	}
+
+	return ""
+}
@@ -0,0 +1,74 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// PowerCmd contains the information needed to pause, unpause, suspend or
+// resume an existing instance.
+type PowerCmd struct {
+	// InstanceUUID is the UUID of the instance to act upon.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// WorkloadAgentUUID identifies the node on which the instance is
+	// running.  This information is needed by the scheduler to route
+	// the command to the correct CN/NN.
+	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+}
+
+// RebootCmd contains the information needed to reboot a running instance.
+type RebootCmd struct {
+	// InstanceUUID is the UUID of the instance to reboot.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// WorkloadAgentUUID identifies the node on which the instance is
+	// running.  This information is needed by the scheduler to route
+	// the command to the correct CN/NN.
+	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+
+	// Hard is true if the instance should be reset rather than asked
+	// to reboot through an ACPI power down.
+	Hard bool `yaml:"hard"`
+}
+
+// Reboot represents the unmarshalled version of the contents of a SSNTP
+// REBOOT payload.
+type Reboot struct {
+	Reboot RebootCmd `yaml:"reboot"`
+}
+
+// Pause represents the unmarshalled version of the contents of a SSNTP
+// PAUSE payload.
+type Pause struct {
+	Pause PowerCmd `yaml:"pause"`
+}
+
+// Unpause represents the unmarshalled version of the contents of a SSNTP
+// UNPAUSE payload.
+type Unpause struct {
+	Unpause PowerCmd `yaml:"unpause"`
+}
+
+// Suspend represents the unmarshalled version of the contents of a SSNTP
+// SUSPEND payload.
+type Suspend struct {
+	Suspend PowerCmd `yaml:"suspend"`
+}
+
+// Resume represents the unmarshalled version of the contents of a SSNTP
+// RESUME payload.
+type Resume struct {
+	Resume PowerCmd `yaml:"resume"`
+}
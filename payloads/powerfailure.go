@@ -0,0 +1,82 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// PowerFailureReason denotes the underlying error that prevented an SSNTP
+// REBOOT, PAUSE, UNPAUSE, SUSPEND or RESUME command from succeeding.
+type PowerFailureReason string
+
+const (
+	// PowerNoInstance indicates that the command could not be executed
+	// as the instance does not exist on the node to which the command
+	// was sent.
+	PowerNoInstance PowerFailureReason = "no_instance"
+
+	// PowerInvalidPayload indicates that the payload of the SSNTP
+	// command was corrupt and could not be unmarshalled.
+	PowerInvalidPayload = "invalid_payload"
+
+	// PowerInvalidData is returned by ciao-launcher if the contents
+	// of the command payload are incorrect, e.g., the instance_uuid
+	// is missing.
+	PowerInvalidData = "invalid_data"
+
+	// PowerInvalidState indicates that the instance is not in a state
+	// allowing the command, e.g., trying to pause a suspended instance.
+	PowerInvalidState = "invalid_state"
+
+	// PowerNotSupported indicates that the command is not supported for
+	// the given workload type, e.g., suspending a container.
+	PowerNotSupported = "not_supported"
+
+	// PowerActionFailure indicates that the hypervisor or the container
+	// runtime failed to carry out the command.
+	PowerActionFailure = "action_failure"
+)
+
+// ErrorPowerFailure represents the unmarshalled version of the contents of a
+// SSNTP ERROR frame whose type is set to ssntp.RebootFailure, ssntp.PauseFailure,
+// ssntp.UnpauseFailure, ssntp.SuspendFailure or ssntp.ResumeFailure.
+type ErrorPowerFailure struct {
+	// NodeUUID is the UUID of the node that generated this error.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// InstanceUUID is the UUID of the instance the command failed for.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// Reason provides the reason for the failure, e.g., PowerInvalidState.
+	Reason PowerFailureReason `yaml:"reason"`
+}
+
+func (r PowerFailureReason) String() string {
+	switch r {
+	case PowerNoInstance:
+		return "Instance does not exist"
+	case PowerInvalidPayload:
+		return "YAML payload is corrupt"
+	case PowerInvalidData:
+		return "Command section of YAML payload is corrupt or missing required information"
+	case PowerInvalidState:
+		return "Instance state does not allow the command"
+	case PowerNotSupported:
+		return "Not Supported"
+	case PowerActionFailure:
+		return "Hypervisor failed to execute the command"
+	}
+
+	return ""
+}
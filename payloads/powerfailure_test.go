@@ -0,0 +1,86 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPowerFailureUnmarshal(t *testing.T) {
+	var error ErrorPowerFailure
+	err := yaml.Unmarshal([]byte(testutil.PowerFailureYaml), &error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if error.NodeUUID != testutil.AgentUUID {
+		t.Error("Wrong Node UUID field")
+	}
+
+	if error.InstanceUUID != testutil.InstanceUUID {
+		t.Error("Wrong Instance UUID field")
+	}
+
+	if error.Reason != PowerInvalidState {
+		t.Error("Wrong Error field")
+	}
+}
+
+func TestPowerFailureMarshal(t *testing.T) {
+	error := ErrorPowerFailure{
+		NodeUUID:     testutil.AgentUUID,
+		InstanceUUID: testutil.InstanceUUID,
+		Reason:       PowerInvalidState,
+	}
+
+	y, err := yaml.Marshal(&error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.PowerFailureYaml {
+		t.Errorf("PowerFailure marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.PowerFailureYaml)
+	}
+}
+
+func TestPowerFailureString(t *testing.T) {
+	var stringTests = []struct {
+		r        PowerFailureReason
+		expected string
+	}{
+		{PowerNoInstance, "Instance does not exist"},
+		{PowerInvalidPayload, "YAML payload is corrupt"},
+		{PowerInvalidData, "Command section of YAML payload is corrupt or missing required information"},
+		{PowerInvalidState, "Instance state does not allow the command"},
+		{PowerNotSupported, "Not Supported"},
+		{PowerActionFailure, "Hypervisor failed to execute the command"},
+	}
+	error := ErrorPowerFailure{
+		InstanceUUID: testutil.InstanceUUID,
+	}
+	for _, test := range stringTests {
+		error.Reason = test.r
+		s := error.Reason.String()
+		if s != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, s)
+		}
+	}
+}
@@ -45,10 +45,17 @@ type Ready struct {
 	// cpu[0-9]+ entries in /proc/stat.
 	CpusOnline int `yaml:"cpus_online"`
 
+	// Number of VCPUs allocated to the instances running on the CN/NN.
+	VCPUsAllocated int `yaml:"vcpus_allocated"`
+
 	// Array containing one entry for each network interface present on the
 	// CN/NN
 	Networks []NetworkStat
 
+	// Labels contains the key/value labels advertised by the CN/NN, e.g.,
+	// disk: ssd.  They can be matched by NodeSelector requested resources.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
 	// Any changes to this struct should be accompanied by a change to
 	// the ciao-scheduler/scheduler.go:updateNodeStat() function
 }
@@ -62,4 +69,5 @@ func (s *Ready) Init() {
 	s.DiskAvailableMB = -1
 	s.Load = -1
 	s.CpusOnline = -1
+	s.VCPUsAllocated = -1
 }
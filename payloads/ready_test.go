@@ -41,6 +41,7 @@ func TestReadyMarshal(t *testing.T) {
 		DiskAvailableMB: 256000,
 		Load:            0,
 		CpusOnline:      4,
+		VCPUsAllocated:  2,
 		Networks: []NetworkStat{
 			{NodeIP: "192.168.1.1", NodeMAC: "02:00:15:03:6f:49"},
 			{NodeIP: "10.168.1.1", NodeMAC: "02:00:8c:ba:f9:45"},
@@ -76,6 +77,7 @@ func TestReadyNodeNotAllStats(t *testing.T) {
 		DiskAvailableMB: -1,
 		Load:            1,
 		CpusOnline:      -1,
+		VCPUsAllocated:  -1,
 	}
 	if cmd.NodeUUID != expectedCmd.NodeUUID ||
 		cmd.MemTotalMB != expectedCmd.MemTotalMB ||
@@ -84,7 +86,31 @@ func TestReadyNodeNotAllStats(t *testing.T) {
 		cmd.DiskAvailableMB != expectedCmd.DiskAvailableMB ||
 		cmd.Load != expectedCmd.Load ||
 		cmd.CpusOnline != expectedCmd.CpusOnline ||
+		cmd.VCPUsAllocated != expectedCmd.VCPUsAllocated ||
 		len(cmd.Networks) != 0 {
 		t.Error("Unexpected values in Ready")
 	}
 }
+
+func TestReadyLabels(t *testing.T) {
+	cmd := Ready{
+		NodeUUID: testutil.AgentUUID,
+		Labels:   map[string]string{"disk": "ssd", "rack": "r1"},
+	}
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd2 Ready
+	err = yaml.Unmarshal(y, &cmd2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cmd2.Labels) != 2 || cmd2.Labels["disk"] != "ssd" ||
+		cmd2.Labels["rack"] != "r1" {
+		t.Errorf("Unexpected labels in Ready: %v", cmd2.Labels)
+	}
+}
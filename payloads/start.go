@@ -83,6 +83,10 @@ const (
 	// Volume is used to indicate that the requested resource is a volume.
 	Volume = "volume"
 
+	// Snapshot is used to indicate that the requested resource is a
+	// volume snapshot.
+	Snapshot = "snapshot"
+
 	// Image is used to indicate that the requested resource is an image.
 	Image = "image"
 
@@ -93,6 +97,21 @@ const (
 	// SharedDiskGiB is used for shared storage across the cluster used for
 	// storing volume and images. (Measured in GiB)
 	SharedDiskGiB = "shared_disk_gib"
+
+	// NodeSelector indicates that a resource struct restricts placement
+	// to the nodes advertising a given label. The label is specified in
+	// the ValueString field, in the key=value format.
+	NodeSelector = "node_selector"
+
+	// Affinity indicates that a resource struct requires an instance to
+	// be placed on a node already hosting instances of the workload whose
+	// ID is specified in the ValueString field.
+	Affinity = "affinity"
+
+	// AntiAffinity indicates that a resource struct forbids an instance
+	// from being placed on a node already hosting instances of the
+	// workload whose ID is specified in the ValueString field.
+	AntiAffinity = "anti_affinity"
 )
 
 const (
@@ -239,9 +258,35 @@ type StartCmd struct {
 	// from storage for the new instance.
 	Storage []StorageResource `yaml:"storage,omitempty"`
 
+	// WorkloadUUID is the UUID of the workload the instance belongs to.
+	// It is used by the scheduler to enforce Affinity and AntiAffinity
+	// requested resources.
+	WorkloadUUID string `yaml:"workload_uuid,omitempty"`
+
+	// AffinityNodes contains the UUIDs of the nodes known to host
+	// instances of the workloads listed in Affinity requested resources.
+	AffinityNodes []string `yaml:"affinity_nodes,omitempty"`
+
+	// AntiAffinityNodes contains the UUIDs of the nodes known to host
+	// instances of the workloads listed in AntiAffinity requested
+	// resources.
+	AntiAffinityNodes []string `yaml:"anti_affinity_nodes,omitempty"`
+
 	// Restart is set to true if the payload represents a request to
 	// restart an existing instance on a new node.
 	Restart bool
+
+	// MigrationSource is the UUID of the node from which the instance
+	// is being live migrated.  When set, the instance is started waiting
+	// for its state to be sent by the source node rather than booted, and
+	// the scheduler will not pick the source node to start it on.
+	MigrationSource string `yaml:"migration_source,omitempty"`
+
+	// PreferredNode is the UUID of a node the scheduler should start the
+	// instance on if it still has enough resources for it.  Resized
+	// instances are restarted on the node they were running on when
+	// possible.
+	PreferredNode string `yaml:"preferred_node,omitempty"`
 }
 
 // Start represents the unmarshalled version of the contents of a SSNTP START
@@ -109,3 +109,37 @@ func TestStartUnmarshalPartial(t *testing.T) {
 		t.Error("Unexpected values in Start")
 	}
 }
+
+func TestStartPlacementConstraints(t *testing.T) {
+	var cmd Start
+	cmd.Start.InstanceUUID = testutil.InstanceUUID
+	cmd.Start.WorkloadUUID = testutil.WorkloadUUID
+	cmd.Start.RequestedResources = []RequestedResource{
+		{Type: NodeSelector, ValueString: "disk=ssd", Mandatory: true},
+		{Type: AntiAffinity, ValueString: testutil.WorkloadUUID, Mandatory: true},
+	}
+	cmd.Start.AntiAffinityNodes = []string{testutil.AgentUUID}
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd2 Start
+	err = yaml.Unmarshal(y, &cmd2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd2.Start.WorkloadUUID != testutil.WorkloadUUID ||
+		len(cmd2.Start.RequestedResources) != 2 ||
+		cmd2.Start.RequestedResources[0].Type != NodeSelector ||
+		cmd2.Start.RequestedResources[0].ValueString != "disk=ssd" ||
+		cmd2.Start.RequestedResources[1].Type != AntiAffinity ||
+		cmd2.Start.RequestedResources[1].ValueString != testutil.WorkloadUUID ||
+		len(cmd2.Start.AffinityNodes) != 0 ||
+		len(cmd2.Start.AntiAffinityNodes) != 1 ||
+		cmd2.Start.AntiAffinityNodes[0] != testutil.AgentUUID {
+		t.Errorf("Unexpected values in Start: %v", cmd2.Start)
+	}
+}
@@ -99,6 +99,9 @@ type Stat struct {
 	// CN/NN
 	Networks []NetworkStat
 
+	// Labels contains the key/value labels advertised by the CN/NN.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
 	// Array containing statistics information for each instance hosted by
 	// the CN/NN
 	Instances []InstanceStat
@@ -148,6 +151,18 @@ const (
 
 	// Hung indicates that an instance is not responding to commands.
 	Hung = "hung"
+
+	// Paused indicates that the execution of an instance has been frozen
+	// by a PAUSE command.
+	Paused = "paused"
+
+	// Suspended indicates that the state of an instance has been saved
+	// to disk and the instance stopped by a SUSPEND command.
+	Suspended = "suspended"
+
+	// Migrating indicates that an instance is being live migrated from
+	// one node to another.
+	Migrating = "migrating"
 )
 
 // Init initialises instances of the Stat structure.
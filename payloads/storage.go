@@ -38,3 +38,36 @@ type VolumeCmd struct {
 type AttachVolume struct {
 	Attach VolumeCmd `yaml:"attach_volume"`
 }
+
+// DetachVolume represents the unmarshalled version of the contents of a SSNTP
+// DetachVolume payload.  The structure contains enough information to detach a
+// volume from an existing instance.
+type DetachVolume struct {
+	Detach VolumeCmd `yaml:"detach_volume"`
+}
+
+// ExtendVolumeCmd contains all the information needed to notify an
+// instance that one of its volumes has been extended.
+type ExtendVolumeCmd struct {
+	// InstanceUUID is the UUID of the instance to which the volume is
+	// attached.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// VolumeUUID is the UUID of the volume that has been extended.
+	VolumeUUID string `yaml:"volume_uuid"`
+
+	// WorkloadAgentUUID identifies the node on which the instance is
+	// running.  This information is needed by the scheduler to route
+	// the command to the correct CN/NN.
+	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+
+	// SizeGiB is the new size of the volume in GiB.
+	SizeGiB int `yaml:"size_gib"`
+}
+
+// ExtendVolume represents the unmarshalled version of the contents of a SSNTP
+// ExtendVolume payload.  The structure contains enough information to inform
+// a running instance that the size of one of its volumes has changed.
+type ExtendVolume struct {
+	Extend ExtendVolumeCmd `yaml:"extend_volume"`
+}
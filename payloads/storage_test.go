@@ -60,3 +60,82 @@ func TestAttachVolmeMarshal(t *testing.T) {
 			string(y), testutil.AttachVolumeYaml)
 	}
 }
+
+func TestDetachVolumeUnmarshal(t *testing.T) {
+	var detach DetachVolume
+	err := yaml.Unmarshal([]byte(testutil.DetachVolumeYaml), &detach)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if detach.Detach.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", detach.Detach.InstanceUUID)
+	}
+
+	if detach.Detach.VolumeUUID != testutil.VolumeUUID {
+		t.Errorf("Wrong Volume UUID field [%s]", detach.Detach.VolumeUUID)
+	}
+
+	if detach.Detach.WorkloadAgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong WorkloadAgentUUID field [%s]", detach.Detach.WorkloadAgentUUID)
+	}
+}
+
+func TestDetachVolumeMarshal(t *testing.T) {
+	var detach DetachVolume
+	detach.Detach.InstanceUUID = testutil.InstanceUUID
+	detach.Detach.VolumeUUID = testutil.VolumeUUID
+	detach.Detach.WorkloadAgentUUID = testutil.AgentUUID
+
+	y, err := yaml.Marshal(&detach)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.DetachVolumeYaml {
+		t.Errorf("DetachVolume marshalling failed\n[%s]\n vs\n[%s]",
+			string(y), testutil.DetachVolumeYaml)
+	}
+}
+
+func TestExtendVolumeUnmarshal(t *testing.T) {
+	var extend ExtendVolume
+	err := yaml.Unmarshal([]byte(testutil.ExtendVolumeYaml), &extend)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if extend.Extend.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", extend.Extend.InstanceUUID)
+	}
+
+	if extend.Extend.VolumeUUID != testutil.VolumeUUID {
+		t.Errorf("Wrong Volume UUID field [%s]", extend.Extend.VolumeUUID)
+	}
+
+	if extend.Extend.WorkloadAgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong WorkloadAgentUUID field [%s]", extend.Extend.WorkloadAgentUUID)
+	}
+
+	if extend.Extend.SizeGiB != 20 {
+		t.Errorf("Wrong SizeGiB field [%d]", extend.Extend.SizeGiB)
+	}
+}
+
+func TestExtendVolumeMarshal(t *testing.T) {
+	var extend ExtendVolume
+	extend.Extend.InstanceUUID = testutil.InstanceUUID
+	extend.Extend.VolumeUUID = testutil.VolumeUUID
+	extend.Extend.WorkloadAgentUUID = testutil.AgentUUID
+	extend.Extend.SizeGiB = 20
+
+	y, err := yaml.Marshal(&extend)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.ExtendVolumeYaml {
+		t.Errorf("ExtendVolume marshalling failed\n[%s]\n vs\n[%s]",
+			string(y), testutil.ExtendVolumeYaml)
+	}
+}
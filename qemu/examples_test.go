@@ -83,3 +83,81 @@ func Example() {
 	// event occurs.
 	<-disconnectedCh
 }
+
+// This example live migrates an instance between two qemu processes running
+// on the same host.  Software emulation (TCG) is used so that it can be run
+// on hosts without KVM support.
+func Example_liveMigration() {
+	launch := func(name string, incoming qemu.Incoming) *qemu.QMP {
+		config := qemu.Config{
+			Name:    name,
+			Machine: qemu.Machine{Type: "pc", Acceleration: "tcg"},
+			QMPSockets: []qemu.QMPSocket{
+				{
+					Type:   qemu.Unix,
+					Name:   "/tmp/qmp-" + name,
+					Server: true,
+					NoWait: true,
+				},
+			},
+			Memory:   qemu.Memory{Size: "256M"},
+			Knobs:    qemu.Knobs{NoGraphic: true, Daemonize: true},
+			Incoming: incoming,
+		}
+
+		_, err := qemu.LaunchQemu(config, nil)
+		if err != nil {
+			panic(err)
+		}
+
+		q, _, err := qemu.QMPStart(context.Background(), "/tmp/qmp-"+name,
+			qemu.QMPConfig{}, make(chan struct{}))
+		if err != nil {
+			panic(err)
+		}
+
+		err = q.ExecuteQMPCapabilities(context.Background())
+		if err != nil {
+			panic(err)
+		}
+
+		return q
+	}
+
+	// The target is started first.  It waits for the state of the
+	// instance on port 4444 rather than booting.
+	source := launch("source", qemu.Incoming{})
+	target := launch("target", qemu.Incoming{
+		Type:    qemu.MigrationTCP,
+		Address: "127.0.0.1:4444",
+	})
+
+	err := source.ExecuteMigrateStart(context.Background(), "tcp:127.0.0.1:4444")
+	if err != nil {
+		panic(err)
+	}
+
+	// Poll the source until the migration is over.  The target resumes
+	// the instance automatically once it has received its state.
+	for {
+		status, err := source.ExecuteQueryMigrate(context.Background())
+		if err != nil {
+			panic(err)
+		}
+
+		if status.Status == "completed" {
+			break
+		} else if status.Status == "failed" || status.Status == "cancelled" {
+			panic(status.ErrorDesc)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// The source instance is paused and can be discarded.
+	_ = source.ExecuteQuit(context.Background())
+	source.Shutdown()
+
+	_ = target.ExecuteQuit(context.Background())
+	target.Shutdown()
+}
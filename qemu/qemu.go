@@ -968,6 +968,38 @@ type Knobs struct {
 	Realtime bool
 }
 
+// MigrationType is the type of an incoming migration, i.e., the way the
+// state of the instance is received.
+type MigrationType string
+
+const (
+	// MigrationTCP receives the state of the instance on a TCP socket.
+	MigrationTCP MigrationType = "tcp"
+
+	// MigrationExec receives the state of the instance from the standard
+	// output of a command.
+	MigrationExec MigrationType = "exec"
+
+	// MigrationDefer starts the instance waiting for an incoming
+	// migration whose URI will be provided later on through
+	// QMP.ExecuteMigrateIncoming.
+	MigrationDefer MigrationType = "defer"
+)
+
+// Incoming describes the incoming migration a qemu instance should be
+// started for.  Such an instance does not boot but waits for its state to be
+// sent by the source of the migration.
+type Incoming struct {
+	// Type is the type of the incoming migration.  No incoming migration
+	// is set up if Type is empty.
+	Type MigrationType
+
+	// Address is the address to listen on for MigrationTCP, e.g.,
+	// 0:4444, or the command to run for MigrationExec.  It is not used
+	// for MigrationDefer.
+	Address string
+}
+
 // Config is the qemu configuration structure.
 // It allows for passing custom settings and parameters to the qemu API.
 type Config struct {
@@ -1019,6 +1051,9 @@ type Config struct {
 	// Bios is the -bios parameter
 	Bios string
 
+	// Incoming is the -incoming parameter
+	Incoming Incoming
+
 	// fds is a list of open file descriptors to be passed to the spawned qemu process
 	fds []*os.File
 
@@ -1271,6 +1306,22 @@ func (config *Config) appendBios() {
 	}
 }
 
+func (config *Config) appendIncoming() {
+	var uri string
+
+	switch config.Incoming.Type {
+	case "":
+		return
+	case MigrationDefer:
+		uri = string(MigrationDefer)
+	default:
+		uri = fmt.Sprintf("%s:%s", config.Incoming.Type, config.Incoming.Address)
+	}
+
+	config.qemuParams = append(config.qemuParams, "-incoming")
+	config.qemuParams = append(config.qemuParams, uri)
+}
+
 // LaunchQemu can be used to launch a new qemu instance.
 //
 // The Config parameter contains a set of qemu parameters and settings.
@@ -1295,6 +1346,7 @@ func LaunchQemu(config Config, logger QMPLog) (string, error) {
 	config.appendKnobs()
 	config.appendKernel()
 	config.appendBios()
+	config.appendIncoming()
 
 	return LaunchCustomQemu(config.Ctx, config.Path, config.qemuParams, config.fds, logger)
 }
@@ -64,6 +64,10 @@ func testAppend(structure interface{}, expected string, t *testing.T) {
 	case RTC:
 		config.RTC = s
 		config.appendRTC()
+
+	case Incoming:
+		config.Incoming = s
+		config.appendIncoming()
 	}
 
 	result := strings.Join(config.qemuParams, " ")
@@ -421,3 +425,10 @@ func TestAppendRTC(t *testing.T) {
 
 	testAppend(rtc, rtcString, t)
 }
+
+func TestAppendIncoming(t *testing.T) {
+	testAppend(Incoming{Type: MigrationTCP, Address: "0:4444"}, "-incoming tcp:0:4444", t)
+	testAppend(Incoming{Type: MigrationExec, Address: "cat /tmp/state"}, "-incoming exec:cat /tmp/state", t)
+	testAppend(Incoming{Type: MigrationDefer}, "-incoming defer", t)
+	testAppend(Incoming{}, "", t)
+}
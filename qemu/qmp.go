@@ -107,7 +107,8 @@ type QMPEvent struct {
 }
 
 type qmpResult struct {
-	err error
+	response interface{}
+	err      error
 }
 
 type qmpCommand struct {
@@ -117,6 +118,7 @@ type qmpCommand struct {
 	args           map[string]interface{}
 	filter         *qmpEventFilter
 	resultReceived bool
+	response       interface{}
 }
 
 // QMP is a structure that contains the internal state used by startQMPLoop and
@@ -130,6 +132,50 @@ type QMP struct {
 	version        *QMPVersion
 }
 
+// MigrationCapability describes a single migration capability that can be
+// passed to ExecuteMigrateSetCapabilities.
+type MigrationCapability struct {
+	// Capability is the name of the capability, e.g., events.
+	Capability string
+
+	// State indicates whether the capability should be enabled.
+	State bool
+}
+
+// MigrationRAM contains statistics about the transfer of the memory of an
+// instance being migrated.
+type MigrationRAM struct {
+	Total            int64 `json:"total"`
+	Remaining        int64 `json:"remaining"`
+	Transferred      int64 `json:"transferred"`
+	DirtyPagesRate   int64 `json:"dirty-pages-rate"`
+	DirtySyncCount   int64 `json:"dirty-sync-count"`
+	PostcopyRequests int64 `json:"postcopy-requests"`
+}
+
+// MigrationStatus contains the status of a migration, as returned by
+// ExecuteQueryMigrate.
+type MigrationStatus struct {
+	// Status is the state of the migration, e.g., active, completed,
+	// failed or cancelled.  It is empty if no migration has been
+	// started.
+	Status string `json:"status"`
+
+	// TotalTime is the total amount of milliseconds since the migration
+	// started.
+	TotalTime int64 `json:"total-time"`
+
+	// Downtime is the amount of milliseconds the instance was stopped
+	// for.  Only available once the migration has completed.
+	Downtime int64 `json:"downtime"`
+
+	// RAM contains statistics about the memory transfer.
+	RAM MigrationRAM `json:"ram"`
+
+	// ErrorDesc describes the error of a failed migration.
+	ErrorDesc string `json:"error-desc"`
+}
+
 // QMPVersion contains the version number and the capabailities of a QEMU
 // instance, as reported in the QMP greeting message.
 type QMPVersion struct {
@@ -210,7 +256,7 @@ func (q *QMP) finaliseCommand(cmdEl *list.Element, cmdQueue *list.List, succeede
 	case <-cmd.ctx.Done():
 	default:
 		if succeeded {
-			cmd.res <- qmpResult{}
+			cmd.res <- qmpResult{response: cmd.response}
 		} else {
 			cmd.res <- qmpResult{err: fmt.Errorf("QMP command failed")}
 		}
@@ -233,7 +279,7 @@ func (q *QMP) processQMPInput(line []byte, cmdQueue *list.List) {
 		return
 	}
 
-	_, succeeded := vmData["return"]
+	response, succeeded := vmData["return"]
 	_, failed := vmData["error"]
 
 	if !succeeded && !failed {
@@ -247,6 +293,7 @@ func (q *QMP) processQMPInput(line []byte, cmdQueue *list.List) {
 		return
 	}
 	cmd := cmdEl.Value.(*qmpCommand)
+	cmd.response = response
 	if failed || cmd.filter == nil {
 		q.finaliseCommand(cmdEl, cmdQueue, succeeded)
 	} else {
@@ -465,7 +512,14 @@ func startQMPLoop(conn io.ReadWriteCloser, cfg QMPConfig,
 
 func (q *QMP) executeCommand(ctx context.Context, name string, args map[string]interface{},
 	filter *qmpEventFilter) error {
+	_, err := q.executeCommandWithResponse(ctx, name, args, filter)
+	return err
+}
+
+func (q *QMP) executeCommandWithResponse(ctx context.Context, name string, args map[string]interface{},
+	filter *qmpEventFilter) (interface{}, error) {
 	var err error
+	var response interface{}
 	resCh := make(chan qmpResult)
 	select {
 	case <-q.disconnectedCh:
@@ -480,17 +534,18 @@ func (q *QMP) executeCommand(ctx context.Context, name string, args map[string]i
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	select {
 	case res := <-resCh:
+		response = res.response
 		err = res.err
 	case <-ctx.Done():
 		err = ctx.Err()
 	}
 
-	return err
+	return response, err
 }
 
 // QMPStart connects to a unix domain socket maintained by a QMP instance.  It
@@ -597,6 +652,107 @@ func (q *QMP) ExecuteQuit(ctx context.Context) error {
 	return q.executeCommand(ctx, "quit", nil, nil)
 }
 
+// ExecuteSystemReset sends the system_reset command to the instance.  The
+// instance is reset immediately without the guest being notified.
+func (q *QMP) ExecuteSystemReset(ctx context.Context) error {
+	return q.executeCommand(ctx, "system_reset", nil, nil)
+}
+
+// ExecuteMigrateSetCapabilities sends the migrate-set-capabilities command to
+// the instance, enabling or disabling each of the migration capabilities
+// in caps, e.g., xbzrle or auto-converge.
+func (q *QMP) ExecuteMigrateSetCapabilities(ctx context.Context, caps []MigrationCapability) error {
+	capabilities := make([]interface{}, 0, len(caps))
+	for _, c := range caps {
+		capabilities = append(capabilities, map[string]interface{}{
+			"capability": c.Capability,
+			"state":      c.State,
+		})
+	}
+	args := map[string]interface{}{
+		"capabilities": capabilities,
+	}
+	return q.executeCommand(ctx, "migrate-set-capabilities", args, nil)
+}
+
+// ExecuteMigrationEvents asks the instance to emit MIGRATION events by
+// enabling the events migration capability.  It must be called before
+// ExecuteMigrate.
+func (q *QMP) ExecuteMigrationEvents(ctx context.Context) error {
+	return q.ExecuteMigrateSetCapabilities(ctx, []MigrationCapability{
+		{Capability: "events", State: true},
+	})
+}
+
+// ExecuteMigrate sends the migrate command to the instance.  uri is the
+// destination of the migration, e.g., tcp:192.168.0.1:4444 or
+// exec:cat > /tmp/state.  This function will block until a MIGRATION event
+// reporting a completed migration is received, so migration events need to
+// have been enabled with ExecuteMigrationEvents.  A failed migration will
+// not generate such an event so ctx should carry a timeout.
+func (q *QMP) ExecuteMigrate(ctx context.Context, uri string) error {
+	args := map[string]interface{}{
+		"uri": uri,
+	}
+	filter := &qmpEventFilter{
+		eventName: "MIGRATION",
+		dataKey:   "status",
+		dataValue: "completed",
+	}
+	return q.executeCommand(ctx, "migrate", args, filter)
+}
+
+// ExecuteMigrateStart sends the migrate command to the instance.  Unlike
+// ExecuteMigrate, this function returns as soon as QEMU has accepted the
+// command.  The progress of the migration can be followed with
+// ExecuteQueryMigrate.
+func (q *QMP) ExecuteMigrateStart(ctx context.Context, uri string) error {
+	args := map[string]interface{}{
+		"uri": uri,
+	}
+	return q.executeCommand(ctx, "migrate", args, nil)
+}
+
+// ExecuteMigrateIncoming sends the migrate-incoming command to an instance
+// launched with a MigrationDefer incoming migration.  uri is the address the
+// instance will receive its state on, e.g., tcp:0:4444.
+func (q *QMP) ExecuteMigrateIncoming(ctx context.Context, uri string) error {
+	args := map[string]interface{}{
+		"uri": uri,
+	}
+	return q.executeCommand(ctx, "migrate-incoming", args, nil)
+}
+
+// ExecuteMigrateCancel sends the migrate_cancel command to the instance,
+// aborting any ongoing migration.  The instance keeps running on the
+// source host.
+func (q *QMP) ExecuteMigrateCancel(ctx context.Context) error {
+	return q.executeCommand(ctx, "migrate_cancel", nil, nil)
+}
+
+// ExecuteQueryMigrate sends the query-migrate command to the instance and
+// returns the status of the current, or last, migration.
+func (q *QMP) ExecuteQueryMigrate(ctx context.Context) (MigrationStatus, error) {
+	var status MigrationStatus
+
+	response, err := q.executeCommandWithResponse(ctx, "query-migrate", nil, nil)
+	if err != nil {
+		return status, err
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return status, fmt.Errorf("Unable to extract migrate status: %v", err)
+	}
+
+	err = json.Unmarshal(data, &status)
+	if err != nil {
+		return status, fmt.Errorf("Unable to parse migrate status: %v", err)
+	}
+
+	return status, nil
+}
+
 // ExecuteBlockdevAdd sends a blockdev-add to the QEMU instance.  device is the
 // path of the device to add, e.g., /dev/rdb0, and blockdevID is an identifier
 // used to name the device.  As this identifier will be passed directly to QMP,
@@ -658,6 +814,28 @@ func (q *QMP) ExecuteBlockdevDel(ctx context.Context, blockdevID string) error {
 	return q.executeCommand(ctx, "x-blockdev-del", args, nil)
 }
 
+// ExecuteBlockResize resizes a block device to size bytes by sending a
+// block_resize command.  blockdevID is either the id of a drive specified on
+// the QEMU command line or the id passed to a previous call to
+// ExecuteBlockdevAdd.  Block devices can only be grown safely, the guest
+// being notified of the new size.
+func (q *QMP) ExecuteBlockResize(ctx context.Context, blockdevID string, size uint64) error {
+	args := map[string]interface{}{
+		"device": blockdevID,
+		"size":   size,
+	}
+
+	// Since qemu 2.9 block devices added with blockdev-add are only known
+	// by their node-name.  QEMU looks up the device first and then the
+	// node-name so we can pass both.
+
+	if q.version.Major > 2 || (q.version.Major == 2 && q.version.Minor >= 9) {
+		args["node-name"] = blockdevID
+	}
+
+	return q.executeCommand(ctx, "block_resize", args, nil)
+}
+
 // ExecuteDeviceDel deletes guest portion of a QEMU device by sending a
 // device_del command.   devId is the identifier of the device to delete.
 // Typically it would match the devID parameter passed to an earlier call
@@ -345,6 +345,162 @@ func TestQMPQuit(t *testing.T) {
 	<-disconnectedCh
 }
 
+// Checks that the system_reset command is correctly sent.
+//
+// We start a QMPLoop, send the system_reset command and stop the
+// loop.
+//
+// The system_reset command should be correctly sent and the QMP loop
+// should exit gracefully.
+func TestQMPSystemReset(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("system_reset", nil, "return", nil)
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	err := q.ExecuteSystemReset(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+}
+
+// Checks that the migrate-set-capabilities command is correctly sent.
+//
+// We start a QMPLoop, send the migrate-set-capabilities command and stop
+// the loop.
+//
+// The migrate-set-capabilities command should be correctly sent and the
+// QMP loop should exit gracefully.
+func TestQMPMigrationEvents(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("migrate-set-capabilities", nil, "return", nil)
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	err := q.ExecuteMigrationEvents(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+}
+
+// Checks that the migrate command is correctly sent.
+//
+// We start a QMPLoop, send the migrate command and stop the loop.
+//
+// The migrate command should be correctly sent and should return
+// as we've provisioned a MIGRATION event with a completed status.
+// The QMP loop should exit gracefully.
+func TestQMPMigrate(t *testing.T) {
+	const (
+		seconds         = 1352167040730
+		microsecondsEv1 = 123456
+	)
+
+	var wg sync.WaitGroup
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("migrate", nil, "return", nil)
+	buf.AddEvent("MIGRATION", time.Millisecond*100,
+		map[string]interface{}{
+			"status": "completed",
+		},
+		map[string]interface{}{
+			"seconds":      seconds,
+			"microseconds": microsecondsEv1,
+		})
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	buf.startEventLoop(&wg)
+	err := q.ExecuteMigrate(context.Background(), "exec:cat > /tmp/state")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+	wg.Wait()
+}
+
+// Checks that the migrate command is correctly sent by ExecuteMigrateStart
+// and that the status of the migration can be queried.
+//
+// We start a QMPLoop, send the migrate command, followed by a query-migrate
+// command and stop the loop.
+//
+// ExecuteMigrateStart should return without waiting for any event and
+// ExecuteQueryMigrate should return the provisioned status.  The QMP loop
+// should exit gracefully.
+func TestQMPMigrateStartQuery(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("migrate", nil, "return", nil)
+	buf.AddCommand("query-migrate", nil, "return",
+		map[string]interface{}{
+			"status":     "active",
+			"total-time": 1500,
+			"ram": map[string]interface{}{
+				"total":       1073741824,
+				"remaining":   536870912,
+				"transferred": 536870912,
+			},
+		})
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	err := q.ExecuteMigrateStart(context.Background(), "tcp:198.51.100.2:49152")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	status, err := q.ExecuteQueryMigrate(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if status.Status != "active" || status.TotalTime != 1500 ||
+		status.RAM.Remaining != 536870912 {
+		t.Errorf("Unexpected migration status %+v", status)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+}
+
+// Checks that the migrate_cancel and migrate-incoming commands are
+// correctly sent.
+//
+// We start a QMPLoop, send the two commands and stop the loop.
+//
+// The commands should be correctly sent and the QMP loop should exit
+// gracefully.
+func TestQMPMigrateCancelIncoming(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("migrate_cancel", nil, "return", nil)
+	buf.AddCommand("migrate-incoming", nil, "return", nil)
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	err := q.ExecuteMigrateCancel(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	err = q.ExecuteMigrateIncoming(context.Background(), "tcp:0:4444")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+}
+
 // Checks that the blockdev-add command is correctly sent.
 //
 // We start a QMPLoop, send the blockdev-add command and stop the loop.
@@ -416,6 +572,29 @@ func TestQMPXBlockdevDel(t *testing.T) {
 	<-disconnectedCh
 }
 
+// Checks that the block_resize command is correctly sent.
+//
+// We start a QMPLoop, send the block_resize command and stop the loop.
+//
+// The block_resize command should be correctly sent and the QMP loop should
+// exit gracefully.
+func TestQMPBlockResize(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("block_resize", nil, "return", nil)
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	q.version = checkVersion(t, connectedCh)
+	err := q.ExecuteBlockResize(context.Background(),
+		fmt.Sprintf("drive_%s", testutil.VolumeUUID), 20<<30)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+}
+
 // Checks that the device_del command is correctly sent.
 //
 // We start a QMPLoop, send the device_del command and wait for it to complete.
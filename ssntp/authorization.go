@@ -0,0 +1,152 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+// FrameAuthorizationRule defines which SSNTP client roles are allowed
+// to send a given SSNTP frame to a server.
+type FrameAuthorizationRule struct {
+	// Operand is the SSNTP frame operand to which this rule applies.
+	// It must be a Command, a Status, an Error or an Event.
+	Operand interface{}
+
+	// Roles is the bitmask of SSNTP client roles allowed to send frames
+	// which operand is Operand. A client playing several roles is
+	// allowed to send the frame if any of its roles is.
+	Roles Role
+}
+
+// clientRoles are all the SSNTP client roles.
+const clientRoles = Controller | AGENT | NETAGENT | CNCIAGENT
+
+// DefaultAuthorizationRules is the set of frame authorization rules
+// matching the frames that ciao components send to the scheduler.
+var DefaultAuthorizationRules = []FrameAuthorizationRule{
+	// Controller commands
+	{Operand: START, Roles: Controller},
+	{Operand: DELETE, Roles: Controller},
+	{Operand: EVACUATE, Roles: Controller},
+	{Operand: Restore, Roles: Controller},
+	{Operand: AttachVolume, Roles: Controller},
+	{Operand: AssignPublicIP, Roles: Controller},
+	{Operand: ReleasePublicIP, Roles: Controller},
+	{Operand: CONFIGURE, Roles: Controller},
+	{Operand: REBOOT, Roles: Controller},
+	{Operand: PAUSE, Roles: Controller},
+	{Operand: UNPAUSE, Roles: Controller},
+	{Operand: SUSPEND, Roles: Controller},
+	{Operand: RESUME, Roles: Controller},
+	{Operand: MIGRATE, Roles: Controller},
+	{Operand: CONSOLE, Roles: Controller},
+	{Operand: ExtendVolume, Roles: Controller},
+	{Operand: DetachVolume, Roles: Controller},
+
+	// Launcher agents commands, statuses, events and errors
+	{Operand: STATS, Roles: AGENT | NETAGENT},
+	{Operand: READY, Roles: AGENT | NETAGENT},
+	{Operand: FULL, Roles: AGENT | NETAGENT},
+	{Operand: OFFLINE, Roles: AGENT | NETAGENT},
+	{Operand: MAINTENANCE, Roles: AGENT | NETAGENT},
+	{Operand: InstanceDeleted, Roles: AGENT | NETAGENT},
+	{Operand: InstanceStopped, Roles: AGENT | NETAGENT},
+	{Operand: MigrationTargetReady, Roles: AGENT | NETAGENT},
+	{Operand: InstanceMigrated, Roles: AGENT | NETAGENT},
+	{Operand: ConsoleLog, Roles: AGENT | NETAGENT},
+	{Operand: ConsoleReady, Roles: AGENT | NETAGENT},
+	{Operand: TenantAdded, Roles: AGENT | NETAGENT},
+	{Operand: TenantRemoved, Roles: AGENT | NETAGENT},
+	{Operand: TraceReport, Roles: AGENT | NETAGENT},
+	{Operand: StartFailure, Roles: AGENT | NETAGENT},
+	{Operand: StopFailure, Roles: AGENT | NETAGENT},
+	{Operand: RestartFailure, Roles: AGENT | NETAGENT},
+	{Operand: DeleteFailure, Roles: AGENT | NETAGENT},
+	{Operand: AttachVolumeFailure, Roles: AGENT | NETAGENT},
+	{Operand: RebootFailure, Roles: AGENT | NETAGENT},
+	{Operand: PauseFailure, Roles: AGENT | NETAGENT},
+	{Operand: UnpauseFailure, Roles: AGENT | NETAGENT},
+	{Operand: SuspendFailure, Roles: AGENT | NETAGENT},
+	{Operand: ResumeFailure, Roles: AGENT | NETAGENT},
+	{Operand: MigrateFailure, Roles: AGENT | NETAGENT},
+	{Operand: ConsoleFailure, Roles: AGENT | NETAGENT},
+	{Operand: ExtendVolumeFailure, Roles: AGENT | NETAGENT},
+	{Operand: DetachVolumeFailure, Roles: AGENT | NETAGENT},
+
+	// CNCI agents events and errors
+	{Operand: ConcentratorInstanceAdded, Roles: CNCIAGENT},
+	{Operand: PublicIPAssigned, Roles: CNCIAGENT},
+	{Operand: PublicIPUnassigned, Roles: CNCIAGENT},
+	{Operand: AssignPublicIPFailure, Roles: CNCIAGENT},
+	{Operand: UnassignPublicIPFailure, Roles: CNCIAGENT},
+
+	// Protocol errors any client can report
+	{Operand: InvalidFrameType, Roles: clientRoles},
+	{Operand: ConnectionFailure, Roles: clientRoles},
+	{Operand: InvalidConfiguration, Roles: clientRoles},
+}
+
+// frameAuthorization is the lookup table built from a set of
+// FrameAuthorizationRule. It is not modified after init and thus
+// needs no locking.
+type frameAuthorization struct {
+	enabled  bool
+	commands map[Command]Role
+	statuses map[Status]Role
+	errors   map[Error]Role
+	events   map[Event]Role
+}
+
+func (a *frameAuthorization) init(rules []FrameAuthorizationRule) {
+	a.enabled = rules != nil
+	a.commands = make(map[Command]Role)
+	a.statuses = make(map[Status]Role)
+	a.errors = make(map[Error]Role)
+	a.events = make(map[Event]Role)
+
+	for _, r := range rules {
+		switch op := r.Operand.(type) {
+		case Command:
+			a.commands[op] |= r.Roles
+		case Status:
+			a.statuses[op] |= r.Roles
+		case Error:
+			a.errors[op] |= r.Roles
+		case Event:
+			a.events[op] |= r.Roles
+		}
+	}
+}
+
+// authorized tells if an SSNTP client playing role is allowed to send
+// frame. All frames are authorized when no rules were configured.
+func (a *frameAuthorization) authorized(role Role, frame *Frame) bool {
+	if !a.enabled {
+		return true
+	}
+
+	var roles Role
+	switch frame.Type {
+	case COMMAND:
+		roles = a.commands[(Command)(frame.Operand)]
+	case STATUS:
+		roles = a.statuses[(Status)(frame.Operand)]
+	case ERROR:
+		roles = a.errors[(Error)(frame.Operand)]
+	case EVENT:
+		roles = a.events[(Event)(frame.Operand)]
+	}
+
+	return role&roles != UNKNOWN
+}
@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"testing"
+)
+
+func TestFrameAuthorization(t *testing.T) {
+	var a frameAuthorization
+	a.init(DefaultAuthorizationRules)
+
+	tests := []struct {
+		role       Role
+		frameType  Type
+		operand    uint8
+		authorized bool
+	}{
+		{Controller, COMMAND, (uint8)(START), true},
+		{Controller, COMMAND, (uint8)(EVACUATE), true},
+		{Controller, COMMAND, (uint8)(STATS), false},
+		{AGENT, COMMAND, (uint8)(STATS), true},
+		{AGENT, COMMAND, (uint8)(DELETE), false},
+		{AGENT | NETAGENT, STATUS, (uint8)(READY), true},
+		{NETAGENT, EVENT, (uint8)(TenantAdded), true},
+		{AGENT, ERROR, (uint8)(StartFailure), true},
+		{CNCIAGENT, COMMAND, (uint8)(DELETE), false},
+		{CNCIAGENT, COMMAND, (uint8)(EVACUATE), false},
+		{CNCIAGENT, EVENT, (uint8)(PublicIPAssigned), true},
+		{CNCIAGENT, EVENT, (uint8)(InstanceDeleted), false},
+		{CNCIAGENT, ERROR, (uint8)(InvalidFrameType), true},
+		{Controller, STATUS, (uint8)(READY), false},
+		{AGENT, Type(0xff), 0, false},
+	}
+
+	for _, test := range tests {
+		frame := Frame{Type: test.frameType, Operand: test.operand}
+		if a.authorized(test.role, &frame) != test.authorized {
+			t.Errorf("%s frame %d/%d: expected authorized %v",
+				&test.role, test.frameType, test.operand, test.authorized)
+		}
+	}
+}
+
+func TestFrameAuthorizationDisabled(t *testing.T) {
+	var a frameAuthorization
+	a.init(nil)
+
+	frame := Frame{Type: COMMAND, Operand: (uint8)(DELETE)}
+	if !a.authorized(CNCIAGENT, &frame) {
+		t.Errorf("Frames should be authorized without rules")
+	}
+}
@@ -0,0 +1,274 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ciao-project/ciao/payloads"
+)
+
+// Capability is a bitmap of the optional SSNTP features an SSNTP
+// client or server supports. Capabilities are exchanged through the
+// CONNECT and CONNECTED frames.
+type Capability uint64
+
+const (
+	// EvacuateCapability is set by peers that handle the EVACUATE command.
+	EvacuateCapability Capability = 1 << iota
+
+	// RestoreCapability is set by peers that handle the Restore command.
+	RestoreCapability
+
+	// AttachVolumeCapability is set by peers that handle the AttachVolume command.
+	AttachVolumeCapability
+
+	// PublicIPCapability is set by peers that handle the AssignPublicIP
+	// and ReleasePublicIP commands.
+	PublicIPCapability
+
+	// GobPayloadCapability is set by peers that can parse payloads.Gob
+	// encoded payloads.
+	GobPayloadCapability
+
+	// CompressionCapability is set by peers that can decompress gzip
+	// compressed frame payloads.
+	CompressionCapability
+
+	// KeepaliveCapability is set by peers that answer PING commands.
+	KeepaliveCapability
+
+	// PowerCapability is set by peers that handle the REBOOT, PAUSE,
+	// UNPAUSE, SUSPEND and RESUME commands.
+	PowerCapability
+
+	// MigrateCapability is set by peers that handle the MIGRATE command.
+	MigrateCapability
+
+	// ConsoleCapability is set by peers that handle the CONSOLE command.
+	ConsoleCapability
+
+	// ExtendVolumeCapability is set by peers that handle the ExtendVolume
+	// command.
+	ExtendVolumeCapability
+
+	// DetachVolumeCapability is set by peers that handle the DetachVolume
+	// command.
+	DetachVolumeCapability
+)
+
+// Capabilities is the set of all capabilities supported by this SSNTP
+// implementation. This is what SSNTP clients and servers advertise
+// unless their Config restricts it.
+const Capabilities = EvacuateCapability | RestoreCapability |
+	AttachVolumeCapability | PublicIPCapability | GobPayloadCapability |
+	CompressionCapability | KeepaliveCapability | PowerCapability |
+	MigrateCapability | ConsoleCapability | ExtendVolumeCapability |
+	DetachVolumeCapability
+
+// capabilitiesMinor is the first SSNTP minor version carrying
+// capabilities in its CONNECT and CONNECTED frames.
+const capabilitiesMinor = 2
+
+// legacyCapabilities are the capabilities we assume peers that predate
+// capabilitiesMinor support. Those peers do not advertise anything but
+// handle all the commands that were defined at the time.
+const legacyCapabilities = EvacuateCapability | RestoreCapability |
+	AttachVolumeCapability | PublicIPCapability
+
+func (command Command) capability() Capability {
+	switch command {
+	case EVACUATE:
+		return EvacuateCapability
+	case Restore:
+		return RestoreCapability
+	case AttachVolume:
+		return AttachVolumeCapability
+	case AssignPublicIP, ReleasePublicIP:
+		return PublicIPCapability
+	case REBOOT, PAUSE, UNPAUSE, SUSPEND, RESUME:
+		return PowerCapability
+	case MIGRATE:
+		return MigrateCapability
+	case CONSOLE:
+		return ConsoleCapability
+	case ExtendVolume:
+		return ExtendVolumeCapability
+	case DetachVolume:
+		return DetachVolumeCapability
+	}
+
+	return 0
+}
+
+// Has checks if a capability bitmap contains all of the cmp capabilities.
+func (c Capability) Has(cmp Capability) bool {
+	return c&cmp == cmp
+}
+
+// Supports checks if a capability bitmap contains the capabilities needed
+// to handle the cmd command.
+func (c Capability) Supports(cmd Command) bool {
+	return c.Has(cmd.capability())
+}
+
+func (c Capability) String() string {
+	names := []struct {
+		c    Capability
+		name string
+	}{
+		{EvacuateCapability, "Evacuate"},
+		{RestoreCapability, "Restore"},
+		{AttachVolumeCapability, "AttachVolume"},
+		{PublicIPCapability, "PublicIP"},
+		{GobPayloadCapability, "GobPayload"},
+		{CompressionCapability, "Compression"},
+		{KeepaliveCapability, "Keepalive"},
+		{PowerCapability, "Power"},
+		{MigrateCapability, "Migrate"},
+		{ConsoleCapability, "Console"},
+		{ExtendVolumeCapability, "ExtendVolume"},
+		{DetachVolumeCapability, "DetachVolume"},
+	}
+
+	var caps []string
+	for _, n := range names {
+		if c.Has(n.c) {
+			caps = append(caps, n.name)
+			c &^= n.c
+		}
+	}
+
+	if c != 0 {
+		caps = append(caps, fmt.Sprintf("%#x", uint64(c)))
+	}
+
+	return strings.Join(caps, "|")
+}
+
+// UnsupportedCommandError is returned when trying to send a command
+// that the SSNTP peer did not advertise support for.
+type UnsupportedCommandError struct {
+	Command    Command
+	Capability Capability
+}
+
+func (e *UnsupportedCommandError) Error() string {
+	return fmt.Sprintf("SSNTP peer does not support %s (missing capability %s)",
+		e.Command, e.Capability)
+}
+
+// negotiate checks that our and the peer's supported minor versions overlap
+// and returns the minor version to use together with the peer capabilities.
+func negotiate(peerMinMinor, peerMinor uint8, peerCapabilities Capability) (uint8, Capability, error) {
+	if peerMinor < minMinor || peerMinMinor > minor {
+		return 0, 0, fmt.Errorf("SSNTP peer minor versions %d-%d not in %d-%d",
+			peerMinMinor, peerMinor, minMinor, minor)
+	}
+
+	if peerMinor < capabilitiesMinor {
+		return peerMinor, legacyCapabilities, nil
+	}
+
+	if peerMinor > minor {
+		return minor, peerCapabilities, nil
+	}
+
+	return peerMinor, peerCapabilities, nil
+}
+
+// checkCommand returns an UnsupportedCommandError if the session peer
+// can not handle cmd.
+func (session *session) checkCommand(cmd Command) error {
+	if session.peerCapabilities.Supports(cmd) {
+		return nil
+	}
+
+	return &UnsupportedCommandError{
+		Command:    cmd,
+		Capability: cmd.capability() &^ session.peerCapabilities,
+	}
+}
+
+// payloadEncoding returns the most efficient payload encoding the session
+// peer can parse.
+func (session *session) payloadEncoding(capabilities Capability) payloads.Encoding {
+	if capabilities.Has(GobPayloadCapability) &&
+		session.peerCapabilities.Has(GobPayloadCapability) {
+		return payloads.Gob
+	}
+
+	return payloads.YAML
+}
+
+// forwardedFrame returns the frame to forward to the session peer for f, or
+// nil if the peer can not handle f. Gob payloads are re-encoded to YAML for
+// peers that can not parse them. As the same frame may be forwarded to
+// several peers, f is copied rather than modified.
+func (session *session) forwardedFrame(f *Frame) (*Frame, error) {
+	if f.Type == COMMAND && !session.peerCapabilities.Supports((Command)(f.Operand)) {
+		return nil, nil
+	}
+
+	if payloads.PayloadEncoding(f.Payload) != payloads.Gob ||
+		session.peerCapabilities.Has(GobPayloadCapability) {
+		return f, nil
+	}
+
+	payload, err := yamlPayload(f)
+	if err != nil {
+		return nil, err
+	}
+
+	reencoded := *f
+	reencoded.Payload = payload
+	reencoded.PayloadLength = (uint32)(len(payload))
+
+	return &reencoded, nil
+}
+
+// gobPayload returns a pointer to the payload structure of the frames
+// that may carry a Gob encoded payload, nil for all other frames.
+// START payloads stay YAML as the cloud-init user data and metadata of
+// the instance follow the payloads.Start document in the same frame.
+func gobPayload(f *Frame) interface{} {
+	switch {
+	case f.Type == COMMAND && (Command)(f.Operand) == STATS:
+		return &payloads.Stat{}
+	case f.Type == STATUS && (Status)(f.Operand) == READY:
+		return &payloads.Ready{}
+	}
+
+	return nil
+}
+
+// yamlPayload re-encodes the Gob payload of f to YAML.
+func yamlPayload(f *Frame) ([]byte, error) {
+	v := gobPayload(f)
+	if v == nil {
+		return nil, fmt.Errorf("Unexpected Gob payload for %s %s",
+			f.Type, operandString(f.Type, f.Operand))
+	}
+
+	err := payloads.Unmarshal(f.Payload, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return payloads.Marshal(payloads.YAML, v)
+}
@@ -0,0 +1,156 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"testing"
+
+	"github.com/ciao-project/ciao/payloads"
+)
+
+// Test minor version and capabilities negotiation
+//
+// Negotiate with legacy, current, newer and incompatible peers.
+//
+// Legacy peers should get the legacy capabilities, newer peers should
+// be downgraded to our minor version and incompatible ones rejected.
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		minMinor     uint8
+		minor        uint8
+		capabilities Capability
+		peerMinor    uint8
+		peerCaps     Capability
+		err          bool
+	}{
+		{0, 1, 0, 1, legacyCapabilities, false},
+		{minMinor, minor, EvacuateCapability, minor, EvacuateCapability, false},
+		{minMinor, minor + 1, RestoreCapability, minor, RestoreCapability, false},
+		{minor + 1, minor + 2, Capabilities, 0, 0, true},
+		{0, minMinor - 1, 0, 0, 0, true},
+	}
+
+	for _, test := range tests {
+		peerMinor, peerCaps, err := negotiate(test.minMinor, test.minor, test.capabilities)
+		if test.err {
+			if err == nil {
+				t.Errorf("Negotiation with %d-%d should fail", test.minMinor, test.minor)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Negotiation with %d-%d failed: %v", test.minMinor, test.minor, err)
+			continue
+		}
+
+		if peerMinor != test.peerMinor || peerCaps != test.peerCaps {
+			t.Errorf("Negotiation with %d-%d: expected %d %s, got %d %s",
+				test.minMinor, test.minor, test.peerMinor, test.peerCaps, peerMinor, peerCaps)
+		}
+	}
+}
+
+// Test command capability checks
+//
+// Commands without a capability are always supported, the other ones
+// only when the capability is present.
+func TestCheckCommand(t *testing.T) {
+	s := &session{peerCapabilities: EvacuateCapability}
+
+	if err := s.checkCommand(START); err != nil {
+		t.Errorf("START should always be supported: %v", err)
+	}
+
+	if err := s.checkCommand(EVACUATE); err != nil {
+		t.Errorf("EVACUATE should be supported: %v", err)
+	}
+
+	err := s.checkCommand(ReleasePublicIP)
+	uerr, ok := err.(*UnsupportedCommandError)
+	if !ok || uerr.Capability != PublicIPCapability {
+		t.Errorf("Expected missing PublicIP capability, got %v", err)
+	}
+
+	err = s.checkCommand(SUSPEND)
+	uerr, ok = err.(*UnsupportedCommandError)
+	if !ok || uerr.Capability != PowerCapability {
+		t.Errorf("Expected missing Power capability, got %v", err)
+	}
+}
+
+// Test frame forwarding checks
+//
+// Gob encoded payloads should be forwarded as is to peers supporting
+// them and re-encoded to YAML for the other ones. Commands should only be
+// forwarded to peers supporting them.
+func TestForwardedFrame(t *testing.T) {
+	stats := &payloads.Stat{NodeUUID: "7ac6ba1e", MemTotalMB: 4096}
+	gob, err := payloads.Marshal(payloads.Gob, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := &session{peerCapabilities: legacyCapabilities}
+	current := &session{peerCapabilities: Capabilities}
+
+	frame := &Frame{Type: COMMAND, Operand: byte(STATS), Payload: gob,
+		PayloadLength: uint32(len(gob))}
+
+	forwarded, err := current.forwardedFrame(frame)
+	if err != nil || forwarded != frame {
+		t.Errorf("Gob payload not forwarded as is to current peer: %v", err)
+	}
+
+	forwarded, err = legacy.forwardedFrame(frame)
+	if err != nil || forwarded == nil {
+		t.Fatalf("Gob payload not forwarded to legacy peer: %v", err)
+	}
+
+	if payloads.PayloadEncoding(forwarded.Payload) != payloads.YAML ||
+		forwarded.PayloadLength != uint32(len(forwarded.Payload)) {
+		t.Fatalf("Gob payload not re-encoded for legacy peer")
+	}
+
+	var parsed payloads.Stat
+	err = payloads.Unmarshal(forwarded.Payload, &parsed)
+	if err != nil || parsed.NodeUUID != stats.NodeUUID ||
+		parsed.MemTotalMB != stats.MemTotalMB {
+		t.Errorf("Wrong re-encoded payload %v: %v", parsed, err)
+	}
+
+	if payloads.PayloadEncoding(frame.Payload) != payloads.Gob {
+		t.Errorf("Original frame modified")
+	}
+
+	forwarded, err = legacy.forwardedFrame(&Frame{Type: EVENT,
+		Operand: byte(InstanceDeleted), Payload: gob})
+	if forwarded != nil || err == nil {
+		t.Errorf("Unexpected Gob payload forwarded to legacy peer")
+	}
+
+	forwarded, _ = legacy.forwardedFrame(&Frame{Type: COMMAND, Operand: byte(REBOOT)})
+	if forwarded != nil {
+		t.Errorf("Unsupported command forwarded to legacy peer")
+	}
+
+	if legacy.payloadEncoding(Capabilities) != payloads.YAML ||
+		current.payloadEncoding(Capabilities) != payloads.Gob ||
+		current.payloadEncoding(legacyCapabilities) != payloads.YAML {
+		t.Errorf("Wrong payload encoding negotiated")
+	}
+}
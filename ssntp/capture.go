@@ -0,0 +1,361 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/uuid"
+	"gopkg.in/yaml.v2"
+)
+
+// CaptureDirection tells if a captured frame was sent or received by
+// the capturing SSNTP node.
+type CaptureDirection string
+
+const (
+	// CaptureRx is the direction of frames received by the capturing node.
+	CaptureRx CaptureDirection = "rx"
+
+	// CaptureTx is the direction of frames sent by the capturing node.
+	CaptureTx CaptureDirection = "tx"
+)
+
+// captureSeparator separates the YAML documents of a capture file.
+const captureSeparator = "---"
+
+// CapturedFrame is the record of an SSNTP frame sent or received by an
+// SSNTP client or server configured with a Config.Capture file.
+// Captures are YAML streams, one CapturedFrame document per frame.
+type CapturedFrame struct {
+	// Timestamp is the time at which the frame was sent or received.
+	Timestamp time.Time `yaml:"timestamp"`
+
+	Direction CaptureDirection `yaml:"direction"`
+
+	// Local and LocalRole are the UUID and role of the capturing node.
+	Local     string `yaml:"local"`
+	LocalRole string `yaml:"local_role"`
+
+	// Peer and PeerRole are the UUID and role of the node the frame
+	// was sent to or received from.
+	Peer     string `yaml:"peer"`
+	PeerRole string `yaml:"peer_role"`
+
+	// Type and Operand are the frame type and operand. Operand is the
+	// string representation of the Command, Status, Event or Error.
+	Type    string `yaml:"type"`
+	Operand string `yaml:"operand"`
+
+	Origin        string `yaml:"origin"`
+	CorrelationID string `yaml:"correlation_id,omitempty"`
+	Label         string `yaml:"label,omitempty"`
+
+	// Encoding is the payload encoding, "yaml" or "gob". YAML payloads
+	// are recorded as is, Gob payloads are base64 encoded.
+	Encoding string `yaml:"encoding,omitempty"`
+	Payload  string `yaml:"payload,omitempty"`
+}
+
+// capture writes CapturedFrame records to a capture file. It is shared
+// by all the sessions of an SSNTP client or server.
+type capture struct {
+	sync.Mutex
+	w   io.WriteCloser
+	log Logger
+}
+
+func openCapture(path string, log Logger) (*capture, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open capture file %s: %s", path, err)
+	}
+
+	return &capture{w: f, log: log}, nil
+}
+
+func operandString(t Type, operand uint8) string {
+	switch t {
+	case COMMAND:
+		return (Command)(operand).String()
+	case STATUS:
+		return (Status)(operand).String()
+	case EVENT:
+		return (Event)(operand).String()
+	case ERROR:
+		return (Error)(operand).String()
+	}
+
+	return ""
+}
+
+func newCapturedFrame(session *session, direction CaptureDirection, f *Frame) *CapturedFrame {
+	c := &CapturedFrame{
+		Timestamp: time.Now(),
+		Direction: direction,
+		Local:     session.src.String(),
+		LocalRole: session.srcRole.String(),
+		Peer:      session.dest.String(),
+		PeerRole:  session.destRole.String(),
+		Type:      f.Type.String(),
+		Operand:   operandString(f.Type, f.Operand),
+		Origin:    f.Origin.String(),
+	}
+
+	if f.correlated() {
+		c.CorrelationID = f.CorrelationID.String()
+	}
+
+	if f.Trace != nil {
+		c.Label = string(f.Trace.Label)
+	}
+
+	if len(f.Payload) == 0 {
+		return c
+	}
+
+	encoding := payloads.PayloadEncoding(f.Payload)
+	c.Encoding = encoding.String()
+	if encoding == payloads.YAML {
+		c.Payload = string(f.Payload)
+	} else {
+		c.Payload = base64.StdEncoding.EncodeToString(f.Payload)
+	}
+
+	return c
+}
+
+// record appends a frame to the capture file. Capture errors are logged
+// but never interrupt the SSNTP traffic.
+func (c *capture) record(session *session, direction CaptureDirection, f *Frame) {
+	data, err := yaml.Marshal(newCapturedFrame(session, direction, f))
+	if err != nil {
+		c.log.Errorf("Could not capture frame: %s\n", err)
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	_, err = fmt.Fprintf(c.w, "%s\n%s", captureSeparator, data)
+	if err != nil {
+		c.log.Errorf("Could not capture frame: %s\n", err)
+	}
+}
+
+func (c *capture) close() {
+	c.Lock()
+	defer c.Unlock()
+
+	_ = c.w.Close()
+}
+
+// ReadCapture parses an SSNTP capture file into the list of frames it
+// recorded.
+func ReadCapture(r io.Reader) ([]CapturedFrame, error) {
+	var frames []CapturedFrame
+	var doc bytes.Buffer
+
+	parse := func() error {
+		if len(bytes.TrimSpace(doc.Bytes())) == 0 {
+			return nil
+		}
+
+		var frame CapturedFrame
+		err := yaml.Unmarshal(doc.Bytes(), &frame)
+		if err != nil {
+			return fmt.Errorf("Invalid captured frame #%d: %s", len(frames), err)
+		}
+
+		frames = append(frames, frame)
+		doc.Reset()
+
+		return nil
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if line == captureSeparator+"\n" || line == captureSeparator {
+			if perr := parse(); perr != nil {
+				return nil, perr
+			}
+		} else {
+			doc.WriteString(line)
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if err := parse(); err != nil {
+		return nil, err
+	}
+
+	return frames, nil
+}
+
+func parseType(name string) (Type, error) {
+	for _, t := range []Type{COMMAND, STATUS, EVENT, ERROR} {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown frame type %q", name)
+}
+
+func parseOperand(t Type, name string) (uint8, error) {
+	if name != "" {
+		for op := 0; op <= 0xff; op++ {
+			if operandString(t, (uint8)(op)) == name {
+				return (uint8)(op), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown %s operand %q", t, name)
+}
+
+// Frame rebuilds the SSNTP frame a CapturedFrame recorded.
+func (c *CapturedFrame) Frame() (*Frame, error) {
+	t, err := parseType(c.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	operand, err := parseOperand(t, c.Operand)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	switch c.Encoding {
+	case "", payloads.YAML.String():
+		payload = []byte(c.Payload)
+	case payloads.Gob.String():
+		payload, err = base64.StdEncoding.DecodeString(c.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid gob payload: %s", err)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown payload encoding %q", c.Encoding)
+	}
+
+	f := &Frame{
+		Major:         Major,
+		Minor:         minor,
+		Type:          t,
+		Operand:       operand,
+		PayloadLength: (uint32)(len(payload)),
+		Payload:       payload,
+	}
+
+	if c.Origin != "" {
+		f.Origin, err = uuid.Parse(c.Origin)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid origin: %s", err)
+		}
+	}
+
+	if c.CorrelationID != "" {
+		f.CorrelationID, err = uuid.Parse(c.CorrelationID)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid correlation ID: %s", err)
+		}
+	}
+
+	if c.Label != "" {
+		f.Trace = &FrameTrace{Label: []byte(c.Label)}
+	}
+
+	return f, nil
+}
+
+// traceConfig returns the TraceConfig to send a replayed frame with.
+func (f *Frame) traceConfig() *TraceConfig {
+	if f.Trace == nil {
+		return nil
+	}
+
+	return &TraceConfig{Label: f.Trace.Label}
+}
+
+// Replay sends a captured frame to the SSNTP server. The frame gets the
+// client UUID as its origin.
+func (client *Client) Replay(c *CapturedFrame) (int, error) {
+	f, err := c.Frame()
+	if err != nil {
+		return -1, err
+	}
+
+	trace := f.traceConfig()
+
+	switch f.Type {
+	case COMMAND:
+		return client.sendCommand((Command)(f.Operand), f.Payload, trace, f.CorrelationID)
+	case STATUS:
+		return client.sendStatus((Status)(f.Operand), f.Payload, trace, f.CorrelationID)
+	case EVENT:
+		return client.sendEvent((Event)(f.Operand), f.Payload, trace, f.CorrelationID)
+	case ERROR:
+		return client.sendError((Error)(f.Operand), f.Payload, trace, f.CorrelationID)
+	}
+
+	return -1, fmt.Errorf("Can not replay %s frame", f.Type)
+}
+
+// Replay sends a captured frame to the SSNTP client which UUID is uuid.
+// The frame gets the server UUID as its origin.
+func (server *Server) Replay(uuid string, c *CapturedFrame) (int, error) {
+	f, err := c.Frame()
+	if err != nil {
+		return -1, err
+	}
+
+	trace := f.traceConfig()
+
+	switch f.Type {
+	case COMMAND:
+		return server.sendCommand(uuid, (Command)(f.Operand), f.Payload, trace, f.CorrelationID)
+	case STATUS:
+		return server.sendStatus(uuid, (Status)(f.Operand), f.Payload, trace, f.CorrelationID)
+	case EVENT:
+		return server.sendEvent(uuid, (Event)(f.Operand), f.Payload, trace, f.CorrelationID)
+	case ERROR:
+		return server.sendError(uuid, (Error)(f.Operand), f.Payload, trace, f.CorrelationID)
+	}
+
+	return -1, fmt.Errorf("Can not replay %s frame", f.Type)
+}
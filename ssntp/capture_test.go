@@ -0,0 +1,170 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/uuid"
+)
+
+type captureBuffer struct {
+	bytes.Buffer
+}
+
+func (b *captureBuffer) Close() error {
+	return nil
+}
+
+func captureFrames(t *testing.T, frames []*Frame) ([]CapturedFrame, []CapturedFrame) {
+	var txBuf, rxBuf captureBuffer
+
+	client, server := net.Pipe()
+	defer func() { _ = client.Close() }()
+	defer func() { _ = server.Close() }()
+
+	srcUUID := uuid.Generate()
+	src := newSession(&srcUUID, AGENT, SERVER, client)
+	src.capture = &capture{w: &txBuf, log: errLog}
+
+	dstUUID := uuid.Generate()
+	dst := newSession(&dstUUID, SERVER, AGENT, server)
+	dst.setDest(srcUUID[:])
+	dst.capture = &capture{w: &rxBuf, log: errLog}
+
+	errCh := make(chan error)
+	go func() {
+		for _, f := range frames {
+			if _, err := src.Write(f); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		errCh <- nil
+	}()
+
+	for range frames {
+		var received Frame
+		if err := dst.Read(&received); err != nil {
+			t.Fatalf("Unable to read frame: %v", err)
+		}
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatalf("Unable to write frame: %v", err)
+	}
+
+	tx, err := ReadCapture(&txBuf)
+	if err != nil {
+		t.Fatalf("Unable to read tx capture: %v", err)
+	}
+
+	rx, err := ReadCapture(&rxBuf)
+	if err != nil {
+		t.Fatalf("Unable to read rx capture: %v", err)
+	}
+
+	if len(tx) != len(frames) || len(rx) != len(frames) {
+		t.Fatalf("Expected %d captured frames, got %d tx and %d rx",
+			len(frames), len(tx), len(rx))
+	}
+
+	return tx, rx
+}
+
+func checkCapturedFrame(t *testing.T, c *CapturedFrame, direction CaptureDirection, f *Frame) {
+	if c.Direction != direction {
+		t.Errorf("Expected %s direction, got %s", direction, c.Direction)
+	}
+
+	replayed, err := c.Frame()
+	if err != nil {
+		t.Fatalf("Unable to rebuild captured frame: %v", err)
+	}
+
+	if replayed.Type != f.Type || replayed.Operand != f.Operand ||
+		replayed.Origin != f.Origin || replayed.CorrelationID != f.CorrelationID ||
+		!bytes.Equal(replayed.Payload, f.Payload) {
+		t.Errorf("Captured frame mismatch\n%s\n%s", replayed, f)
+	}
+}
+
+// Test that the frames sent and received by SSNTP sessions are captured
+// with their decoded payload and can be rebuilt from the capture.
+//
+// Test is expected to pass.
+func TestCapture(t *testing.T) {
+	yamlPayload := []byte("node_uuid: 7ac6ba1e-6e6d-4b36-a6c4-d3d8e3e67f45\nload: 1\n")
+	gobPayload, err := payloads.Marshal(payloads.Gob, &payloads.Ready{NodeUUID: "7ac6ba1e"})
+	if err != nil {
+		t.Fatalf("Unable to marshal payload: %v", err)
+	}
+
+	s := &session{}
+	cmd := s.commandFrame(START, yamlPayload, &TraceConfig{Label: []byte("label")})
+	cmd.CorrelationID = uuid.Generate()
+	status := s.statusFrame(READY, gobPayload, nil)
+	errFrame := s.errorFrame(UnauthorizedFrame, nil, nil)
+	frames := []*Frame{cmd, status, errFrame}
+
+	tx, rx := captureFrames(t, frames)
+
+	for i, f := range frames {
+		checkCapturedFrame(t, &tx[i], CaptureTx, f)
+		checkCapturedFrame(t, &rx[i], CaptureRx, f)
+	}
+
+	if tx[0].Payload != string(yamlPayload) || tx[0].Encoding != "yaml" ||
+		tx[0].Label != "label" || tx[0].Operand != "START" {
+		t.Errorf("Unexpected YAML frame capture %+v", tx[0])
+	}
+
+	if tx[1].Encoding != "gob" || tx[1].Operand != "READY" {
+		t.Errorf("Unexpected Gob frame capture %+v", tx[1])
+	}
+
+	if tx[0].LocalRole != rx[0].PeerRole || tx[0].Local != rx[0].Peer {
+		t.Errorf("Capture peers mismatch: %+v %+v", tx[0], rx[0])
+	}
+}
+
+// Test that invalid captures are rejected.
+//
+// Test is expected to pass.
+func TestCaptureInvalid(t *testing.T) {
+	invalid := []CapturedFrame{
+		{Type: "FOO", Operand: "START"},
+		{Type: "COMMAND", Operand: "READY"},
+		{Type: "STATUS", Operand: "READY", Encoding: "gob", Payload: "!!"},
+		{Type: "STATUS", Operand: "READY", Encoding: "json"},
+		{Type: "STATUS", Operand: "READY", Origin: "origin"},
+	}
+
+	for _, c := range invalid {
+		if _, err := c.Frame(); err == nil {
+			t.Errorf("Invalid captured frame %+v accepted", c)
+		}
+	}
+
+	_, err := ReadCapture(bytes.NewBufferString("---\ntype: [\n"))
+	if err == nil {
+		t.Errorf("Invalid capture accepted")
+	}
+}
@@ -173,7 +173,7 @@ func CreateAnchorCert(template *x509.Certificate, certOutput io.Writer, caCertOu
 	}
 
 	template.IsCA = true
-	template.KeyUsage = template.KeyUsage | x509.KeyUsageCertSign
+	template.KeyUsage = template.KeyUsage | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
 
 	// Create self-signed certificate
 	derBytes, err := x509.CreateCertificate(rand.Reader, template, template, publicKey(priv), priv)
@@ -292,6 +292,9 @@ func createCertTemplateFromCSR(role ssntp.Role, request *x509.CertificateRequest
 func CreateCertFromCSR(role ssntp.Role, csr []byte, anchorCert []byte, certOutput io.Writer) error {
 	// Parent public key first
 	certBlock, rest := pem.Decode(anchorCert)
+	if certBlock == nil {
+		return errors.New("Unable to decode anchor cert")
+	}
 	parentCert, err := x509.ParseCertificate(certBlock.Bytes)
 	if err != nil {
 		return errors.Wrap(err, "Unable to parse anchor cert")
@@ -300,7 +303,7 @@ func CreateCertFromCSR(role ssntp.Role, csr []byte, anchorCert []byte, certOutpu
 	// Parent private key
 	privKeyBlock, _ := pem.Decode(rest)
 	if privKeyBlock == nil {
-		return errors.Wrap(err, "Unable to extract private key from anchor cert")
+		return errors.New("Unable to extract private key from anchor cert")
 	}
 
 	anchorPrivKey, err := keyFromPemBlock(privKeyBlock)
@@ -310,6 +313,9 @@ func CreateCertFromCSR(role ssntp.Role, csr []byte, anchorCert []byte, certOutpu
 
 	// Decode and parse csr
 	csrBlock, _ := pem.Decode(csr)
+	if csrBlock == nil {
+		return errors.New("Unable to decode CSR")
+	}
 	request, err := x509.ParseCertificateRequest(csrBlock.Bytes)
 	if err != nil {
 		return errors.Wrap(err, "Unable to parse CSR")
@@ -354,7 +360,7 @@ func CreateCertificateRequest(organization string, email string, hosts []string,
 	request.DNSNames = addDNSNames(hosts, request.DNSNames)
 	request.IPAddresses = addMgmtIPs(mgmtIPs, request.IPAddresses)
 
-	request.SignatureAlgorithm = x509.SHA1WithRSA
+	request.SignatureAlgorithm = x509.SHA256WithRSA
 
 	return &request
 }
@@ -464,3 +470,149 @@ func FingerPrint(c interface{}) string {
 	h.Write(*input)
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
+
+func parseAnchorCert(anchorCert []byte) (*x509.Certificate, interface{}, error) {
+	certBlock, rest := pem.Decode(anchorCert)
+	if certBlock == nil {
+		return nil, nil, errors.New("Unable to decode anchor cert")
+	}
+
+	parentCert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Unable to parse anchor cert")
+	}
+
+	privKeyBlock, _ := pem.Decode(rest)
+	if privKeyBlock == nil {
+		return nil, nil, errors.New("Unable to extract private key from anchor cert")
+	}
+
+	anchorPrivKey, err := keyFromPemBlock(privKeyBlock)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Unable to parse private key from anchor cert")
+	}
+
+	return parentCert, anchorPrivKey, nil
+}
+
+func parseCert(bytesCert []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(bytesCert)
+	if block == nil {
+		return nil, errors.New("Unable to decode certificate")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// RevokeCerts adds the given certificates to a certificate revocation list
+// signed by the given anchor certificate. The crl argument is the current
+// PEM encoded CRL, or nil when creating a new one. The updated CRL is
+// written PEM encoded.
+func RevokeCerts(anchorCert []byte, crl []byte, revokedCerts [][]byte, crlOutput io.Writer) error {
+	parentCert, anchorPrivKey, err := parseAnchorCert(anchorCert)
+	if err != nil {
+		return err
+	}
+
+	var revoked []pkix.RevokedCertificate
+	serials := make(map[string]bool)
+
+	if len(crl) > 0 {
+		certList, err := x509.ParseCRL(crl)
+		if err != nil {
+			return errors.Wrap(err, "Unable to parse CRL")
+		}
+
+		err = parentCert.CheckCRLSignature(certList)
+		if err != nil {
+			return errors.Wrap(err, "CRL not signed by anchor cert")
+		}
+
+		for _, r := range certList.TBSCertList.RevokedCertificates {
+			serials[r.SerialNumber.String()] = true
+			revoked = append(revoked, r)
+		}
+	}
+
+	now := time.Now()
+	for _, bytesCert := range revokedCerts {
+		cert, err := parseCert(bytesCert)
+		if err != nil {
+			return errors.Wrap(err, "Unable to parse certificate to revoke")
+		}
+
+		if serials[cert.SerialNumber.String()] {
+			continue
+		}
+
+		serials[cert.SerialNumber.String()] = true
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   cert.SerialNumber,
+			RevocationTime: now,
+		})
+	}
+
+	derBytes, err := parentCert.CreateCRL(rand.Reader, anchorPrivKey, revoked, now, now.Add(365*24*time.Hour))
+	if err != nil {
+		return errors.Wrap(err, "Unable to create CRL")
+	}
+
+	err = pem.Encode(crlOutput, &pem.Block{Type: "X509 CRL", Bytes: derBytes})
+	if err != nil {
+		return errors.Wrap(err, "Unable to encode PEM block")
+	}
+
+	return nil
+}
+
+// RevokedSerials returns the serial numbers of the certificates revoked
+// by a PEM encoded CRL.
+func RevokedSerials(crl []byte) ([]*big.Int, error) {
+	certList, err := x509.ParseCRL(crl)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to parse CRL")
+	}
+
+	var serials []*big.Int
+	for _, r := range certList.TBSCertList.RevokedCertificates {
+		serials = append(serials, r.SerialNumber)
+	}
+
+	return serials, nil
+}
+
+// RenewCert creates a new certificate, for a newly generated private key,
+// with the same subject, roles, hosts and IPs as the given certificate and
+// signed by the given anchor certificate. It is written PEM encoded.
+func RenewCert(anchorCert []byte, bytesCert []byte, certOutput io.Writer) error {
+	cert, err := parseCert(bytesCert)
+	if err != nil {
+		return errors.Wrap(err, "Unable to parse certificate to renew")
+	}
+
+	notBefore := time.Now()
+	notAfter := notBefore.Add(365 * 24 * time.Hour)
+
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate certificate serial number")
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      cert.Subject,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+
+		KeyUsage:              cert.KeyUsage,
+		ExtKeyUsage:           cert.ExtKeyUsage,
+		UnknownExtKeyUsage:    cert.UnknownExtKeyUsage,
+		EmailAddresses:        cert.EmailAddresses,
+		DNSNames:              cert.DNSNames,
+		IPAddresses:           cert.IPAddresses,
+		BasicConstraintsValid: true,
+	}
+
+	return CreateCert(&template, anchorCert, certOutput)
+}
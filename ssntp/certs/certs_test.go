@@ -305,3 +305,132 @@ func TestCSRFlow(t *testing.T) {
 		t.Fatalf("Unexpected error when checking merged cert: %v", err)
 	}
 }
+
+func TestRevokeCerts(t *testing.T) {
+	var anchorCertOutput, caCertOutput, certOutput, crlOutput, updatedCRLOutput bytes.Buffer
+
+	hosts := []string{"test.example.com"}
+	mgmtIPs := []string{}
+
+	template, err := CreateCertTemplate(ssntp.AGENT, "ACME Corp", "test@example.com", hosts, mgmtIPs)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating cert template: %v", err)
+	}
+
+	err = CreateAnchorCert(template, &anchorCertOutput, &caCertOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating anchor cert: %v", err)
+	}
+
+	template, err = CreateCertTemplate(ssntp.AGENT, "ACME Corp", "test@example.com", hosts, mgmtIPs)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating cert template: %v", err)
+	}
+
+	err = CreateCert(template, anchorCertOutput.Bytes(), &certOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating signed cert: %v", err)
+	}
+
+	err = RevokeCerts(anchorCertOutput.Bytes(), nil, [][]byte{certOutput.Bytes()}, &crlOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating CRL: %v", err)
+	}
+
+	// Revoking the same certificate twice should not duplicate it
+	err = RevokeCerts(anchorCertOutput.Bytes(), crlOutput.Bytes(), [][]byte{certOutput.Bytes()}, &updatedCRLOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error when updating CRL: %v", err)
+	}
+
+	serials, err := RevokedSerials(updatedCRLOutput.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error when parsing CRL: %v", err)
+	}
+
+	if len(serials) != 1 || serials[0].Cmp(template.SerialNumber) != 0 {
+		t.Errorf("Expected serial %v to be revoked, got %v", template.SerialNumber, serials)
+	}
+
+	// The CRL must be signed by the anchor cert
+	caCert, err := parseCert(caCertOutput.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+
+	crl, err := x509.ParseCRL(updatedCRLOutput.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse CRL: %v", err)
+	}
+
+	err = caCert.CheckCRLSignature(crl)
+	if err != nil {
+		t.Errorf("Failed to verify CRL signature: %v", err)
+	}
+}
+
+func TestRenewCert(t *testing.T) {
+	var anchorCertOutput, caCertOutput, certOutput, renewedCertOutput bytes.Buffer
+
+	hosts := []string{"test.example.com"}
+	mgmtIPs := []string{"127.0.0.1"}
+
+	template, err := CreateCertTemplate(ssntp.AGENT|ssntp.NETAGENT, "ACME Corp", "test@example.com", hosts, mgmtIPs)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating cert template: %v", err)
+	}
+
+	err = CreateAnchorCert(template, &anchorCertOutput, &caCertOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating anchor cert: %v", err)
+	}
+
+	err = CreateCert(template, anchorCertOutput.Bytes(), &certOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error when creating signed cert: %v", err)
+	}
+
+	err = RenewCert(anchorCertOutput.Bytes(), certOutput.Bytes(), &renewedCertOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error when renewing cert: %v", err)
+	}
+
+	err = VerifyCert(caCertOutput.Bytes(), renewedCertOutput.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error when verifying renewed cert: %v", err)
+	}
+
+	cert, err := parseCert(certOutput.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	renewed, err := parseCert(renewedCertOutput.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse renewed certificate: %v", err)
+	}
+
+	if renewed.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+		t.Errorf("Renewed certificate has the same serial number")
+	}
+
+	if FingerPrint(renewed) == FingerPrint(cert) {
+		t.Errorf("Renewed certificate has the same public key")
+	}
+
+	role := ssntp.GetRoleFromOIDs(renewed.UnknownExtKeyUsage)
+	if role != ssntp.AGENT|ssntp.NETAGENT {
+		t.Errorf("Unexpected renewed certificate role: %s", role.String())
+	}
+
+	if !reflect.DeepEqual(renewed.DNSNames, hosts) || len(renewed.IPAddresses) != 1 ||
+		!renewed.IPAddresses[0].Equal(net.ParseIP(mgmtIPs[0])) {
+		t.Errorf("Renewed certificate hosts and IPs don't match: %v %v",
+			renewed.DNSNames, renewed.IPAddresses)
+	}
+
+	_, err = tls.X509KeyPair(renewedCertOutput.Bytes(), renewedCertOutput.Bytes())
+	if err != nil {
+		t.Fatalf("Unexpected error when checking renewed cert: %v", err)
+	}
+}
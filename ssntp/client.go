@@ -17,9 +17,11 @@
 package ssntp
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"math/rand"
+	"net"
 	"sync"
 	"time"
 
@@ -69,6 +71,7 @@ type Client struct {
 	uris      []string
 	role      Role
 	tls       *tls.Config
+	creds     *credentials
 	ntf       ClientNotifier
 	transport string
 	port      uint32
@@ -76,12 +79,20 @@ type Client struct {
 	status    connectionStatus
 	closed    chan struct{}
 
+	capabilities         Capability
+	compressionThreshold int
+	keepaliveInterval    time.Duration
+	keepaliveMisses      int
+
+	replies replyWaiters
+
 	frameWg              sync.WaitGroup
 	frameRoutinesChannel chan struct{}
 
 	log Logger
 
-	trace *TraceConfig
+	trace   *TraceConfig
+	capture *capture
 
 	configuration clusterConfiguration
 }
@@ -89,6 +100,14 @@ type Client struct {
 func (client *Client) processSSNTPFrame(frame *Frame) {
 	defer client.frameWg.Done()
 
+	if client.session.handleKeepalive(frame) {
+		return
+	}
+
+	if client.replies.deliver(frame) {
+		return
+	}
+
 	switch (Type)(frame.Type) {
 	case COMMAND:
 		if (Command)(frame.Operand) == CONFIGURE {
@@ -112,12 +131,18 @@ func (client *Client) handleSSNTPServer() {
 	for {
 		client.ntf.ConnectNotify()
 
+		session := client.session
+		session.startKeepalive(client.capabilities, client.keepaliveInterval,
+			client.keepaliveMisses, client.log)
+
 		for {
 			client.log.Infof("Waiting for next frame\n")
 
 			var frame Frame
-			err := client.session.Read(&frame)
+			err := session.Read(&frame)
 			if err != nil {
+				session.stopKeepalive()
+
 				client.status.Lock()
 				if client.status.status == ssntpClosed {
 					client.status.Unlock()
@@ -133,6 +158,7 @@ func (client *Client) handleSSNTPServer() {
 			client.status.Lock()
 			if client.status.status == ssntpClosed {
 				client.status.Unlock()
+				session.stopKeepalive()
 				return
 			}
 			//insure new frame doesn't race with client.Close()
@@ -154,7 +180,7 @@ func (client *Client) sendConnect() (bool, error) {
 	var connected ConnectedFrame
 	client.log.Infof("Sending CONNECT\n")
 
-	connect := client.session.connectFrame()
+	connect := client.session.connectFrame(client.capabilities)
 	_, err := client.session.Write(connect)
 	if err != nil {
 		return true, err
@@ -193,6 +219,15 @@ func (client *Client) sendConnect() (bool, error) {
 		return false, fmt.Errorf("SSNTP Client: Connection failure")
 	}
 
+	peerMinor, peerCapabilities, err := negotiate(connected.MinMinor, connected.Minor, connected.Capabilities)
+	if err != nil {
+		client.log.Errorf("%s\n", err)
+		client.SendError(ConnectionFailure, nil)
+		return false, fmt.Errorf("SSNTP Client: Connection failure")
+	}
+	client.session.setPeer(peerMinor, peerCapabilities)
+	client.session.setCompression(client.capabilities, client.compressionThreshold)
+
 	client.status.Lock()
 	client.status.status = ssntpConnected
 	client.status.Unlock()
@@ -221,9 +256,17 @@ func (client *Client) attemptDial() error {
 	for {
 	URILoop:
 		for d := 0; ; d++ {
+			if client.creds != nil {
+				_, err := client.creds.reload(false)
+				if err != nil {
+					client.log.Errorf("%s\n", err)
+				}
+			}
+
 			for _, uri := range client.uris {
 				client.log.Infof("%s connecting to %s\n", client.uuid, uri)
-				conn, err := tls.Dial(client.transport, uri, client.tls)
+				dialer := &net.Dialer{Timeout: dialTimeout * time.Second}
+				conn, err := tls.DialWithDialer(dialer, client.transport, uri, client.tls)
 
 				client.status.Lock()
 				if client.status.status == ssntpClosed {
@@ -235,6 +278,7 @@ func (client *Client) attemptDial() error {
 				if err == nil {
 					client.log.Infof("Connected\n")
 					session := newSession(&client.uuid, client.role, 0, conn)
+					session.capture = client.capture
 					client.session = session
 
 					break URILoop
@@ -329,8 +373,17 @@ func (client *Client) Dial(config *Config, ntf ClientNotifier) error {
 	client.uris = config.ConfigURIs(client.uris, client.port)
 
 	client.trace = config.Trace
+	client.capabilities = config.capabilities()
+	client.compressionThreshold = config.compressionThreshold()
+	client.keepaliveInterval, client.keepaliveMisses = config.keepalive()
 	client.ntf = ntf
-	client.tls = prepareTLSConfig(config, false)
+	client.tls, client.creds = prepareTLSConfig(config, false)
+	client.capture, err = openCapture(config.Capture, client.log)
+	if err != nil {
+		client.log.Errorf("%s", err)
+		config.pushToSyncChannel(err)
+		return err
+	}
 
 	err = client.attemptDial()
 	if err != nil {
@@ -345,6 +398,18 @@ func (client *Client) Dial(config *Config, ntf ClientNotifier) error {
 	return nil
 }
 
+// ReloadCredentials loads the client certificate and CRL files again.
+// SSNTP clients check those files for changes before connecting to a
+// server, ReloadCredentials allows for applying changes immediately.
+func (client *Client) ReloadCredentials() error {
+	if client.creds == nil {
+		return fmt.Errorf("Client not connected")
+	}
+
+	_, err := client.creds.reload(true)
+	return err
+}
+
 // Close terminates the client connection.
 func (client *Client) Close() {
 	client.status.Lock()
@@ -375,10 +440,14 @@ func (client *Client) Close() {
 		break
 	}
 
+	if client.capture != nil {
+		client.capture.close()
+	}
+
 	freeUUID(client.lUUID)
 }
 
-func (client *Client) sendCommand(cmd Command, payload []byte, trace *TraceConfig) (int, error) {
+func (client *Client) sendCommand(cmd Command, payload []byte, trace *TraceConfig, id uuid.UUID) (int, error) {
 	client.status.Lock()
 	if client.status.status == ssntpClosed {
 		client.status.Unlock()
@@ -387,12 +456,17 @@ func (client *Client) sendCommand(cmd Command, payload []byte, trace *TraceConfi
 	client.status.Unlock()
 
 	session := client.session
+	if err := session.checkCommand(cmd); err != nil {
+		return -1, err
+	}
+
 	frame := session.commandFrame(cmd, payload, trace)
+	frame.CorrelationID = id
 
 	return session.Write(frame)
 }
 
-func (client *Client) sendStatus(status Status, payload []byte, trace *TraceConfig) (int, error) {
+func (client *Client) sendStatus(status Status, payload []byte, trace *TraceConfig, id uuid.UUID) (int, error) {
 	client.status.Lock()
 	if client.status.status == ssntpClosed {
 		client.status.Unlock()
@@ -402,11 +476,12 @@ func (client *Client) sendStatus(status Status, payload []byte, trace *TraceConf
 
 	session := client.session
 	frame := session.statusFrame(status, payload, trace)
+	frame.CorrelationID = id
 
 	return session.Write(frame)
 }
 
-func (client *Client) sendEvent(event Event, payload []byte, trace *TraceConfig) (int, error) {
+func (client *Client) sendEvent(event Event, payload []byte, trace *TraceConfig, id uuid.UUID) (int, error) {
 	client.status.Lock()
 	if client.status.status == ssntpClosed {
 		client.status.Unlock()
@@ -416,11 +491,12 @@ func (client *Client) sendEvent(event Event, payload []byte, trace *TraceConfig)
 
 	session := client.session
 	frame := session.eventFrame(event, payload, trace)
+	frame.CorrelationID = id
 
 	return session.Write(frame)
 }
 
-func (client *Client) sendError(error Error, payload []byte, trace *TraceConfig) (int, error) {
+func (client *Client) sendError(error Error, payload []byte, trace *TraceConfig, id uuid.UUID) (int, error) {
 	client.status.Lock()
 	if client.status.status == ssntpClosed {
 		client.status.Unlock()
@@ -430,48 +506,49 @@ func (client *Client) sendError(error Error, payload []byte, trace *TraceConfig)
 
 	session := client.session
 	frame := session.errorFrame(error, payload, trace)
+	frame.CorrelationID = id
 
 	return session.Write(frame)
 }
 
 // SendCommand sends a specific command and its payload to the SSNTP server.
 func (client *Client) SendCommand(cmd Command, payload []byte) (int, error) {
-	return client.sendCommand(cmd, payload, client.trace)
+	return client.sendCommand(cmd, payload, client.trace, noCorrelation)
 }
 
 // SendStatus sends a specific status and its payload to the SSNTP server.
 func (client *Client) SendStatus(status Status, payload []byte) (int, error) {
-	return client.sendStatus(status, payload, client.trace)
+	return client.sendStatus(status, payload, client.trace, noCorrelation)
 }
 
 // SendEvent sends a specific status and its payload to the SSNTP server.
 func (client *Client) SendEvent(event Event, payload []byte) (int, error) {
-	return client.sendEvent(event, payload, client.trace)
+	return client.sendEvent(event, payload, client.trace, noCorrelation)
 }
 
 // SendError sends an error back to the SSNTP server.
 // This is just for notification purposes, to let e.g. the server know that
 // it sent an unexpected frame.
 func (client *Client) SendError(error Error, payload []byte) (int, error) {
-	return client.sendError(error, payload, client.trace)
+	return client.sendError(error, payload, client.trace, noCorrelation)
 }
 
 // SendTracedCommand sends a specific command and its payload to the SSNTP server.
 // The SSNTP command frame will be traced according to the trace argument.
 func (client *Client) SendTracedCommand(cmd Command, payload []byte, trace *TraceConfig) (int, error) {
-	return client.sendCommand(cmd, payload, trace)
+	return client.sendCommand(cmd, payload, trace, noCorrelation)
 }
 
 // SendTracedStatus sends a specific status and its payload to the SSNTP server.
 // The SSNTP status frame will be traced according to the trace argument.
 func (client *Client) SendTracedStatus(status Status, payload []byte, trace *TraceConfig) (int, error) {
-	return client.sendStatus(status, payload, trace)
+	return client.sendStatus(status, payload, trace, noCorrelation)
 }
 
 // SendTracedEvent sends a specific status and its payload to the SSNTP server.
 // The SSNTP event frame will be traced according to the trace argument.
 func (client *Client) SendTracedEvent(event Event, payload []byte, trace *TraceConfig) (int, error) {
-	return client.sendEvent(event, payload, trace)
+	return client.sendEvent(event, payload, trace, noCorrelation)
 }
 
 // SendTracedError sends an error back to the SSNTP server.
@@ -479,7 +556,44 @@ func (client *Client) SendTracedEvent(event Event, payload []byte, trace *TraceC
 // it sent an unexpected frame.
 // The SSNTP error frame will be traced according to the trace argument.
 func (client *Client) SendTracedError(error Error, payload []byte, trace *TraceConfig) (int, error) {
-	return client.sendError(error, payload, trace)
+	return client.sendError(error, payload, trace, noCorrelation)
+}
+
+// SendCommandWait sends a specific command and its payload to the SSNTP
+// server and waits for the STATUS, EVENT or ERROR frame replying to it.
+// Replies are matched through the frame correlation ID and must thus be
+// sent with one of the Send*Reply methods. Such replies are not passed
+// to the ClientNotifier. When the server forwards the command to other
+// clients, their reply is only forwarded back to this client.
+// If ctx expires before the reply arrives, ctx.Err() is returned.
+func (client *Client) SendCommandWait(ctx context.Context, cmd Command, payload []byte) (*Frame, error) {
+	id, reply := client.replies.add("")
+
+	_, err := client.sendCommand(cmd, payload, client.trace, id)
+	if err != nil {
+		client.replies.remove(id)
+		return nil, err
+	}
+
+	return client.replies.wait(ctx, id, reply)
+}
+
+// SendStatusReply sends a specific status and its payload to the SSNTP server,
+// as a reply to the frame command frame.
+func (client *Client) SendStatusReply(frame *Frame, status Status, payload []byte) (int, error) {
+	return client.sendStatus(status, payload, client.trace, frame.CorrelationID)
+}
+
+// SendEventReply sends a specific event and its payload to the SSNTP server,
+// as a reply to the frame command frame.
+func (client *Client) SendEventReply(frame *Frame, event Event, payload []byte) (int, error) {
+	return client.sendEvent(event, payload, client.trace, frame.CorrelationID)
+}
+
+// SendErrorReply sends an error back to the SSNTP server, as a reply to the
+// frame command frame.
+func (client *Client) SendErrorReply(frame *Frame, error Error, payload []byte) (int, error) {
+	return client.sendError(error, payload, client.trace, frame.CorrelationID)
 }
 
 // Role exports the SSNTP client role.
@@ -492,6 +606,49 @@ func (client *Client) UUID() string {
 	return client.uuid.String()
 }
 
+// ServerUUID returns the UUID of the server the client is currently
+// connected to.
+func (client *Client) ServerUUID() string {
+	if client.session == nil {
+		return ""
+	}
+
+	return client.session.dest.String()
+}
+
+// ServerMinor returns the SSNTP minor version negotiated with the server
+// the client is currently connected to.
+func (client *Client) ServerMinor() uint8 {
+	if client.session == nil {
+		return 0
+	}
+
+	return client.session.peerMinor
+}
+
+// ServerCapabilities returns the SSNTP capabilities of the server the
+// client is currently connected to.
+// Sending a command the server does not support fails with an
+// UnsupportedCommandError.
+func (client *Client) ServerCapabilities() Capability {
+	if client.session == nil {
+		return 0
+	}
+
+	return client.session.peerCapabilities
+}
+
+// PayloadEncoding returns the most efficient payload encoding the server
+// the client is currently connected to can parse. Payloads marshalled
+// with payloads.Marshal and this encoding can be sent to the server.
+func (client *Client) PayloadEncoding() payloads.Encoding {
+	if client.session == nil {
+		return payloads.YAML
+	}
+
+	return client.session.payloadEncoding(client.capabilities)
+}
+
 // ClusterConfiguration returns the latest cluster configuration
 // payload a client received. Clients should use that payload to
 // configure themselves based on the information provided to them
@@ -0,0 +1,122 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// defaultCompressionThreshold is the payload size, in bytes, above which
+// frame payloads get compressed unless the Config says otherwise.
+const defaultCompressionThreshold = 4096
+
+// maxPayloadSize bounds the size of decompressed frame payloads.
+const maxPayloadSize = 64 << 20
+
+func compressPayload(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decompressPayload(payload []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxPayloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxPayloadSize {
+		return nil, fmt.Errorf("Decompressed payload larger than %d bytes", maxPayloadSize)
+	}
+
+	return data, nil
+}
+
+// compressFrame returns the frame to put on the wire for f. Frames whose
+// payload exceeds the session compression threshold are copied and their
+// payload compressed, as the same frame may be forwarded to several peers.
+func (session *session) compressFrame(f *Frame) (*Frame, error) {
+	if session.compressionThreshold <= 0 || f.Compressed ||
+		len(f.Payload) <= session.compressionThreshold {
+		return f, nil
+	}
+
+	payload, err := compressPayload(f.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) >= len(f.Payload) {
+		return f, nil
+	}
+
+	compressed := *f
+	compressed.Payload = payload
+	compressed.PayloadLength = (uint32)(len(payload))
+	compressed.Compressed = true
+
+	return &compressed, nil
+}
+
+func (session *session) decompressFrame(f *Frame) error {
+	if !f.Compressed {
+		return nil
+	}
+
+	payload, err := decompressPayload(f.Payload)
+	if err != nil {
+		return err
+	}
+
+	f.Payload = payload
+	f.PayloadLength = (uint32)(len(payload))
+	f.Compressed = false
+
+	return nil
+}
+
+// setCompression enables payload compression on the session if both ends
+// support it.
+func (session *session) setCompression(capabilities Capability, threshold int) {
+	if !capabilities.Has(CompressionCapability) ||
+		!session.peerCapabilities.Has(CompressionCapability) {
+		threshold = 0
+	}
+
+	session.compressionThreshold = threshold
+}
@@ -0,0 +1,124 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+type countingConn struct {
+	net.Conn
+	written int
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	c.written += len(b)
+	return c.Conn.Write(b)
+}
+
+func transferFrame(t *testing.T, threshold int, peerCapabilities Capability, payload []byte) (*Frame, int) {
+	client, server := net.Pipe()
+	defer func() { _ = client.Close() }()
+	defer func() { _ = server.Close() }()
+
+	conn := &countingConn{Conn: client}
+	src := newSession(nil, AGENT, SERVER, conn)
+	src.setPeer(minor, peerCapabilities)
+	src.setCompression(Capabilities, threshold)
+	dst := newSession(nil, SERVER, AGENT, server)
+
+	frame := src.commandFrame(STATS, payload, nil)
+	errCh := make(chan error)
+	go func() {
+		_, err := src.Write(frame)
+		errCh <- err
+	}()
+
+	var received Frame
+	err := dst.Read(&received)
+	if err != nil {
+		t.Fatalf("Unable to read frame: %v", err)
+	}
+
+	err = <-errCh
+	if err != nil {
+		t.Fatalf("Unable to write frame: %v", err)
+	}
+
+	if frame.Compressed || !bytes.Equal(frame.Payload, payload) {
+		t.Fatalf("Written frame was modified")
+	}
+
+	return &received, conn.written
+}
+
+// Test frame payload compression
+//
+// Send a large, compressible, payload over a session with compression
+// enabled and then with compression disabled.
+//
+// The received payloads should be identical to the sent one, and the
+// compressed frame should be much smaller on the wire.
+func TestCompression(t *testing.T) {
+	payload := bytes.Repeat([]byte("instance_uuid: 3390740c-dce9-48d6-b83a-a717417072ce\n"), 1000)
+
+	compressed, compressedSize := transferFrame(t, defaultCompressionThreshold, Capabilities, payload)
+	if compressed.Compressed || !bytes.Equal(compressed.Payload, payload) ||
+		compressed.PayloadLength != (uint32)(len(payload)) {
+		t.Errorf("Compressed payload corrupted")
+	}
+
+	plain, plainSize := transferFrame(t, defaultCompressionThreshold, legacyCapabilities, payload)
+	if !bytes.Equal(plain.Payload, payload) {
+		t.Errorf("Uncompressed payload corrupted")
+	}
+
+	if compressedSize*10 > plainSize {
+		t.Errorf("Payload not compressed: %d vs %d bytes", compressedSize, plainSize)
+	}
+}
+
+// Test the compression threshold
+//
+// Send a small payload over a session with compression enabled.
+//
+// The payload should not be compressed.
+func TestCompressionThreshold(t *testing.T) {
+	payload := []byte("node_uuid: 4cb19522-1e18-439a-883a-f9b2a3a95f5e\n")
+	s := &session{compressionThreshold: defaultCompressionThreshold}
+
+	f, err := s.compressFrame(&Frame{Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Compressed {
+		t.Errorf("Payload below threshold compressed")
+	}
+
+	s.compressionThreshold = 1
+	f, err = s.compressFrame(&Frame{Payload: bytes.Repeat(payload, 10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !f.Compressed {
+		t.Errorf("Payload above threshold not compressed")
+	}
+}
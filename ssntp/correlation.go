@@ -0,0 +1,172 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ciao-project/ciao/uuid"
+)
+
+// replyRouteTimeout bounds the time a server remembers where to send the
+// reply to a forwarded COMMAND frame.
+const replyRouteTimeout = 10 * time.Minute
+
+// noCorrelation is the correlation ID of uncorrelated frames.
+var noCorrelation uuid.UUID
+
+type replyWaiter struct {
+	peer  string
+	reply chan *Frame
+}
+
+// replyWaiters tracks the COMMAND frames we sent with a correlation ID
+// and that we are waiting for a reply to. Correlation IDs are UUIDs and
+// thus unique across the cluster, so that forwarded replies to COMMAND
+// frames sent by other peers are never mistaken for ours. When we know
+// which peer must reply, the reply Origin must also match that peer.
+type replyWaiters struct {
+	sync.Mutex
+	waiters map[uuid.UUID]replyWaiter
+}
+
+// add registers a waiter for a reply from peer, or from any peer if peer
+// is empty, and returns the correlation ID to send the COMMAND frame with.
+func (w *replyWaiters) add(peer string) (uuid.UUID, chan *Frame) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.waiters == nil {
+		w.waiters = make(map[uuid.UUID]replyWaiter)
+	}
+
+	id := uuid.Generate()
+	reply := make(chan *Frame, 1)
+	w.waiters[id] = replyWaiter{peer: peer, reply: reply}
+
+	return id, reply
+}
+
+func (w *replyWaiters) remove(id uuid.UUID) {
+	w.Lock()
+	delete(w.waiters, id)
+	w.Unlock()
+}
+
+// deliver hands a frame over to the go routine waiting for it, if any.
+// It returns false if nobody is waiting for this frame.
+func (w *replyWaiters) deliver(frame *Frame) bool {
+	if !frame.correlated() || frame.Type == COMMAND {
+		return false
+	}
+
+	w.Lock()
+	waiter, ok := w.waiters[frame.CorrelationID]
+	if ok && waiter.peer != "" && waiter.peer != frame.Origin.String() {
+		ok = false
+	}
+	if ok {
+		delete(w.waiters, frame.CorrelationID)
+	}
+	w.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	waiter.reply <- frame
+	return true
+}
+
+func (w *replyWaiters) wait(ctx context.Context, id uuid.UUID, reply chan *Frame) (*Frame, error) {
+	select {
+	case frame := <-reply:
+		return frame, nil
+	case <-ctx.Done():
+		w.remove(id)
+		return nil, ctx.Err()
+	}
+}
+
+type replyRoute struct {
+	source  string
+	expires time.Time
+}
+
+// replyRoutes remembers which client sent each correlated COMMAND frame a
+// server forwarded, so that the reply is sent back to that client only
+// instead of being forwarded according to the server forwarding rules.
+type replyRoutes struct {
+	sync.Mutex
+	routes map[uuid.UUID]replyRoute
+}
+
+func (r *replyRoutes) add(id uuid.UUID, source string) {
+	now := time.Now()
+
+	r.Lock()
+	defer r.Unlock()
+
+	if r.routes == nil {
+		r.routes = make(map[uuid.UUID]replyRoute)
+	}
+
+	for k, route := range r.routes {
+		if now.After(route.expires) {
+			delete(r.routes, k)
+		}
+	}
+
+	r.routes[id] = replyRoute{source: source, expires: now.Add(replyRouteTimeout)}
+}
+
+// lookup returns the client the reply frame must be sent to, if any. Only
+// the first reply to a COMMAND frame is routed.
+func (r *replyRoutes) lookup(frame *Frame) (string, bool) {
+	if !frame.correlated() || frame.Type == COMMAND {
+		return "", false
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	route, ok := r.routes[frame.CorrelationID]
+	if !ok {
+		return "", false
+	}
+	delete(r.routes, frame.CorrelationID)
+
+	if time.Now().After(route.expires) {
+		return "", false
+	}
+
+	return route.source, true
+}
+
+// removeSource forgets the routes to a client that disconnected.
+func (r *replyRoutes) removeSource(source string) {
+	r.Lock()
+	defer r.Unlock()
+
+	for k, route := range r.routes {
+		if route.source == source {
+			delete(r.routes, k)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"testing"
+
+	"github.com/ciao-project/ciao/uuid"
+)
+
+// Test that replies are only delivered to the go routine waiting for them
+// if they come from the expected peer.
+//
+// Test is expected to pass.
+func TestReplyWaitersOrigin(t *testing.T) {
+	var w replyWaiters
+
+	peer := uuid.Generate()
+	id, reply := w.add(peer.String())
+
+	frame := &Frame{Type: EVENT, Origin: uuid.Generate(), CorrelationID: id}
+	if w.deliver(frame) {
+		t.Fatalf("Reply from the wrong peer delivered")
+	}
+
+	frame.Origin = peer
+	frame.Type = COMMAND
+	if w.deliver(frame) {
+		t.Fatalf("COMMAND frame delivered as a reply")
+	}
+
+	frame.Type = EVENT
+	if !w.deliver(frame) {
+		t.Fatalf("Reply not delivered")
+	}
+
+	if <-reply != frame {
+		t.Fatalf("Wrong reply delivered")
+	}
+
+	if w.deliver(frame) {
+		t.Fatalf("Reply delivered twice")
+	}
+}
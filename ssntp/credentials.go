@@ -0,0 +1,285 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// credentialsCheckInterval is the interval at which the certificate and
+// CRL files are checked for changes.
+const credentialsCheckInterval = 30 * time.Second
+
+// credentials holds the TLS certificate of an SSNTP client or server
+// together with the list of revoked peer certificates. Both are loaded
+// from files that can be replaced at runtime, e.g. when rolling
+// certificates or revoking a compromised node's certificate.
+type credentials struct {
+	sync.RWMutex
+	reloadLock sync.Mutex
+
+	certPath    string
+	certModTime time.Time
+	cert        *tls.Certificate
+
+	cas        []*x509.Certificate
+	crlPath    string
+	crlModTime time.Time
+	revoked    map[string]struct{}
+}
+
+func parseCACerts(caPEM []byte) ([]*x509.Certificate, error) {
+	var cas []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		block, caPEM = pem.Decode(caPEM)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		ca, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		cas = append(cas, ca)
+	}
+
+	if len(cas) == 0 {
+		return nil, fmt.Errorf("No CA certificate found")
+	}
+
+	return cas, nil
+}
+
+func newCredentials(certPath, crlPath string, caPEM []byte) (*credentials, error) {
+	cas, err := parseCACerts(caPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := &credentials{
+		certPath: certPath,
+		cas:      cas,
+		crlPath:  crlPath,
+		revoked:  make(map[string]struct{}),
+	}
+
+	_, err = creds.reload(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+func modTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
+
+func (creds *credentials) loadCert() error {
+	certPEM, err := ioutil.ReadFile(creds.certPath)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, certPEM)
+	if err != nil {
+		return err
+	}
+
+	creds.Lock()
+	creds.cert = &cert
+	creds.Unlock()
+
+	return nil
+}
+
+func (creds *credentials) loadCRL() error {
+	crlPEM, err := ioutil.ReadFile(creds.crlPath)
+	if err != nil {
+		return err
+	}
+
+	crl, err := x509.ParseCRL(crlPEM)
+	if err != nil {
+		return err
+	}
+
+	err = fmt.Errorf("CRL %s is not signed by any CA", creds.crlPath)
+	for _, ca := range creds.cas {
+		if ca.CheckCRLSignature(crl) == nil {
+			err = nil
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	revoked := make(map[string]struct{})
+	for _, r := range crl.TBSCertList.RevokedCertificates {
+		revoked[r.SerialNumber.String()] = struct{}{}
+	}
+
+	creds.Lock()
+	creds.revoked = revoked
+	creds.Unlock()
+
+	return nil
+}
+
+// reload loads the certificate and CRL files again if they changed since
+// they were last loaded, or unconditionally if force is set. It returns
+// true if the CRL was reloaded. When a file fails to load, the previously
+// loaded version is kept.
+func (creds *credentials) reload(force bool) (bool, error) {
+	creds.reloadLock.Lock()
+	defer creds.reloadLock.Unlock()
+
+	certModTime, err := modTime(creds.certPath)
+	if err != nil {
+		return false, err
+	}
+
+	if force || !certModTime.Equal(creds.certModTime) {
+		err = creds.loadCert()
+		if err != nil {
+			return false, fmt.Errorf("Could not load certificate %s: %s", creds.certPath, err)
+		}
+		creds.certModTime = certModTime
+	}
+
+	if creds.crlPath == "" {
+		return false, nil
+	}
+
+	crlModTime, err := modTime(creds.crlPath)
+	if err != nil {
+		return false, err
+	}
+
+	if !force && crlModTime.Equal(creds.crlModTime) {
+		return false, nil
+	}
+
+	err = creds.loadCRL()
+	if err != nil {
+		return false, fmt.Errorf("Could not load CRL %s: %s", creds.crlPath, err)
+	}
+	creds.crlModTime = crlModTime
+
+	return true, nil
+}
+
+// watch periodically reloads the credentials files and calls crlChanged
+// whenever a new CRL is loaded, until stop is closed.
+func (creds *credentials) watch(stop <-chan struct{}, log Logger, crlChanged func()) {
+	ticker := time.NewTicker(credentialsCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := creds.reload(false)
+		if err != nil {
+			log.Errorf("%s\n", err)
+		}
+
+		if changed && crlChanged != nil {
+			crlChanged()
+		}
+	}
+}
+
+func (creds *credentials) isRevoked(cert *x509.Certificate) bool {
+	creds.RLock()
+	_, revoked := creds.revoked[cert.SerialNumber.String()]
+	creds.RUnlock()
+
+	return revoked
+}
+
+// verifyPeer checks that none of the peer certificates was revoked.
+func (creds *credentials) verifyPeer(certs []*x509.Certificate) error {
+	for _, cert := range certs {
+		if creds.isRevoked(cert) {
+			return fmt.Errorf("Certificate %s for %v was revoked",
+				cert.SerialNumber, cert.Subject.Organization)
+		}
+	}
+
+	return nil
+}
+
+func (creds *credentials) verifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	for _, chain := range verifiedChains {
+		err := creds.verifyPeer(chain)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (creds *credentials) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	creds.RLock()
+	defer creds.RUnlock()
+
+	return creds.cert, nil
+}
+
+func (creds *credentials) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	creds.RLock()
+	defer creds.RUnlock()
+
+	return creds.cert, nil
+}
+
+// setup makes a TLS configuration use the reloadable certificate and
+// reject revoked peer certificates.
+func (creds *credentials) setup(config *tls.Config, server bool) {
+	config.Certificates = nil
+	if server {
+		config.GetCertificate = creds.getCertificate
+	} else {
+		config.GetClientCertificate = creds.getClientCertificate
+	}
+	config.VerifyPeerCertificate = creds.verifyPeerCertificate
+}
@@ -273,7 +273,14 @@ func forwardDestination(destination ForwardDestination, server *Server, frame *F
 			continue
 		}
 
-		session.Write(frame)
+		forwarded, err := session.forwardedFrame(frame)
+		if forwarded == nil {
+			server.log.Warningf("%s does not support frame, not forwarding:\n%s\n%v\n",
+				uuid, frame, err)
+			continue
+		}
+
+		session.Write(forwarded)
 	}
 	server.sessionMutex.RUnlock()
 }
@@ -354,6 +361,12 @@ func (f *frameForward) forwardFrame(server *Server, source *session, operand int
 		if s == source {
 			continue
 		}
-		s.Write(frame)
+		forwarded, err := s.forwardedFrame(frame)
+		if forwarded == nil {
+			server.log.Warningf("%s does not support frame, not forwarding:\n%s\n%v\n",
+				s.dest, frame, err)
+			continue
+		}
+		s.Write(forwarded)
 	}
 }
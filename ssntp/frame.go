@@ -76,6 +76,17 @@ type Frame struct {
 	PayloadLength uint32
 	Trace         *FrameTrace
 	Payload       []byte
+
+	// CorrelationID is an optional identifier used to match a
+	// STATUS, EVENT or ERROR frame with the COMMAND frame it replies
+	// to. It is a UUID chosen by the COMMAND sender and is thus unique
+	// across the cluster. It is the nil UUID for uncorrelated frames.
+	CorrelationID uuid.UUID
+
+	// Compressed tells if the payload is gzip compressed. Frames are
+	// compressed and decompressed by the SSNTP sessions, SSNTP users
+	// never see compressed frames.
+	Compressed bool
 }
 
 // ConnectFrame is the SSNTP connection frame structure.
@@ -87,6 +98,12 @@ type ConnectFrame struct {
 	Role        Role
 	Source      []byte
 	Destination []byte
+
+	// MinMinor is the oldest SSNTP minor version the client supports.
+	MinMinor uint8
+
+	// Capabilities is the bitmap of SSNTP capabilities the client supports.
+	Capabilities Capability
 }
 
 // ConnectedFrame is the SSNTP connected frame structure.
@@ -100,6 +117,12 @@ type ConnectedFrame struct {
 	Destination   []byte
 	PayloadLength uint32
 	Payload       []byte
+
+	// MinMinor is the oldest SSNTP minor version the server supports.
+	MinMinor uint8
+
+	// Capabilities is the bitmap of SSNTP capabilities the server supports.
+	Capabilities Capability
 }
 
 const majorMask = 0x7f
@@ -133,6 +156,11 @@ func (f Frame) GetMajor() uint8 {
 	return f.Major & majorMask
 }
 
+// correlated tells if the frame carries a correlation ID.
+func (f Frame) correlated() bool {
+	return f.CorrelationID != noCorrelation
+}
+
 func (f Frame) String() string {
 	var node uuid.UUID
 	var op string
@@ -166,12 +194,12 @@ func (f Frame) String() string {
 			path = path + fmt.Sprintf("\n\t\tNode #%d\n\t\tUUID %s\n", i, node) + ts
 		}
 
-		return fmt.Sprintf("\n\tMajor %d\n\tMinor %d\n\tType %s\n\tOp %s\n\tOrigin %s\n\tPayload len %d\n\tPath %s\n",
-			f.GetMajor(), f.Minor, t, op, f.Origin, f.PayloadLength, path)
+		return fmt.Sprintf("\n\tMajor %d\n\tMinor %d\n\tType %s\n\tOp %s\n\tOrigin %s\n\tCorrelation ID %s\n\tPayload len %d\n\tPath %s\n",
+			f.GetMajor(), f.Minor, t, op, f.Origin, f.CorrelationID, f.PayloadLength, path)
 	}
 
-	return fmt.Sprintf("\n\tMajor %d\n\tMinor %d\n\tType %s\n\tOp %s\n\tOrigin %s\n\tPayload len %d\n",
-		f.GetMajor(), f.Minor, t, op, f.Origin, f.PayloadLength)
+	return fmt.Sprintf("\n\tMajor %d\n\tMinor %d\n\tType %s\n\tOp %s\n\tOrigin %s\n\tCorrelation ID %s\n\tPayload len %d\n",
+		f.GetMajor(), f.Minor, t, op, f.Origin, f.CorrelationID, f.PayloadLength)
 }
 
 func (f ConnectFrame) String() string {
@@ -191,8 +219,8 @@ func (f ConnectFrame) String() string {
 	copy(src[:], f.Source[:16])
 	copy(dest[:], f.Destination[:16])
 
-	return fmt.Sprintf("\tMajor %d\n\tMinor %d\n\tType %s\n\tOp %s\n\tRole %s\n\tSource %s\n\tDestination %s\n",
-		f.Major, f.Minor, (Type)(f.Type), op, &f.Role, src, dest)
+	return fmt.Sprintf("\tMajor %d\n\tMinor %d\n\tMinMinor %d\n\tType %s\n\tOp %s\n\tRole %s\n\tSource %s\n\tDestination %s\n\tCapabilities %s\n",
+		f.Major, f.Minor, f.MinMinor, (Type)(f.Type), op, &f.Role, src, dest, f.Capabilities)
 }
 
 func (f ConnectedFrame) String() string {
@@ -212,8 +240,8 @@ func (f ConnectedFrame) String() string {
 	copy(src[:], f.Source[:16])
 	copy(dest[:], f.Destination[:16])
 
-	return fmt.Sprintf("\tMajor %d\n\tMinor %d\n\tType %s\n\tOp %s\n\tRole %s\n\tSource %s\n\tDestination %s\n",
-		f.Major, f.Minor, (Type)(f.Type), op, &f.Role, src, dest)
+	return fmt.Sprintf("\tMajor %d\n\tMinor %d\n\tMinMinor %d\n\tType %s\n\tOp %s\n\tRole %s\n\tSource %s\n\tDestination %s\n\tCapabilities %s\n",
+		f.Major, f.Minor, f.MinMinor, (Type)(f.Type), op, &f.Role, src, dest, f.Capabilities)
 }
 
 func (f *Frame) addPathNode(session *session) {
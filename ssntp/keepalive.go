@@ -0,0 +1,82 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"time"
+)
+
+const defaultKeepaliveInterval = 15 * time.Second
+const defaultKeepaliveMisses = 3
+
+// startKeepalive starts sending PING frames to the session peer every
+// interval, and makes session reads fail when nothing was received from
+// the peer for misses intervals. Both ends of the session must support
+// keepalives. startKeepalive must be called from the go routine reading
+// from the session.
+func (session *session) startKeepalive(capabilities Capability, interval time.Duration, misses int, log Logger) {
+	if interval <= 0 || !capabilities.Has(KeepaliveCapability) ||
+		!session.peerCapabilities.Has(KeepaliveCapability) {
+		return
+	}
+
+	session.keepaliveTimeout = interval * time.Duration(misses)
+	session.keepaliveStop = make(chan struct{})
+
+	go session.keepalive(interval, session.keepaliveStop, log)
+}
+
+func (session *session) stopKeepalive() {
+	if session.keepaliveStop == nil {
+		return
+	}
+
+	close(session.keepaliveStop)
+	session.keepaliveStop = nil
+}
+
+func (session *session) keepalive(interval time.Duration, stop chan struct{}, log Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		_, err := session.Write(session.commandFrame(PING, nil, nil))
+		if err != nil {
+			log.Warningf("Could not send PING to %s: %s\n", session.dest, err)
+		}
+	}
+}
+
+// handleKeepalive answers PING frames and swallows PONG ones. It returns
+// true if frame was a keepalive frame, that SSNTP users should not see.
+func (session *session) handleKeepalive(frame *Frame) bool {
+	switch {
+	case frame.Type == COMMAND && (Command)(frame.Operand) == PING:
+		_, _ = session.Write(session.statusFrame(PONG, nil, nil))
+		return true
+	case frame.Type == STATUS && (Status)(frame.Operand) == PONG:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package ssntp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// Test dead peer detection
+//
+// Start keepalives on a session whose peer never answers.
+//
+// Reading from the session should fail after the configured number of
+// missed intervals.
+func TestKeepaliveTimeout(t *testing.T) {
+	local, peer := net.Pipe()
+	defer func() { _ = peer.Close() }()
+	defer func() { _ = local.Close() }()
+
+	s := newSession(nil, SERVER, AGENT, local)
+	s.setPeer(minor, Capabilities)
+	s.startKeepalive(Capabilities, 20*time.Millisecond, 2, errLog)
+	defer s.stopKeepalive()
+
+	errCh := make(chan error)
+	go func() {
+		var frame Frame
+		errCh <- s.Read(&frame)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatalf("Read from a dead peer succeeded")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Dead peer not detected")
+	}
+}
+
+// Test keepalives with legacy peers
+//
+// Start keepalives on a session whose peer does not support them.
+//
+// No read deadline should be set.
+func TestKeepaliveLegacy(t *testing.T) {
+	local, peer := net.Pipe()
+	defer func() { _ = peer.Close() }()
+	defer func() { _ = local.Close() }()
+
+	s := newSession(nil, SERVER, AGENT, local)
+	s.setPeer(1, legacyCapabilities)
+	s.startKeepalive(Capabilities, 20*time.Millisecond, 2, errLog)
+	defer s.stopKeepalive()
+
+	if s.keepaliveTimeout != 0 || s.keepaliveStop != nil {
+		t.Fatalf("Keepalives enabled for a legacy peer")
+	}
+}
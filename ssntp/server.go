@@ -17,6 +17,7 @@
 package ssntp
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/gob"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 	"time"
 
 	"github.com/ciao-project/ciao/configuration"
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/uuid"
 )
 
@@ -64,6 +66,7 @@ type Server struct {
 	uuid          uuid.UUID
 	lUUID         lockedUUID
 	tls           *tls.Config
+	creds         *credentials
 	ntf           ServerNotifier
 	sessionMutex  sync.RWMutex
 	sessions      map[string]*session
@@ -74,11 +77,21 @@ type Server struct {
 	role          Role
 	clientWg      sync.WaitGroup
 
-	forwardRules frameForward
+	forwardRules  frameForward
+	authorization frameAuthorization
 
 	log Logger
 
-	trace *TraceConfig
+	trace   *TraceConfig
+	capture *capture
+
+	capabilities         Capability
+	compressionThreshold int
+	keepaliveInterval    time.Duration
+	keepaliveMisses      int
+
+	replies replyWaiters
+	routes  replyRoutes
 
 	configuration clusterConfiguration
 }
@@ -133,12 +146,21 @@ func handleClientConnect(server *Server, conn net.Conn) *session {
 		return sendConnectionFailure(conn)
 	}
 
+	peerMinor, peerCapabilities, err := negotiate(connect.MinMinor, connect.Minor, connect.Capabilities)
+	if err != nil {
+		server.log.Errorf("%s\n", err)
+		return sendConnectionFailure(conn)
+	}
+
 	session := newSession(&server.uuid, server.role, connect.Role, conn)
 	session.setDest(connect.Source[:16])
+	session.capture = server.capture
+	session.setPeer(peerMinor, peerCapabilities)
+	session.setCompression(server.capabilities, server.compressionThreshold)
 
 	/* TODO Get the CONFIGURE payload from the config package */
 	server.configuration.RLock()
-	connected := session.connectedFrame(server.role, server.configuration.configuration)
+	connected := session.connectedFrame(server.role, server.capabilities, server.configuration.configuration)
 	server.configuration.RUnlock()
 
 	server.log.Infof("Sending CONNECTED\n")
@@ -166,6 +188,10 @@ func handleSSNTPClient(server *Server, conn net.Conn) {
 	server.forwardRules.addForwardDestination(session)
 	server.ntf.ConnectNotify(uuidString, session.destRole)
 
+	session.startKeepalive(server.capabilities, server.keepaliveInterval,
+		server.keepaliveMisses, server.log)
+	defer session.stopKeepalive()
+
 	for {
 		var frame Frame
 		err := session.Read(&frame)
@@ -173,26 +199,55 @@ func handleSSNTPClient(server *Server, conn net.Conn) {
 			server.log.Infof("Client disconnection: %s %d\n", err)
 			server.ntf.DisconnectNotify(uuidString, session.destRole)
 			server.forwardRules.deleteForwardDestination(session)
+			server.routes.removeSource(uuidString)
 			server.removeSession(uuidString)
 			break
 		}
 
+		if session.handleKeepalive(&frame) {
+			continue
+		}
+
+		if !server.authorization.authorized(session.destRole, &frame) {
+			server.log.Errorf("%s (%s) is not authorized to send frame:\n%s\n",
+				uuidString, &session.destRole, frame)
+			server.sendError(uuidString, UnauthorizedFrame, nil, server.trace, frame.CorrelationID)
+			continue
+		}
+
+		if server.replies.deliver(&frame) {
+			continue
+		}
+
+		// Replies to forwarded correlated commands only go back to
+		// the command sender.
+		routed := server.forwardReply(&frame)
+
 		switch frame.Type {
 		case COMMAND:
 			if (Command)(frame.Operand) == CONFIGURE && session.destRole.IsController() {
 				/* TODO Send the CONFIGURE payload to the config package */
 				server.configuration.setConfiguration(frame.Payload)
 			}
+			if frame.correlated() {
+				server.routes.add(frame.CorrelationID, uuidString)
+			}
 			server.forwardRules.forwardFrame(server, session, (Command)(frame.Operand), &frame)
 			server.ntf.CommandNotify(uuidString, (Command)(frame.Operand), &frame)
 		case STATUS:
-			server.forwardRules.forwardFrame(server, session, (Status)(frame.Operand), &frame)
+			if !routed {
+				server.forwardRules.forwardFrame(server, session, (Status)(frame.Operand), &frame)
+			}
 			server.ntf.StatusNotify(uuidString, (Status)(frame.Operand), &frame)
 		case EVENT:
-			server.forwardRules.forwardFrame(server, session, (Event)(frame.Operand), &frame)
+			if !routed {
+				server.forwardRules.forwardFrame(server, session, (Event)(frame.Operand), &frame)
+			}
 			server.ntf.EventNotify(uuidString, (Event)(frame.Operand), &frame)
 		case ERROR:
-			server.forwardRules.forwardFrame(server, session, (Error)(frame.Operand), &frame)
+			if !routed {
+				server.forwardRules.forwardFrame(server, session, (Error)(frame.Operand), &frame)
+			}
 			server.ntf.ErrorNotify(uuidString, (Error)(frame.Operand), &frame)
 		default:
 			server.SendError(uuidString, InvalidFrameType, nil)
@@ -200,6 +255,31 @@ func handleSSNTPClient(server *Server, conn net.Conn) {
 	}
 }
 
+// forwardReply sends a reply to a forwarded correlated COMMAND frame back
+// to the client that sent the COMMAND frame. It returns false if frame is
+// not such a reply.
+func (server *Server) forwardReply(frame *Frame) bool {
+	source, ok := server.routes.lookup(frame)
+	if !ok {
+		return false
+	}
+
+	session := server.getSession(source)
+	if session == nil {
+		return true
+	}
+
+	forwarded, err := session.forwardedFrame(frame)
+	if forwarded == nil {
+		server.log.Warningf("%s does not support frame, not forwarding:\n%s\n%v\n",
+			source, frame, err)
+		return true
+	}
+
+	session.Write(forwarded)
+	return true
+}
+
 /*
  * SSNTP Server methods
  */
@@ -260,9 +340,13 @@ func (server *Server) Serve(config *Config, ntf ServerNotifier) error {
 	server.ntf = ntf
 	server.sessions = make(map[string]*session)
 	server.forwardRules.init(config.ForwardRules)
-	server.tls = prepareTLSConfig(config, true)
+	server.tls, server.creds = prepareTLSConfig(config, true)
 	server.forwardRules.forwardRules = config.ForwardRules
+	server.authorization.init(config.AuthorizationRules)
 	server.trace = config.Trace
+	server.capabilities = config.capabilities()
+	server.compressionThreshold = config.compressionThreshold()
+	server.keepaliveInterval, server.keepaliveMisses = config.keepalive()
 	server.stoppedChan = make(chan struct{})
 
 	service := fmt.Sprintf("%s:%d", uri, serverPort)
@@ -279,6 +363,17 @@ func (server *Server) Serve(config *Config, ntf ServerNotifier) error {
 	server.listenerMutex.Unlock()
 	defer listener.Close()
 
+	server.capture, err = openCapture(config.Capture, server.log)
+	if err != nil {
+		server.log.Errorf("%s", err)
+		config.pushToSyncChannel(err)
+		return err
+	}
+
+	if server.creds != nil {
+		go server.creds.watch(server.stoppedChan, server.log, server.verifySessions)
+	}
+
 	config.pushToSyncChannel(nil)
 
 	for {
@@ -353,99 +448,193 @@ func (server *Server) Stop() {
 		server.log.Errorf("Timeout waiting for main server thread\n")
 	}
 
+	if server.capture != nil {
+		server.capture.close()
+	}
+
 	freeUUID(server.lUUID)
 }
 
-func (server *Server) sendCommand(uuid string, cmd Command, payload []byte, trace *TraceConfig) (int, error) {
+// verifySessions closes the connections of the clients which certificate
+// was revoked.
+func (server *Server) verifySessions() {
+	var revoked []*session
+
+	server.sessionMutex.RLock()
+	for uuid, session := range server.sessions {
+		tlsConn, ok := session.conn.(*tls.Conn)
+		if !ok {
+			continue
+		}
+
+		err := server.creds.verifyPeer(tlsConn.ConnectionState().PeerCertificates)
+		if err != nil {
+			server.log.Errorf("Closing connection for %s: %s\n", uuid, err)
+			revoked = append(revoked, session)
+		}
+	}
+	server.sessionMutex.RUnlock()
+
+	for _, session := range revoked {
+		_, _ = session.Write(session.errorFrame(ConnectionAborted, nil, server.trace))
+		session.conn.Close()
+	}
+}
+
+// ReloadCredentials loads the server certificate and CRL files again, and
+// disconnects the clients which certificate was revoked. SSNTP servers
+// periodically check those files for changes, ReloadCredentials allows
+// for applying changes immediately, e.g. on SIGHUP.
+func (server *Server) ReloadCredentials() error {
+	if server.creds == nil {
+		return fmt.Errorf("Server not started")
+	}
+
+	_, err := server.creds.reload(true)
+	if err != nil {
+		return err
+	}
+
+	server.verifySessions()
+
+	return nil
+}
+
+func (server *Server) sendCommand(uuid string, cmd Command, payload []byte, trace *TraceConfig, id uuid.UUID) (int, error) {
 	session := server.getSession(uuid)
 	if session == nil {
 		return -1, fmt.Errorf("Unknown UUID %s", uuid)
 	}
 
+	if err := session.checkCommand(cmd); err != nil {
+		return -1, err
+	}
+
 	frame := session.commandFrame(cmd, payload, trace)
+	frame.CorrelationID = id
 	return session.Write(frame)
 }
 
-func (server *Server) sendStatus(uuid string, status Status, payload []byte, trace *TraceConfig) (int, error) {
+func (server *Server) sendStatus(uuid string, status Status, payload []byte, trace *TraceConfig, id uuid.UUID) (int, error) {
 	session := server.getSession(uuid)
 	if session == nil {
 		return -1, fmt.Errorf("Unknown UUID %s", uuid)
 	}
 
 	frame := session.statusFrame(status, payload, trace)
+	frame.CorrelationID = id
 	return session.Write(frame)
 }
 
-func (server *Server) sendEvent(uuid string, event Event, payload []byte, trace *TraceConfig) (int, error) {
+func (server *Server) sendEvent(uuid string, event Event, payload []byte, trace *TraceConfig, id uuid.UUID) (int, error) {
 	session := server.getSession(uuid)
 	if session == nil {
 		return -1, fmt.Errorf("Unknown UUID %s", uuid)
 	}
 
 	frame := session.eventFrame(event, payload, trace)
+	frame.CorrelationID = id
 	return session.Write(frame)
 }
 
-func (server *Server) sendError(uuid string, error Error, payload []byte, trace *TraceConfig) (int, error) {
+func (server *Server) sendError(uuid string, error Error, payload []byte, trace *TraceConfig, id uuid.UUID) (int, error) {
 	session := server.getSession(uuid)
 	if session == nil {
 		return -1, fmt.Errorf("Unknown UUID %s", uuid)
 	}
 
 	frame := session.errorFrame(error, payload, trace)
+	frame.CorrelationID = id
 	return session.Write(frame)
 }
 
 // SendCommand sends a specific command and its payload to a client.
 // The client is specified by its uuid
 func (server *Server) SendCommand(uuid string, cmd Command, payload []byte) (int, error) {
-	return server.sendCommand(uuid, cmd, payload, server.trace)
+	return server.sendCommand(uuid, cmd, payload, server.trace, noCorrelation)
 }
 
 // SendStatus sends a specific status and its payload to a client.
 // The client is specified by its uuid
 func (server *Server) SendStatus(uuid string, status Status, payload []byte) (int, error) {
-	return server.sendStatus(uuid, status, payload, server.trace)
+	return server.sendStatus(uuid, status, payload, server.trace, noCorrelation)
 }
 
 // SendEvent sends a specific status and its payload to a client.
 // The client is specified by its uuid
 func (server *Server) SendEvent(uuid string, event Event, payload []byte) (int, error) {
-	return server.sendEvent(uuid, event, payload, server.trace)
+	return server.sendEvent(uuid, event, payload, server.trace, noCorrelation)
 }
 
 // SendError sends an error back to a client.
 // The client is specified by its uuid
 func (server *Server) SendError(uuid string, error Error, payload []byte) (int, error) {
-	return server.sendError(uuid, error, payload, server.trace)
+	return server.sendError(uuid, error, payload, server.trace, noCorrelation)
 }
 
 // SendTracedCommand sends a specific command and its payload to a client.
 // The SSNTP command frame will be traced according to the trace argument.
 // The client is specified by its uuid
 func (server *Server) SendTracedCommand(uuid string, cmd Command, payload []byte, trace *TraceConfig) (int, error) {
-	return server.sendCommand(uuid, cmd, payload, trace)
+	return server.sendCommand(uuid, cmd, payload, trace, noCorrelation)
 }
 
 // SendTracedStatus sends a specific status and its payload to a client.
 // The SSNTP status frame will be traced according to the trace argument.
 // The client is specified by its uuid
 func (server *Server) SendTracedStatus(uuid string, status Status, payload []byte, trace *TraceConfig) (int, error) {
-	return server.sendStatus(uuid, status, payload, trace)
+	return server.sendStatus(uuid, status, payload, trace, noCorrelation)
 }
 
 // SendTracedEvent sends a specific event and its payload to a client.
 // The SSNTP event frame will be traced according to the trace argument.
 // The client is specified by its uuid
 func (server *Server) SendTracedEvent(uuid string, event Event, payload []byte, trace *TraceConfig) (int, error) {
-	return server.sendEvent(uuid, event, payload, trace)
+	return server.sendEvent(uuid, event, payload, trace, noCorrelation)
 }
 
 // SendTracedError sends an error back to a client.
 // The SSNTP error frame will be traced according to the trace argument.
 // The client is specified by its uuid
 func (server *Server) SendTracedError(uuid string, error Error, payload []byte, trace *TraceConfig) (int, error) {
-	return server.sendError(uuid, error, payload, trace)
+	return server.sendError(uuid, error, payload, trace, noCorrelation)
+}
+
+// SendCommandWait sends a specific command and its payload to the SSNTP
+// client with the specified uuid and waits for the STATUS, EVENT or ERROR
+// frame replying to it.
+// Replies are matched through the frame correlation ID and must thus be
+// sent with one of the Send*Reply methods. Such replies are neither
+// forwarded nor passed to the ServerNotifier.
+// If ctx expires before the reply arrives, ctx.Err() is returned.
+func (server *Server) SendCommandWait(ctx context.Context, uuid string, cmd Command, payload []byte) (*Frame, error) {
+	id, reply := server.replies.add(uuid)
+
+	_, err := server.sendCommand(uuid, cmd, payload, server.trace, id)
+	if err != nil {
+		server.replies.remove(id)
+		return nil, err
+	}
+
+	return server.replies.wait(ctx, id, reply)
+}
+
+// SendStatusReply sends a specific status and its payload to the SSNTP client
+// with the specified uuid, as a reply to the frame command frame.
+func (server *Server) SendStatusReply(uuid string, frame *Frame, status Status, payload []byte) (int, error) {
+	return server.sendStatus(uuid, status, payload, server.trace, frame.CorrelationID)
+}
+
+// SendEventReply sends a specific event and its payload to the SSNTP client
+// with the specified uuid, as a reply to the frame command frame.
+func (server *Server) SendEventReply(uuid string, frame *Frame, event Event, payload []byte) (int, error) {
+	return server.sendEvent(uuid, event, payload, server.trace, frame.CorrelationID)
+}
+
+// SendErrorReply sends an error back to the SSNTP client with the specified
+// uuid, as a reply to the frame command frame.
+func (server *Server) SendErrorReply(uuid string, frame *Frame, error Error, payload []byte) (int, error) {
+	return server.sendError(uuid, error, payload, server.trace, frame.CorrelationID)
 }
 
 // UUID exports the SSNTP server Universally Unique ID.
@@ -463,3 +652,41 @@ func (server *Server) ClientRole(uuid string) (Role, error) {
 	}
 	return session.destRole, nil
 }
+
+// ClientMinor returns the SSNTP minor version negotiated with the ssntp
+// session peer with the specified uuid.
+func (server *Server) ClientMinor(uuid string) (uint8, error) {
+	server.sessionMutex.RLock()
+	session := server.sessions[uuid]
+	defer server.sessionMutex.RUnlock()
+	if session == nil {
+		return 0, fmt.Errorf("SSNTP session missing for uuid %s", uuid)
+	}
+	return session.peerMinor, nil
+}
+
+// ClientCapabilities returns the SSNTP capabilities of the ssntp session
+// peer with the specified uuid.
+// Sending a command the client does not support fails with an
+// UnsupportedCommandError.
+func (server *Server) ClientCapabilities(uuid string) (Capability, error) {
+	server.sessionMutex.RLock()
+	session := server.sessions[uuid]
+	defer server.sessionMutex.RUnlock()
+	if session == nil {
+		return 0, fmt.Errorf("SSNTP session missing for uuid %s", uuid)
+	}
+	return session.peerCapabilities, nil
+}
+
+// PayloadEncoding returns the most efficient payload encoding the ssntp
+// session peer with the specified uuid can parse.
+func (server *Server) PayloadEncoding(uuid string) (payloads.Encoding, error) {
+	server.sessionMutex.RLock()
+	session := server.sessions[uuid]
+	defer server.sessionMutex.RUnlock()
+	if session == nil {
+		return payloads.YAML, fmt.Errorf("SSNTP session missing for uuid %s", uuid)
+	}
+	return session.payloadEncoding(server.capabilities), nil
+}
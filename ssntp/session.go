@@ -19,6 +19,7 @@ package ssntp
 import (
 	"encoding/gob"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/ciao-project/ciao/uuid"
@@ -47,6 +48,26 @@ type session struct {
 	destRole Role
 	conn     net.Conn
 
+	// peerMinor and peerCapabilities are the SSNTP minor version
+	// and capabilities negotiated with the peer on connection.
+	peerMinor        uint8
+	peerCapabilities Capability
+
+	// compressionThreshold is the payload size above which frame
+	// payloads are compressed, 0 if compression is disabled.
+	compressionThreshold int
+
+	// keepaliveTimeout is the maximum time to wait for a frame from
+	// the peer, 0 if keepalives are disabled.
+	keepaliveTimeout time.Duration
+	keepaliveStop    chan struct{}
+
+	// capture records the frames exchanged with the peer, nil if
+	// capturing is disabled.
+	capture *capture
+
+	writeLock sync.Mutex
+
 	encoder *gob.Encoder
 	decoder *gob.Decoder
 }
@@ -75,7 +96,12 @@ func (session *session) setDest(uuid []byte) {
 	copy(session.dest[:], uuid[:16])
 }
 
-func (session *session) connectedFrame(serverRole Role, payload []byte) (f *ConnectedFrame) {
+func (session *session) setPeer(peerMinor uint8, peerCapabilities Capability) {
+	session.peerMinor = peerMinor
+	session.peerCapabilities = peerCapabilities
+}
+
+func (session *session) connectedFrame(serverRole Role, capabilities Capability, payload []byte) (f *ConnectedFrame) {
 	f = &ConnectedFrame{
 		Major:         Major,
 		Minor:         minor,
@@ -86,20 +112,24 @@ func (session *session) connectedFrame(serverRole Role, payload []byte) (f *Conn
 		Destination:   session.dest[:],
 		PayloadLength: (uint32)(len(payload)),
 		Payload:       payload,
+		MinMinor:      minMinor,
+		Capabilities:  capabilities,
 	}
 
 	return
 }
 
-func (session *session) connectFrame() (f *ConnectFrame) {
+func (session *session) connectFrame(capabilities Capability) (f *ConnectFrame) {
 	f = &ConnectFrame{
-		Major:       Major,
-		Minor:       minor,
-		Type:        COMMAND,
-		Operand:     byte(CONNECT),
-		Role:        session.srcRole,
-		Source:      session.src[:],
-		Destination: session.dest[:],
+		Major:        Major,
+		Minor:        minor,
+		Type:         COMMAND,
+		Operand:      byte(CONNECT),
+		Role:         session.srcRole,
+		Source:       session.src[:],
+		Destination:  session.dest[:],
+		MinMinor:     minMinor,
+		Capabilities: capabilities,
 	}
 
 	return
@@ -174,27 +204,57 @@ func (session *session) errorFrame(error Error, payload []byte, trace *TraceConf
 }
 
 func (session *session) Write(frame interface{}) (int, error) {
+	var captured *Frame
+
 	switch f := frame.(type) {
 	case *Frame:
-		if f.PathTrace() == false {
-			break
+		captured = f
+
+		if f.PathTrace() == true {
+			f.Trace.Path[f.Trace.PathLength-1].TxTimestamp = time.Now()
 		}
 
-		f.Trace.Path[f.Trace.PathLength-1].TxTimestamp = time.Now()
+		compressed, err := session.compressFrame(f)
+		if err != nil {
+			return 0, err
+		}
+		frame = compressed
 	}
 
+	session.writeLock.Lock()
 	setWriteTimeout(session.conn)
 	err := session.encoder.Encode(frame)
 	clearWriteTimeout(session.conn)
+	session.writeLock.Unlock()
+
+	if captured != nil && err == nil && session.capture != nil {
+		session.capture.record(session, CaptureTx, captured)
+	}
 
 	return 0, err
 }
 
 func (session *session) Read(frame interface{}) error {
+	if session.keepaliveTimeout > 0 {
+		session.conn.SetReadDeadline(time.Now().Add(session.keepaliveTimeout))
+	}
+
 	err := session.decoder.Decode(frame)
+	if err != nil {
+		return err
+	}
 
 	switch f := frame.(type) {
 	case *Frame:
+		err = session.decompressFrame(f)
+		if err != nil {
+			return err
+		}
+
+		if session.capture != nil {
+			session.capture.record(session, CaptureRx, f)
+		}
+
 		if f.PathTrace() == false {
 			break
 		}
@@ -26,11 +26,13 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/ciao-project/ciao/uuid"
 	"github.com/golang/glog"
@@ -42,7 +44,9 @@ type Type uint8
 
 // Command is the SSNTP Command operand.
 // It can be CONNECT, START, STOP, STATS, EVACUATE, DELETE, RESTART,
-// AssignPublicIP, ReleasePublicIP, CONFIGURE or AttachVolume.
+// AssignPublicIP, ReleasePublicIP, CONFIGURE, AttachVolume, REBOOT,
+// PAUSE, UNPAUSE, SUSPEND, RESUME, MIGRATE, CONSOLE, ExtendVolume or
+// DetachVolume.
 type Command uint8
 
 // Status is the SSNTP Status operand.
@@ -56,13 +60,17 @@ type Role uint32
 // Error is the SSNTP Error operand.
 // It can be InvalidFrameType Error, StartFailure,
 // StopFailure, ConnectionFailure, RestartFailure,
-// DeleteFailure, ConnectionAborted or InvalidConfiguration.
+// DeleteFailure, ConnectionAborted, InvalidConfiguration,
+// UnauthorizedFrame, RebootFailure, PauseFailure, UnpauseFailure,
+// SuspendFailure, ResumeFailure, MigrateFailure, ConsoleFailure,
+// ExtendVolumeFailure or DetachVolumeFailure.
 type Error uint8
 
 // Event is the SSNTP Event operand.
 // It can be TenantAdded, TenantRemoval, InstanceDeleted, InstanceStopped,
 // ConcentratorInstanceAdded, PublicIPAssigned, PublicIPUnassigned, TraceReport,
-// NodeConnected or NodeDisconnected
+// NodeConnected, NodeDisconnected, MigrationTargetReady, InstanceMigrated,
+// ConsoleLog or ConsoleReady
 type Event uint8
 
 const (
@@ -229,6 +237,141 @@ const (
 	//	|       |       | (0x0) |  (0x4)  |                 |                             |
 	//	+---------------------------------------------------------------------------------+
 	Restore
+
+	// PING is periodically sent by SSNTP clients and servers to check that their
+	// peer is still alive. PING frames are answered with a PONG status frame by the
+	// SSNTP package itself and are never passed to SSNTP users. They are only sent
+	// to peers advertising the Keepalive capability.
+	//
+	//					 SSNTP PING Command frame
+	//
+	//	+---------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length |
+	//	|       |       | (0x0) |  (0xa)  |       (0x0)     |
+	//	+---------------------------------------------------+
+	PING
+
+	// REBOOT is a command sent to ciao-launcher for rebooting a running instance.
+	// Soft reboots go through an ACPI power down of the guest, hard reboots
+	// reset the instance right away.
+	//
+	// The REBOOT command payload includes an instance UUID, an agent UUID and
+	// the reboot type.
+	//
+	//                                       SSNTP REBOOT Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xb)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	REBOOT
+
+	// PAUSE is a command sent to ciao-launcher for freezing the execution of
+	// a running instance. The instance keeps all its resources and can be
+	// resumed with the UNPAUSE command.
+	//
+	// The PAUSE command payload includes an instance UUID and an agent UUID.
+	//
+	//                                       SSNTP PAUSE Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xc)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	PAUSE
+
+	// UNPAUSE is a command sent to ciao-launcher for resuming the execution
+	// of a paused instance.
+	//
+	// The UNPAUSE command payload includes an instance UUID and an agent UUID.
+	//
+	//                                       SSNTP UNPAUSE Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xd)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	UNPAUSE
+
+	// SUSPEND is a command sent to ciao-launcher for saving the state of a
+	// running instance to the compute node disk and then stopping it. A
+	// suspended instance no longer uses any CPU or memory and can be
+	// brought back with the RESUME command.
+	//
+	// The SUSPEND command payload includes an instance UUID and an agent UUID.
+	//
+	//                                       SSNTP SUSPEND Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xe)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	SUSPEND
+
+	// RESUME is a command sent to ciao-launcher for restarting a suspended
+	// instance from its saved state.
+	//
+	// The RESUME command payload includes an instance UUID and an agent UUID.
+	//
+	//                                       SSNTP RESUME Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xf)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	RESUME
+
+	// MIGRATE is a command sent to the ciao-launcher running an instance
+	// for live migrating that instance to another node. The target node
+	// must already be running the instance, waiting for its state, as
+	// notified by a MigrationTargetReady event.
+	//
+	// The MIGRATE command payload includes an instance UUID, the source and
+	// target agent UUIDs and the URI the target instance is listening on.
+	//
+	//                                       SSNTP MIGRATE Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x10) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	MIGRATE
+
+	// CONSOLE is a command sent to the ciao-launcher running an instance
+	// for accessing the serial console of that instance. It either asks for
+	// the tail of the console log, replied to with a ConsoleLog event, or for
+	// an interactive console, replied to with a ConsoleReady event.
+	//
+	// The CONSOLE command payload includes an instance UUID, an agent UUID,
+	// a request UUID used to match the reply with the command and the type
+	// of console access requested.
+	//
+	//                                       SSNTP CONSOLE Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x11) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	CONSOLE
+
+	// ExtendVolume is a command sent to the ciao-launcher running an instance
+	// to which a storage volume is attached, once that volume has been grown,
+	// so that the instance is notified of the new size of the volume.
+	//
+	// The ExtendVolume command payload includes a volume UUID, an instance UUID,
+	// an agent UUID and the new size of the volume.
+	//
+	//                                       SSNTP ExtendVolume Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x12) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	ExtendVolume
+
+	// DetachVolume is a command sent to ciao-launcher for detaching a storage volume
+	// from a specific instance.  Volumes are hot unplugged from running VMs.
+	//
+	// The DetachVolume command payload includes a volume UUID and an instance UUID.
+	//
+	//                                       SSNTP DetachVolume Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x13) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	DetachVolume
 )
 
 const (
@@ -293,6 +436,17 @@ const (
 	//	|       |       | (0x1) |  (0x4)  |       (0x0)     |
 	//	+---------------------------------------------------+
 	MAINTENANCE
+
+	// PONG is the reply to a PING command. Like PING, it is handled by the SSNTP
+	// package and never passed to SSNTP users.
+	//
+	//					 SSNTP PONG Status frame
+	//
+	//	+---------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length |
+	//	|       |       | (0x1) |  (0x5)  |       (0x0)     |
+	//	+---------------------------------------------------+
+	PONG
 )
 
 const (
@@ -443,6 +597,58 @@ const (
 	//	|       |       | (0x3) |  (0x2)  |                 | instance information  |
 	//	+---------------------------------------------------------------------------+
 	InstanceStopped
+
+	// MigrationTargetReady is sent by workload agents to notify the Controller that
+	// an instance has been started on their node as the target of a live migration
+	// and is waiting for its state to be sent by the source node.
+	// The MigrationTargetReady event payload contains the instance and node UUIDs
+	// and the URI the target instance is listening on.
+	//
+	//					 SSNTP MigrationTargetReady Event frame
+	//
+	//	+---------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted        |
+	//	|       |       | (0x3) |  (0xa)  |                 | migration information |
+	//	+---------------------------------------------------------------------------+
+	MigrationTargetReady
+
+	// InstanceMigrated is sent by workload agents to notify the Controller that
+	// an instance has been live migrated from their node to another one. The local
+	// state of the instance has been deleted from the source node.
+	//
+	//					 SSNTP InstanceMigrated Event frame
+	//
+	//	+---------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted        |
+	//	|       |       | (0x3) |  (0xb)  |                 | migration information |
+	//	+---------------------------------------------------------------------------+
+	InstanceMigrated
+
+	// ConsoleLog is sent by workload agents in reply to a CONSOLE command asking
+	// for the console log of an instance. The ConsoleLog event payload contains
+	// the instance, node and request UUIDs and the tail of the console log.
+	//
+	//					 SSNTP ConsoleLog Event frame
+	//
+	//	+---------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted        |
+	//	|       |       | (0x3) |  (0xc)  |                 | console log           |
+	//	+---------------------------------------------------------------------------+
+	ConsoleLog
+
+	// ConsoleReady is sent by workload agents in reply to a CONSOLE command asking
+	// for an interactive console. The ConsoleReady event payload contains the
+	// instance, node and request UUIDs, the address on which the agent waits for a
+	// single connection to the instance serial console and the token that must be
+	// sent first on that connection.
+	//
+	//					 SSNTP ConsoleReady Event frame
+	//
+	//	+---------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted        |
+	//	|       |       | (0x3) |  (0xd)  |                 | console information   |
+	//	+---------------------------------------------------------------------------+
+	ConsoleReady
 )
 
 // SSNTP clients and servers can have one or several roles and are expected to declare their
@@ -534,15 +740,55 @@ const (
 	// UnassignPublicIPFailure is sent by the CNCI when a an external IP
 	// cannot be unassigned.
 	UnassignPublicIPFailure
+
+	// UnauthorizedFrame is sent by SSNTP servers to reject a frame that
+	// the client role is not allowed to send. The rejected frame is
+	// neither forwarded nor notified.
+	UnauthorizedFrame
+
+	// RebootFailure is sent by launcher agents to report a workload reboot failure.
+	RebootFailure
+
+	// PauseFailure is sent by launcher agents to report a workload pause failure.
+	PauseFailure
+
+	// UnpauseFailure is sent by launcher agents to report a workload unpause failure.
+	UnpauseFailure
+
+	// SuspendFailure is sent by launcher agents to report a workload suspend failure.
+	SuspendFailure
+
+	// ResumeFailure is sent by launcher agents to report a workload resume failure.
+	ResumeFailure
+
+	// MigrateFailure is sent by launcher agents to report a workload live
+	// migration failure, either on the source or on the target node.
+	MigrateFailure
+
+	// ConsoleFailure is sent by launcher agents to report that the console
+	// of a workload could not be accessed.
+	ConsoleFailure
+
+	// ExtendVolumeFailure is sent by launcher agents to report that a
+	// workload could not be notified of the new size of a volume.
+	ExtendVolumeFailure
+
+	// DetachVolumeFailure is sent by launcher agents to report a failure to detach
+	// a volume from an instance.
+	DetachVolumeFailure
 )
 
 // Major is the SSNTP protocol major version
 const Major = 0
-const minor = 1
+const minor = 2
+
+// minMinor is the oldest SSNTP minor version we can talk to.
+const minMinor = 1
 const defaultURL = "localhost"
 const port = 8888
 const readTimeout = 30
 const writeTimeout = 30
+const dialTimeout = 5
 
 // UUIDPrefix is the default storage path for persistent UUIDs
 const UUIDPrefix = "/var/lib/ciao/local/uuid-storage/role"
@@ -585,6 +831,26 @@ func (command Command) String() string {
 		return "Attach storage volume"
 	case Restore:
 		return "Restore"
+	case PING:
+		return "PING"
+	case REBOOT:
+		return "REBOOT"
+	case PAUSE:
+		return "PAUSE"
+	case UNPAUSE:
+		return "UNPAUSE"
+	case SUSPEND:
+		return "SUSPEND"
+	case RESUME:
+		return "RESUME"
+	case MIGRATE:
+		return "MIGRATE"
+	case CONSOLE:
+		return "CONSOLE"
+	case ExtendVolume:
+		return "Extend storage volume"
+	case DetachVolume:
+		return "Detach storage volume"
 	}
 
 	return ""
@@ -602,6 +868,8 @@ func (status Status) String() string {
 		return "OFFLINE"
 	case MAINTENANCE:
 		return "MAINTENANCE"
+	case PONG:
+		return "PONG"
 	}
 
 	return ""
@@ -629,6 +897,14 @@ func (status Event) String() string {
 		return "Node Connected"
 	case NodeDisconnected:
 		return "Node Disconnected"
+	case MigrationTargetReady:
+		return "Migration Target Ready"
+	case InstanceMigrated:
+		return "Instance Migrated"
+	case ConsoleLog:
+		return "Console Log"
+	case ConsoleReady:
+		return "Console Ready"
 	}
 
 	return ""
@@ -652,6 +928,26 @@ func (error Error) String() string {
 		return "SSNTP Connection aborted"
 	case InvalidConfiguration:
 		return "Cluster configuration is invalid"
+	case UnauthorizedFrame:
+		return "Frame not authorized for SSNTP role"
+	case RebootFailure:
+		return "Could not reboot instance"
+	case PauseFailure:
+		return "Could not pause instance"
+	case UnpauseFailure:
+		return "Could not unpause instance"
+	case SuspendFailure:
+		return "Could not suspend instance"
+	case ResumeFailure:
+		return "Could not resume instance"
+	case MigrateFailure:
+		return "Could not migrate instance"
+	case ConsoleFailure:
+		return "Could not access instance console"
+	case ExtendVolumeFailure:
+		return "Could not extend volume"
+	case DetachVolumeFailure:
+		return "Could not detach volume"
 	}
 
 	return ""
@@ -823,6 +1119,12 @@ type Config struct {
 	// and IPs on the running host.
 	URI string
 
+	// URIs is an optional list of fallback SSNTP server URIs for
+	// clients. Clients try them in order, after URI, whenever they
+	// connect or lose their connection to a server. Entries may
+	// specify a port, otherwise Port is used.
+	URIs []string
+
 	// CACert is the Certification Authority certificate path
 	// to use when verifiying the peer identity.
 	// If set to "", /etc/pki/ciao/ciao_ca_cert.crt will be used.
@@ -833,6 +1135,16 @@ type Config struct {
 	// will be used for SSNTP clients and server, respectively.
 	Cert string
 
+	// CRL is the optional path to a PEM encoded certificate revocation
+	// list, signed by the Certification Authority. Peers presenting a
+	// revoked certificate are refused.
+	// The CRL and Cert files are reloaded when they change, so that
+	// certificates can be revoked or rolled without restarting. SSNTP
+	// servers check them every 30 seconds and disconnect the clients
+	// which certificate gets revoked. SSNTP clients check them whenever
+	// they (re)connect.
+	CRL string
+
 	// Transport is the underlying transport protocol. Only "tcp" and "unix"
 	// transports are supported. The default is "tcp".
 	Transport string
@@ -840,6 +1152,13 @@ type Config struct {
 	// ForwardRules is optional and contains a list of frame forwarding rules.
 	ForwardRules []FrameForwardRule
 
+	// AuthorizationRules is optional and contains the list of frames
+	// SSNTP server clients are allowed to send, depending on their role.
+	// Frames not matching any rule are rejected with an UnauthorizedFrame
+	// error. If nil, clients can send any frame.
+	// DefaultAuthorizationRules matches the frames ciao components send.
+	AuthorizationRules []FrameAuthorizationRule
+
 	// Log is the SSNTP logging interface.
 	// If not set, only error messages will be logged.
 	// The SSNTP Log implementation provides a default logger.
@@ -852,6 +1171,13 @@ type Config struct {
 	// Trace configures the desired level of SSNTP frame tracing.
 	Trace *TraceConfig
 
+	// Capture is the optional path of a file to append a record of
+	// every frame sent and received to, for debugging purposes. Frames
+	// are recorded as CapturedFrame YAML documents, with their decoded
+	// payload, and can be replayed through the Client and Server Replay
+	// methods. Captures can be inspected with ciao-ssntp-dump.
+	Capture string
+
 	// SyncChannel is an optional channel provided by SSNTP servers
 	// and clients to get respectively notified about their Serve()
 	// and Dial() calls.
@@ -862,6 +1188,31 @@ type Config struct {
 	// pushed to SyncChannel
 	SyncChannel chan error
 
+	// Capabilities is an optional bitmap of the SSNTP capabilities
+	// to advertise to peers. Peers will refuse to send us commands
+	// requiring a capability we do not advertise.
+	// If set to 0, all the capabilities defined in Capabilities are
+	// advertised.
+	Capabilities Capability
+
+	// CompressionThreshold is the payload size, in bytes, above which
+	// frame payloads are gzip compressed when the peer supports it.
+	// Large STATS and TraceReport payloads shrink a lot when compressed.
+	// If set to 0, payloads larger than 4KiB are compressed. A negative
+	// value disables compression.
+	CompressionThreshold int
+
+	// KeepaliveInterval is the interval at which PING frames are sent
+	// to peers supporting them. If set to 0, the default interval of
+	// 15 seconds is used. A negative value disables keepalives.
+	KeepaliveInterval time.Duration
+
+	// KeepaliveMisses is the number of keepalive intervals without
+	// receiving any frame after which a peer is considered dead and
+	// disconnected. If set to 0, peers are disconnected after 3 missed
+	// intervals.
+	KeepaliveMisses int
+
 	// ConfigURI contains the location of the configuration that the
 	// SSNTP server will fetch to setup the cluster.
 	ConfigURI string
@@ -950,7 +1301,7 @@ func (conf *clusterConfiguration) setConfiguration(configuration []byte) {
 	conf.Unlock()
 }
 
-func prepareTLSConfig(config *Config, server bool) *tls.Config {
+func prepareTLSConfig(config *Config, server bool) (*tls.Config, *credentials) {
 	caPEM, err := ioutil.ReadFile(config.CAcert)
 	if err != nil {
 		log.Fatalf("SSNTP: Load CA certificate: %s", err)
@@ -961,7 +1312,18 @@ func prepareTLSConfig(config *Config, server bool) *tls.Config {
 		log.Fatalf("SSNTP: Load Certificate: %s", err)
 	}
 
-	return prepareTLS(caPEM, certPEM, server, config.Rand)
+	tlsConfig := prepareTLS(caPEM, certPEM, server, config.Rand)
+	if tlsConfig == nil {
+		return nil, nil
+	}
+
+	creds, err := newCredentials(config.Cert, config.CRL, caPEM)
+	if err != nil {
+		log.Fatalf("SSNTP: Load credentials: %s", err)
+	}
+	creds.setup(tlsConfig, server)
+
+	return tlsConfig, creds
 }
 
 func prepareTLS(caPEM, certPEM []byte, server bool, rand io.Reader) *tls.Config {
@@ -1071,6 +1433,76 @@ func verifyRole(conn interface{}, role Role) (bool, error) {
 	return false, oidError
 }
 
+// PeerTLSConfig returns a TLS configuration, built from the SSNTP
+// certificates of config, for connections between cluster components that
+// do not go through SSNTP, e.g. interactive consoles.
+// Peers must present a certificate signed by the SSNTP CA and carrying the
+// peerRole role. Node certificates do not necessarily name the addresses
+// nodes listen on, so host names are not verified.
+func PeerTLSConfig(config *Config, server bool, peerRole Role) (*tls.Config, error) {
+	caPEM, err := ioutil.ReadFile(config.CAcert)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM, err := ioutil.ReadFile(config.Cert)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := prepareTLS(caPEM, certPEM, server, config.Rand)
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("Could not load SSNTP certificates")
+	}
+
+	roots := tlsConfig.RootCAs
+	if server {
+		tlsConfig.ClientAuth = tls.RequireAnyClientCert
+	} else {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verifyPeerRole(rawCerts, roots, peerRole)
+	}
+
+	return tlsConfig, nil
+}
+
+func verifyPeerRole(rawCerts [][]byte, roots *x509.CertPool, role Role) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("No peer certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return err
+	}
+
+	if GetRoleFromOIDs(certs[0].UnknownExtKeyUsage)&role != role {
+		return fmt.Errorf("Wrong certificate or missing/mismatched role OID")
+	}
+
+	return nil
+}
+
 func (config *Config) pushToSyncChannel(err error) {
 	if config.SyncChannel != nil {
 		config.SyncChannel <- err
@@ -1168,6 +1600,15 @@ func (config *Config) ConfigURIs(uris []string, port uint32) []string {
 		uris = append(uris, fmt.Sprintf("%s:%d", config.URI, port))
 	}
 
+	/* Then the fallback ones */
+	for _, uri := range config.URIs {
+		if _, _, err := net.SplitHostPort(uri); err == nil {
+			uris = append(uris, uri)
+		} else {
+			uris = append(uris, fmt.Sprintf("%s:%d", uri, port))
+		}
+	}
+
 	/* Then we parse the CA certificate to find FQDNs and/or IPs to connect to */
 	ips, fqdns, err := config.parseCertificateAuthority()
 	if err == nil {
@@ -1196,6 +1637,36 @@ func (config *Config) role() (Role, error) {
 	return role, nil
 }
 
+func (config *Config) capabilities() Capability {
+	if config.Capabilities == 0 {
+		return Capabilities
+	}
+
+	return config.Capabilities
+}
+
+func (config *Config) compressionThreshold() int {
+	if config.CompressionThreshold == 0 {
+		return defaultCompressionThreshold
+	}
+
+	return config.CompressionThreshold
+}
+
+func (config *Config) keepalive() (time.Duration, int) {
+	interval := config.KeepaliveInterval
+	if interval == 0 {
+		interval = defaultKeepaliveInterval
+	}
+
+	misses := config.KeepaliveMisses
+	if misses <= 0 {
+		misses = defaultKeepaliveMisses
+	}
+
+	return interval, misses
+}
+
 func (config *Config) port() uint32 {
 	if config.Port != 0 {
 		return config.Port
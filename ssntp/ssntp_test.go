@@ -18,9 +18,13 @@ package ssntp_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/asn1"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -29,7 +33,9 @@ import (
 	"time"
 
 	. "github.com/ciao-project/ciao/ssntp"
+	"github.com/ciao-project/ciao/ssntp/certs"
 	"github.com/ciao-project/ciao/testutil"
+	"github.com/ciao-project/ciao/uuid"
 )
 
 const tempCertPath = "/tmp/ssntp-test-certs"
@@ -994,6 +1000,45 @@ func TestConnectPort(t *testing.T) {
 	server.ssntp.Stop()
 }
 
+// Test SSNTP client connection to a fallback server URI
+//
+// Test that an SSNTP client fails over to the next server URI
+// when the first one does not answer.
+//
+// Test is expected to pass.
+func TestConnectFallbackURI(t *testing.T) {
+	var server ssntpEchoServer
+	var client ssntpClient
+
+	server.t = t
+	serverConfig, err := buildTestConfig(SERVER)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	serverConfig.Port = 9998
+
+	client.t = t
+	clientConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	clientConfig.URI = "localhost"
+	clientConfig.URIs = []string{"localhost:9998"}
+	clientConfig.Port = 9997
+
+	err = server.ssntp.ServeThreadSync(serverConfig, &server)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	err = client.ssntp.Dial(clientConfig, &client)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+	client.ssntp.Close()
+	server.ssntp.Stop()
+}
+
 func testMultiURIs(t *testing.T, CACert string, expectedURIs []string, configURI string, configPort uint32) {
 	var role Role = AGENT
 
@@ -1050,6 +1095,36 @@ func TestURIMultiHomedConfigured(t *testing.T) {
 		[]string{"192.168.0.0", "clearlinux.org", "intel.com"}, "github.com", 8888)
 }
 
+// Test the URI list building routine with fallback URIs
+//
+// Test that when passing fallback server URIs through the SSNTP
+// configuration they come right after the configured URI, with
+// the configured port when they do not specify one.
+//
+// Test is expected to pass
+func TestURIFallbacks(t *testing.T) {
+	clientConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	clientConfig.URI = "primary"
+	clientConfig.URIs = []string{"standby", "10.0.0.2:9999"}
+
+	parsedURIs := clientConfig.ConfigURIs(nil, 8888)
+
+	expectedURIs := []string{"primary:8888", "standby:8888", "10.0.0.2:9999"}
+	if len(parsedURIs) < len(expectedURIs) {
+		t.Fatalf("Wrong parsed URI slice length %d", len(parsedURIs))
+	}
+
+	for i, uri := range expectedURIs {
+		if uri != parsedURIs[i] {
+			t.Fatalf("Index %d: Mismatch URI %s vs %s", i, uri, parsedURIs[i])
+		}
+	}
+}
+
 // Test the CA parsing routine for a single URI configuration and an empty CA
 //
 // Test that we only get the localhost from the default CA.
@@ -2463,50 +2538,857 @@ var (
 	payloadSize = flag.Int("payload", 1<<11, "Frames payload size")
 )
 
-func TestCommandStringer(t *testing.T) {
-	var stringTests = []struct {
-		cmd      Command
-		expected string
-	}{
-		{CONNECT, "CONNECT"},
-		{START, "START"},
-		{STATS, "STATISTICS"},
-		{EVACUATE, "EVACUATE"},
-		{DELETE, "DELETE"},
-		{AssignPublicIP, "Assign public IP"},
-		{ReleasePublicIP, "Release public IP"},
-		{CONFIGURE, "CONFIGURE"},
-		{AttachVolume, "Attach storage volume"},
+// Test SSNTP capability negotiation
+//
+// Start a server that only advertises the EVACUATE capability and
+// connect a client to it. The client should see the server capabilities
+// and minor version, the server should see the client ones.
+// Sending an AttachVolume command to the server should fail locally
+// with an UnsupportedCommandError, while EVACUATE should succeed.
+//
+// Test is expected to pass.
+func TestCapabilities(t *testing.T) {
+	var server ssntpServer
+	var client ssntpClient
+
+	server.t = t
+	client.t = t
+
+	serverConfig, err := buildTestConfig(SERVER)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
 	}
+	serverConfig.Capabilities = EvacuateCapability
 
-	for _, test := range stringTests {
-		str := test.cmd.String()
-		if str != test.expected {
-			t.Errorf("expected \"%s\", got \"%s\"", test.expected, str)
-		}
+	clientConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	err = server.ssntp.ServeThreadSync(serverConfig, &server)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer server.ssntp.Stop()
+
+	err = client.ssntp.Dial(clientConfig, &client)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+	defer client.ssntp.Close()
+
+	if client.ssntp.ServerCapabilities() != EvacuateCapability {
+		t.Fatalf("Wrong server capabilities %s", client.ssntp.ServerCapabilities())
+	}
+
+	if client.ssntp.ServerMinor() == 0 {
+		t.Fatalf("Server minor version was not negotiated")
+	}
+
+	caps, err := server.ssntp.ClientCapabilities(client.ssntp.UUID())
+	if err != nil {
+		t.Fatalf("Could not get client capabilities: %s", err)
+	}
+
+	if caps != Capabilities {
+		t.Fatalf("Wrong client capabilities %s", caps)
+	}
+
+	_, err = client.ssntp.SendCommand(AttachVolume, nil)
+	if _, ok := err.(*UnsupportedCommandError); !ok {
+		t.Fatalf("Expected UnsupportedCommandError, got %v", err)
+	}
+
+	_, err = client.ssntp.SendCommand(EVACUATE, nil)
+	if err != nil {
+		t.Fatalf("Could not send EVACUATE: %s", err)
 	}
 }
 
-func TestStatusStringer(t *testing.T) {
-	var stringTests = []struct {
-		sta      Status
+// Test the capability stringer
+//
+// Test is expected to pass.
+func TestCapabilityStringer(t *testing.T) {
+	tests := []struct {
+		c        Capability
 		expected string
 	}{
-		{CONNECTED, "CONNECTED"},
-		{READY, "READY"},
-		{FULL, "FULL"},
-		{OFFLINE, "OFFLINE"},
-		{MAINTENANCE, "MAINTENANCE"},
+		{0, ""},
+		{EvacuateCapability, "Evacuate"},
+		{RestoreCapability | PublicIPCapability, "Restore|PublicIP"},
+		{Capabilities, "Evacuate|Restore|AttachVolume|PublicIP|GobPayload|Compression|Keepalive|Power|Migrate|Console|ExtendVolume|DetachVolume"},
+		{AttachVolumeCapability | 1<<63, "AttachVolume|0x8000000000000000"},
 	}
 
-	for _, test := range stringTests {
-		str := test.sta.String()
-		if str != test.expected {
-			t.Errorf("expected \"%s\", got \"%s\"", test.expected, str)
+	for _, test := range tests {
+		if test.c.String() != test.expected {
+			t.Errorf("Expected %s, got %s", test.expected, test.c.String())
 		}
 	}
 }
 
+type ssntpReplyServer struct {
+	ssntp Server
+}
+
+func (server *ssntpReplyServer) ConnectNotify(uuid string, role Role) {
+}
+
+func (server *ssntpReplyServer) DisconnectNotify(uuid string, role Role) {
+}
+
+func (server *ssntpReplyServer) StatusNotify(uuid string, status Status, frame *Frame) {
+}
+
+func (server *ssntpReplyServer) CommandNotify(uuid string, command Command, frame *Frame) {
+	if command == START {
+		server.ssntp.SendErrorReply(uuid, frame, StartFailure, frame.Payload)
+	}
+}
+
+func (server *ssntpReplyServer) EventNotify(uuid string, event Event, frame *Frame) {
+}
+
+func (server *ssntpReplyServer) ErrorNotify(uuid string, error Error, frame *Frame) {
+}
+
+type ssntpReplyClient struct {
+	ssntp Client
+}
+
+func (client *ssntpReplyClient) ConnectNotify() {
+}
+
+func (client *ssntpReplyClient) DisconnectNotify() {
+}
+
+func (client *ssntpReplyClient) StatusNotify(status Status, frame *Frame) {
+}
+
+func (client *ssntpReplyClient) CommandNotify(command Command, frame *Frame) {
+	client.ssntp.SendEventReply(frame, InstanceDeleted, frame.Payload)
+}
+
+func (client *ssntpReplyClient) EventNotify(event Event, frame *Frame) {
+}
+
+func (client *ssntpReplyClient) ErrorNotify(error Error, frame *Frame) {
+}
+
+// Test synchronous SSNTP commands
+//
+// Connect a client to a server replying to START commands with a
+// correlated StartFailure error. Send a START command with
+// Client.SendCommandWait and then a DELETE command with
+// Server.SendCommandWait, the client replying with a correlated
+// InstanceDeleted event.
+//
+// Both calls should return the matching replies.
+//
+// Test is expected to pass.
+func TestSendCommandWait(t *testing.T) {
+	var server ssntpReplyServer
+	var client ssntpReplyClient
+
+	serverConfig, err := buildTestConfig(SERVER)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	clientConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	err = server.ssntp.ServeThreadSync(serverConfig, &server)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer server.ssntp.Stop()
+
+	err = client.ssntp.Dial(clientConfig, &client)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+	defer client.ssntp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	payload := []byte{'Y', 'A', 'M', 'L'}
+	reply, err := client.ssntp.SendCommandWait(ctx, START, payload)
+	if err != nil {
+		t.Fatalf("No reply to START: %s", err)
+	}
+
+	if reply.Type != ERROR || (Error)(reply.Operand) != StartFailure ||
+		!bytes.Equal(reply.Payload, payload) {
+		t.Fatalf("Wrong reply to START:\n%s", reply)
+	}
+
+	reply, err = server.ssntp.SendCommandWait(ctx, client.ssntp.UUID(), DELETE, payload)
+	if err != nil {
+		t.Fatalf("No reply to DELETE: %s", err)
+	}
+
+	if reply.Type != EVENT || (Event)(reply.Operand) != InstanceDeleted {
+		t.Fatalf("Wrong reply to DELETE:\n%s", reply)
+	}
+}
+
+// Test synchronous SSNTP commands timeout
+//
+// Send a STATS command to a server that does not reply to it with
+// Client.SendCommandWait.
+//
+// The call should time out.
+//
+// Test is expected to pass.
+func TestSendCommandWaitTimeout(t *testing.T) {
+	var server ssntpReplyServer
+	var client ssntpReplyClient
+
+	serverConfig, err := buildTestConfig(SERVER)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	clientConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	err = server.ssntp.ServeThreadSync(serverConfig, &server)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer server.ssntp.Stop()
+
+	err = client.ssntp.Dial(clientConfig, &client)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+	defer client.ssntp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = client.ssntp.SendCommandWait(ctx, STATS, nil)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+}
+
+type ssntpEventClient struct {
+	ssntp  Client
+	events chan []byte
+}
+
+func (client *ssntpEventClient) ConnectNotify() {
+}
+
+func (client *ssntpEventClient) DisconnectNotify() {
+}
+
+func (client *ssntpEventClient) StatusNotify(status Status, frame *Frame) {
+}
+
+func (client *ssntpEventClient) CommandNotify(command Command, frame *Frame) {
+}
+
+func (client *ssntpEventClient) EventNotify(event Event, frame *Frame) {
+	client.events <- frame.Payload
+}
+
+func (client *ssntpEventClient) ErrorNotify(error Error, frame *Frame) {
+}
+
+// Test synchronous SSNTP commands forwarded by the server
+//
+// Connect an agent replying to DELETE commands with a correlated
+// InstanceDeleted event and two Controllers to a server forwarding
+// DELETE commands to agents and InstanceDeleted events to Controllers.
+// Send a DELETE command from the first Controller with
+// Client.SendCommandWait and then an uncorrelated InstanceDeleted event
+// from the agent.
+//
+// The first Controller should get the correlated reply and the second
+// one should only be notified of the uncorrelated event.
+//
+// Test is expected to pass.
+func TestSendCommandWaitForwarded(t *testing.T) {
+	var server ssntpServer
+	var agent, requester ssntpReplyClient
+	var observer ssntpEventClient
+
+	server.t = t
+	serverConfig, err := buildTestConfig(SCHEDULER)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	serverConfig.ForwardRules = []FrameForwardRule{
+		{
+			Operand: DELETE,
+			Dest:    AGENT,
+		},
+		{
+			Operand: InstanceDeleted,
+			Dest:    Controller,
+		},
+	}
+
+	agentConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	requesterConfig, err := buildTestConfig(Controller)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	requesterConfig.UUID = uuid.Generate().String()
+
+	observerConfig, err := buildTestConfig(Controller)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	observerConfig.UUID = uuid.Generate().String()
+	observer.events = make(chan []byte, 2)
+
+	err = server.ssntp.ServeThreadSync(serverConfig, &server)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer server.ssntp.Stop()
+
+	err = agent.ssntp.Dial(agentConfig, &agent)
+	if err != nil {
+		t.Fatalf("Agent failed to connect")
+	}
+	defer agent.ssntp.Close()
+
+	err = requester.ssntp.Dial(requesterConfig, &requester)
+	if err != nil {
+		t.Fatalf("Controller failed to connect")
+	}
+	defer requester.ssntp.Close()
+
+	err = observer.ssntp.Dial(observerConfig, &observer)
+	if err != nil {
+		t.Fatalf("Controller failed to connect")
+	}
+	defer observer.ssntp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	correlated := []byte("correlated")
+	reply, err := requester.ssntp.SendCommandWait(ctx, DELETE, correlated)
+	if err != nil {
+		t.Fatalf("No reply to DELETE: %s", err)
+	}
+
+	if reply.Type != EVENT || (Event)(reply.Operand) != InstanceDeleted ||
+		reply.Origin.String() != agent.ssntp.UUID() {
+		t.Fatalf("Wrong reply to DELETE:\n%s", reply)
+	}
+
+	uncorrelated := []byte("uncorrelated")
+	_, err = agent.ssntp.SendEvent(InstanceDeleted, uncorrelated)
+	if err != nil {
+		t.Fatalf("Could not send event: %s", err)
+	}
+
+	select {
+	case payload := <-observer.events:
+		if !bytes.Equal(payload, uncorrelated) {
+			t.Fatalf("Reply forwarded to the wrong Controller: %s", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Uncorrelated event not forwarded")
+	}
+}
+
+// Test SSNTP frame authorization
+//
+// Connect an AGENT client to a server enforcing the default frame
+// authorization rules and replying to START commands with a correlated
+// StartFailure error. Send a START command with Client.SendCommandWait.
+//
+// The server should reject the START command with a correlated
+// UnauthorizedFrame error, without notifying it.
+//
+// Test is expected to pass.
+func TestAuthorization(t *testing.T) {
+	var server ssntpReplyServer
+	var client ssntpReplyClient
+
+	serverConfig, err := buildTestConfig(SERVER)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	serverConfig.AuthorizationRules = DefaultAuthorizationRules
+
+	clientConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	err = server.ssntp.ServeThreadSync(serverConfig, &server)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer server.ssntp.Stop()
+
+	err = client.ssntp.Dial(clientConfig, &client)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+	defer client.ssntp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	reply, err := client.ssntp.SendCommandWait(ctx, START, nil)
+	if err != nil {
+		t.Fatalf("No reply to START: %s", err)
+	}
+
+	if reply.Type != ERROR || (Error)(reply.Operand) != UnauthorizedFrame {
+		t.Fatalf("Wrong reply to START:\n%s", reply)
+	}
+}
+
+// Test SSNTP keepalives
+//
+// Connect a client to a server with both of them sending PING frames
+// every 20ms, and leave the connection idle for 10 keepalive intervals.
+//
+// Neither the client nor the server should be disconnected.
+//
+// Test is expected to pass.
+func TestKeepalive(t *testing.T) {
+	var server ssntpEchoServer
+	var client ssntpClient
+
+	server.t = t
+	server.roleDisconnectChannel = make(chan string, 1)
+	client.t = t
+	client.disconnected = make(chan struct{})
+
+	serverConfig, err := buildTestConfig(SERVER)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	serverConfig.KeepaliveInterval = 20 * time.Millisecond
+	serverConfig.KeepaliveMisses = 3
+
+	clientConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	clientConfig.KeepaliveInterval = 20 * time.Millisecond
+	clientConfig.KeepaliveMisses = 3
+
+	err = server.ssntp.ServeThreadSync(serverConfig, &server)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer server.ssntp.Stop()
+
+	err = client.ssntp.Dial(clientConfig, &client)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+
+	select {
+	case <-client.disconnected:
+		t.Fatalf("Client disconnected")
+	case role := <-server.roleDisconnectChannel:
+		t.Fatalf("Server disconnected %s", role)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	client.ssntp.Close()
+}
+
+func writeTestCert(t *testing.T, template *x509.Certificate, anchorCert []byte, certPath string) []byte {
+	var cert bytes.Buffer
+
+	err := certs.CreateCert(template, anchorCert, &cert)
+	if err != nil {
+		t.Fatalf("Could not create certificate: %s", err)
+	}
+
+	err = ioutil.WriteFile(certPath, cert.Bytes(), 0600)
+	if err != nil {
+		t.Fatalf("Could not write certificate: %s", err)
+	}
+
+	return cert.Bytes()
+}
+
+// Test SSNTP certificates revocation
+//
+// Start a server with an empty CRL and connect a client to it. Revoke
+// the client certificate and make the server reload its credentials.
+//
+// The server should disconnect the client.
+//
+// Test is expected to pass.
+func TestCertificateRevocation(t *testing.T) {
+	var server ssntpEchoServer
+	var client ssntpClient
+	var anchorCert, caCert, crl bytes.Buffer
+
+	server.t = t
+	server.roleDisconnectChannel = make(chan string, 1)
+	client.t = t
+
+	dir, err := ioutil.TempDir("", "ssntp-crl")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	template, err := certs.CreateCertTemplate(SERVER, "ACME Corp", "test@example.com",
+		[]string{"localhost"}, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Could not create certificate template: %s", err)
+	}
+
+	err = certs.CreateAnchorCert(template, &anchorCert, &caCert)
+	if err != nil {
+		t.Fatalf("Could not create anchor certificate: %s", err)
+	}
+
+	template, err = certs.CreateCertTemplate(AGENT, "ACME Corp", "test@example.com",
+		[]string{"localhost"}, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Could not create certificate template: %s", err)
+	}
+
+	caPath := path.Join(dir, "CAcert.pem")
+	serverPath := path.Join(dir, "cert-Server.pem")
+	agentPath := path.Join(dir, "cert-CNAgent.pem")
+	crlPath := path.Join(dir, "crl.pem")
+
+	agentCert := writeTestCert(t, template, anchorCert.Bytes(), agentPath)
+
+	err = certs.RevokeCerts(anchorCert.Bytes(), nil, nil, &crl)
+	if err != nil {
+		t.Fatalf("Could not create CRL: %s", err)
+	}
+
+	files := []struct {
+		path string
+		data []byte
+	}{
+		{caPath, caCert.Bytes()},
+		{serverPath, anchorCert.Bytes()},
+		{crlPath, crl.Bytes()},
+	}
+	for _, f := range files {
+		err = ioutil.WriteFile(f.path, f.data, 0600)
+		if err != nil {
+			t.Fatalf("Could not write %s: %s", f.path, err)
+		}
+	}
+
+	serverConfig := &Config{
+		Transport: *transport,
+		CAcert:    caPath,
+		Cert:      serverPath,
+		CRL:       crlPath,
+	}
+
+	clientConfig := &Config{
+		Transport: *transport,
+		CAcert:    caPath,
+		Cert:      agentPath,
+	}
+
+	err = server.ssntp.ServeThreadSync(serverConfig, &server)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer server.ssntp.Stop()
+
+	err = client.ssntp.Dial(clientConfig, &client)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+	defer client.ssntp.Close()
+
+	crl.Reset()
+	err = certs.RevokeCerts(anchorCert.Bytes(), nil, [][]byte{agentCert}, &crl)
+	if err != nil {
+		t.Fatalf("Could not revoke certificate: %s", err)
+	}
+
+	err = ioutil.WriteFile(crlPath, crl.Bytes(), 0600)
+	if err != nil {
+		t.Fatalf("Could not write CRL: %s", err)
+	}
+
+	err = server.ssntp.ReloadCredentials()
+	if err != nil {
+		t.Fatalf("Could not reload credentials: %s", err)
+	}
+
+	select {
+	case <-server.roleDisconnectChannel:
+	case <-time.After(time.Second):
+		t.Fatalf("Revoked client not disconnected")
+	}
+}
+
+// Test SSNTP frame capture and replay
+//
+// Start a server capturing frames to a file and have a client send it a
+// START command, which the server echoes back. Then replay the captured
+// START command through another client.
+//
+// The capture should contain the received and echoed START commands and
+// the replayed command should be echoed back to the second client.
+//
+// Test is expected to pass.
+func TestCaptureReplay(t *testing.T) {
+	var server1, server2 ssntpEchoServer
+	var client1, client2 ssntpClient
+
+	dir, err := ioutil.TempDir("", "ssntp-capture")
+	if err != nil {
+		t.Fatalf("Could not create temporary directory: %s", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	capturePath := path.Join(dir, "capture.yaml")
+
+	serverConfig, err := buildTestConfig(SERVER)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+	serverConfig.Capture = capturePath
+
+	clientConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	client1.payload = []byte("start:\n  instance_uuid: 7ac6ba1e-6e6d-4b36-a6c4-d3d8e3e67f45\n")
+	client1.cmdChannel = make(chan string)
+
+	err = server1.ssntp.ServeThreadSync(serverConfig, &server1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	err = client1.ssntp.Dial(clientConfig, &client1)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+
+	_, err = client1.ssntp.SendCommand(START, client1.payload)
+	if err != nil {
+		t.Fatalf("Could not send START: %s", err)
+	}
+
+	select {
+	case <-client1.cmdChannel:
+	case <-time.After(time.Second):
+		t.Fatalf("Did not receive the START echo")
+	}
+
+	client1.ssntp.Close()
+	server1.ssntp.Stop()
+
+	captureFile, err := os.Open(capturePath)
+	if err != nil {
+		t.Fatalf("Could not open capture: %s", err)
+	}
+	defer func() { _ = captureFile.Close() }()
+
+	frames, err := ReadCapture(captureFile)
+	if err != nil {
+		t.Fatalf("Could not read capture: %s", err)
+	}
+
+	var starts []CapturedFrame
+	for _, f := range frames {
+		if f.Operand == START.String() {
+			starts = append(starts, f)
+		}
+	}
+
+	if len(starts) != 2 || starts[0].Direction != CaptureRx || starts[1].Direction != CaptureTx {
+		t.Fatalf("Unexpected captured frames %+v", frames)
+	}
+
+	if starts[0].Payload != string(client1.payload) || starts[0].PeerRole != "CNAgent" {
+		t.Fatalf("Unexpected captured START %+v", starts[0])
+	}
+
+	serverConfig.Capture = ""
+	err = server2.ssntp.ServeThreadSync(serverConfig, &server2)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer server2.ssntp.Stop()
+
+	client2.payload = client1.payload
+	client2.cmdChannel = make(chan string)
+
+	err = client2.ssntp.Dial(clientConfig, &client2)
+	if err != nil {
+		t.Fatalf("Failed to connect")
+	}
+	defer client2.ssntp.Close()
+
+	_, err = client2.ssntp.Replay(&starts[0])
+	if err != nil {
+		t.Fatalf("Could not replay START: %s", err)
+	}
+
+	select {
+	case <-client2.cmdChannel:
+	case <-time.After(time.Second):
+		t.Fatalf("Did not receive the replayed START echo")
+	}
+}
+
+func TestCommandStringer(t *testing.T) {
+	var stringTests = []struct {
+		cmd      Command
+		expected string
+	}{
+		{CONNECT, "CONNECT"},
+		{START, "START"},
+		{STATS, "STATISTICS"},
+		{EVACUATE, "EVACUATE"},
+		{DELETE, "DELETE"},
+		{AssignPublicIP, "Assign public IP"},
+		{ReleasePublicIP, "Release public IP"},
+		{CONFIGURE, "CONFIGURE"},
+		{AttachVolume, "Attach storage volume"},
+		{PING, "PING"},
+		{REBOOT, "REBOOT"},
+		{PAUSE, "PAUSE"},
+		{UNPAUSE, "UNPAUSE"},
+		{SUSPEND, "SUSPEND"},
+		{RESUME, "RESUME"},
+		{MIGRATE, "MIGRATE"},
+		{CONSOLE, "CONSOLE"},
+		{ExtendVolume, "Extend storage volume"},
+		{DetachVolume, "Detach storage volume"},
+	}
+
+	for _, test := range stringTests {
+		str := test.cmd.String()
+		if str != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, str)
+		}
+	}
+}
+
+func TestStatusStringer(t *testing.T) {
+	var stringTests = []struct {
+		sta      Status
+		expected string
+	}{
+		{CONNECTED, "CONNECTED"},
+		{READY, "READY"},
+		{FULL, "FULL"},
+		{OFFLINE, "OFFLINE"},
+		{MAINTENANCE, "MAINTENANCE"},
+		{PONG, "PONG"},
+	}
+
+	for _, test := range stringTests {
+		str := test.sta.String()
+		if str != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, str)
+		}
+	}
+}
+
+func dialPeerTLS(t *testing.T, address string, role Role, peerRole Role) error {
+	config, err := buildTestConfig(role)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	tlsConfig, err := PeerTLSConfig(config, false, peerRole)
+	if err != nil {
+		t.Fatalf("Could not build a TLS config: %s", err)
+	}
+
+	conn, err := tls.Dial("tcp", address, tlsConfig)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	_, _ = conn.Write([]byte("ping\n"))
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 5))
+	return err
+}
+
+// Test TLS configurations for connections outside of SSNTP
+//
+// Start a TLS server with an AGENT certificate only accepting
+// Controllers and connect to it with a Controller certificate, with a
+// Controller certificate expecting a SCHEDULER and with a NETAGENT
+// certificate.
+//
+// Only the first connection should succeed.
+//
+// Test is expected to pass.
+func TestPeerTLSConfig(t *testing.T) {
+	serverConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	tlsConfig, err := PeerTLSConfig(serverConfig, true, Controller)
+	if err != nil {
+		t.Fatalf("Could not build a TLS config: %s", err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if err != nil {
+		t.Fatalf("Could not listen: %s", err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	address := ln.Addr().String()
+
+	if err := dialPeerTLS(t, address, Controller, AGENT); err != nil {
+		t.Fatalf("Controller could not connect: %s", err)
+	}
+
+	if err := dialPeerTLS(t, address, Controller, SCHEDULER); err == nil {
+		t.Fatalf("Controller accepted an AGENT certificate as a SCHEDULER one")
+	}
+
+	if err := dialPeerTLS(t, address, NETAGENT, AGENT); err == nil {
+		t.Fatalf("NETAGENT accepted as a Controller")
+	}
+}
+
 func TestEventStringer(t *testing.T) {
 	var stringTests = []struct {
 		evt      Event
@@ -2520,6 +3402,10 @@ func TestEventStringer(t *testing.T) {
 		{TraceReport, "Trace Report"},
 		{NodeConnected, "Node Connected"},
 		{NodeDisconnected, "Node Disconnected"},
+		{MigrationTargetReady, "Migration Target Ready"},
+		{InstanceMigrated, "Instance Migrated"},
+		{ConsoleLog, "Console Log"},
+		{ConsoleReady, "Console Ready"},
 	}
 
 	for _, test := range stringTests {
@@ -2543,6 +3429,16 @@ func TestErrorStringer(t *testing.T) {
 		{DeleteFailure, "Could not delete instance"},
 		{ConnectionAborted, "SSNTP Connection aborted"},
 		{InvalidConfiguration, "Cluster configuration is invalid"},
+		{UnauthorizedFrame, "Frame not authorized for SSNTP role"},
+		{RebootFailure, "Could not reboot instance"},
+		{PauseFailure, "Could not pause instance"},
+		{UnpauseFailure, "Could not unpause instance"},
+		{SuspendFailure, "Could not suspend instance"},
+		{ResumeFailure, "Could not resume instance"},
+		{MigrateFailure, "Could not migrate instance"},
+		{ConsoleFailure, "Could not access instance console"},
+		{ExtendVolumeFailure, "Could not extend volume"},
+		{DetachVolumeFailure, "Could not detach volume"},
 	}
 
 	for _, test := range stringTests {
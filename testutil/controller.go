@@ -270,7 +270,7 @@ func (ctl *SsntpTestController) CommandNotify(command ssntp.Command, frame *ssnt
 
 		stats.Init()
 
-		err := yaml.Unmarshal(frame.Payload, &stats)
+		err := payloads.Unmarshal(frame.Payload, &stats)
 		if err != nil {
 			result.Err = err
 		}
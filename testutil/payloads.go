@@ -87,6 +87,9 @@ const DockerImage = "docker/latest"
 // InstanceUUID is an instance UUID for use in start/stop/restart/delete tests
 const InstanceUUID = "3390740c-dce9-48d6-b83a-a717417072ce"
 
+// WorkloadUUID is a workload UUID for use in start tests
+const WorkloadUUID = "ab68111c-03a6-11e6-87de-001320fb6e31"
+
 // CNCIInstanceUUID is a CNCI instance UUID for use in start/stop/restart/delete tests
 const CNCIInstanceUUID = "c6beb8b5-0bfc-43fd-9638-7dd788179fd8"
 
@@ -286,6 +289,62 @@ const MigrateYaml = `delete:
   stop: true
 `
 
+// RebootYaml is a sample workload REBOOT ssntp.Command payload for test cases
+const RebootYaml = `reboot:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+  hard: true
+`
+
+// PauseYaml is a sample workload PAUSE ssntp.Command payload for test cases
+const PauseYaml = `pause:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+`
+
+// SuspendYaml is a sample workload SUSPEND ssntp.Command payload for test cases
+const SuspendYaml = `suspend:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+`
+
+// PowerFailureYaml is a sample PauseFailure ssntp.Error payload for test cases
+const PowerFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+reason: invalid_state
+`
+
+// MigrateURI is a sample live migration URI for test cases
+const MigrateURI = "tcp:198.51.100.2:49152"
+
+// LiveMigrateYaml is a sample workload MIGRATE ssntp.Command payload for test cases
+const LiveMigrateYaml = `migrate:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+  target_agent_uuid: ` + NetAgentUUID + `
+  uri: ` + MigrateURI + `
+`
+
+// MigrateFailureYaml is a sample MigrateFailure ssntp.Error payload for test cases
+const MigrateFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+reason: transfer_failure
+`
+
+// ConsoleLogCmdYaml is a sample workload CONSOLE ssntp.Command payload for test cases
+const ConsoleLogCmdYaml = `console:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+  type: log
+  lines: 20
+`
+
+// ConsoleFailureYaml is a sample ConsoleFailure ssntp.Error payload for test cases
+const ConsoleFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+reason: not_supported
+`
+
 // EvacuateYaml is a sample node EVACUATE ssntp.Command payload for test cases
 const EvacuateYaml = `evacuate:
   workload_agent_uuid: ` + AgentUUID + `
@@ -407,6 +466,43 @@ const InsStopYaml = `instance_stopped:
   instance_uuid: ` + InstanceUUID + `
 `
 
+// MigrationTargetReadyYaml is a sample MigrationTargetReady ssntp.Event payload for test cases
+const MigrationTargetReadyYaml = `migration_target_ready:
+  instance_uuid: ` + InstanceUUID + `
+  node_uuid: ` + NetAgentUUID + `
+  uri: ` + MigrateURI + `
+`
+
+// InstanceMigratedYaml is a sample InstanceMigrated ssntp.Event payload for test cases
+const InstanceMigratedYaml = `instance_migrated:
+  instance_uuid: ` + InstanceUUID + `
+  node_uuid: ` + AgentUUID + `
+  target_node_uuid: ` + NetAgentUUID + `
+`
+
+// ConsoleAddress is a sample interactive console address for test cases
+const ConsoleAddress = "198.51.100.2:34567"
+
+// ConsoleToken is a sample interactive console token for test cases
+const ConsoleToken = "5b8e2b6cf3a24b1e"
+
+// ConsoleLogYaml is a sample ConsoleLog ssntp.Event payload for test cases
+const ConsoleLogYaml = `console_log:
+  instance_uuid: ` + InstanceUUID + `
+  node_uuid: ` + AgentUUID + `
+  log: |
+    Booting from Hard Disk...
+    login:
+`
+
+// ConsoleReadyYaml is a sample ConsoleReady ssntp.Event payload for test cases
+const ConsoleReadyYaml = `console_ready:
+  instance_uuid: ` + InstanceUUID + `
+  node_uuid: ` + AgentUUID + `
+  address: ` + ConsoleAddress + `
+  token: ` + ConsoleToken + `
+`
+
 // NodeConnectedYaml is a sample node NodeConnected ssntp.Event payload for test cases
 const NodeConnectedYaml = `node_connected:
   node_uuid: ` + AgentUUID + `
@@ -437,6 +533,7 @@ disk_total_mb: 500000
 disk_available_mb: 256000
 load: 0
 cpus_online: 4
+vcpus_allocated: 2
 networks:
 - ip: 192.168.1.1
   mac: 02:00:15:03:6f:49
@@ -598,3 +695,37 @@ instance_uuid: ` + InstanceUUID + `
 volume_uuid: ` + VolumeUUID + `
 reason: attach_failure
 `
+
+// DetachVolumeYaml is a sample yaml payload for the ssntp Detach Volume command.
+const DetachVolumeYaml = `detach_volume:
+  instance_uuid: ` + InstanceUUID + `
+  volume_uuid: ` + VolumeUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+`
+
+// BadDetachVolumeYaml is a corrupt yaml payload for the ssntp Detach Volume command.
+const BadDetachVolumeYaml = `detach_volume:
+  volume_uuid: ` + VolumeUUID + `
+`
+
+// DetachVolumeFailureYaml is a sample DetachVolumeFailure ssntp.Error payload for test cases
+const DetachVolumeFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+volume_uuid: ` + VolumeUUID + `
+reason: detach_failure
+`
+
+// ExtendVolumeYaml is a sample yaml payload for the ssntp Extend Volume command.
+const ExtendVolumeYaml = `extend_volume:
+  instance_uuid: ` + InstanceUUID + `
+  volume_uuid: ` + VolumeUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+  size_gib: 20
+`
+
+// ExtendVolumeFailureYaml is a sample ExtendVolumeFailure ssntp.Error payload for test cases
+const ExtendVolumeFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+volume_uuid: ` + VolumeUUID + `
+reason: resize_failure
+`
@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package testutil
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ciao-project/ciao/ssntp"
+)
+
+// LoadCapture reads the frames recorded in an SSNTP capture file, as
+// written by SSNTP clients and servers configured with ssntp.Config.Capture.
+func LoadCapture(path string) ([]ssntp.CapturedFrame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return ssntp.ReadCapture(f)
+}
+
+// replayable tells if a captured frame going in direction should be
+// replayed. Connection and keepalive frames are generated by the SSNTP
+// library itself and are never replayed.
+func replayable(frame *ssntp.CapturedFrame, direction ssntp.CaptureDirection) bool {
+	if frame.Direction != direction {
+		return false
+	}
+
+	switch frame.Operand {
+	case ssntp.CONNECT.String(), ssntp.PING.String(),
+		ssntp.CONNECTED.String(), ssntp.PONG.String():
+		return false
+	}
+
+	return true
+}
+
+// Replay sends, in order, the frames a captured SSNTP client sent.
+// This allows for feeding an SSNTP server with the exact frame sequence
+// a field node sent, e.g. to reproduce a scheduler or controller bug.
+func (client *SsntpTestClient) Replay(frames []ssntp.CapturedFrame) error {
+	for i := range frames {
+		if !replayable(&frames[i], ssntp.CaptureTx) {
+			continue
+		}
+
+		_, err := client.Ssntp.Replay(&frames[i])
+		if err != nil {
+			return fmt.Errorf("Could not replay frame #%d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Replay sends, in order, the frames a captured SSNTP client received to
+// the client which UUID is uuid. This allows for feeding e.g. a launcher
+// with the exact frame sequence a field node received.
+func (server *SsntpTestServer) Replay(uuid string, frames []ssntp.CapturedFrame) error {
+	for i := range frames {
+		if !replayable(&frames[i], ssntp.CaptureRx) {
+			continue
+		}
+
+		_, err := server.Ssntp.Replay(uuid, &frames[i])
+		if err != nil {
+			return fmt.Errorf("Could not replay frame #%d: %v", i, err)
+		}
+	}
+
+	return nil
+}
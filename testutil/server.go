@@ -325,6 +325,30 @@ func getAttachVolumeResult(payload []byte, result *Result) {
 	}
 }
 
+func getDetachVolumeResult(payload []byte, result *Result) {
+	var volCmd payloads.DetachVolume
+
+	err := yaml.Unmarshal(payload, &volCmd)
+	result.Err = err
+	if err == nil {
+		result.NodeUUID = volCmd.Detach.WorkloadAgentUUID
+		result.InstanceUUID = volCmd.Detach.InstanceUUID
+		result.VolumeUUID = volCmd.Detach.VolumeUUID
+	}
+}
+
+func getExtendVolumeResult(payload []byte, result *Result) {
+	var volCmd payloads.ExtendVolume
+
+	err := yaml.Unmarshal(payload, &volCmd)
+	result.Err = err
+	if err == nil {
+		result.NodeUUID = volCmd.Extend.WorkloadAgentUUID
+		result.InstanceUUID = volCmd.Extend.InstanceUUID
+		result.VolumeUUID = volCmd.Extend.VolumeUUID
+	}
+}
+
 func getStartResults(payload []byte, result *Result) {
 	var startCmd payloads.Start
 	var nn bool
@@ -389,6 +413,7 @@ func (server *SsntpTestServer) CommandNotify(uuid string, command ssntp.Command,
 		result.Err = err
 		if err == nil {
 			result.InstanceUUID = delCmd.Delete.InstanceUUID
+			result.NodeUUID = delCmd.Delete.WorkloadAgentUUID
 			server.Ssntp.SendCommand(delCmd.Delete.WorkloadAgentUUID, command, frame.Payload)
 		}
 
@@ -401,12 +426,18 @@ func (server *SsntpTestServer) CommandNotify(uuid string, command ssntp.Command,
 	case ssntp.STATS:
 		var statsCmd payloads.Stat
 
-		err := yaml.Unmarshal(payload, &statsCmd)
+		err := payloads.Unmarshal(payload, &statsCmd)
 		result.Err = err
 
 	case ssntp.AttachVolume:
 		getAttachVolumeResult(payload, &result)
 
+	case ssntp.DetachVolume:
+		getDetachVolumeResult(payload, &result)
+
+	case ssntp.ExtendVolume:
+		getExtendVolumeResult(payload, &result)
+
 	default:
 		fmt.Fprintf(os.Stderr, "server unhandled command %s\n", command.String())
 	}
@@ -601,6 +632,27 @@ func (server *SsntpTestServer) handleAttachVolume(payload []byte) ssntp.ForwardD
 	return dest
 }
 
+func (server *SsntpTestServer) handleDetachVolume(payload []byte) ssntp.ForwardDestination {
+	var cmd payloads.DetachVolume
+	var dest ssntp.ForwardDestination
+
+	err := yaml.Unmarshal(payload, &cmd)
+	if err != nil {
+		return dest
+	}
+
+	server.clientsLock.Lock()
+	defer server.clientsLock.Unlock()
+
+	for _, c := range server.clients {
+		if c == cmd.Detach.WorkloadAgentUUID {
+			dest.AddRecipient(c)
+		}
+	}
+
+	return dest
+}
+
 // CommandForward implements an SSNTP CommandForward callback for SsntpTestServer
 func (server *SsntpTestServer) CommandForward(uuid string, command ssntp.Command, frame *ssntp.Frame) (dest ssntp.ForwardDestination) {
 	payload := frame.Payload
@@ -610,6 +662,8 @@ func (server *SsntpTestServer) CommandForward(uuid string, command ssntp.Command
 		dest = server.handleStart(payload)
 	case ssntp.AttachVolume:
 		dest = server.handleAttachVolume(payload)
+	case ssntp.DetachVolume:
+		dest = server.handleDetachVolume(payload)
 	case ssntp.EVACUATE:
 		fallthrough
 	case ssntp.DELETE:
@@ -685,6 +739,10 @@ func StartTestServer() *SsntpTestServer {
 				Operand: ssntp.AttachVolumeFailure,
 				Dest:    ssntp.Controller,
 			},
+			{ // all DetachVolumeFailure errors go to all Controllers
+				Operand: ssntp.DetachVolumeFailure,
+				Dest:    ssntp.Controller,
+			},
 			{ // all PublicIPAssigned events go to all Controllers
 				Operand: ssntp.PublicIPAssigned,
 				Dest:    ssntp.Controller,
@@ -713,6 +771,10 @@ func StartTestServer() *SsntpTestServer {
 				Operand:        ssntp.AttachVolume,
 				CommandForward: server,
 			},
+			{ // all DetachVolume commands are processed by the Command forwarder
+				Operand:        ssntp.DetachVolume,
+				CommandForward: server,
+			},
 		},
 	}
 